@@ -8,13 +8,19 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/chzyer/readline"
@@ -24,11 +30,16 @@ import (
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/cost"
 	"github.com/sipeed/picoclaw/pkg/cron"
+	"github.com/sipeed/picoclaw/pkg/eval"
 	"github.com/sipeed/picoclaw/pkg/heartbeat"
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/metrics"
 	"github.com/sipeed/picoclaw/pkg/skills"
 	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/tracing"
+	"github.com/sipeed/picoclaw/pkg/tui"
 	"github.com/sipeed/picoclaw/pkg/voice"
+	"golang.org/x/term"
 )
 
 const version = "0.1.0"
@@ -36,6 +47,9 @@ const logo = "🦞"
 
 var customConfigPath string
 
+// debugLogging is set by --debug and overrides log.level.
+var debugLogging bool
+
 func copyDirectory(src, dst string) error {
 	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -102,6 +116,12 @@ func main() {
 		statusCmd()
 	case "cron":
 		cronCmd()
+	case "config":
+		configCmd()
+	case "logs":
+		logsCmd()
+	case "eval":
+		evalCmd()
 	case "skills":
 		if len(os.Args) < 3 {
 			skillsHelp()
@@ -173,6 +193,9 @@ func printHelp() {
 	fmt.Println("  gateway     Start picoclaw gateway")
 	fmt.Println("  status      Show picoclaw status")
 	fmt.Println("  cron        Manage scheduled tasks")
+	fmt.Println("  config      Validate, show and edit config.json")
+	fmt.Println("  logs        Show and follow the log file")
+	fmt.Println("  eval        Replay sessions and eval cases against the agent")
 	fmt.Println("  skills      Manage skills (install, list, remove)")
 	fmt.Println("  version     Show version information")
 }
@@ -371,11 +394,13 @@ Discussions: https://github.com/sipeed/picoclaw/discussions
 func agentCmd() {
 	message := ""
 	sessionKey := "cli:default"
+	plain := false
 
 	args := os.Args[2:]
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
 		case "--debug", "-d":
+			debugLogging = true
 			logger.SetLevel(logger.DEBUG)
 			fmt.Println("🔍 Debug mode enabled")
 		case "-m", "--message":
@@ -388,8 +413,11 @@ func agentCmd() {
 				sessionKey = args[i+1]
 				i++
 			}
+		case "--plain":
+			plain = true
 		}
 	}
+	useTUI := message == "" && !plain && term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
 
 	cfg, err := loadConfig()
 	if err != nil {
@@ -397,6 +425,14 @@ func agentCmd() {
 		os.Exit(1)
 	}
 
+	if useTUI {
+		// Console logs would draw over the TUI; the log file still has them
+		logger.SetConsoleOutput(io.Discard)
+	}
+	setupLogging(cfg)
+	stopTracing := setupTracing(cfg)
+	defer stopTracing()
+
 	msgBus := bus.NewMessageBus()
 	agentLoop, err := agent.NewAgentLoop(cfg, msgBus)
 	if err != nil {
@@ -421,18 +457,33 @@ func agentCmd() {
 			os.Exit(1)
 		}
 		fmt.Printf("\n%s %s\n", logo, response)
+	} else if useTUI {
+		err := tui.Run(context.Background(), tui.NewBackend(agentLoop), tui.Options{
+			Session:     sessionKey,
+			HistoryPath: historyPath("history.jsonl"),
+		})
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
 	} else {
 		fmt.Printf("%s Interactive mode (Ctrl+C to exit)\n\n", logo)
 		interactiveMode(agentLoop, sessionKey)
 	}
 }
 
+// historyPath places input history next to the config file, so it
+// survives reboots and follows --config.
+func historyPath(name string) string {
+	return filepath.Join(filepath.Dir(getConfigPath()), name)
+}
+
 func interactiveMode(agentLoop *agent.AgentLoop, sessionKey string) {
 	prompt := fmt.Sprintf("%s You: ", logo)
 
 	rl, err := readline.NewEx(&readline.Config{
 		Prompt:          prompt,
-		HistoryFile:     filepath.Join(os.TempDir(), ".picoclaw_history"),
+		HistoryFile:     historyPath("history"),
 		HistoryLimit:    100,
 		InterruptPrompt: "^C",
 		EOFPrompt:       "exit",
@@ -518,6 +569,7 @@ func gatewayCmd() {
 	args := os.Args[2:]
 	for _, arg := range args {
 		if arg == "--debug" || arg == "-d" {
+			debugLogging = true
 			logger.SetLevel(logger.DEBUG)
 			fmt.Println("🔍 Debug mode enabled")
 			break
@@ -530,6 +582,10 @@ func gatewayCmd() {
 		os.Exit(1)
 	}
 
+	setupLogging(cfg)
+	stopTracing := setupTracing(cfg)
+	defer stopTracing()
+
 	msgBus := bus.NewMessageBus()
 	agentLoop, err := agent.NewAgentLoop(cfg, msgBus)
 	if err != nil {
@@ -563,22 +619,39 @@ func gatewayCmd() {
 		cfg.Heartbeat.IntervalSeconds,
 		cfg.Heartbeat.Enabled,
 	)
-	// Resolve heartbeat delivery target from channel's allow_from
 	if cfg.Heartbeat.Enabled {
-		heartbeatChannel := cfg.Heartbeat.Channel
-		allowFrom := cfg.GetChannelAllowFrom(heartbeatChannel)
-		if heartbeatChannel == "" || len(allowFrom) == 0 {
-			fmt.Printf("Warning: heartbeat enabled but channel '%s' has no allow_from configured, disabling heartbeat\n", heartbeatChannel)
+		items, err := heartbeat.ItemsFromConfig(cfg.Heartbeat)
+		if err != nil {
+			fmt.Printf("Warning: invalid heartbeat config, disabling heartbeat: %v\n", err)
 			heartbeatService = heartbeat.NewHeartbeatService(cfg.WorkspacePath(), cfg.Heartbeat.IntervalSeconds, false)
 		} else {
-			heartbeatChatID := allowFrom[0]
-			heartbeatService.SetOnHeartbeat(func(prompt string) (string, error) {
-				return agentLoop.ProcessDirectWithChannel(
+			// Items without explicit targets deliver to the heartbeat
+			// channel's first allow_from entry.
+			heartbeatChannel := cfg.Heartbeat.Channel
+			var heartbeatChatID string
+			if allowFrom := cfg.GetChannelAllowFrom(heartbeatChannel); heartbeatChannel != "" && len(allowFrom) > 0 {
+				heartbeatChatID = allowFrom[0]
+			}
+			missingTarget := heartbeatChatID == "" && len(items) == 0
+			for _, item := range items {
+				if len(item.Targets) == 0 && heartbeatChatID == "" {
+					missingTarget = true
+				}
+			}
+			if missingTarget {
+				fmt.Printf("Warning: heartbeat channel '%s' has no allow_from configured; items without targets will not be delivered\n", heartbeatChannel)
+			}
+
+			heartbeatService.SetItems(items)
+			heartbeatService.SetDedupWindow(time.Duration(cfg.Heartbeat.DedupWindowMinutes) * time.Minute)
+			heartbeatService.SetOnHeartbeat(func(agentID, prompt, sessionKey, channel, chatID string) (string, error) {
+				return agentLoop.ProcessDirectForAgent(
 					context.Background(),
+					agentID,
 					prompt,
-					"heartbeat:system",
-					heartbeatChannel,
-					heartbeatChatID,
+					sessionKey,
+					channel,
+					chatID,
 				)
 			})
 			heartbeatService.SetDelivery(msgBus, heartbeatChannel, heartbeatChatID)
@@ -591,25 +664,11 @@ func gatewayCmd() {
 		os.Exit(1)
 	}
 
-	var transcriber *voice.GroqTranscriber
-	if groqCfg := cfg.GetProviderConfig("groq"); groqCfg != nil && groqCfg.APIKey != "" {
-		transcriber = voice.NewGroqTranscriber(groqCfg.APIKey)
-		logger.InfoC("voice", "Groq voice transcription enabled")
-	}
-
-	if transcriber != nil {
-		if telegramChannel, ok := channelManager.GetChannel("telegram"); ok {
-			if tc, ok := telegramChannel.(*channels.TelegramChannel); ok {
-				tc.SetTranscriber(transcriber)
-				logger.InfoC("voice", "Groq transcription attached to Telegram channel")
-			}
-		}
-		if discordChannel, ok := channelManager.GetChannel("discord"); ok {
-			if dc, ok := discordChannel.(*channels.DiscordChannel); ok {
-				dc.SetTranscriber(transcriber)
-				logger.InfoC("voice", "Groq transcription attached to Discord channel")
-			}
-		}
+	if transcriber := voice.NewTranscriberFromConfig(cfg); transcriber != nil {
+		attached := channelManager.SetTranscriber(transcriber)
+		logger.InfoCF("voice", "Voice transcription enabled", map[string]interface{}{
+			"channels": attached,
+		})
 	}
 
 	enabledChannels := channelManager.GetEnabledChannels()
@@ -619,12 +678,46 @@ func gatewayCmd() {
 		fmt.Println("⚠ Warning: No channels enabled")
 	}
 
-	fmt.Printf("✓ Gateway started on %s:%d\n", cfg.Gateway.Host, cfg.Gateway.Port)
-	fmt.Println("Press Ctrl+C to stop")
-
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	mux := http.NewServeMux()
+	mux.Handle(cron.WebhookPathPrefix, cronService.WebhookHandler())
+	if cfg.Gateway.Metrics {
+		mux.Handle("/metrics", metrics.Handler())
+	}
+	gatewayServer := &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", cfg.Gateway.Host, cfg.Gateway.Port),
+		Handler: mux,
+	}
+	// The HTTP server only serves metrics and webhook jobs, so it is not
+	// started until one of them needs it
+	var serveOnce sync.Once
+	serveGateway := func() {
+		serveOnce.Do(func() {
+			logger.InfoCF("gateway", "Gateway HTTP server listening", map[string]interface{}{
+				"addr": gatewayServer.Addr,
+			})
+			go func() {
+				if err := gatewayServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					logger.ErrorCF("gateway", "Gateway HTTP server failed", map[string]interface{}{
+						"addr":  gatewayServer.Addr,
+						"error": err.Error(),
+					})
+				}
+			}()
+		})
+	}
+	cronService.SetOnWebhookJob(serveGateway)
+
+	if cfg.Gateway.Metrics || cronService.HasWebhookJobs() {
+		serveGateway()
+		fmt.Printf("✓ Gateway started on %s:%d\n", cfg.Gateway.Host, cfg.Gateway.Port)
+	} else {
+		fmt.Println("✓ Gateway started")
+	}
+	fmt.Println("Press Ctrl+C to stop")
+
 	if err := cronService.Start(); err != nil {
 		fmt.Printf("Error starting cron service: %v\n", err)
 	}
@@ -641,12 +734,40 @@ func gatewayCmd() {
 
 	go agentLoop.Run(ctx)
 
+	// Hot reload on save or SIGHUP. Reloads run one at a time, each against
+	// the config the previous one installed; cfg itself is never modified.
+	var reloadMu sync.Mutex
+	current := cfg
+	reload := func() {
+		reloadMu.Lock()
+		defer reloadMu.Unlock()
+		if next := reloadConfig(ctx, current, agentLoop, channelManager); next != nil {
+			current = next
+		}
+	}
+	if watcher, err := config.WatchFile(getConfigPath(), reload); err != nil {
+		logger.WarnCF("config", "Config file watching disabled", map[string]interface{}{"error": err.Error()})
+	} else {
+		defer watcher.Close()
+	}
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	go func() {
+		for range hupChan {
+			reload()
+		}
+	}()
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt)
 	<-sigChan
+	signal.Stop(hupChan)
 
 	fmt.Println("\nShutting down...")
 	cancel()
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	gatewayServer.Shutdown(shutdownCtx)
+	shutdownCancel()
 	heartbeatService.Stop()
 	cronService.Stop()
 	agentLoop.Stop()
@@ -655,6 +776,41 @@ func gatewayCmd() {
 	fmt.Println("✓ Gateway stopped")
 }
 
+// reloadConfig re-reads config.json and applies what changed compared to
+// cfg. It returns the new config once applied. An invalid config is logged,
+// the running config is kept and nil is returned.
+func reloadConfig(ctx context.Context, cfg *config.Config, agentLoop *agent.AgentLoop, channelManager *channels.Manager) *config.Config {
+	configPath := getConfigPath()
+	if _, err := os.Stat(configPath); err != nil {
+		logger.WarnCF("config", "Config reload skipped", map[string]interface{}{"error": err.Error()})
+		return nil
+	}
+	newCfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		logger.ErrorCF("config", "Config reload failed, keeping current config", map[string]interface{}{"error": err.Error()})
+		return nil
+	}
+
+	changes := config.Diff(cfg, newCfg)
+	if changes.Empty() {
+		return nil
+	}
+	if err := agentLoop.Reload(newCfg, changes); err != nil {
+		logger.ErrorCF("config", "Config reload failed, keeping current config", map[string]interface{}{"error": err.Error()})
+		return nil
+	}
+	channelManager.Reload(ctx, newCfg, changes)
+	if changes.Log {
+		setupLogging(newCfg)
+	}
+
+	logger.InfoCF("config", "Config reloaded", map[string]interface{}{"changed": changes.Sections()})
+	if len(changes.RestartRequired) > 0 {
+		logger.WarnCF("config", "Some changes take effect after a restart", map[string]interface{}{"sections": changes.RestartRequired})
+	}
+	return newCfg
+}
+
 func statusCmd() {
 	cfg, err := loadConfig()
 	if err != nil {
@@ -743,6 +899,102 @@ func getConfigPath() string {
 	return filepath.Join(home, ".picoclaw", "config.json")
 }
 
+// setupTracing installs the span exporters from cfg.Tracing. The returned
+// function flushes pending spans and should run on shutdown.
+// setupLogging applies the log section: levels, caller recording and the
+// rotating log file. It also routes log/slog and the standard log package
+// through the logger. It is called again when the section is reloaded.
+func setupLogging(cfg *config.Config) {
+	lc := cfg.Log
+	level, err := logger.ParseLevel(lc.Level)
+	if err != nil || lc.Level == "" {
+		level = logger.INFO
+	}
+	if debugLogging {
+		level = logger.DEBUG
+	}
+	logger.SetLevel(level)
+	components := make(map[string]logger.LogLevel, len(lc.Components))
+	for name, l := range lc.Components {
+		if cl, err := logger.ParseLevel(l); err == nil {
+			components[name] = cl
+		}
+	}
+	logger.SetComponentLevels(components)
+	logger.SetCaller(lc.Caller)
+	logger.InstallSlog("lib")
+
+	path := logFilePath(cfg)
+	if path == "" {
+		logger.DisableFileLogging()
+		return
+	}
+	opts := logger.RotateOptions{
+		MaxSizeMB:  lc.MaxSizeMB,
+		MaxBackups: lc.MaxBackups,
+		MaxAge:     time.Duration(lc.MaxAgeDays) * 24 * time.Hour,
+	}
+	switch lc.Rotate {
+	case "daily":
+		opts.Interval = 24 * time.Hour
+	case "hourly":
+		opts.Interval = time.Hour
+	}
+	if err := logger.EnableRotatingFileLogging(path, opts); err != nil {
+		logger.ErrorCF("logger", "Failed to open log file", map[string]interface{}{"path": path, "error": err.Error()})
+	}
+}
+
+// logFilePath resolves log.file against the config directory, keeping
+// logs out of the workspace the agent can read; "" means file logging is
+// off.
+func logFilePath(cfg *config.Config) string {
+	return cfg.ResolvePath(cfg.Log.File)
+}
+
+func setupTracing(cfg *config.Config) func() {
+	tc := cfg.Tracing
+	if !tc.Enabled {
+		return func() {}
+	}
+	serviceName := tc.ServiceName
+	if serviceName == "" {
+		serviceName = "picoclaw"
+	}
+	var exporters []tracing.Exporter
+	if tc.OTLPEndpoint != "" {
+		exporters = append(exporters, tracing.NewOTLPExporter(tc.OTLPEndpoint, serviceName, tc.OTLPHeaders))
+	}
+	if tc.File != "" {
+		path := tc.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(cfg.WorkspacePath(), path)
+		}
+		fileExporter, err := tracing.NewFileExporter(path)
+		if err != nil {
+			logger.ErrorCF("tracing", "Failed to open trace file", map[string]interface{}{"path": path, "error": err.Error()})
+		} else {
+			exporters = append(exporters, fileExporter)
+		}
+	}
+	if len(exporters) == 0 {
+		return func() {}
+	}
+
+	tracer := tracing.NewTracer(5*time.Second, 256, exporters...)
+	tracing.SetTracer(tracer)
+	logger.InfoCF("tracing", "Tracing enabled", map[string]interface{}{
+		"otlp_endpoint": tc.OTLPEndpoint,
+		"file":          tc.File,
+	})
+	return func() {
+		tracing.SetTracer(nil)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		tracer.Shutdown(ctx)
+	}
+}
+
 func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, workspace string) *cron.CronService {
 	cronStorePath := filepath.Join(workspace, "cron", "jobs.json")
 
@@ -750,9 +1002,18 @@ func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, workspace
 	cronService := cron.NewCronService(cronStorePath, nil)
 
 	// Create and register CronTool
-	cronTool := tools.NewCronTool(cronService, agentLoop, msgBus)
+	cronTool := tools.NewCronTool(cronService, agentLoop, msgBus, workspace)
+	cronTool.SetOutboundFilter(agentLoop.FilterOutbound)
 	agentLoop.RegisterTool(cronTool)
 
+	// Expose jobs to the /jobs chat command
+	agentLoop.SetCronService(cronService)
+
+	// Feed chat messages and memory writes to event-triggered jobs
+	agentLoop.SetEventSink(func(ev cron.Event) {
+		cronService.Emit(ev)
+	})
+
 	// Set the onJob handler
 	cronService.SetOnJob(func(job *cron.CronJob) (string, error) {
 		result := cronTool.ExecuteJob(context.Background(), job)
@@ -787,7 +1048,7 @@ func cronCmd() {
 	case "list":
 		cronListCmd(cronStorePath)
 	case "add":
-		cronAddCmd(cronStorePath)
+		cronAddCmd(cronStorePath, cfg.WorkspacePath())
 	case "remove":
 		if len(os.Args) < 4 {
 			fmt.Println("Usage: picoclaw cron remove <job_id>")
@@ -804,6 +1065,505 @@ func cronCmd() {
 	}
 }
 
+func configCmd() {
+	if len(os.Args) < 3 {
+		configHelp()
+		return
+	}
+
+	subcommand := os.Args[2]
+	args := os.Args[3:]
+	reveal, force := false, false
+	var positional []string
+	for _, arg := range args {
+		switch arg {
+		case "--reveal":
+			reveal = true
+		case "--force":
+			force = true
+		default:
+			positional = append(positional, arg)
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch subcommand {
+	case "validate":
+		errs := cfg.Validate()
+		if len(errs) == 0 {
+			fmt.Printf("✓ %s is valid\n", getConfigPath())
+			return
+		}
+		fmt.Printf("✗ %s has %d problem(s):\n", getConfigPath(), len(errs))
+		for _, e := range errs {
+			fmt.Printf("  %s\n", e)
+		}
+		os.Exit(1)
+	case "show":
+		values, err := cfg.Values(reveal)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		printJSON(values)
+	case "get":
+		if len(positional) != 1 {
+			fmt.Println("Usage: picoclaw config get <path> [--reveal]")
+			return
+		}
+		values, err := cfg.Values(reveal)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		value, err := config.LookupPath(values, positional[0])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if str, ok := value.(string); ok {
+			fmt.Println(str)
+		} else {
+			printJSON(value)
+		}
+	case "set":
+		if len(positional) != 2 {
+			fmt.Println("Usage: picoclaw config set <path> <value> [--force]")
+			return
+		}
+		before := make(map[string]bool)
+		for _, e := range cfg.Validate() {
+			before[e.Error()] = true
+		}
+		if err := cfg.SetPath(positional[0], positional[1]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		var introduced []config.ValidationError
+		for _, e := range cfg.Validate() {
+			if !before[e.Error()] {
+				introduced = append(introduced, e)
+			}
+		}
+		if len(introduced) > 0 {
+			fmt.Println("✗ This change makes the config invalid:")
+			for _, e := range introduced {
+				fmt.Printf("  %s\n", e)
+			}
+			if !force {
+				fmt.Println("Not saved. Use --force to save anyway.")
+				os.Exit(1)
+			}
+		}
+		// The change is validated against the effective config but saved to
+		// the file as written, so env overrides and provider defaults stay out
+		raw, err := config.LoadRawConfig(getConfigPath())
+		if err == nil {
+			err = raw.SetPath(positional[0], positional[1])
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := config.SaveConfig(getConfigPath(), raw); err != nil {
+			fmt.Printf("Error saving config: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Set %s\n", positional[0])
+	case "diff":
+		changes, err := cfg.DiffDefaults()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(changes) == 0 {
+			fmt.Println("No changes from the defaults.")
+			return
+		}
+		for _, c := range changes {
+			switch {
+			case c.Modified:
+				fmt.Printf("~ %s: %s -> %s\n", c.Path, compactJSON(c.Default), compactJSON(c.Current))
+			case c.Current != nil:
+				fmt.Printf("+ %s: %s\n", c.Path, compactJSON(c.Current))
+			default:
+				fmt.Printf("- %s: %s\n", c.Path, compactJSON(c.Default))
+			}
+		}
+	default:
+		fmt.Printf("Unknown config command: %s\n", subcommand)
+		configHelp()
+	}
+}
+
+func configHelp() {
+	fmt.Println("\nConfig commands:")
+	fmt.Println("  validate              Check config.json and list every problem")
+	fmt.Println("  show [--reveal]       Print the effective config (secrets masked)")
+	fmt.Println("  get <path> [--reveal] Print one setting, e.g. agents.list[0].model")
+	fmt.Println("  set <path> <value>    Change one setting and save (use --force to save an invalid config)")
+	fmt.Println("  diff                  Show settings that differ from the defaults")
+}
+
+func logsCmd() {
+	query := logger.Query{Fields: make(map[string]string)}
+	lines, follow, raw := 50, false, false
+	path := ""
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		value := func() string {
+			if i+1 >= len(args) {
+				fmt.Printf("Missing value for %s\n", arg)
+				os.Exit(1)
+			}
+			i++
+			return args[i]
+		}
+		switch arg {
+		case "-n", "--lines":
+			n, err := strconv.Atoi(value())
+			if err != nil || n < 0 {
+				fmt.Println("--lines must be a non-negative number")
+				os.Exit(1)
+			}
+			lines = n
+		case "-f", "--follow":
+			follow = true
+		case "-l", "--level":
+			level, err := logger.ParseLevel(value())
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			query.MinLevel = level
+		case "--component":
+			query.Components = append(query.Components, value())
+		case "--trace":
+			query.Fields["trace_id"] = value()
+		case "--session":
+			query.Fields["session_key"] = value()
+		case "--agent":
+			query.Fields["agent_id"] = value()
+		case "-g", "--grep":
+			query.Contains = value()
+		case "--since":
+			d, err := time.ParseDuration(value())
+			if err != nil {
+				fmt.Printf("Error: --since: %v\n", err)
+				os.Exit(1)
+			}
+			query.Since = time.Now().Add(-d)
+		case "--json":
+			raw = true
+		case "--file":
+			path = value()
+		case "-h", "--help", "help":
+			logsHelp()
+			return
+		default:
+			fmt.Printf("Unknown option: %s\n", arg)
+			logsHelp()
+			os.Exit(1)
+		}
+	}
+
+	if path == "" {
+		cfg, err := loadConfig()
+		if err != nil {
+			fmt.Printf("Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+		path = logFilePath(cfg)
+		if path == "" {
+			fmt.Println("File logging is off (log.file is empty).")
+			os.Exit(1)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	printLine := func(line []byte) {
+		entry, err := logger.ParseEntry(line)
+		if err != nil || !query.Match(entry) {
+			return
+		}
+		if raw {
+			fmt.Println(string(line))
+		} else {
+			fmt.Println(logger.FormatEntry(entry))
+		}
+	}
+
+	// Keep the last n matching lines
+	var tail [][]byte
+	reader := bufio.NewReader(f)
+	offset := int64(0)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 && line[len(line)-1] == '\n' {
+			offset += int64(len(line))
+			if entry, perr := logger.ParseEntry(line); perr == nil && query.Match(entry) && lines > 0 {
+				tail = append(tail, line[:len(line)-1])
+				if len(tail) > lines {
+					tail = tail[1:]
+				}
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	for _, line := range tail {
+		printLine(line)
+	}
+	if !follow {
+		return
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	var partial []byte
+	for {
+		select {
+		case <-sigChan:
+			return
+		case <-ticker.C:
+		}
+		// Reopen after rotation: the path now names a new, smaller file
+		if info, err := os.Stat(path); err == nil {
+			if cur, err := f.Stat(); err == nil && (!os.SameFile(info, cur) || info.Size() < offset) {
+				if nf, err := os.Open(path); err == nil {
+					f.Close()
+					f, offset, partial = nf, 0, nil
+				}
+			}
+		}
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			continue
+		}
+		data, _ := io.ReadAll(f)
+		offset += int64(len(data))
+		data = append(partial, data...)
+		for {
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				break
+			}
+			printLine(data[:i])
+			data = data[i+1:]
+		}
+		partial = append([]byte(nil), data...)
+	}
+}
+
+func logsHelp() {
+	fmt.Println("\nUsage: picoclaw logs [options]")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  -n, --lines <n>          Show the last n matching entries (default 50)")
+	fmt.Println("  -f, --follow             Keep printing new entries")
+	fmt.Println("  -l, --level <level>      Minimum level: debug, info, warn, error")
+	fmt.Println("  --component <name>       Only this component and its sub-components (repeatable)")
+	fmt.Println("  --trace <id>             Only entries with this trace_id")
+	fmt.Println("  --session <key>          Only entries with this session_key")
+	fmt.Println("  --agent <id>             Only entries with this agent_id")
+	fmt.Println("  -g, --grep <text>        Only entries whose message or fields contain text")
+	fmt.Println("  --since <duration>       Only entries newer than this, e.g. 30m or 2h")
+	fmt.Println("  --json                   Print the raw JSON lines")
+	fmt.Println("  --file <path>            Read this file instead of log.file")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  picoclaw logs -f --level warn")
+	fmt.Println("  picoclaw logs --component agent --trace 4bf92f3577b34da6a3ce929d0e0e4736")
+}
+
+func evalCmd() {
+	var paths, sessionKeys []string
+	agentID, format, output, workspace := "", "text", "", ""
+	live, verbose := false, false
+	timeout := time.Duration(0)
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		value := func() string {
+			if i+1 >= len(args) {
+				fmt.Printf("Missing value for %s\n", arg)
+				os.Exit(1)
+			}
+			i++
+			return args[i]
+		}
+		switch arg {
+		case "-s", "--session":
+			sessionKeys = append(sessionKeys, value())
+		case "--agent":
+			agentID = value()
+		case "--live":
+			live = true
+		case "--format":
+			format = value()
+		case "-o", "--output":
+			output = value()
+		case "--workspace":
+			workspace = value()
+		case "--timeout":
+			d, err := time.ParseDuration(value())
+			if err != nil {
+				fmt.Printf("Error: --timeout: %v\n", err)
+				os.Exit(1)
+			}
+			timeout = d
+		case "-v", "--verbose":
+			verbose = true
+		case "-h", "--help", "help":
+			evalHelp()
+			return
+		default:
+			if strings.HasPrefix(arg, "-") {
+				fmt.Printf("Unknown option: %s\n", arg)
+				evalHelp()
+				os.Exit(1)
+			}
+			paths = append(paths, arg)
+		}
+	}
+	if format != "text" && format != "json" && format != "junit" {
+		fmt.Printf("Unknown format %q (use text, json or junit)\n", format)
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if !verbose {
+		// Agent logs still go to the log file; keep the console for results
+		logger.SetConsoleOutput(io.Discard)
+	}
+	setupLogging(cfg)
+
+	if len(paths) == 0 && len(sessionKeys) == 0 {
+		evalsDir := filepath.Join(cfg.WorkspacePath(), "evals")
+		if _, err := os.Stat(evalsDir); err != nil {
+			fmt.Println("Nothing to run: pass case files, --session keys, or create <workspace>/evals.")
+			evalHelp()
+			os.Exit(1)
+		}
+		paths = append(paths, evalsDir)
+	}
+
+	var cases []eval.Case
+	if len(paths) > 0 {
+		cases, err = eval.LoadCases(paths...)
+		if err != nil {
+			fmt.Printf("Error loading cases: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	for _, key := range sessionKeys {
+		s, err := eval.LoadSession(eval.SessionsDir(cfg, agentID), key)
+		if err != nil {
+			fmt.Printf("Error loading session %s: %v\n", key, err)
+			os.Exit(1)
+		}
+		cases = append(cases, eval.CaseFromSession(s))
+	}
+	for i := range cases {
+		if cases[i].Agent == "" {
+			cases[i].Agent = agentID
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	runner := eval.NewRunner(cfg, eval.Options{Live: live, Workspace: workspace, TurnTimeout: timeout})
+	report := runner.Run(ctx, cases)
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+	switch format {
+	case "json":
+		err = report.WriteJSON(w)
+	case "junit":
+		err = report.WriteJUnit(w)
+	default:
+		err = report.WriteText(w)
+	}
+	if err != nil {
+		fmt.Printf("Error writing report: %v\n", err)
+		os.Exit(1)
+	}
+	if output != "" {
+		fmt.Printf("%d passed, %d failed; report written to %s\n", report.Passed, report.Failed, output)
+	}
+	if !report.OK() {
+		os.Exit(1)
+	}
+}
+
+func evalHelp() {
+	fmt.Println("\nUsage: picoclaw eval [case files or dirs...] [options]")
+	fmt.Println()
+	fmt.Println("Runs each case in a scratch copy of the workspace and checks the replies.")
+	fmt.Println("Without arguments, runs the cases in <workspace>/evals.")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  -s, --session <key>      Replay a recorded session (repeatable)")
+	fmt.Println("      --agent <id>         Agent to run cases with (default: the default agent)")
+	fmt.Println("      --live               Call the configured LLM instead of fixture responses")
+	fmt.Println("      --format <fmt>       Report format: text, json or junit (default text)")
+	fmt.Println("  -o, --output <file>      Write the report to a file")
+	fmt.Println("      --workspace <dir>    Copy bootstrap files and skills from this workspace")
+	fmt.Println("      --timeout <dur>      Per-turn timeout (default 5m)")
+	fmt.Println("  -v, --verbose            Print agent logs to the console")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  picoclaw eval evals/")
+	fmt.Println("  picoclaw eval --session telegram:123456 --live")
+	fmt.Println("  picoclaw eval evals/ --format junit -o eval.xml")
+}
+
+func printJSON(v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	fmt.Println(string(data))
+}
+
+func compactJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
 func cronHelp() {
 	fmt.Println("\nCron commands:")
 	fmt.Println("  list              List all scheduled jobs")
@@ -820,6 +1580,15 @@ func cronHelp() {
 	fmt.Println("  -d, --deliver     Deliver response to channel")
 	fmt.Println("  --to             Recipient for delivery")
 	fmt.Println("  --channel        Channel for delivery")
+	fmt.Println()
+	fmt.Println("Event trigger options (instead of --every/--cron):")
+	fmt.Println("  --on <source>    Trigger source: file, webhook, memory, message")
+	fmt.Println("  --path <dir>     file: workspace directory to watch")
+	fmt.Println("  --pattern <p>    file: name glob; memory: key regex; message: text regex")
+	fmt.Println("  --hook <name>    webhook: served at POST /hooks/<name> on the gateway")
+	fmt.Println("  --category <c>   memory: only this category")
+	fmt.Println("  --cooldown <s>   Minimum seconds between runs")
+	fmt.Println("  Message triggers only match the --channel/--to chat when given.")
 }
 
 func cronListCmd(storePath string) {
@@ -839,6 +1608,8 @@ func cronListCmd(storePath string) {
 			schedule = fmt.Sprintf("every %ds", *job.Schedule.EveryMS/1000)
 		} else if job.Schedule.Kind == "cron" {
 			schedule = job.Schedule.Expr
+		} else if job.Schedule.Kind == "event" && job.Schedule.Event != nil {
+			schedule = job.Schedule.Event.String()
 		} else {
 			schedule = "one-time"
 		}
@@ -847,6 +1618,8 @@ func cronListCmd(storePath string) {
 		if job.State.NextRunAtMS != nil {
 			nextTime := time.UnixMilli(*job.State.NextRunAtMS)
 			nextRun = nextTime.Format("2006-01-02 15:04")
+		} else if job.Schedule.Kind == "event" {
+			nextRun = "on event"
 		}
 
 		status := "enabled"
@@ -861,7 +1634,7 @@ func cronListCmd(storePath string) {
 	}
 }
 
-func cronAddCmd(storePath string) {
+func cronAddCmd(storePath, workspace string) {
 	name := ""
 	message := ""
 	var everySec *int64
@@ -869,6 +1642,7 @@ func cronAddCmd(storePath string) {
 	deliver := false
 	channel := ""
 	to := ""
+	var trigger *cron.EventTrigger
 
 	args := os.Args[3:]
 	for i := 0; i < len(args); i++ {
@@ -907,6 +1681,29 @@ func cronAddCmd(storePath string) {
 				channel = args[i+1]
 				i++
 			}
+		case "--on", "--path", "--pattern", "--hook", "--category", "--cooldown":
+			if i+1 >= len(args) {
+				break
+			}
+			if trigger == nil {
+				trigger = &cron.EventTrigger{}
+			}
+			value := args[i+1]
+			switch args[i] {
+			case "--on":
+				trigger.Source = value
+			case "--path":
+				trigger.Path = value
+			case "--pattern":
+				trigger.Pattern = value
+			case "--hook":
+				trigger.Name = value
+			case "--category":
+				trigger.Category = value
+			case "--cooldown":
+				fmt.Sscanf(value, "%d", &trigger.CooldownS)
+			}
+			i++
 		}
 	}
 
@@ -920,13 +1717,37 @@ func cronAddCmd(storePath string) {
 		return
 	}
 
-	if everySec == nil && cronExpr == "" {
-		fmt.Println("Error: Either --every or --cron must be specified")
+	if everySec == nil && cronExpr == "" && trigger == nil {
+		fmt.Println("Error: One of --every, --cron or --on must be specified")
 		return
 	}
 
+	if trigger != nil && deliver {
+		fmt.Println("Note: --deliver is ignored for event jobs; they always run as agent tasks")
+		deliver = false
+	}
+
 	var schedule cron.CronSchedule
-	if everySec != nil {
+	if trigger != nil {
+		switch trigger.Source {
+		case cron.EventSourceFile:
+			dir, err := tools.ResolveWorkspaceDir(workspace, trigger.Path)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return
+			}
+			trigger.Path = dir
+		case cron.EventSourceWebhook:
+			trigger.Secret = tools.GenerateWebhookSecret()
+		case cron.EventSourceMessage:
+			trigger.Channel = channel
+			trigger.ChatID = to
+		}
+		schedule = cron.CronSchedule{
+			Kind:  "event",
+			Event: trigger,
+		}
+	} else if everySec != nil {
 		everyMS := *everySec * 1000
 		schedule = cron.CronSchedule{
 			Kind:    "every",
@@ -947,6 +1768,10 @@ func cronAddCmd(storePath string) {
 	}
 
 	fmt.Printf("✓ Added job '%s' (%s)\n", job.Name, job.ID)
+	if trigger != nil && trigger.Source == cron.EventSourceWebhook {
+		fmt.Printf("  Webhook: POST %s%s\n", cron.WebhookPathPrefix, trigger.Name)
+		fmt.Printf("  Header:  X-Webhook-Secret: %s\n", trigger.Secret)
+	}
 }
 
 func cronRemoveCmd(storePath, jobID string) {
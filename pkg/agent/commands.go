@@ -0,0 +1,261 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/commands"
+	"github.com/sipeed/picoclaw/pkg/cron"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// SetCronService gives the /jobs command access to scheduled jobs.
+func (al *AgentLoop) SetCronService(cs *cron.CronService) {
+	al.cronService = cs
+}
+
+// isCommand reports whether text is a built-in slash command.
+func isCommand(text string) bool {
+	name, _, ok := commands.Parse(text)
+	if !ok {
+		return false
+	}
+	_, known := commands.Lookup(name)
+	return known
+}
+
+// handleCommand answers built-in slash commands without calling the LLM.
+// Returns the reply and true when the message was a command.
+func (al *AgentLoop) handleCommand(inst *AgentInstance, msg bus.InboundMessage) (string, bool) {
+	name, args, ok := commands.Parse(msg.Content)
+	if !ok {
+		return "", false
+	}
+	cmd, known := commands.Lookup(name)
+	if !known {
+		return "", false
+	}
+
+	if cmd.Admin && !al.isAdmin(msg) {
+		logger.WarnCF("agent", "Admin command denied", map[string]interface{}{
+			"command":   name,
+			"channel":   msg.Channel,
+			"sender_id": msg.SenderID,
+		})
+		return fmt.Sprintf("/%s is restricted to admins.", name), true
+	}
+
+	logger.InfoCF("agent", "Handling chat command", map[string]interface{}{
+		"command":     name,
+		"session_key": msg.SessionKey,
+		"agent_id":    inst.ID,
+	})
+
+	switch name {
+	case "help":
+		return al.cmdHelp(msg), true
+	case "new":
+		al.summarizeForReset(inst, msg.SessionKey)
+		inst.Sessions.Reset(msg.SessionKey)
+		return "Started a new conversation. Earlier context is kept as a summary.", true
+	case "agent":
+		return al.cmdAgent(msg, args), true
+	case "model":
+		return al.cmdModel(inst, msg, args), true
+	case "voice":
+		return al.cmdVoice(msg, args), true
+	case "memory":
+		return al.cmdMemory(msg, args), true
+	case "forget":
+		return al.cmdForget(msg, args), true
+	case "cost":
+		return al.cmdCost(), true
+	case "jobs":
+		return al.cmdJobs(), true
+	case "approve":
+		return al.cmdApprove(msg, args), true
+	case "deny":
+		return al.cmdDeny(msg, args), true
+	}
+	return "", false
+}
+
+// isAdmin reports whether the sender may run admin commands. The local CLI
+// always may.
+func (al *AgentLoop) isAdmin(msg bus.InboundMessage) bool {
+	return msg.Channel == "cli" || commands.IsAdmin(al.currentConfig().Commands.Admins, msg.Channel, msg.SenderID)
+}
+
+// summarizeForReset folds the whole history into the session summary so
+// /new keeps the earlier context. A background summarization already in
+// progress covers it instead.
+func (al *AgentLoop) summarizeForReset(inst *AgentInstance, sessionKey string) {
+	if _, busy := al.summarizing.LoadOrStore(sessionKey, true); busy {
+		return
+	}
+	defer al.summarizing.Delete(sessionKey)
+	al.summarizeSession(inst, sessionKey, 0)
+}
+
+func (al *AgentLoop) cmdHelp(msg bus.InboundMessage) string {
+	admin := al.isAdmin(msg)
+	var b strings.Builder
+	b.WriteString("Commands:\n")
+	for _, c := range commands.Builtin {
+		if c.Admin && !admin {
+			continue
+		}
+		usage := "/" + c.Name
+		if c.Args != "" {
+			usage += " " + c.Args
+		}
+		fmt.Fprintf(&b, "%s - %s\n", usage, c.Description)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// agentList returns the registered agent IDs, sorted, for display.
+func (al *AgentLoop) agentList() string {
+	ids := al.registry.ListIDs()
+	sort.Strings(ids)
+	return strings.Join(ids, ", ")
+}
+
+// cmdAgent implements "/agent [id|auto]" for per-session routing overrides.
+func (al *AgentLoop) cmdAgent(msg bus.InboundMessage, arg string) string {
+	switch arg {
+	case "":
+		status := fmt.Sprintf("Routing is automatic (default agent: %s).", al.registry.GetDefault().ID)
+		if id, pinned := al.router.Override(msg.SessionKey); pinned {
+			status = fmt.Sprintf("This chat is pinned to agent %s.", id)
+		}
+		return fmt.Sprintf("%s\nAvailable: %s\nUse /agent <id> to switch, /agent auto to reset.",
+			status, al.agentList())
+	case "auto", "reset":
+		al.router.ClearOverride(msg.SessionKey)
+		return "Agent routing reset to automatic."
+	}
+
+	if _, exists := al.registry.Get(arg); !exists {
+		return fmt.Sprintf("Unknown agent %q. Available: %s", arg, al.agentList())
+	}
+	al.router.SetOverride(msg.SessionKey, arg)
+	return fmt.Sprintf("This chat is now handled by agent %s.", arg)
+}
+
+// modelKey identifies a per-session model override.
+func modelKey(inst *AgentInstance, sessionKey string) string {
+	return inst.ID + "|" + sessionKey
+}
+
+// modelFor returns the model to use for a session: the /model override if
+// set, otherwise the agent's configured model.
+func (al *AgentLoop) modelFor(inst *AgentInstance, sessionKey string) string {
+	if v, ok := al.sessionModels.Load(modelKey(inst, sessionKey)); ok {
+		return v.(string)
+	}
+	return inst.Model
+}
+
+// cmdModel implements "/model [name|default]". Switching is admin-only
+// because it changes what the session spends.
+func (al *AgentLoop) cmdModel(inst *AgentInstance, msg bus.InboundMessage, arg string) string {
+	if arg == "" {
+		return fmt.Sprintf("Agent %s is using model %s.", inst.ID, al.modelFor(inst, msg.SessionKey))
+	}
+	if !al.isAdmin(msg) {
+		return "Switching models is restricted to admins."
+	}
+	if arg == "default" || arg == "reset" {
+		al.sessionModels.Delete(modelKey(inst, msg.SessionKey))
+		return fmt.Sprintf("Model reset to %s.", inst.Model)
+	}
+	al.sessionModels.Store(modelKey(inst, msg.SessionKey), arg)
+	return fmt.Sprintf("This chat now uses model %s (provider unchanged).", arg)
+}
+
+func (al *AgentLoop) cmdMemory(msg bus.InboundMessage, args string) string {
+	if al.memoryDB == nil {
+		return "Memory is not available."
+	}
+	sub, query, _ := strings.Cut(args, " ")
+	query = strings.TrimSpace(query)
+	if sub != "search" || query == "" {
+		return "Usage: /memory search <query>"
+	}
+
+	results, err := al.memoryDB.Search(query, 5, resolveOwner(msg.Metadata))
+	if err != nil {
+		return fmt.Sprintf("Memory search failed: %v", err)
+	}
+	if len(results) == 0 {
+		return "No memories found."
+	}
+	var b strings.Builder
+	for _, r := range results {
+		fmt.Fprintf(&b, "[%s] (%s) %s\n", r.Entry.Key, r.Entry.Category, utils.Truncate(r.Entry.Content, 200))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (al *AgentLoop) cmdForget(msg bus.InboundMessage, key string) string {
+	if al.memoryDB == nil {
+		return "Memory is not available."
+	}
+	if key == "" {
+		return "Usage: /forget <key>"
+	}
+	if !al.memoryDB.DeleteAccessible(key, resolveOwner(msg.Metadata)) {
+		return fmt.Sprintf("No memory with key %q.", key)
+	}
+	return fmt.Sprintf("Forgot %q.", key)
+}
+
+func (al *AgentLoop) cmdCost() string {
+	if al.costTracker == nil {
+		return "Cost tracking is not enabled."
+	}
+	summary := al.costTracker.GetSummary()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Session: $%.4f (%d requests, %d tokens)\n", summary.SessionCostUSD, summary.RequestCount, summary.TotalTokens)
+	fmt.Fprintf(&b, "Today:   $%.4f\n", summary.DailyCostUSD)
+	fmt.Fprintf(&b, "Month:   $%.4f", summary.MonthlyCostUSD)
+
+	models := make([]string, 0, len(summary.ByModel))
+	for m := range summary.ByModel {
+		models = append(models, m)
+	}
+	sort.Strings(models)
+	for i, m := range models {
+		if i == 0 {
+			b.WriteString("\n\nBy model:")
+		}
+		ms := summary.ByModel[m]
+		fmt.Fprintf(&b, "\n  %s: $%.4f (%d reqs, %d tokens)", ms.Model, ms.CostUSD, ms.RequestCount, ms.TotalTokens)
+	}
+	return b.String()
+}
+
+func (al *AgentLoop) cmdJobs() string {
+	if al.cronService == nil {
+		return "Scheduling is not available."
+	}
+	jobs := al.cronService.ListJobs(true)
+	if len(jobs) == 0 {
+		return "No scheduled jobs."
+	}
+	var b strings.Builder
+	b.WriteString("Scheduled jobs:")
+	for _, j := range jobs {
+		status := ""
+		if !j.Enabled {
+			status = ", disabled"
+		}
+		fmt.Fprintf(&b, "\n- %s (id: %s, %s%s)", j.Name, j.ID, j.Schedule, status)
+	}
+	return b.String()
+}
@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+func newCommandTestLoop(t *testing.T, admins []string) (*AgentLoop, *AgentInstance) {
+	t.Helper()
+	al := newRoutingTestLoop(t, nil)
+	al.currentConfig().Commands = config.CommandsConfig{Admins: admins}
+	inst, _ := al.registry.Get("main")
+	inst.Model = "base-model"
+	inst.Sessions = session.NewSessionManager("")
+	return al, inst
+}
+
+func chatMsg(sender, content string) bus.InboundMessage {
+	return bus.InboundMessage{Channel: "telegram", ChatID: "1", SessionKey: "telegram:1", SenderID: sender, Content: content}
+}
+
+func TestHandleCommand_NotACommand(t *testing.T) {
+	al, inst := newCommandTestLoop(t, nil)
+	for _, text := range []string{"hello", "/unknown thing", "see /help"} {
+		if _, handled := al.handleCommand(inst, chatMsg("1", text)); handled {
+			t.Errorf("%q should not be handled", text)
+		}
+	}
+}
+
+func TestHandleCommand_AdminRestriction(t *testing.T) {
+	al, inst := newCommandTestLoop(t, []string{"telegram:admin", "discord:42"})
+
+	if reply, _ := al.handleCommand(inst, chatMsg("42|guest", "/cost")); !strings.Contains(reply, "restricted") {
+		t.Errorf("non-admin /cost: got %q", reply)
+	}
+	if reply, _ := al.handleCommand(inst, chatMsg("7|admin", "/cost")); reply != "Cost tracking is not enabled." {
+		t.Errorf("admin /cost: got %q", reply)
+	}
+
+	help, _ := al.handleCommand(inst, chatMsg("42|guest", "/help"))
+	if strings.Contains(help, "/cost") || !strings.Contains(help, "/new") {
+		t.Errorf("help should hide admin commands from guests: %q", help)
+	}
+
+	noAdmins, _ := newCommandTestLoop(t, nil)
+	if reply, _ := noAdmins.handleCommand(inst, chatMsg("7|admin", "/cost")); !strings.Contains(reply, "restricted") {
+		t.Errorf("empty admin list should deny /cost, got %q", reply)
+	}
+
+	cli := bus.InboundMessage{Channel: "cli", SenderID: "cron", SessionKey: "cli:default", Content: "/jobs"}
+	if reply, _ := al.handleCommand(inst, cli); reply != "Scheduling is not available." {
+		t.Errorf("cli should always be admin, got %q", reply)
+	}
+}
+
+func TestHandleCommand_NewKeepsSummary(t *testing.T) {
+	al, inst := newCommandTestLoop(t, nil)
+	inst.Provider = &streamingTestProvider{} // summarizes as "Hello"
+	inst.Sessions.AddMessage("telegram:1", "user", "hi")
+	inst.Sessions.SetSummary("telegram:1", "user likes tea")
+
+	al.handleCommand(inst, chatMsg("1", "/new"))
+	if n := len(inst.Sessions.GetHistory("telegram:1")); n != 0 {
+		t.Errorf("history should be cleared, got %d messages", n)
+	}
+	if s := inst.Sessions.GetSummary("telegram:1"); s != "Hello" {
+		t.Errorf("history should be summarized before the reset, got %q", s)
+	}
+
+	al.handleCommand(inst, chatMsg("1", "/new"))
+	if s := inst.Sessions.GetSummary("telegram:1"); s != "Hello" {
+		t.Errorf("summary should be kept, got %q", s)
+	}
+}
+
+func TestHandleCommand_Agent(t *testing.T) {
+	al, inst := newCommandTestLoop(t, []string{"telegram:1"})
+
+	if reply, _ := al.handleCommand(inst, chatMsg("1", "/agent nope")); !strings.Contains(reply, "Unknown agent") || !strings.Contains(reply, "coder, main") {
+		t.Errorf("unexpected reply: %q", reply)
+	}
+	al.handleCommand(inst, chatMsg("1", "/agent coder"))
+	if id, ok := al.router.Override("telegram:1"); !ok || id != "coder" {
+		t.Fatalf("override not set: %q %v", id, ok)
+	}
+	if reply, _ := al.handleCommand(inst, chatMsg("1", "/agent")); !strings.Contains(reply, "pinned to agent coder") {
+		t.Errorf("unexpected status: %q", reply)
+	}
+	al.handleCommand(inst, chatMsg("1", "/agent auto"))
+	if _, ok := al.router.Override("telegram:1"); ok {
+		t.Error("override should be cleared")
+	}
+}
+
+func TestHandleCommand_AgentTempAllowedGuest(t *testing.T) {
+	al, inst := newCommandTestLoop(t, []string{"telegram:admin"})
+
+	// Telegram binds temp-allowed users to temp_allow_agent
+	guest := chatMsg("42|guest", "/agent main")
+	guest.Metadata = map[string]string{"agent_id": "coder"}
+	if reply, _ := al.handleCommand(inst, guest); !strings.Contains(reply, "restricted") {
+		t.Errorf("guest /agent main: got %q", reply)
+	}
+	if _, ok := al.router.Override("telegram:1"); ok {
+		t.Fatal("guest should not pin the chat")
+	}
+
+	// Even with an admin's pin in place, the guest stays on its agent
+	al.handleCommand(inst, chatMsg("7|admin", "/agent main"))
+	guest.Content = "hello"
+	if got := al.resolveAgent(context.Background(), &guest); got.ID != "coder" {
+		t.Errorf("guest routed to %q, want coder", got.ID)
+	}
+}
+
+func TestHandleCommand_Model(t *testing.T) {
+	al, inst := newCommandTestLoop(t, []string{"telegram:admin"})
+
+	if reply, _ := al.handleCommand(inst, chatMsg("1", "/model")); !strings.Contains(reply, "base-model") {
+		t.Errorf("unexpected reply: %q", reply)
+	}
+	al.handleCommand(inst, chatMsg("2|guest", "/model other"))
+	if got := al.modelFor(inst, "telegram:1"); got != "base-model" {
+		t.Errorf("non-admin should not switch model, got %q", got)
+	}
+	al.handleCommand(inst, chatMsg("3|admin", "/model fast-model"))
+	if got := al.modelFor(inst, "telegram:1"); got != "fast-model" {
+		t.Errorf("expected override, got %q", got)
+	}
+	if got := al.modelFor(inst, "telegram:2"); got != "base-model" {
+		t.Errorf("override should be per session, got %q", got)
+	}
+	al.handleCommand(inst, chatMsg("3|admin", "/model default"))
+	if got := al.modelFor(inst, "telegram:1"); got != "base-model" {
+		t.Errorf("expected reset, got %q", got)
+	}
+}
+
+func TestReloadSwapsConfig(t *testing.T) {
+	al, inst := newCommandTestLoop(t, nil)
+	old := al.currentConfig()
+
+	next := config.DefaultConfig()
+	next.Commands.Admins = []string{"telegram:admin"}
+	if err := al.Reload(next, config.Changes{Commands: true}); err != nil {
+		t.Fatal(err)
+	}
+	if reply, _ := al.handleCommand(inst, chatMsg("7|admin", "/cost")); reply != "Cost tracking is not enabled." {
+		t.Errorf("reloaded admins not applied, got %q", reply)
+	}
+	if len(old.Commands.Admins) != 0 {
+		t.Errorf("reload modified the previous config: %v", old.Commands.Admins)
+	}
+}
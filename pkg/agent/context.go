@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -14,6 +15,7 @@ import (
 	"github.com/sipeed/picoclaw/pkg/memory"
 	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/skills"
+	"github.com/sipeed/picoclaw/pkg/tracing"
 )
 
 // SubagentInfo describes a delegatable agent for system prompt injection.
@@ -451,13 +453,22 @@ func containsWord(haystack, needle string) bool {
 }
 
 func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary string, currentMessage string, media []string, channel, chatID, owner string) []providers.Message {
+	return cb.BuildMessagesContext(context.Background(), history, summary, currentMessage, media, channel, chatID, owner)
+}
+
+// BuildMessagesContext is BuildMessages with a context for tracing the
+// memory lookup.
+func (cb *ContextBuilder) BuildMessagesContext(ctx context.Context, history []providers.Message, summary string, currentMessage string, media []string, channel, chatID, owner string) []providers.Message {
 	messages := []providers.Message{}
 
 	systemPrompt := cb.BuildSystemPrompt()
 
 	// Append relevance-filtered memory context (full prompt always, lightweight only if "memory" opted in)
 	if cb.instructions == "" || cb.contextSections["memory"] {
+		_, span := tracing.Start(ctx, "memory.lookup")
 		memoryContext := cb.buildRelevantMemoryContext(currentMessage, owner)
+		span.SetAttr("chars", len(memoryContext))
+		span.End()
 		if memoryContext != "" {
 			systemPrompt += "\n\n---\n\n" + memoryContext
 		}
@@ -0,0 +1,310 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/metrics"
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+// Egress points scanned by the leak detector.
+const (
+	egressResponse = "response"
+	egressDelegate = "delegate"
+	egressToolArgs = "tool_args"
+	egressCron     = "cron"
+)
+
+// heldEgressTTL is how long held items wait for /approve before they are
+// dropped.
+const heldEgressTTL = 24 * time.Hour
+
+// heldEgress is outbound content withheld until an admin approves it:
+// either a message (Tool empty) or a tool call.
+type heldEgress struct {
+	ID       string
+	Point    string
+	Channel  string
+	ChatID   string
+	Content  string
+	Inst     *AgentInstance
+	Tool     string
+	Args     map[string]interface{}
+	Patterns []string
+	Created  time.Time
+}
+
+// egressApprovals stores held items by ID.
+type egressApprovals struct {
+	mu    sync.Mutex
+	next  int
+	items map[string]*heldEgress
+}
+
+func (a *egressApprovals) hold(item *heldEgress) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.items == nil {
+		a.items = make(map[string]*heldEgress)
+	}
+	for id, old := range a.items {
+		if time.Since(old.Created) > heldEgressTTL {
+			delete(a.items, id)
+		}
+	}
+	a.next++
+	item.ID = fmt.Sprintf("h%d", a.next)
+	item.Created = time.Now()
+	a.items[item.ID] = item
+	return item.ID
+}
+
+// take removes and returns the held item with the given ID. Items held for
+// another chat are left alone, so an admin can only release what was
+// meant for the chat they are in.
+func (a *egressApprovals) take(id, channel, chatID string) (*heldEgress, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	item, ok := a.items[id]
+	if !ok || item.Channel != channel || item.ChatID != chatID {
+		return nil, false
+	}
+	delete(a.items, id)
+	return item, time.Since(item.Created) <= heldEgressTTL
+}
+
+// list returns the pending items for a chat, oldest first.
+func (a *egressApprovals) list(channel, chatID string) []*heldEgress {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	items := make([]*heldEgress, 0, len(a.items))
+	for _, item := range a.items {
+		if item.Channel == channel && item.ChatID == chatID && time.Since(item.Created) <= heldEgressTTL {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Created.Before(items[j].Created) })
+	return items
+}
+
+// scanEgress runs the leak detector over content leaving the agent. It
+// returns the scan result and the configured action, or an empty action
+// when the detector is off or the content is clean.
+func (al *AgentLoop) scanEgress(point, channel, chatID, content string) (security.LeakResult, string) {
+	ld := al.leakDetector.Load()
+	if ld == nil {
+		return security.LeakResult{Clean: true, Redacted: content}, ""
+	}
+	result := ld.Scan(content)
+	if result.Clean {
+		return result, ""
+	}
+	action := al.currentConfig().Security.LeakDetector.Action
+	if action == "" {
+		action = security.LeakActionRedact
+	}
+	if point == egressDelegate && action == security.LeakActionApprove {
+		// Delegate results go back to the calling agent, not to a chat
+		action = security.LeakActionBlock
+	}
+	logger.WarnCF("security", "Credential leak detected in outbound content",
+		map[string]interface{}{
+			"point":    point,
+			"patterns": result.Patterns,
+			"action":   action,
+			"channel":  channel,
+			"chat_id":  chatID,
+		})
+	metrics.SecurityDetections.Inc("leak_detector", action)
+	return result, action
+}
+
+// filterResponse applies the leak policy to a final reply or delegate result.
+func (al *AgentLoop) filterResponse(opts processOptions, content string) string {
+	point := egressResponse
+	if strings.HasPrefix(opts.SessionKey, "delegate:") {
+		point = egressDelegate
+	}
+	result, action := al.scanEgress(point, opts.Channel, opts.ChatID, content)
+	switch action {
+	case "":
+		return content
+	case security.LeakActionBlock:
+		if point == egressDelegate {
+			return "[Delegate result withheld: it contained credentials]"
+		}
+		return "I can't send that reply: it contained credentials."
+	case security.LeakActionApprove:
+		id := al.approvals.hold(&heldEgress{
+			Point:    point,
+			Channel:  opts.Channel,
+			ChatID:   opts.ChatID,
+			Content:  content,
+			Patterns: result.Patterns,
+		})
+		return fmt.Sprintf("This reply contains credentials and is waiting for an admin (/approve %s or /deny %s).", id, id)
+	}
+	return result.Redacted
+}
+
+// FilterOutbound applies the leak policy to a message published outside the
+// agent loop, such as a cron delivery. It reports false when the message
+// must not be sent now.
+func (al *AgentLoop) FilterOutbound(msg bus.OutboundMessage) (bus.OutboundMessage, bool) {
+	result, action := al.scanEgress(egressCron, msg.Channel, msg.ChatID, msg.Content)
+	switch action {
+	case "":
+		return msg, true
+	case security.LeakActionBlock:
+		return msg, false
+	case security.LeakActionApprove:
+		al.approvals.hold(&heldEgress{
+			Point:    egressCron,
+			Channel:  msg.Channel,
+			ChatID:   msg.ChatID,
+			Content:  msg.Content,
+			Patterns: result.Patterns,
+		})
+		return msg, false
+	}
+	msg.Content = result.Redacted
+	return msg, true
+}
+
+// checkToolArgs scans the arguments of the tools listed in scan_tools before
+// they run. It returns the arguments to run with, or a non-empty result that
+// replaces the call.
+func (al *AgentLoop) checkToolArgs(inst *AgentInstance, name string, args map[string]interface{}, channel, chatID string) (map[string]interface{}, string) {
+	if al.leakDetector.Load() == nil || !containsString(al.currentConfig().Security.LeakDetector.ScanTools, name) {
+		return args, ""
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return args, ""
+	}
+	result, action := al.scanEgress(egressToolArgs, channel, chatID, string(data))
+	switch action {
+	case "":
+		return args, ""
+	case security.LeakActionBlock:
+		return nil, fmt.Sprintf("Error: %s call blocked: its arguments contain credentials (%s). Do not send secrets out of the workspace.",
+			name, strings.Join(result.Patterns, ", "))
+	case security.LeakActionApprove:
+		id := al.approvals.hold(&heldEgress{
+			Point:    egressToolArgs,
+			Channel:  channel,
+			ChatID:   chatID,
+			Inst:     inst,
+			Tool:     name,
+			Args:     args,
+			Patterns: result.Patterns,
+		})
+		return nil, fmt.Sprintf("The %s call was held because its arguments contain credentials (%s). Tell the user an admin must run /approve %s (or /deny %s); the result will be reported when it runs.",
+			name, strings.Join(result.Patterns, ", "), id, id)
+	}
+	return redactArgs(al.leakDetector.Load(), args).(map[string]interface{}), ""
+}
+
+// redactArgs returns a copy of v with every string value redacted.
+func redactArgs(ld *security.LeakDetector, v interface{}) interface{} {
+	switch x := v.(type) {
+	case string:
+		return ld.Scan(x).Redacted
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, child := range x {
+			out[k] = redactArgs(ld, child)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, child := range x {
+			out[i] = redactArgs(ld, child)
+		}
+		return out
+	}
+	return v
+}
+
+// cmdApprove implements "/approve [id]": without an ID it lists the items
+// held for the current chat. Held content is never echoed, since it is
+// exactly what the leak detector kept out of the chat.
+func (al *AgentLoop) cmdApprove(msg bus.InboundMessage, arg string) string {
+	if arg == "" {
+		items := al.approvals.list(msg.Channel, msg.ChatID)
+		if len(items) == 0 {
+			return "Nothing is waiting for approval in this chat."
+		}
+		var b strings.Builder
+		b.WriteString("Waiting for approval:")
+		for _, item := range items {
+			what := "message"
+			if item.Tool != "" {
+				what = "tool " + item.Tool
+			}
+			fmt.Fprintf(&b, "\n- %s: %s to %s:%s (%s)", item.ID, what, item.Channel, item.ChatID, strings.Join(item.Patterns, ", "))
+		}
+		return b.String()
+	}
+
+	item, ok := al.approvals.take(arg, msg.Channel, msg.ChatID)
+	if !ok {
+		return fmt.Sprintf("No held item %q in this chat.", arg)
+	}
+	logger.InfoCF("security", "Held outbound content approved",
+		map[string]interface{}{"id": item.ID, "point": item.Point, "tool": item.Tool, "sender_id": msg.SenderID})
+
+	if item.Tool == "" {
+		al.bus.PublishOutbound(bus.OutboundMessage{
+			Channel: item.Channel,
+			ChatID:  item.ChatID,
+			Content: item.Content,
+		})
+		return fmt.Sprintf("Approved %s: message sent to %s:%s.", item.ID, item.Channel, item.ChatID)
+	}
+
+	go func() {
+		result, err := item.Inst.Tools.ExecuteWithContext(context.Background(), item.Tool, item.Args, item.Channel, item.ChatID)
+		if err != nil {
+			result = fmt.Sprintf("Error: %v", err)
+		}
+		al.bus.PublishInbound(bus.InboundMessage{
+			Channel:  "system",
+			SenderID: fmt.Sprintf("approval:%s", item.ID),
+			ChatID:   fmt.Sprintf("%s:%s", item.Channel, item.ChatID),
+			Content:  fmt.Sprintf("Approved %s call completed.\n\nResult:\n%s", item.Tool, result),
+		})
+	}()
+	return fmt.Sprintf("Approved %s: running %s.", item.ID, item.Tool)
+}
+
+// cmdDeny implements "/deny <id>" for items held for the current chat.
+func (al *AgentLoop) cmdDeny(msg bus.InboundMessage, arg string) string {
+	if arg == "" {
+		return "Usage: /deny <id>"
+	}
+	item, ok := al.approvals.take(arg, msg.Channel, msg.ChatID)
+	if !ok {
+		return fmt.Sprintf("No held item %q in this chat.", arg)
+	}
+	logger.InfoCF("security", "Held outbound content denied",
+		map[string]interface{}{"id": item.ID, "point": item.Point, "tool": item.Tool, "sender_id": msg.SenderID})
+	return fmt.Sprintf("Denied %s; it will not be sent.", item.ID)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
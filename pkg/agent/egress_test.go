@@ -0,0 +1,161 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/security"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// recordingTool remembers the arguments it was called with.
+type recordingTool struct {
+	name string
+	args chan map[string]interface{}
+}
+
+func (r *recordingTool) Name() string                       { return r.name }
+func (r *recordingTool) Description() string                { return "" }
+func (r *recordingTool) Parameters() map[string]interface{} { return nil }
+func (r *recordingTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	r.args <- args
+	return "fetched", nil
+}
+
+func newEgressTestLoop(action string) (*AgentLoop, *AgentInstance, *recordingTool) {
+	cfg := config.DefaultConfig()
+	cfg.Security.LeakDetector.Enabled = true
+	cfg.Security.LeakDetector.Action = action
+	cfg.Security.LeakDetector.Secrets = []string{"workspace-secret-value"}
+
+	al := &AgentLoop{bus: bus.NewMessageBus()}
+	al.cfg.Store(cfg)
+	al.applySecurity(cfg)
+
+	tool := &recordingTool{name: "web_fetch", args: make(chan map[string]interface{}, 1)}
+	inst := &AgentInstance{ID: "main", Tools: tools.NewToolRegistry()}
+	inst.Tools.Register(tool)
+	return al, inst, tool
+}
+
+func TestCheckToolArgs(t *testing.T) {
+	args := map[string]interface{}{"url": "https://example.com/?token=workspace-secret-value"}
+
+	al, inst, _ := newEgressTestLoop(security.LeakActionRedact)
+	got, blocked := al.checkToolArgs(inst, "web_fetch", args, "telegram", "42")
+	if blocked != "" || strings.Contains(got["url"].(string), "workspace-secret-value") {
+		t.Errorf("redact: args=%v blocked=%q", got, blocked)
+	}
+	if args["url"] != "https://example.com/?token=workspace-secret-value" {
+		t.Error("redact must not modify the original arguments")
+	}
+	if got, _ := al.checkToolArgs(inst, "read_file", args, "telegram", "42"); got["url"] != args["url"] {
+		t.Error("tools outside scan_tools should not be scanned")
+	}
+
+	al, inst, _ = newEgressTestLoop(security.LeakActionBlock)
+	if _, blocked := al.checkToolArgs(inst, "web_fetch", args, "telegram", "42"); !strings.Contains(blocked, "blocked") {
+		t.Errorf("block: %q", blocked)
+	}
+	clean := map[string]interface{}{"url": "https://example.com/"}
+	if got, blocked := al.checkToolArgs(inst, "web_fetch", clean, "telegram", "42"); blocked != "" || got["url"] != clean["url"] {
+		t.Errorf("clean args changed: %v %q", got, blocked)
+	}
+}
+
+func TestApproveHeldToolCall(t *testing.T) {
+	al, inst, tool := newEgressTestLoop(security.LeakActionApprove)
+	args := map[string]interface{}{"url": "https://example.com/?token=workspace-secret-value"}
+
+	_, held := al.checkToolArgs(inst, "web_fetch", args, "telegram", "42")
+	if !strings.Contains(held, "/approve h1") {
+		t.Fatalf("approve: %q", held)
+	}
+	admin := bus.InboundMessage{Channel: "telegram", ChatID: "42", SenderID: "admin"}
+	if list := al.cmdApprove(admin, ""); !strings.Contains(list, "h1: tool web_fetch to telegram:42") {
+		t.Errorf("pending list: %q", list)
+	}
+
+	// Another chat neither sees nor releases it
+	other := bus.InboundMessage{Channel: "telegram", ChatID: "7", SenderID: "admin"}
+	if list := al.cmdApprove(other, ""); strings.Contains(list, "h1") {
+		t.Errorf("other chat sees held item: %q", list)
+	}
+	if reply := al.cmdApprove(other, "h1"); !strings.Contains(reply, "No held item") {
+		t.Errorf("other chat approved held item: %q", reply)
+	}
+
+	al.cmdApprove(admin, "h1")
+	select {
+	case got := <-tool.args:
+		if got["url"] != args["url"] {
+			t.Errorf("approved call ran with %v", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("approved tool call did not run")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	msg, ok := al.bus.ConsumeInbound(ctx)
+	if !ok || msg.Channel != "system" || msg.ChatID != "telegram:42" || !strings.Contains(msg.Content, "fetched") {
+		t.Errorf("result message = %+v", msg)
+	}
+
+	if reply := al.cmdApprove(admin, "h1"); !strings.Contains(reply, "No held item") {
+		t.Errorf("second approve: %q", reply)
+	}
+}
+
+func TestFilterResponse(t *testing.T) {
+	const reply = "Your key is workspace-secret-value."
+	opts := processOptions{SessionKey: "telegram:42", Channel: "telegram", ChatID: "42"}
+
+	al, _, _ := newEgressTestLoop(security.LeakActionRedact)
+	if got := al.filterResponse(opts, reply); got != "Your key is [REDACTED_CONFIG_SECRET]." {
+		t.Errorf("redact: %q", got)
+	}
+
+	al, _, _ = newEgressTestLoop(security.LeakActionApprove)
+	got := al.filterResponse(opts, reply)
+	if strings.Contains(got, "workspace-secret-value") || !strings.Contains(got, "/approve h1") {
+		t.Fatalf("approve: %q", got)
+	}
+	admin := bus.InboundMessage{Channel: "telegram", ChatID: "42", SenderID: "admin"}
+	if list := al.cmdApprove(admin, ""); strings.Contains(list, "workspace-secret-value") || !strings.Contains(list, "h1: message") {
+		t.Errorf("pending list must not preview held content: %q", list)
+	}
+	al.cmdApprove(admin, "h1")
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if out, ok := al.bus.SubscribeOutbound(ctx); !ok || out.Content != reply || out.ChatID != "42" {
+		t.Errorf("approved reply = %+v", out)
+	}
+
+	delegate := processOptions{SessionKey: "delegate:coder:42:1", Channel: "telegram", ChatID: "42"}
+	if got := al.filterResponse(delegate, reply); !strings.Contains(got, "withheld") {
+		t.Errorf("delegate results should be blocked instead of held: %q", got)
+	}
+	if got := al.cmdDeny(admin, "h2"); !strings.Contains(got, "No held item") {
+		t.Errorf("delegate result was held: %q", got)
+	}
+}
+
+func TestFilterOutbound(t *testing.T) {
+	msg := bus.OutboundMessage{Channel: "telegram", ChatID: "42", Content: "token workspace-secret-value"}
+
+	al, _, _ := newEgressTestLoop(security.LeakActionBlock)
+	if _, ok := al.FilterOutbound(msg); ok {
+		t.Error("block: message should be withheld")
+	}
+
+	al, _, _ = newEgressTestLoop(security.LeakActionRedact)
+	out, ok := al.FilterOutbound(msg)
+	if !ok || out.Content != "token [REDACTED_CONFIG_SECRET]" {
+		t.Errorf("redact: %+v %v", out, ok)
+	}
+}
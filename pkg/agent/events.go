@@ -0,0 +1,144 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/cost"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+// TurnEventType identifies what a TurnEvent reports.
+type TurnEventType string
+
+const (
+	// EventLLMStart is sent before each LLM call.
+	EventLLMStart TurnEventType = "llm_start"
+	// EventDelta carries streamed response text or reasoning.
+	EventDelta TurnEventType = "delta"
+	// EventLLMDone is sent after each LLM call with its usage and cost.
+	EventLLMDone TurnEventType = "llm_done"
+	// EventToolStart is sent before a tool runs.
+	EventToolStart TurnEventType = "tool_start"
+	// EventToolDone is sent with a tool's result.
+	EventToolDone TurnEventType = "tool_done"
+)
+
+// TurnEvent reports the progress of an agent turn to the observer set
+// with WithTurnObserver. Fields are set as relevant to Type.
+type TurnEvent struct {
+	Type       TurnEventType
+	AgentID    string
+	SessionKey string
+	Iteration  int
+	Model      string
+
+	// Content is streamed text (EventDelta) or the complete response
+	// content (EventLLMDone); Reasoning likewise.
+	Content   string
+	Reasoning string
+
+	ToolCallID string
+	Tool       string
+	Args       map[string]interface{}
+	Result     string
+
+	Usage    *providers.UsageInfo
+	CostUSD  float64
+	Duration time.Duration
+	Err      error
+}
+
+type turnObserverKey struct{}
+
+type turnObserver struct {
+	fn     func(TurnEvent)
+	stream bool
+}
+
+// WithTurnObserver returns a context whose turns report their progress to
+// fn, which runs on the agent's goroutine and must not block. With stream
+// set, providers that support it stream responses as EventDelta.
+func WithTurnObserver(ctx context.Context, fn func(TurnEvent), stream bool) context.Context {
+	return context.WithValue(ctx, turnObserverKey{}, turnObserver{fn: fn, stream: stream})
+}
+
+func observerFrom(ctx context.Context) (turnObserver, bool) {
+	o, ok := ctx.Value(turnObserverKey{}).(turnObserver)
+	return o, ok && o.fn != nil
+}
+
+// emitTurn sends ev to the context's observer, if any.
+func emitTurn(ctx context.Context, inst *AgentInstance, opts processOptions, ev TurnEvent) {
+	o, ok := observerFrom(ctx)
+	if !ok {
+		return
+	}
+	ev.AgentID = inst.ID
+	ev.SessionKey = opts.SessionKey
+	o.fn(ev)
+}
+
+// chat calls the agent's provider, streaming to the turn observer when
+// one asked for it and the provider can.
+func (al *AgentLoop) chat(ctx context.Context, inst *AgentInstance, opts processOptions, iteration int, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	o, observed := observerFrom(ctx)
+	if !observed {
+		return inst.Provider.Chat(ctx, messages, tools, model, options)
+	}
+
+	emitTurn(ctx, inst, opts, TurnEvent{Type: EventLLMStart, Iteration: iteration, Model: model})
+	start := time.Now()
+	var response *providers.LLMResponse
+	var err error
+	if sp, ok := inst.Provider.(providers.StreamingProvider); ok && o.stream {
+		response, err = sp.ChatStream(ctx, messages, tools, model, options, func(d providers.StreamDelta) {
+			emitTurn(ctx, inst, opts, TurnEvent{Type: EventDelta, Iteration: iteration, Model: model, Content: d.Content, Reasoning: d.Reasoning})
+		})
+	} else {
+		response, err = inst.Provider.Chat(ctx, messages, tools, model, options)
+	}
+
+	done := TurnEvent{Type: EventLLMDone, Iteration: iteration, Model: model, Duration: time.Since(start), Err: err}
+	if response != nil {
+		done.Content = response.Content
+		done.Reasoning = response.ReasoningContent
+		done.Usage = response.Usage
+		if response.Usage != nil {
+			done.CostUSD = al.costOf(model, response.Usage)
+		}
+	}
+	emitTurn(ctx, inst, opts, done)
+	return response, err
+}
+
+// costOf prices an LLM call at the configured rates.
+func (al *AgentLoop) costOf(model string, usage *providers.UsageInfo) float64 {
+	configured := al.currentConfig().Cost.Prices
+	prices := make(map[string]cost.ModelPrice, len(configured))
+	for k, v := range configured {
+		prices[k] = cost.ModelPrice{Input: v.Input, Output: v.Output}
+	}
+	price := cost.PriceForModel(model, prices)
+	return cost.NewTokenUsage(model, usage.PromptTokens, usage.CompletionTokens, price.Input, price.Output).CostUSD
+}
+
+// Sessions returns the session store of an agent, or of the default agent
+// when agentID is empty or unknown.
+func (al *AgentLoop) Sessions(agentID string) *session.SessionManager {
+	return al.instance(agentID).Sessions
+}
+
+// ModelFor returns the model an agent uses for a session, including a
+// /model override.
+func (al *AgentLoop) ModelFor(agentID, sessionKey string) string {
+	return al.modelFor(al.instance(agentID), sessionKey)
+}
+
+func (al *AgentLoop) instance(agentID string) *AgentInstance {
+	if inst, ok := al.registry.Get(agentID); ok {
+		return inst
+	}
+	return al.registry.GetDefault()
+}
@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+type streamingTestProvider struct {
+	streamed bool
+}
+
+func (p *streamingTestProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	return &providers.LLMResponse{Content: "Hello", Usage: &providers.UsageInfo{PromptTokens: 1000, CompletionTokens: 500}}, nil
+}
+
+func (p *streamingTestProvider) ChatStream(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}, onDelta func(providers.StreamDelta)) (*providers.LLMResponse, error) {
+	p.streamed = true
+	onDelta(providers.StreamDelta{Content: "Hel"})
+	onDelta(providers.StreamDelta{Content: "lo"})
+	return p.Chat(ctx, messages, tools, model, options)
+}
+
+func (p *streamingTestProvider) GetDefaultModel() string {
+	return "m"
+}
+
+func TestChatReportsTurnEvents(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Cost.Prices = map[string]config.ModelPriceConfig{"m": {Input: 1, Output: 2}}
+	al := &AgentLoop{}
+	al.cfg.Store(cfg)
+	provider := &streamingTestProvider{}
+	inst := &AgentInstance{ID: "main", Provider: provider}
+	opts := processOptions{SessionKey: "cli:default"}
+
+	// Without an observer the provider is called as before
+	if _, err := al.chat(context.Background(), inst, opts, 1, nil, nil, "m", nil); err != nil || provider.streamed {
+		t.Fatalf("unobserved chat: streamed = %v, err = %v", provider.streamed, err)
+	}
+
+	var events []TurnEvent
+	ctx := WithTurnObserver(context.Background(), func(ev TurnEvent) { events = append(events, ev) }, true)
+	if _, err := al.chat(ctx, inst, opts, 2, nil, nil, "m", nil); err != nil {
+		t.Fatal(err)
+	}
+	want := []TurnEventType{EventLLMStart, EventDelta, EventDelta, EventLLMDone}
+	if len(events) != len(want) {
+		t.Fatalf("events = %+v", events)
+	}
+	for i, ev := range events {
+		if ev.Type != want[i] || ev.AgentID != "main" || ev.SessionKey != "cli:default" || ev.Iteration != 2 {
+			t.Errorf("event %d = %+v", i, ev)
+		}
+	}
+	done := events[3]
+	if done.Content != "Hello" || done.Usage.PromptTokens != 1000 {
+		t.Errorf("done = %+v", done)
+	}
+	// $1/M input and $2/M output
+	if done.CostUSD < 0.00199 || done.CostUSD > 0.00201 {
+		t.Errorf("cost = %f, want 0.002", done.CostUSD)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
@@ -36,15 +37,15 @@ type AgentInstance struct {
 
 // sharedTools holds tool instances that are shared across all agent instances.
 type sharedTools struct {
-	messageTool tools.Tool
-	spawnTool   tools.Tool
-	searchTool  tools.Tool
-	fetchTool   tools.Tool
-	memStore    tools.Tool
-	memForget   tools.Tool
-	memSearch   tools.Tool
-	costTool    tools.Tool
-	stmTool     tools.Tool
+	spawnTool  tools.Tool
+	searchTool tools.Tool
+	fetchTool  tools.Tool
+	memStore   tools.Tool
+	memForget  tools.Tool
+	memSearch  tools.Tool
+	costTool   tools.Tool
+	stmTool    tools.Tool
+	opts       Options
 }
 
 // newAgentInstance creates a new AgentInstance from an AgentConfig, falling back to defaults.
@@ -56,6 +57,7 @@ func newAgentInstance(
 	memoryCfg *config.MemoryConfig,
 	costTracker *cost.CostTracker,
 	msgBus *bus.MessageBus,
+	sessionsManager *session.SessionManager,
 ) (*AgentInstance, error) {
 	// Resolve values with fallback to defaults
 	model := agentCfg.Model
@@ -63,12 +65,7 @@ func newAgentInstance(
 		model = cfg.Agents.Defaults.Model
 	}
 
-	workspace := agentCfg.Workspace
-	if workspace == "" {
-		workspace = cfg.WorkspacePath()
-	} else {
-		workspace = expandWorkspacePath(workspace)
-	}
+	workspace := agentWorkspace(agentCfg, cfg)
 
 	maxIterations := agentCfg.MaxToolIterations
 	if maxIterations == 0 {
@@ -96,16 +93,26 @@ func newAgentInstance(
 	}
 
 	// Create per-agent provider
-	provider, err := providers.CreateProviderForModel(model, providerName, cfg)
-	if err != nil {
-		return nil, fmt.Errorf("agent %q: %w", agentCfg.ID, err)
+	provider := shared.opts.Provider
+	if provider == nil {
+		var err error
+		provider, err = providers.CreateProviderForModel(model, providerName, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("agent %q: %w", agentCfg.ID, err)
+		}
+	}
+	if shared.opts.WrapProvider != nil {
+		provider = shared.opts.WrapProvider(provider)
 	}
 
 	// Ensure workspace exists
 	os.MkdirAll(workspace, 0755)
 
-	// Per-agent sessions
-	sessionsManager := session.NewSessionManager(filepath.Join(workspace, "sessions"))
+	// Per-agent sessions; a reload passes in the running agent's manager so
+	// in-memory history is kept
+	if sessionsManager == nil {
+		sessionsManager = session.NewSessionManager(filepath.Join(workspace, "sessions"))
+	}
 
 	// Per-agent tools registry
 	toolsRegistry := tools.NewToolRegistry()
@@ -129,11 +136,35 @@ func newAgentInstance(
 	registerIfAllowed(tools.NewReadFileTool(allowedDir))
 	registerIfAllowed(tools.NewWriteFileTool(allowedDir))
 	registerIfAllowed(tools.NewListDirTool(allowedDir))
+	registerIfAllowed(tools.NewGrepFilesTool(workspace, allowedDir))
 	execTool := tools.NewExecTool(workspace)
 	execTool.SetRestrictToWorkspace(cfg.IsRestrictToWorkspace())
 	registerIfAllowed(execTool)
 	registerIfAllowed(tools.NewEditFileTool(allowedDir))
 
+	// The message tool is per agent so attachments resolve against the
+	// agent's own workspace
+	messageTool := tools.NewMessageTool()
+	messageTool.SetWorkspace(workspace)
+	messageTool.SetSendCallback(func(channel, chatID, content string, attachments []bus.Attachment) error {
+		msgBus.PublishOutbound(bus.OutboundMessage{
+			Channel:     channel,
+			ChatID:      chatID,
+			Content:     content,
+			Attachments: attachments,
+		})
+		return nil
+	})
+	registerIfAllowed(messageTool)
+
+	// http_request is per agent so each agent gets its own internal-host
+	// allowlist and cookie jars
+	if httpCfg := cfg.Tools.HTTP; httpCfg.Enabled {
+		allowedHosts := append(append([]string{}, httpCfg.AllowedHosts...), agentCfg.HTTPAllowedHosts...)
+		registerIfAllowed(tools.NewHTTPRequestTool(allowedHosts, httpCredentials(httpCfg.Credentials),
+			time.Duration(httpCfg.TimeoutSeconds)*time.Second, httpCfg.MaxChars))
+	}
+
 	// Register shared tools
 	if shared.searchTool != nil {
 		registerIfAllowed(shared.searchTool)
@@ -141,9 +172,6 @@ func newAgentInstance(
 	if shared.fetchTool != nil {
 		registerIfAllowed(shared.fetchTool)
 	}
-	if shared.messageTool != nil {
-		registerIfAllowed(shared.messageTool)
-	}
 	if shared.spawnTool != nil {
 		registerIfAllowed(shared.spawnTool)
 	}
@@ -201,6 +229,14 @@ func newAgentInstance(
 	}, nil
 }
 
+// agentWorkspace resolves an agent's workspace, falling back to the default.
+func agentWorkspace(agentCfg config.AgentConfig, cfg *config.Config) string {
+	if agentCfg.Workspace == "" {
+		return cfg.WorkspacePath()
+	}
+	return expandWorkspacePath(agentCfg.Workspace)
+}
+
 // expandWorkspacePath handles ~ expansion for workspace paths.
 func expandWorkspacePath(path string) string {
 	if path == "" {
@@ -215,3 +251,23 @@ func expandWorkspacePath(path string) string {
 	}
 	return path
 }
+
+// httpCredentials converts configured credentials for the http_request tool.
+func httpCredentials(creds map[string]*config.HTTPCredential) map[string]tools.HTTPCredential {
+	out := make(map[string]tools.HTTPCredential, len(creds))
+	for name, c := range creds {
+		if c == nil {
+			continue
+		}
+		out[name] = tools.HTTPCredential{
+			Type:     c.Type,
+			Token:    c.Token,
+			Username: c.Username,
+			Password: c.Password,
+			Header:   c.Header,
+			Value:    c.Value,
+			Hosts:    c.Hosts,
+		}
+	}
+	return out
+}
@@ -20,26 +20,41 @@ import (
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/cost"
+	"github.com/sipeed/picoclaw/pkg/cron"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/memory"
+	"github.com/sipeed/picoclaw/pkg/metrics"
 	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/security"
+	"github.com/sipeed/picoclaw/pkg/session"
 	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/tracing"
 	"github.com/sipeed/picoclaw/pkg/utils"
+	"github.com/sipeed/picoclaw/pkg/voice"
 )
 
 type AgentLoop struct {
 	bus         *bus.MessageBus
-	cfg         *config.Config
+	cfg         atomic.Pointer[config.Config] // replaced, never mutated, on reload
 	registry    *AgentRegistry
 	running     atomic.Bool
 	summarizing sync.Map
 	memoryDB    *memory.MemoryDB
 	memoryCfg    *config.MemoryConfig
 	costTracker  *cost.CostTracker
-	promptGuard       *security.PromptGuard
-	leakDetector      *security.LeakDetector
+	quotas       *cost.QuotaTracker
+	promptGuard       atomic.Pointer[security.PromptGuard]
+	leakDetector      atomic.Pointer[security.LeakDetector]
+	reloadMu          sync.Mutex
 	promptLeakGuards  sync.Map // agentID -> *security.PromptLeakDetector
+	eventSink         atomic.Value // func(cron.Event)
+	router            *Router
+	cronService       *cron.CronService
+	sessionModels     sync.Map // agentID|sessionKey -> model override
+	sessionVoice      sync.Map // sessionKey -> /voice mode override
+	synthesizer       voice.Synthesizer
+	approvals         egressApprovals
+	opts              Options
 }
 
 // processOptions configures how a message is processed
@@ -53,9 +68,26 @@ type processOptions struct {
 	SendResponse    bool              // Whether to send response via bus
 	Metadata        map[string]string // Original inbound message metadata
 	Owner           string            // Memory owner (username for scoped access)
+	SenderID        string            // Original sender, for quota accounting
+}
+
+// Options customizes an AgentLoop beyond its config. The eval harness uses
+// it to serve fixture responses and to observe LLM calls.
+type Options struct {
+	// Provider serves every agent instead of the configured providers, so
+	// no API keys are needed.
+	Provider providers.LLMProvider
+	// WrapProvider wraps each agent's provider.
+	WrapProvider func(p providers.LLMProvider) providers.LLMProvider
 }
 
 func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus) (*AgentLoop, error) {
+	return NewAgentLoopWithOptions(cfg, msgBus, Options{})
+}
+
+// NewAgentLoopWithOptions is NewAgentLoop with opts applied to every agent,
+// including agents rebuilt by Reload.
+func NewAgentLoopWithOptions(cfg *config.Config, msgBus *bus.MessageBus, opts Options) (*AgentLoop, error) {
 	workspace := cfg.WorkspacePath()
 	os.MkdirAll(workspace, 0755)
 
@@ -67,6 +99,8 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus) (*AgentLoop, error
 	}
 
 	if memDB != nil {
+		metrics.MemoryDBBytes.SetFunc(func() float64 { return float64(memDB.SizeBytes()) })
+
 		// One-time migration from markdown files
 		memoryDir := filepath.Join(workspace, "memory")
 		if migErr := memDB.MigrateFromMarkdown(memoryDir); migErr != nil {
@@ -100,12 +134,56 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus) (*AgentLoop, error
 		}
 	}
 
+	// Quotas are enforced independently of cost tracking
+	quotas, quotaErr := cost.NewQuotaTracker(&cfg.Cost, workspace)
+	if quotaErr != nil {
+		logger.ErrorCF("cost", "Failed to initialize quota tracker, continuing without quotas",
+			map[string]interface{}{"error": quotaErr.Error()})
+	}
+
 	// Build shared tool instances
-	shared := buildSharedTools(cfg, msgBus, memDB, costTracker, workspace)
+	shared := buildSharedTools(cfg, msgBus, memDB, costTracker, workspace, opts)
 
 	// Build agent registry
 	registry := NewAgentRegistry()
+	instances, defaultID, err := buildAgents(cfg, shared, memDB, costTracker, msgBus, nil)
+	if err != nil {
+		return nil, err
+	}
+	registry.Replace(instances, defaultID)
+
+	router, err := NewRouter(cfg.Agents.Routing)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRouteAgents(router, registry); err != nil {
+		return nil, err
+	}
+
+	al := &AgentLoop{
+		bus:         msgBus,
+		registry:    registry,
+		summarizing: sync.Map{},
+		memoryDB:    memDB,
+		memoryCfg:   &cfg.Memory,
+		costTracker: costTracker,
+		quotas:      quotas,
+		router:      router,
+		synthesizer: voice.NewSynthesizerFromConfig(cfg),
+		opts:        opts,
+	}
+
+	al.cfg.Store(cfg)
+	al.applySecurity(cfg)
 
+	al.initDelegateTools()
+	return al, nil
+}
+
+// buildAgents creates an instance for every configured agent, synthesizing
+// an implicit "main" agent when none are listed. Agents found in previous
+// with the same workspace keep their session manager.
+func buildAgents(cfg *config.Config, shared *sharedTools, memDB *memory.MemoryDB, costTracker *cost.CostTracker, msgBus *bus.MessageBus, previous *AgentRegistry) ([]*AgentInstance, string, error) {
 	agentList := cfg.Agents.List
 	if len(agentList) == 0 {
 		// Synthesize implicit "main" agent from defaults
@@ -115,79 +193,179 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus) (*AgentLoop, error
 		}}
 	}
 
+	var (
+		instances []*AgentInstance
+		defaultID string
+	)
 	for _, agentCfg := range agentList {
-		inst, err := newAgentInstance(agentCfg, cfg, shared, memDB, &cfg.Memory, costTracker, msgBus)
+		var sessions *session.SessionManager
+		if previous != nil {
+			if old, ok := previous.Get(agentCfg.ID); ok && old.Workspace == agentWorkspace(agentCfg, cfg) {
+				sessions = old.Sessions
+			}
+		}
+		inst, err := newAgentInstance(agentCfg, cfg, shared, memDB, &cfg.Memory, costTracker, msgBus, sessions)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create agent %q: %w", agentCfg.ID, err)
+			return nil, "", fmt.Errorf("failed to create agent %q: %w", agentCfg.ID, err)
 		}
-		registry.Register(inst)
+		instances = append(instances, inst)
 		if agentCfg.Default {
-			registry.SetDefault(agentCfg.ID)
+			defaultID = agentCfg.ID
 		}
 	}
+	return instances, defaultID, nil
+}
 
-	al := &AgentLoop{
-		bus:         msgBus,
-		cfg:         cfg,
-		registry:    registry,
-		summarizing: sync.Map{},
-		memoryDB:    memDB,
-		memoryCfg:   &cfg.Memory,
-		costTracker: costTracker,
+// checkRouteAgents verifies that every routing rule names a known agent.
+func checkRouteAgents(router *Router, registry *AgentRegistry) error {
+	for _, id := range router.Agents() {
+		if _, ok := registry.Get(id); !ok {
+			return fmt.Errorf("routing rule references unknown agent %q", id)
+		}
 	}
+	return nil
+}
 
-	// Initialize security modules
+// applySecurity (re)creates the prompt guard and leak detector from cfg.
+func (al *AgentLoop) applySecurity(cfg *config.Config) {
+	var guard *security.PromptGuard
 	if cfg.Security.PromptGuard.Enabled {
-		al.promptGuard = security.NewPromptGuard(cfg.Security.PromptGuard.Action, cfg.Security.PromptGuard.Sensitivity)
+		guard = al.newPromptGuard(cfg)
 		logger.InfoCF("security", "Prompt guard enabled",
 			map[string]interface{}{"action": cfg.Security.PromptGuard.Action, "sensitivity": cfg.Security.PromptGuard.Sensitivity})
 	}
+	al.promptGuard.Store(guard)
+
+	var detector *security.LeakDetector
 	if cfg.Security.LeakDetector.Enabled {
-		al.leakDetector = security.NewLeakDetector(cfg.Security.LeakDetector.Sensitivity)
+		detector = security.NewLeakDetector(cfg.Security.LeakDetector.Sensitivity)
+		detector.AddSecrets(cfg.SecretValues())
 		logger.InfoCF("security", "Leak detector enabled",
-			map[string]interface{}{"sensitivity": cfg.Security.LeakDetector.Sensitivity})
+			map[string]interface{}{"sensitivity": cfg.Security.LeakDetector.Sensitivity, "action": cfg.Security.LeakDetector.Action})
 	}
+	al.leakDetector.Store(detector)
 
-	al.initDelegateTools()
-	return al, nil
+	al.resetPromptLeakGuards()
+}
+
+// resetPromptLeakGuards drops cached prompt leak guards so they are rebuilt
+// from the current system prompts.
+func (al *AgentLoop) resetPromptLeakGuards() {
+	al.promptLeakGuards.Range(func(key, _ interface{}) bool {
+		al.promptLeakGuards.Delete(key)
+		return true
+	})
+}
+
+// Reload applies a reloaded config. Agents, tools and routing are rebuilt
+// from newCfg and swapped in only once everything was created, so an
+// invalid config leaves the running agents untouched. Session history and
+// memory are kept. newCfg becomes the loop's config on success and must not
+// be modified afterwards.
+func (al *AgentLoop) Reload(newCfg *config.Config, changes config.Changes) error {
+	al.reloadMu.Lock()
+	defer al.reloadMu.Unlock()
+
+	if changes.Agents || changes.Routing {
+		router, err := NewRouter(newCfg.Agents.Routing)
+		if err != nil {
+			return err
+		}
+		registry := al.registry
+		if changes.Agents {
+			shared := buildSharedTools(newCfg, al.bus, al.memoryDB, al.costTracker, newCfg.WorkspacePath(), al.opts)
+			instances, defaultID, err := buildAgents(newCfg, shared, al.memoryDB, al.costTracker, al.bus, al.registry)
+			if err != nil {
+				return err
+			}
+			registry = NewAgentRegistry()
+			registry.Replace(instances, defaultID)
+		}
+		if err := checkRouteAgents(router, registry); err != nil {
+			return err
+		}
+
+		al.router.SetRules(newCfg.Agents.Routing)
+		if registry != al.registry {
+			al.registry.Replace(registry.List(), registry.GetDefault().ID)
+			al.initDelegateTools()
+			al.resetPromptLeakGuards()
+		}
+	}
+	if changes.Security {
+		al.applySecurity(newCfg)
+	}
+	if changes.Cost {
+		al.costTracker.SetConfig(newCfg.Cost)
+		al.quotas.SetConfig(newCfg.Cost)
+	}
+	al.cfg.Store(newCfg)
+	return nil
+}
+
+// currentConfig returns the config of the last successful reload.
+func (al *AgentLoop) currentConfig() *config.Config {
+	return al.cfg.Load()
+}
+
+// buildSearchBackends returns the web_search backends in the configured
+// order. Backends without an API key are skipped.
+func buildSearchBackends(cfg *config.Config, webCache *tools.WebCache) []tools.SearchBackend {
+	order := cfg.Tools.Web.SearchBackends
+	if len(order) == 0 {
+		order = []string{"ollama", "brave", "duckduckgo"}
+	}
+	var backends []tools.SearchBackend
+	for _, name := range order {
+		switch strings.ToLower(name) {
+		case "brave":
+			if cfg.Tools.Web.Search.APIKey != "" {
+				backends = append(backends, tools.NewBraveSearchBackend(cfg.Tools.Web.Search.APIKey, webCache))
+			}
+		case "ollama":
+			if cfg.Tools.Web.Ollama.APIKey != "" {
+				backends = append(backends, tools.NewOllamaSearchBackend(cfg.Tools.Web.Ollama.APIKey, webCache))
+			}
+		case "duckduckgo":
+			backends = append(backends, tools.NewDuckDuckGoSearchBackend(webCache))
+		default:
+			logger.WarnCF("agent", "Unknown search backend",
+				map[string]interface{}{"backend": name})
+		}
+	}
+	return backends
 }
 
 // buildSharedTools creates tool instances that are shared across all agents.
-func buildSharedTools(cfg *config.Config, msgBus *bus.MessageBus, memDB *memory.MemoryDB, costTracker *cost.CostTracker, workspace string) *sharedTools {
-	shared := &sharedTools{}
+func buildSharedTools(cfg *config.Config, msgBus *bus.MessageBus, memDB *memory.MemoryDB, costTracker *cost.CostTracker, workspace string, opts Options) *sharedTools {
+	shared := &sharedTools{opts: opts}
 
 	// Web search / fetch tools
-	ollamaAPIKey := cfg.Tools.Web.Ollama.APIKey
-	if ollamaAPIKey != "" {
-		shared.searchTool = tools.NewOllamaSearchTool(ollamaAPIKey, cfg.Tools.Web.Ollama.MaxResults)
-		shared.fetchTool = tools.NewOllamaFetchTool(ollamaAPIKey)
+	var webCache *tools.WebCache
+	if cacheCfg := cfg.Tools.Web.Cache; cacheCfg.Enabled && cacheCfg.TTLMinutes > 0 {
+		webCache = tools.NewWebCache(filepath.Join(workspace, "cache", "web"),
+			time.Duration(cacheCfg.TTLMinutes)*time.Minute, int64(cacheCfg.MaxSizeMB)<<20)
+	}
+	shared.searchTool = tools.NewWebSearchTool(buildSearchBackends(cfg, webCache),
+		cfg.Tools.Web.Search.MaxResults, cfg.Tools.Web.SearchStrategy)
+	if ollamaAPIKey := cfg.Tools.Web.Ollama.APIKey; ollamaAPIKey != "" {
+		shared.fetchTool = tools.NewOllamaFetchTool(ollamaAPIKey, webCache)
 	} else {
-		braveAPIKey := cfg.Tools.Web.Search.APIKey
-		if braveAPIKey != "" {
-			shared.searchTool = tools.NewWebSearchTool(braveAPIKey, cfg.Tools.Web.Search.MaxResults)
-		} else {
-			shared.searchTool = tools.NewDuckDuckGoSearchTool(5)
-		}
-		shared.fetchTool = tools.NewWebFetchTool(50000)
+		shared.fetchTool = tools.NewWebFetchTool(50000, webCache)
 	}
 
-	// Message tool
-	messageTool := tools.NewMessageTool()
-	messageTool.SetSendCallback(func(channel, chatID, content string) error {
-		msgBus.PublishOutbound(bus.OutboundMessage{
-			Channel: channel,
-			ChatID:  chatID,
-			Content: content,
-		})
-		return nil
-	})
-	shared.messageTool = messageTool
-
 	// Spawn tool (uses default provider -- will be created per first agent)
 	// We use a deferred provider approach: create with nil, set later
 	// For now, spawn needs a provider. We create one from defaults.
-	defaultProvider, provErr := providers.CreateProvider(cfg)
+	defaultProvider := opts.Provider
+	var provErr error
+	if defaultProvider == nil {
+		defaultProvider, provErr = providers.CreateProvider(cfg)
+	}
 	if provErr == nil {
+		if opts.WrapProvider != nil {
+			defaultProvider = opts.WrapProvider(defaultProvider)
+		}
 		subagentManager := tools.NewSubagentManager(defaultProvider, workspace, msgBus)
 		shared.spawnTool = tools.NewSpawnTool(subagentManager)
 	}
@@ -217,54 +395,154 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 			if !ok {
 				continue
 			}
+			al.handleInbound(ctx, msg)
+		}
+	}
 
-			// Resolve agent for this message
-			inst := al.resolveAgent(msg)
+	return nil
+}
 
-			senderName := msg.Metadata["username"]
-			if senderName == "" {
-				senderName = msg.Metadata["user_id"]
-			}
-			inst.Sessions.AddToLog(msg.SessionKey, msg.Content, msg.SenderID, senderName)
+// handleInbound runs one turn for an inbound message and publishes the
+// reply. With tracing on, the turn is a root span whose trace ID is kept in
+// msg.Metadata so follow-up system messages join the same trace.
+func (al *AgentLoop) handleInbound(ctx context.Context, msg bus.InboundMessage) {
+	if tracing.Enabled() {
+		if msg.Metadata == nil {
+			msg.Metadata = make(map[string]string)
+		}
+		if msg.Metadata[tracing.MetadataKey] == "" {
+			msg.Metadata[tracing.MetadataKey] = tracing.NewTraceID()
+		}
+		ctx = tracing.WithTraceID(ctx, msg.Metadata[tracing.MetadataKey])
+	}
+	ctx, span := tracing.Start(ctx, "agent.turn")
+	defer span.End()
+	span.SetAttr("channel", msg.Channel)
+	span.SetAttr("chat_id", msg.ChatID)
 
-			if msg.Metadata["observe_only"] == "true" {
-				continue
-			}
+	// Resolve agent for this message
+	inst := al.resolveAgent(ctx, &msg)
+	span.SetAttr("agent_id", inst.ID)
+	ctx = logger.WithSession(ctx, msg.SessionKey, inst.ID)
 
-			response, err := al.processMessage(ctx, inst, msg)
-			if err != nil {
-				logger.ErrorCF("agent", "Failed to process message", map[string]interface{}{
-					"error":   err.Error(),
-					"channel": msg.Channel,
-					"chat_id": msg.ChatID,
-				})
-				response = "Something went wrong, please try again later."
-			}
+	senderName := msg.Metadata["username"]
+	if senderName == "" {
+		senderName = msg.Metadata["user_id"]
+	}
+	inst.Sessions.AddToLog(msg.SessionKey, msg.Content, msg.SenderID, senderName)
 
-			if response != "" {
-				al.bus.PublishOutbound(bus.OutboundMessage{
-					Channel: msg.Channel,
-					ChatID:  msg.ChatID,
-					Content: response,
-				})
-			}
-		}
+	if msg.Channel != "system" {
+		al.emitEvent(cron.Event{
+			Source: cron.EventSourceMessage,
+			Attrs: map[string]string{
+				"channel": msg.Channel,
+				"chat_id": msg.ChatID,
+				"sender":  senderName,
+			},
+			Payload: msg.Content,
+		})
 	}
 
-	return nil
+	if msg.Metadata["observe_only"] == "true" {
+		span.SetAttr("observe_only", true)
+		return
+	}
+
+	response, err := al.processMessage(ctx, inst, msg, false)
+	if err != nil {
+		logger.ErrorCtx(ctx, "agent", "Failed to process message", map[string]interface{}{
+			"error":   err.Error(),
+			"channel": msg.Channel,
+			"chat_id": msg.ChatID,
+		})
+		span.SetError(err)
+		response = "Something went wrong, please try again later."
+	}
+
+	if response != "" {
+		_, sendSpan := tracing.Start(ctx, "agent.send")
+		out := bus.OutboundMessage{
+			Channel: msg.Channel,
+			ChatID:  msg.ChatID,
+			Content: response,
+		}
+		if err == nil && !isCommand(msg.Content) {
+			al.attachVoiceReply(ctx, msg, &out)
+		}
+		sendSpan.SetAttr("chars", len(out.Content))
+		al.bus.PublishOutbound(out)
+		sendSpan.End()
+	}
 }
 
-// resolveAgent picks the agent instance for a message.
-// Uses msg.Metadata["agent_id"] if set, otherwise the default agent.
-func (al *AgentLoop) resolveAgent(msg bus.InboundMessage) *AgentInstance {
+// resolveAgent picks the agent instance for a message. In order:
+// msg.Metadata["agent_id"] from allow_from or temp_allow_agent, the
+// session's /agent override, the first matching routing rule (whose prefix
+// is stripped from msg.Content), the LLM classifier, and finally the
+// default agent. The sender binding comes first so a restricted sender
+// cannot reach another agent through a pin or a rule prefix.
+func (al *AgentLoop) resolveAgent(ctx context.Context, msg *bus.InboundMessage) *AgentInstance {
 	if agentID := msg.Metadata["agent_id"]; agentID != "" {
 		if inst, ok := al.registry.Get(agentID); ok {
 			return inst
 		}
 	}
+	if msg.Channel != "system" {
+		if agentID, ok := al.router.Override(msg.SessionKey); ok {
+			if inst, ok := al.registry.Get(agentID); ok {
+				return inst
+			}
+		}
+		if agentID, content, ok := al.router.Match(*msg); ok {
+			if inst, ok := al.registry.Get(agentID); ok {
+				msg.Content = content
+				logger.DebugCF("agent", "Message routed by rule", map[string]interface{}{
+					"agent_id": agentID,
+					"channel":  msg.Channel,
+					"chat_id":  msg.ChatID,
+				})
+				return inst
+			}
+		}
+	}
+	if msg.Channel != "system" && msg.Metadata["observe_only"] != "true" && !isCommand(msg.Content) {
+		if agentID := al.classifyAgent(ctx, msg.Content); agentID != "" {
+			if inst, ok := al.registry.Get(agentID); ok {
+				logger.DebugCF("agent", "Message routed by classifier", map[string]interface{}{
+					"agent_id": agentID,
+				})
+				return inst
+			}
+		}
+	}
 	return al.registry.GetDefault()
 }
 
+// SetEventSink registers a callback that receives inbound chat messages and
+// memory writes, so event-triggered cron jobs can react to them.
+func (al *AgentLoop) SetEventSink(sink func(cron.Event)) {
+	al.eventSink.Store(sink)
+	if al.memoryDB != nil {
+		al.memoryDB.SetStoreHook(func(key, content, category, owner string) {
+			al.emitEvent(cron.Event{
+				Source: cron.EventSourceMemory,
+				Attrs: map[string]string{
+					"key":      key,
+					"category": category,
+				},
+				Payload: content,
+			})
+		})
+	}
+}
+
+// emitEvent forwards an event to the registered sink, if any.
+func (al *AgentLoop) emitEvent(ev cron.Event) {
+	if sink, ok := al.eventSink.Load().(func(cron.Event)); ok && sink != nil {
+		sink(ev)
+	}
+}
+
 func (al *AgentLoop) Stop() {
 	al.running.Store(false)
 }
@@ -276,7 +554,7 @@ func (al *AgentLoop) Shutdown() {
 	}
 
 	defaultInst := al.registry.GetDefault()
-	workspace := al.cfg.WorkspacePath()
+	workspace := al.currentConfig().WorkspacePath()
 	if defaultInst != nil {
 		workspace = defaultInst.Workspace
 	}
@@ -305,10 +583,21 @@ func (al *AgentLoop) RegisterTool(tool tools.Tool) {
 	}
 }
 
+// ProcessDirect runs a turn for input typed into the local CLI.
 func (al *AgentLoop) ProcessDirect(ctx context.Context, content, sessionKey string) (string, error) {
-	return al.ProcessDirectWithChannel(ctx, content, sessionKey, "cli", "direct")
+	msg := bus.InboundMessage{
+		Channel:    "cli",
+		SenderID:   "cron",
+		ChatID:     "direct",
+		Content:    content,
+		SessionKey: sessionKey,
+	}
+
+	return al.processMessage(ctx, al.registry.GetDefault(), msg, false)
 }
 
+// ProcessDirectWithChannel runs an internal turn, such as a scheduled job,
+// on the default agent. Slash commands are not parsed.
 func (al *AgentLoop) ProcessDirectWithChannel(ctx context.Context, content, sessionKey, channel, chatID string) (string, error) {
 	inst := al.registry.GetDefault()
 
@@ -320,19 +609,45 @@ func (al *AgentLoop) ProcessDirectWithChannel(ctx context.Context, content, sess
 		SessionKey: sessionKey,
 	}
 
-	return al.processMessage(ctx, inst, msg)
+	return al.processMessage(ctx, inst, msg, true)
+}
+
+// ProcessDirectForAgent is ProcessDirectWithChannel for a specific agent.
+// Unknown or empty agent IDs fall back to the default agent.
+func (al *AgentLoop) ProcessDirectForAgent(ctx context.Context, agentID, content, sessionKey, channel, chatID string) (string, error) {
+	inst, ok := al.registry.Get(agentID)
+	if !ok {
+		if agentID != "" {
+			logger.WarnCF("agent", "Unknown agent, using default", map[string]interface{}{
+				"agent_id": agentID,
+			})
+		}
+		inst = al.registry.GetDefault()
+	}
+
+	msg := bus.InboundMessage{
+		Channel:    channel,
+		SenderID:   "cron",
+		ChatID:     chatID,
+		Content:    content,
+		SessionKey: sessionKey,
+	}
+
+	return al.processMessage(ctx, inst, msg, true)
 }
 
-func (al *AgentLoop) processMessage(ctx context.Context, inst *AgentInstance, msg bus.InboundMessage) (string, error) {
+// processMessage runs one turn. Internal turns come from cron jobs and
+// heartbeats rather than a user, so they are not parsed for commands.
+func (al *AgentLoop) processMessage(ctx context.Context, inst *AgentInstance, msg bus.InboundMessage, internal bool) (string, error) {
+	ctx = logger.WithSession(ctx, msg.SessionKey, inst.ID)
+
 	// Add message preview to log
 	preview := utils.Truncate(msg.Content, 80)
-	logger.InfoCF("agent", fmt.Sprintf("Processing message from %s:%s: %s", msg.Channel, msg.SenderID, preview),
+	logger.InfoCtx(ctx, "agent", fmt.Sprintf("Processing message from %s:%s: %s", msg.Channel, msg.SenderID, preview),
 		map[string]interface{}{
-			"channel":     msg.Channel,
-			"chat_id":     msg.ChatID,
-			"sender_id":   msg.SenderID,
-			"session_key": msg.SessionKey,
-			"agent_id":    inst.ID,
+			"channel":   msg.Channel,
+			"chat_id":   msg.ChatID,
+			"sender_id": msg.SenderID,
 		})
 
 	// Route system messages to processSystemMessage
@@ -340,6 +655,26 @@ func (al *AgentLoop) processMessage(ctx context.Context, inst *AgentInstance, ms
 		return al.processSystemMessage(ctx, inst, msg)
 	}
 
+	// Slash commands are answered directly without calling the LLM
+	if !internal {
+		if reply, handled := al.handleCommand(inst, msg); handled {
+			return reply, nil
+		}
+	}
+
+	// Per-owner and per-chat quotas (the local CLI and internal turns are never limited)
+	if msg.Channel != "cli" && !internal {
+		if check := al.quotas.Allow(msg.Channel, msg.ChatID, msg.SenderID, resolveOwner(msg.Metadata)); !check.Allowed {
+			logger.WarnCF("cost", "Quota exceeded",
+				map[string]interface{}{
+					"channel":   msg.Channel,
+					"chat_id":   msg.ChatID,
+					"sender_id": msg.SenderID,
+				})
+			return check.Message, nil
+		}
+	}
+
 	// In group chats, prepend sender name so the LLM can distinguish users
 	userMessage := msg.Content
 	if isGroupMessage(msg.Metadata) {
@@ -350,18 +685,21 @@ func (al *AgentLoop) processMessage(ctx context.Context, inst *AgentInstance, ms
 	}
 
 	// Prompt guard: scan user input
-	if al.promptGuard != nil {
-		guardResult := al.promptGuard.Scan(userMessage)
+	if promptGuard := al.promptGuard.Load(); promptGuard != nil {
+		guardResult := promptGuard.ScanContext(ctx, userMessage)
 		if !guardResult.Safe {
+			action := promptGuard.ActionFor(msg.Channel, isGroupMessage(msg.Metadata))
 			logger.WarnCF("security", "Prompt injection detected in user input",
 				map[string]interface{}{
-					"patterns": guardResult.Patterns,
-					"score":    guardResult.Score,
-					"action":   string(guardResult.Action),
-					"channel":  msg.Channel,
-					"chat_id":  msg.ChatID,
+					"patterns":   guardResult.Patterns,
+					"score":      guardResult.Score,
+					"classified": guardResult.Classified,
+					"action":     string(action),
+					"channel":    msg.Channel,
+					"chat_id":    msg.ChatID,
 				})
-			if guardResult.Action == security.ActionBlock {
+			metrics.SecurityDetections.Inc("prompt_guard", string(action))
+			if action == security.ActionBlock {
 				return "Message blocked by security policy.", nil
 			}
 		}
@@ -378,6 +716,7 @@ func (al *AgentLoop) processMessage(ctx context.Context, inst *AgentInstance, ms
 		SendResponse:    false,
 		Metadata:        msg.Metadata,
 		Owner:           resolveOwner(msg.Metadata),
+		SenderID:        msg.SenderID,
 	})
 }
 
@@ -480,13 +819,21 @@ func (al *AgentLoop) processSystemMessage(ctx context.Context, inst *AgentInstan
 // runAgentLoop is the core message processing logic.
 // It handles context building, LLM calls, tool execution, and response handling.
 func (al *AgentLoop) runAgentLoop(ctx context.Context, inst *AgentInstance, opts processOptions) (string, error) {
+	ctx, span := tracing.Start(ctx, "agent.run")
+	defer span.End()
+	span.SetAttr("agent_id", inst.ID)
+	span.SetAttr("session_key", opts.SessionKey)
+	ctx = logger.WithSession(ctx, opts.SessionKey, inst.ID)
+
 	// 1. Update tool contexts
 	al.updateToolContexts(inst, opts.Channel, opts.ChatID, opts.Owner)
 
 	// 2. Build messages
+	buildCtx, buildSpan := tracing.Start(ctx, "agent.context")
 	history := inst.Sessions.GetHistory(opts.SessionKey)
 	summary := inst.Sessions.GetSummary(opts.SessionKey)
-	messages := inst.ContextBuilder.BuildMessages(
+	messages := inst.ContextBuilder.BuildMessagesContext(
+		buildCtx,
 		history,
 		summary,
 		opts.UserMessage,
@@ -495,6 +842,8 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, inst *AgentInstance, opts
 		opts.ChatID,
 		opts.Owner,
 	)
+	buildSpan.SetAttr("messages", len(messages))
+	buildSpan.End()
 
 	// 3. Save user message to session
 	inst.Sessions.AddMessage(opts.SessionKey, "user", opts.UserMessage)
@@ -502,6 +851,7 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, inst *AgentInstance, opts
 	// 4. Run LLM iteration loop
 	finalContent, iteration, err := al.runLLMIteration(ctx, inst, messages, opts)
 	if err != nil {
+		span.SetError(err)
 		return "", err
 	}
 
@@ -510,33 +860,23 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, inst *AgentInstance, opts
 		finalContent = opts.DefaultResponse
 	}
 
-	// 5.5. Leak detector: scan outbound content
-	if al.leakDetector != nil {
-		leakResult := al.leakDetector.Scan(finalContent)
-		if !leakResult.Clean {
-			logger.WarnCF("security", "Credential leak detected in response",
-				map[string]interface{}{
-					"patterns":    leakResult.Patterns,
-					"session_key": opts.SessionKey,
-				})
-			finalContent = leakResult.Redacted
-		}
-	}
+	// 5.5. Leak detector: scan outbound content (replies and delegate results)
+	finalContent = al.filterResponse(opts, finalContent)
 
 	// 5.6. Prompt leak guard: detect system prompt content in output
-	if al.cfg.Security.PromptLeakGuard.Enabled {
+	if al.currentConfig().Security.PromptLeakGuard.Enabled {
 		plg := al.getPromptLeakGuard(inst)
 		if plg != nil {
 			plResult := plg.Scan(finalContent)
 			if plResult.Leaked {
-				logger.WarnCF("security", "System prompt leakage detected in response",
+				logger.WarnCtx(ctx, "security", "System prompt leakage detected in response",
 					map[string]interface{}{
-						"matched":     plResult.MatchedCount,
-						"total":       plResult.TotalPrints,
-						"score":       plResult.Score,
-						"action":      string(plResult.Action),
-						"session_key": opts.SessionKey,
+						"matched": plResult.MatchedCount,
+						"total":   plResult.TotalPrints,
+						"score":   plResult.Score,
+						"action":  string(plResult.Action),
 					})
+				metrics.SecurityDetections.Inc("prompt_leak_guard", string(plResult.Action))
 				if plResult.Action == security.ActionBlock {
 					finalContent = "I'm unable to share my system instructions."
 				}
@@ -565,12 +905,12 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, inst *AgentInstance, opts
 
 	// 9. Log response
 	responsePreview := utils.Truncate(finalContent, 120)
-	logger.InfoCF("agent", fmt.Sprintf("Response: %s", responsePreview),
+	logger.InfoCtx(ctx, "agent", fmt.Sprintf("Response: %s", responsePreview),
 		map[string]interface{}{
-			"session_key":  opts.SessionKey,
 			"iterations":   iteration,
 			"final_length": len(finalContent),
 		})
+	span.SetAttr("iterations", iteration)
 
 	return finalContent, nil
 }
@@ -583,10 +923,11 @@ func (al *AgentLoop) getPromptLeakGuard(inst *AgentInstance) *security.PromptLea
 		return v.(*security.PromptLeakDetector)
 	}
 	systemPrompt := inst.ContextBuilder.BuildSystemPrompt()
+	plgCfg := al.currentConfig().Security.PromptLeakGuard
 	plg := security.NewPromptLeakDetector(
 		systemPrompt,
-		al.cfg.Security.PromptLeakGuard.Threshold,
-		al.cfg.Security.PromptLeakGuard.Action,
+		plgCfg.Threshold,
+		plgCfg.Action,
 	)
 	al.promptLeakGuards.Store(inst.ID, plg)
 	logger.DebugCF("security", "Prompt leak guard initialized",
@@ -602,11 +943,15 @@ func (al *AgentLoop) getPromptLeakGuard(inst *AgentInstance) *security.PromptLea
 func (al *AgentLoop) runLLMIteration(ctx context.Context, inst *AgentInstance, messages []providers.Message, opts processOptions) (string, int, error) {
 	iteration := 0
 	var finalContent string
+	model := al.modelFor(inst, opts.SessionKey)
 
 	for iteration < inst.MaxIterations {
 		iteration++
 
-		logger.DebugCF("agent", "LLM iteration",
+		iterCtx, iterSpan := tracing.Start(ctx, "llm.iteration")
+		iterSpan.SetAttr("iteration", iteration)
+
+		logger.DebugCtx(iterCtx, "agent", "LLM iteration",
 			map[string]interface{}{
 				"iteration": iteration,
 				"max":       inst.MaxIterations,
@@ -627,10 +972,10 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, inst *AgentInstance, m
 		}
 
 		// Log LLM request details
-		logger.DebugCF("agent", "LLM request",
+		logger.DebugCtx(iterCtx, "agent", "LLM request",
 			map[string]interface{}{
 				"iteration":         iteration,
-				"model":             inst.Model,
+				"model":             model,
 				"messages_count":    len(messages),
 				"tools_count":       len(providerToolDefs),
 				"max_tokens":        8192,
@@ -639,7 +984,7 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, inst *AgentInstance, m
 			})
 
 		// Log full messages (detailed)
-		logger.DebugCF("agent", "Full LLM request",
+		logger.DebugCtx(iterCtx, "agent", "Full LLM request",
 			map[string]interface{}{
 				"iteration":     iteration,
 				"messages_json": formatMessagesForLog(messages),
@@ -653,6 +998,7 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, inst *AgentInstance, m
 				msg := fmt.Sprintf("Budget exceeded: $%.4f / $%.4f %s limit",
 					check.CurrentUSD, check.LimitUSD, check.Period)
 				logger.ErrorCF("cost", msg, nil)
+				iterSpan.End()
 				return msg, iteration, nil
 			}
 			if check.Status == cost.BudgetWarning {
@@ -662,33 +1008,49 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, inst *AgentInstance, m
 		}
 
 		// Call LLM
-		response, err := inst.Provider.Chat(ctx, messages, providerToolDefs, inst.Model, map[string]interface{}{
+		chatCtx, chatSpan := tracing.Start(iterCtx, "llm.chat")
+		chatSpan.SetAttr("model", model)
+		chatSpan.SetAttr("messages", len(messages))
+		response, err := al.chat(chatCtx, inst, opts, iteration, messages, providerToolDefs, model, map[string]interface{}{
 			"max_tokens":  8192,
 			"temperature": inst.Temperature,
 		})
+		chatSpan.SetError(err)
+		if err == nil && response.Usage != nil {
+			chatSpan.SetAttr("prompt_tokens", response.Usage.PromptTokens)
+			chatSpan.SetAttr("completion_tokens", response.Usage.CompletionTokens)
+		}
+		chatSpan.End()
 
 		if err != nil {
-			logger.ErrorCF("agent", "LLM call failed",
+			logger.ErrorCtx(iterCtx, "agent", "LLM call failed",
 				map[string]interface{}{
 					"iteration": iteration,
 					"error":     err.Error(),
 				})
+			iterSpan.SetError(err)
+			iterSpan.End()
 			return "", iteration, fmt.Errorf("LLM call failed: %w", err)
 		}
 
 		// Record usage after successful LLM call
 		if al.costTracker != nil && response.Usage != nil {
-			al.costTracker.RecordUsage(inst.Model, response.Usage.PromptTokens, response.Usage.CompletionTokens)
+			al.costTracker.RecordUsage(model, response.Usage.PromptTokens, response.Usage.CompletionTokens)
+		}
+		if response.Usage != nil && opts.SenderID != "" {
+			al.quotas.RecordUsage(opts.Channel, opts.ChatID, opts.SenderID, opts.Owner,
+				model, response.Usage.PromptTokens, response.Usage.CompletionTokens)
 		}
 
 		// Check if no tool calls - we're done
 		if len(response.ToolCalls) == 0 {
 			finalContent = response.Content
-			logger.InfoCF("agent", "LLM response without tool calls (direct answer)",
+			logger.InfoCtx(iterCtx, "agent", "LLM response without tool calls (direct answer)",
 				map[string]interface{}{
 					"iteration":     iteration,
 					"content_chars": len(finalContent),
 				})
+			iterSpan.End()
 			break
 		}
 
@@ -697,12 +1059,13 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, inst *AgentInstance, m
 		for _, tc := range response.ToolCalls {
 			toolNames = append(toolNames, tc.Name)
 		}
-		logger.InfoCF("agent", "LLM requested tool calls",
+		logger.InfoCtx(iterCtx, "agent", "LLM requested tool calls",
 			map[string]interface{}{
 				"tools":     toolNames,
 				"count":     len(toolNames),
 				"iteration": iteration,
 			})
+		iterSpan.SetAttr("tool_calls", len(toolNames))
 
 		// React to sender message to indicate tool call activity
 		if msgID := opts.Metadata["message_id"]; msgID != "" {
@@ -740,32 +1103,39 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, inst *AgentInstance, m
 
 		// Execute tool calls
 		for _, tc := range response.ToolCalls {
+			// Leak detector: scan arguments before they leave the workspace
+			args, blocked := al.checkToolArgs(inst, tc.Name, tc.Arguments, opts.Channel, opts.ChatID)
+
 			// Log tool call with arguments preview
-			argsJSON, _ := json.Marshal(tc.Arguments)
+			argsJSON, _ := json.Marshal(args)
 			argsPreview := utils.Truncate(string(argsJSON), 200)
-			logger.InfoCF("agent", fmt.Sprintf("Tool call: %s(%s)", tc.Name, argsPreview),
+			toolCtx, toolSpan := tracing.Start(iterCtx, "tool."+tc.Name)
+			toolSpan.SetAttr("tool", tc.Name)
+			logger.InfoCtx(toolCtx, "agent", fmt.Sprintf("Tool call: %s(%s)", tc.Name, argsPreview),
 				map[string]interface{}{
 					"tool":      tc.Name,
 					"iteration": iteration,
 				})
 
-			result, err := inst.Tools.ExecuteWithContext(ctx, tc.Name, tc.Arguments, opts.Channel, opts.ChatID)
-			if err != nil {
-				result = fmt.Sprintf("Error: %v", err)
+			emitTurn(ctx, inst, opts, TurnEvent{Type: EventToolStart, Iteration: iteration, ToolCallID: tc.ID, Tool: tc.Name, Args: args})
+			toolStart := time.Now()
+			result := blocked
+			if blocked == "" {
+				var err error
+				result, err = inst.Tools.ExecuteWithContext(toolCtx, tc.Name, args, opts.Channel, opts.ChatID)
+				if err != nil {
+					result = fmt.Sprintf("Error: %v", err)
+				}
+				toolSpan.SetError(err)
+			} else {
+				toolSpan.SetAttr("withheld", true)
 			}
 
 			// Prompt guard: scan tool results for injection attempts
-			if al.promptGuard != nil {
-				toolGuard := al.promptGuard.Scan(result)
-				if !toolGuard.Safe {
-					logger.WarnCF("security", "Prompt injection detected in tool result",
-						map[string]interface{}{
-							"tool":     tc.Name,
-							"patterns": toolGuard.Patterns,
-							"score":    toolGuard.Score,
-						})
-				}
-			}
+			result = al.guardToolResult(toolCtx, inst, tc.Name, result)
+			toolSpan.SetAttr("result_chars", len(result))
+			toolSpan.End()
+			emitTurn(ctx, inst, opts, TurnEvent{Type: EventToolDone, Iteration: iteration, ToolCallID: tc.ID, Tool: tc.Name, Args: args, Result: result, Duration: time.Since(toolStart)})
 
 			toolResultMsg := providers.Message{
 				Role:       "tool",
@@ -777,6 +1147,7 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, inst *AgentInstance, m
 			// Save tool result message to session
 			inst.Sessions.AddFullMessage(opts.SessionKey, toolResultMsg)
 		}
+		iterSpan.End()
 	}
 
 	return finalContent, iteration, nil
@@ -849,6 +1220,10 @@ func (al *AgentLoop) RunDelegate(ctx context.Context, agentID, task, channel, ch
 
 	sessionKey := fmt.Sprintf("delegate:%s:%s:%d", agentID, chatID, time.Now().UnixMilli())
 
+	ctx, span := tracing.Start(ctx, "agent.delegate")
+	defer span.End()
+	span.SetAttr("target_agent", agentID)
+
 	return al.runAgentLoop(ctx, inst, processOptions{
 		SessionKey:      sessionKey,
 		Channel:         channel,
@@ -868,10 +1243,16 @@ func (al *AgentLoop) RunDelegateAsync(ctx context.Context, agentID, task, label,
 		return "", fmt.Errorf("agent %q not found", agentID)
 	}
 
+	// The delegate outlives this tool call but stays in the caller's trace
+	spanCtx, span := tracing.Start(tracing.Detach(ctx), "agent.delegate")
+	span.SetAttr("target_agent", agentID)
+	span.SetAttr("async", true)
+
 	go func() {
+		defer span.End()
 		sessionKey := fmt.Sprintf("delegate:%s:%s:%d", agentID, chatID, time.Now().UnixMilli())
 
-		result, err := al.runAgentLoop(context.Background(), inst, processOptions{
+		result, err := al.runAgentLoop(spanCtx, inst, processOptions{
 			SessionKey:      sessionKey,
 			Channel:         channel,
 			ChatID:          chatID,
@@ -884,6 +1265,7 @@ func (al *AgentLoop) RunDelegateAsync(ctx context.Context, agentID, task, label,
 		content := result
 		if err != nil {
 			content = fmt.Sprintf("Delegate to %s failed: %v", agentID, err)
+			span.SetError(err)
 		}
 
 		al.bus.PublishInbound(bus.InboundMessage{
@@ -891,12 +1273,21 @@ func (al *AgentLoop) RunDelegateAsync(ctx context.Context, agentID, task, label,
 			SenderID: fmt.Sprintf("delegate:%s", agentID),
 			ChatID:   fmt.Sprintf("%s:%s", channel, chatID),
 			Content:  fmt.Sprintf("Task '%s' completed.\n\nResult:\n%s", label, content),
+			Metadata: traceMetadata(spanCtx),
 		})
 	}()
 
 	return fmt.Sprintf("Delegated task to agent %q (async). Result will be reported when done.", agentID), nil
 }
 
+// traceMetadata carries ctx's trace ID to a follow-up inbound message.
+func traceMetadata(ctx context.Context) map[string]string {
+	if id := tracing.TraceIDFromContext(ctx); id != "" {
+		return map[string]string{tracing.MetadataKey: id}
+	}
+	return nil
+}
+
 // ListAgents returns metadata for all registered agents.
 func (al *AgentLoop) ListAgents() []tools.AgentInfo {
 	agents := al.registry.List()
@@ -917,7 +1308,7 @@ func (al *AgentLoop) maybeSummarize(inst *AgentInstance, sessionKey string) {
 		if _, loading := al.summarizing.LoadOrStore(sessionKey, true); !loading {
 			go func() {
 				defer al.summarizing.Delete(sessionKey)
-				al.summarizeSession(inst, sessionKey)
+				al.summarizeSession(inst, sessionKey, 4)
 			}()
 		}
 	}
@@ -1002,20 +1393,21 @@ func formatToolsForLog(toolDefs []providers.ToolDefinition) string {
 	return result
 }
 
-// summarizeSession summarizes the conversation history for a session.
-func (al *AgentLoop) summarizeSession(inst *AgentInstance, sessionKey string) {
+// summarizeSession summarizes the conversation history for a session,
+// keeping the last keepLast messages.
+func (al *AgentLoop) summarizeSession(inst *AgentInstance, sessionKey string, keepLast int) {
 	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
 	defer cancel()
 
 	history := inst.Sessions.GetHistory(sessionKey)
 	summary := inst.Sessions.GetSummary(sessionKey)
 
-	// Keep last 4 messages for continuity
-	if len(history) <= 4 {
+	// Keep the last messages for continuity
+	if len(history) <= keepLast {
 		return
 	}
 
-	toSummarize := history[:len(history)-4]
+	toSummarize := history[:len(history)-keepLast]
 
 	// Oversized Message Guard
 	// Skip messages larger than 50% of context window to prevent summarizer overflow
@@ -1072,7 +1464,7 @@ func (al *AgentLoop) summarizeSession(inst *AgentInstance, sessionKey string) {
 
 	if finalSummary != "" {
 		inst.Sessions.SetSummary(sessionKey, finalSummary)
-		inst.Sessions.TruncateHistory(sessionKey, 4)
+		inst.Sessions.TruncateHistory(sessionKey, keepLast)
 		inst.Sessions.Save(inst.Sessions.GetOrCreate(sessionKey))
 	}
 }
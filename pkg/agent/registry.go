@@ -33,6 +33,21 @@ func (r *AgentRegistry) SetDefault(id string) {
 	r.defaultID = id
 }
 
+// Replace swaps in a new set of agents, e.g. after a config reload.
+func (r *AgentRegistry) Replace(instances []*AgentInstance, defaultID string) {
+	agents := make(map[string]*AgentInstance, len(instances))
+	for _, inst := range instances {
+		agents[inst.ID] = inst
+	}
+	if defaultID == "" && len(instances) > 0 {
+		defaultID = instances[0].ID
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.agents = agents
+	r.defaultID = defaultID
+}
+
 // Get returns an agent by ID.
 func (r *AgentRegistry) Get(id string) (*AgentInstance, bool) {
 	r.mu.RLock()
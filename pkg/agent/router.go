@@ -0,0 +1,230 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// Router picks an agent for inbound messages using configured rules and
+// per-session overrides set with the /agent chat command.
+type Router struct {
+	rules     []routeRule
+	overrides sync.Map // sessionKey -> agentID
+	mu        sync.RWMutex
+}
+
+type routeRule struct {
+	config.RoutingRule
+	chatIDs map[string]bool
+	re      *regexp.Regexp
+}
+
+// NewRouter compiles routing rules. Rules must name an agent and use a
+// valid chat_type and pattern.
+func NewRouter(cfg config.RoutingConfig) (*Router, error) {
+	rules, err := compileRules(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Router{rules: rules}, nil
+}
+
+// SetRules replaces the routing rules, keeping session overrides. The
+// current rules stay in place if the new ones do not compile.
+func (r *Router) SetRules(cfg config.RoutingConfig) error {
+	rules, err := compileRules(cfg)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.rules = rules
+	r.mu.Unlock()
+	return nil
+}
+
+func compileRules(cfg config.RoutingConfig) ([]routeRule, error) {
+	var rules []routeRule
+	for i, rc := range cfg.Rules {
+		if rc.Agent == "" {
+			return nil, fmt.Errorf("routing rule %d: agent is required", i)
+		}
+		if rc.ChatType != "" && rc.ChatType != "group" && rc.ChatType != "dm" {
+			return nil, fmt.Errorf("routing rule %d: chat_type must be \"group\" or \"dm\"", i)
+		}
+		rule := routeRule{RoutingRule: rc}
+		if len(rc.ChatIDs) > 0 {
+			rule.chatIDs = make(map[string]bool, len(rc.ChatIDs))
+			for _, id := range rc.ChatIDs {
+				rule.chatIDs[id] = true
+			}
+		}
+		if rc.Pattern != "" {
+			re, err := regexp.Compile(rc.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("routing rule %d: invalid pattern: %w", i, err)
+			}
+			rule.re = re
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Agents returns the agent IDs referenced by rules.
+func (r *Router) Agents() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ids := make([]string, 0, len(r.rules))
+	for _, rule := range r.rules {
+		ids = append(ids, rule.Agent)
+	}
+	return ids
+}
+
+// Match returns the agent of the first matching rule and the message text
+// with that rule's prefix removed.
+func (r *Router) Match(msg bus.InboundMessage) (agentID, content string, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, rule := range r.rules {
+		if rule.Channel != "" && rule.Channel != msg.Channel {
+			continue
+		}
+		if rule.chatIDs != nil && !rule.chatIDs[msg.ChatID] {
+			continue
+		}
+		if rule.ChatType != "" && (rule.ChatType == "group") != isGroupMessage(msg.Metadata) {
+			continue
+		}
+		content = msg.Content
+		if rule.Prefix != "" {
+			rest, found := cutCommandPrefix(content, rule.Prefix)
+			if !found {
+				continue
+			}
+			content = rest
+		}
+		if rule.re != nil && !rule.re.MatchString(content) {
+			continue
+		}
+		return rule.Agent, content, true
+	}
+	return "", msg.Content, false
+}
+
+// SetOverride pins a session to an agent until cleared.
+func (r *Router) SetOverride(sessionKey, agentID string) {
+	r.overrides.Store(sessionKey, agentID)
+}
+
+// ClearOverride removes a session's pinned agent.
+func (r *Router) ClearOverride(sessionKey string) {
+	r.overrides.Delete(sessionKey)
+}
+
+// Override returns the agent pinned to a session, if any.
+func (r *Router) Override(sessionKey string) (string, bool) {
+	v, ok := r.overrides.Load(sessionKey)
+	if !ok {
+		return "", false
+	}
+	return v.(string), true
+}
+
+// cutCommandPrefix reports whether text starts with prefix as a whole word
+// (case-insensitive) and returns the remainder. A Telegram-style "@botname"
+// suffix directly after the prefix is skipped.
+func cutCommandPrefix(text, prefix string) (string, bool) {
+	text = strings.TrimLeft(text, " \t")
+	if len(text) < len(prefix) || !strings.EqualFold(text[:len(prefix)], prefix) {
+		return "", false
+	}
+	rest := text[len(prefix):]
+	if strings.HasPrefix(rest, "@") {
+		if i := strings.IndexAny(rest, " \t\n"); i >= 0 {
+			rest = rest[i:]
+		} else {
+			rest = ""
+		}
+	}
+	if rest != "" && !strings.ContainsRune(" \t\n", rune(rest[0])) {
+		return "", false
+	}
+	return strings.TrimSpace(rest), true
+}
+
+// classifyAgent asks the default agent's provider to pick one of the
+// candidate agents for a message. Returns "" when no candidate fits.
+func (al *AgentLoop) classifyAgent(ctx context.Context, content string) string {
+	cfg := al.currentConfig().Agents.Routing.Classifier
+	if !cfg.Enabled || strings.TrimSpace(content) == "" {
+		return ""
+	}
+
+	candidates := cfg.Agents
+	if len(candidates) == 0 {
+		candidates = al.registry.ListIDs()
+	}
+
+	var sb strings.Builder
+	valid := make(map[string]bool)
+	for _, id := range candidates {
+		inst, ok := al.registry.Get(id)
+		if !ok {
+			continue
+		}
+		desc := inst.Description
+		if desc == "" {
+			desc = inst.Name
+		}
+		fmt.Fprintf(&sb, "- %s: %s\n", id, desc)
+		valid[id] = true
+	}
+	if len(valid) < 2 {
+		return ""
+	}
+
+	def := al.registry.GetDefault()
+	model := cfg.Model
+	if model == "" {
+		model = def.Model
+	}
+
+	prompt := fmt.Sprintf(`Pick the agent best suited to handle the user message below.
+
+Agents:
+%s
+Reply with only the agent id, or "none" if no agent clearly fits.
+
+User message:
+%s`, sb.String(), content)
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	resp, err := def.Provider.Chat(ctx, []providers.Message{{Role: "user", Content: prompt}}, nil, model, map[string]interface{}{
+		"max_tokens":  20,
+		"temperature": 0.0,
+	})
+	if err != nil {
+		logger.WarnCF("agent", "Routing classifier failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return ""
+	}
+
+	choice := strings.Trim(strings.TrimSpace(resp.Content), "`\"'.")
+	if !valid[choice] {
+		return ""
+	}
+	return choice
+}
@@ -0,0 +1,175 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestCutCommandPrefix(t *testing.T) {
+	tests := []struct {
+		text   string
+		want   string
+		wantOK bool
+	}{
+		{"/code fix the bug", "fix the bug", true},
+		{"  /CODE   fix", "fix", true},
+		{"/code", "", true},
+		{"/code@picobot refactor", "refactor", true},
+		{"/coder fix", "", false},
+		{"please /code fix", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := cutCommandPrefix(tt.text, "/code")
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("cutCommandPrefix(%q) = (%q, %v), want (%q, %v)", tt.text, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestRouterMatch(t *testing.T) {
+	r, err := NewRouter(config.RoutingConfig{Rules: []config.RoutingRule{
+		{Agent: "coder", Prefix: "/code"},
+		{Agent: "team", Channel: "telegram", ChatIDs: []string{"-100"}, ChatType: "group"},
+		{Agent: "research", Channel: "discord", Pattern: `(?i)\bpaper\b`},
+	}})
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		msg         bus.InboundMessage
+		wantAgent   string
+		wantContent string
+		wantOK      bool
+	}{
+		{
+			name:        "prefix strips command",
+			msg:         bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "/code write tests"},
+			wantAgent:   "coder",
+			wantContent: "write tests",
+			wantOK:      true,
+		},
+		{
+			name:        "group chat",
+			msg:         bus.InboundMessage{Channel: "telegram", ChatID: "-100", Content: "hi", Metadata: map[string]string{"is_group": "true"}},
+			wantAgent:   "team",
+			wantContent: "hi",
+			wantOK:      true,
+		},
+		{
+			name:        "same chat as DM does not match group rule",
+			msg:         bus.InboundMessage{Channel: "telegram", ChatID: "-100", Content: "hi"},
+			wantContent: "hi",
+		},
+		{
+			name:        "pattern",
+			msg:         bus.InboundMessage{Channel: "discord", ChatID: "5", Content: "summarize this Paper"},
+			wantAgent:   "research",
+			wantContent: "summarize this Paper",
+			wantOK:      true,
+		},
+		{
+			name:        "pattern on other channel",
+			msg:         bus.InboundMessage{Channel: "telegram", ChatID: "5", Content: "summarize this paper"},
+			wantContent: "summarize this paper",
+		},
+	}
+	for _, tt := range tests {
+		agent, content, ok := r.Match(tt.msg)
+		if agent != tt.wantAgent || content != tt.wantContent || ok != tt.wantOK {
+			t.Errorf("%s: got (%q, %q, %v), want (%q, %q, %v)", tt.name, agent, content, ok, tt.wantAgent, tt.wantContent, tt.wantOK)
+		}
+	}
+}
+
+func TestNewRouterRejectsInvalidRules(t *testing.T) {
+	bad := []config.RoutingRule{
+		{Prefix: "/x"},
+		{Agent: "a", ChatType: "channel"},
+		{Agent: "a", Pattern: "("},
+	}
+	for _, rule := range bad {
+		if _, err := NewRouter(config.RoutingConfig{Rules: []config.RoutingRule{rule}}); err == nil {
+			t.Errorf("expected error for %+v", rule)
+		}
+	}
+}
+
+func TestRouterSetRules(t *testing.T) {
+	r, err := NewRouter(config.RoutingConfig{Rules: []config.RoutingRule{{Agent: "coder", Prefix: "/code"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetOverride("s1", "main")
+
+	if err := r.SetRules(config.RoutingConfig{Rules: []config.RoutingRule{{Agent: "a", Pattern: "("}}}); err == nil {
+		t.Error("expected error for invalid rules")
+	}
+	if agent, _, ok := r.Match(bus.InboundMessage{Content: "/code x"}); !ok || agent != "coder" {
+		t.Errorf("invalid rules replaced the current ones: %q %v", agent, ok)
+	}
+
+	if err := r.SetRules(config.RoutingConfig{Rules: []config.RoutingRule{{Agent: "writer", Prefix: "/write"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := r.Match(bus.InboundMessage{Content: "/code x"}); ok {
+		t.Error("old rule still matches")
+	}
+	if agent, content, ok := r.Match(bus.InboundMessage{Content: "/write hi"}); !ok || agent != "writer" || content != "hi" {
+		t.Errorf("got (%q, %q, %v)", agent, content, ok)
+	}
+	if id, ok := r.Override("s1"); !ok || id != "main" {
+		t.Error("session override lost")
+	}
+}
+
+func newRoutingTestLoop(t *testing.T, rules []config.RoutingRule) *AgentLoop {
+	t.Helper()
+	registry := NewAgentRegistry()
+	for _, id := range []string{"main", "coder"} {
+		registry.Register(&AgentInstance{ID: id})
+	}
+	router, err := NewRouter(config.RoutingConfig{Rules: rules})
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	al := &AgentLoop{registry: registry, router: router}
+	al.cfg.Store(config.DefaultConfig())
+	return al
+}
+
+func TestResolveAgentPrecedence(t *testing.T) {
+	al := newRoutingTestLoop(t, []config.RoutingRule{{Agent: "coder", Prefix: "/code"}})
+	ctx := context.Background()
+
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SessionKey: "telegram:1", Content: "/code fix it"}
+	if inst := al.resolveAgent(ctx, &msg); inst.ID != "coder" || msg.Content != "fix it" {
+		t.Errorf("rule: got agent %q content %q", inst.ID, msg.Content)
+	}
+
+	msg = bus.InboundMessage{Channel: "telegram", ChatID: "1", SessionKey: "telegram:1", Content: "hello", Metadata: map[string]string{"agent_id": "coder"}}
+	if inst := al.resolveAgent(ctx, &msg); inst.ID != "coder" {
+		t.Errorf("allow_from agent_id: got %q", inst.ID)
+	}
+
+	msg = bus.InboundMessage{Channel: "telegram", ChatID: "1", SessionKey: "telegram:1", Content: "hello"}
+	if inst := al.resolveAgent(ctx, &msg); inst.ID != "main" {
+		t.Errorf("default: got %q", inst.ID)
+	}
+
+	al.router.SetOverride("telegram:1", "main")
+	msg = bus.InboundMessage{Channel: "telegram", ChatID: "1", SessionKey: "telegram:1", Content: "/code fix it"}
+	if inst := al.resolveAgent(ctx, &msg); inst.ID != "main" || msg.Content != "/code fix it" {
+		t.Errorf("override should win over rules: got agent %q content %q", inst.ID, msg.Content)
+	}
+
+	// A sender bound to an agent stays there despite pins and rule prefixes
+	msg = bus.InboundMessage{Channel: "telegram", ChatID: "1", SessionKey: "telegram:1", Content: "/code fix it", Metadata: map[string]string{"agent_id": "coder"}}
+	if inst := al.resolveAgent(ctx, &msg); inst.ID != "coder" {
+		t.Errorf("agent_id binding should win over the override: got %q", inst.ID)
+	}
+}
@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/voice"
+)
+
+// voiceReplyChannels are the channels that can send synthesized speech.
+var voiceReplyChannels = map[string]bool{"telegram": true, "discord": true, "whatsapp": true}
+
+// voiceMode returns the TTS mode for a message: the session's /voice
+// override, then voice.tts.channels, then voice.tts.mode.
+func (al *AgentLoop) voiceMode(msg bus.InboundMessage) string {
+	if v, ok := al.sessionVoice.Load(msg.SessionKey); ok {
+		return v.(string)
+	}
+	tc := al.currentConfig().Voice.TTS
+	if mode, ok := tc.Channels[msg.Channel]; ok && mode != "" {
+		return mode
+	}
+	if tc.Mode == "" {
+		return voice.TTSModeOff
+	}
+	return tc.Mode
+}
+
+// isVoiceMessage reports whether the user spoke rather than typed: the
+// message carries an audio file or a channel transcription.
+func isVoiceMessage(msg bus.InboundMessage) bool {
+	for _, path := range msg.Media {
+		if voice.IsAudioFile(path) {
+			return true
+		}
+	}
+	return strings.Contains(msg.Content, "[voice transcription:")
+}
+
+// wantsVoiceReply decides whether the response to msg should be spoken.
+func (al *AgentLoop) wantsVoiceReply(msg bus.InboundMessage) bool {
+	if al.synthesizer == nil || !voiceReplyChannels[msg.Channel] {
+		return false
+	}
+	switch al.voiceMode(msg) {
+	case voice.TTSModeAlways:
+		return true
+	case voice.TTSModeWhenVoice:
+		return isVoiceMessage(msg)
+	}
+	return false
+}
+
+// attachVoiceReply synthesizes out.Content and marks out as an audio reply.
+// Failures are logged and the reply falls back to plain text.
+func (al *AgentLoop) attachVoiceReply(ctx context.Context, msg bus.InboundMessage, out *bus.OutboundMessage) {
+	if !al.wantsVoiceReply(msg) {
+		return
+	}
+	tc := al.currentConfig().Voice.TTS
+	text := out.Content
+	if tc.MaxChars > 0 && len([]rune(text)) > tc.MaxChars {
+		logger.DebugCF("voice", "Response too long for a voice reply", map[string]interface{}{
+			"chars":     len([]rune(text)),
+			"max_chars": tc.MaxChars,
+		})
+		return
+	}
+
+	speech, err := al.synthesizer.Synthesize(ctx, text)
+	if err != nil {
+		logger.WarnCF("voice", "Speech synthesis failed, sending text only", map[string]interface{}{
+			"channel": msg.Channel,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	out.Metadata = map[string]string{
+		"type":         "audio",
+		"audio_path":   speech.Path,
+		"audio_format": speech.Format,
+	}
+	if !tc.IncludeText {
+		out.Metadata["audio_only"] = "true"
+	}
+}
+
+// cmdVoice implements "/voice [on|off|auto|default]".
+func (al *AgentLoop) cmdVoice(msg bus.InboundMessage, arg string) string {
+	if al.synthesizer == nil {
+		return "Voice replies are not configured; set voice.tts in the config first."
+	}
+	switch arg {
+	case "":
+		return fmt.Sprintf("Voice replies: %s.\nUse /voice on, /voice off, /voice auto or /voice default.", al.voiceMode(msg))
+	case "on":
+		al.sessionVoice.Store(msg.SessionKey, voice.TTSModeAlways)
+	case "off":
+		al.sessionVoice.Store(msg.SessionKey, voice.TTSModeOff)
+	case "auto":
+		al.sessionVoice.Store(msg.SessionKey, voice.TTSModeWhenVoice)
+	case "default", "reset":
+		al.sessionVoice.Delete(msg.SessionKey)
+		return fmt.Sprintf("Voice replies reset to %s.", al.voiceMode(msg))
+	default:
+		return "Usage: /voice [on|off|auto|default]"
+	}
+	return fmt.Sprintf("Voice replies for this chat: %s.", al.voiceMode(msg))
+}
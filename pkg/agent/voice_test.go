@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/voice"
+)
+
+type fakeSynthesizer struct {
+	calls int
+	err   error
+}
+
+func (f *fakeSynthesizer) Synthesize(ctx context.Context, text string) (*voice.Speech, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &voice.Speech{Path: "/tmp/reply.ogg", Format: "ogg"}, nil
+}
+
+func TestVoiceReplyModes(t *testing.T) {
+	al, _ := newCommandTestLoop(t, nil)
+	synth := &fakeSynthesizer{}
+	al.synthesizer = synth
+	al.currentConfig().Voice.TTS.Mode = voice.TTSModeWhenVoice
+	al.currentConfig().Voice.TTS.IncludeText = true
+
+	typed := chatMsg("1", "hello")
+	spoken := chatMsg("1", "[voice transcription: hello]")
+	withAudio := chatMsg("1", "listen")
+	withAudio.Media = []string{"/tmp/note.ogg"}
+
+	if al.wantsVoiceReply(typed) || !al.wantsVoiceReply(spoken) || !al.wantsVoiceReply(withAudio) {
+		t.Error("when_voice should only answer voice messages with voice")
+	}
+
+	al.currentConfig().Voice.TTS.Channels = map[string]string{"telegram": voice.TTSModeAlways}
+	if !al.wantsVoiceReply(typed) {
+		t.Error("channel mode should override the global mode")
+	}
+
+	al.cmdVoice(typed, "off")
+	if al.wantsVoiceReply(spoken) {
+		t.Error("/voice off should override the channel mode")
+	}
+	if reply := al.cmdVoice(typed, "default"); !strings.Contains(reply, voice.TTSModeAlways) {
+		t.Errorf("/voice default: got %q", reply)
+	}
+
+	cli := bus.InboundMessage{Channel: "cli", SessionKey: "cli:default", Content: "hi"}
+	al.currentConfig().Voice.TTS.Mode = voice.TTSModeAlways
+	if al.wantsVoiceReply(cli) {
+		t.Error("cli should never get voice replies")
+	}
+	whatsapp := bus.InboundMessage{Channel: "whatsapp", SessionKey: "whatsapp:1", Content: "hi"}
+	if !al.wantsVoiceReply(whatsapp) {
+		t.Error("whatsapp should get voice replies")
+	}
+	slack := bus.InboundMessage{Channel: "slack", SessionKey: "slack:1", Content: "hi"}
+	if al.wantsVoiceReply(slack) {
+		t.Error("slack cannot send voice replies")
+	}
+}
+
+func TestAttachVoiceReply(t *testing.T) {
+	al, _ := newCommandTestLoop(t, nil)
+	synth := &fakeSynthesizer{}
+	al.synthesizer = synth
+	al.currentConfig().Voice.TTS.Mode = voice.TTSModeAlways
+	al.currentConfig().Voice.TTS.MaxChars = 10
+	al.currentConfig().Voice.TTS.IncludeText = false
+
+	msg := chatMsg("1", "hi")
+	out := bus.OutboundMessage{Channel: "telegram", ChatID: "1", Content: "short"}
+	al.attachVoiceReply(context.Background(), msg, &out)
+	if out.Metadata["type"] != "audio" || out.Metadata["audio_path"] != "/tmp/reply.ogg" || out.Metadata["audio_only"] != "true" {
+		t.Errorf("unexpected metadata %v", out.Metadata)
+	}
+
+	long := bus.OutboundMessage{Channel: "telegram", ChatID: "1", Content: "this reply is too long"}
+	al.attachVoiceReply(context.Background(), msg, &long)
+	if long.Metadata != nil || synth.calls != 1 {
+		t.Error("replies over max_chars should stay text")
+	}
+
+	synth.err = errors.New("offline")
+	failed := bus.OutboundMessage{Channel: "telegram", ChatID: "1", Content: "short"}
+	al.attachVoiceReply(context.Background(), msg, &failed)
+	if failed.Metadata != nil {
+		t.Error("synthesis failure should fall back to text")
+	}
+}
+
+func TestCmdVoiceNotConfigured(t *testing.T) {
+	al, inst := newCommandTestLoop(t, nil)
+	reply, handled := al.handleCommand(inst, chatMsg("1", "/voice on"))
+	if !handled || !strings.Contains(reply, "not configured") {
+		t.Errorf("got %q, %v", reply, handled)
+	}
+}
@@ -3,6 +3,8 @@ package bus
 import (
 	"context"
 	"sync"
+
+	"github.com/sipeed/picoclaw/pkg/metrics"
 )
 
 type MessageBus struct {
@@ -12,15 +14,21 @@ type MessageBus struct {
 	mu       sync.RWMutex
 }
 
+// NewMessageBus creates a bus and reports its queue depths as
+// picoclaw_bus_queue_depth; the most recently created bus is reported.
 func NewMessageBus() *MessageBus {
-	return &MessageBus{
+	mb := &MessageBus{
 		inbound:  make(chan InboundMessage, 100),
 		outbound: make(chan OutboundMessage, 100),
 		handlers: make(map[string]MessageHandler),
 	}
+	metrics.BusQueueDepth.SetFunc(func() float64 { return float64(len(mb.inbound)) }, "inbound")
+	metrics.BusQueueDepth.SetFunc(func() float64 { return float64(len(mb.outbound)) }, "outbound")
+	return mb
 }
 
 func (mb *MessageBus) PublishInbound(msg InboundMessage) {
+	metrics.MessagesInbound.Inc(msg.Channel)
 	mb.inbound <- msg
 }
 
@@ -34,6 +42,7 @@ func (mb *MessageBus) ConsumeInbound(ctx context.Context) (InboundMessage, bool)
 }
 
 func (mb *MessageBus) PublishOutbound(msg OutboundMessage) {
+	metrics.MessagesOutbound.Inc(msg.Channel)
 	mb.outbound <- msg
 }
 
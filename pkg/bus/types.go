@@ -11,10 +11,18 @@ type InboundMessage struct {
 }
 
 type OutboundMessage struct {
-	Channel  string            `json:"channel"`
-	ChatID   string            `json:"chat_id"`
-	Content  string            `json:"content"`
-	Metadata map[string]string `json:"metadata,omitempty"`
+	Channel     string            `json:"channel"`
+	ChatID      string            `json:"chat_id"`
+	Content     string            `json:"content"`
+	Attachments []Attachment      `json:"attachments,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// Attachment is a local file delivered with an outbound message. Channels
+// upload it natively where they can.
+type Attachment struct {
+	Path string `json:"path"`
+	Name string `json:"name,omitempty"` // display name, defaults to the file name
 }
 
 type MessageHandler func(InboundMessage) error
@@ -0,0 +1,60 @@
+package channels
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+// AttachmentChannel is implemented by channels that upload
+// OutboundMessage.Attachments natively. Messages for other channels get a
+// text notice listing the files instead.
+type AttachmentChannel interface {
+	Channel
+	SupportsAttachments() bool
+}
+
+// supportsAttachments reports whether ch can upload files.
+func supportsAttachments(ch Channel) bool {
+	ac, ok := ch.(AttachmentChannel)
+	return ok && ac.SupportsAttachments()
+}
+
+// withAttachmentNotice moves attachments into the text for channels that
+// cannot upload them.
+func withAttachmentNotice(msg bus.OutboundMessage) bus.OutboundMessage {
+	if len(msg.Attachments) == 0 {
+		return msg
+	}
+	names := make([]string, 0, len(msg.Attachments))
+	for _, a := range msg.Attachments {
+		names = append(names, attachmentName(a))
+	}
+	notice := fmt.Sprintf("[attachments not supported on this channel: %s]", strings.Join(names, ", "))
+	if msg.Content == "" {
+		msg.Content = notice
+	} else {
+		msg.Content += "\n\n" + notice
+	}
+	msg.Attachments = nil
+	return msg
+}
+
+func attachmentName(a bus.Attachment) string {
+	if a.Name != "" {
+		return a.Name
+	}
+	return filepath.Base(a.Path)
+}
+
+// isImageFile reports whether path is an image most chat platforms render
+// inline rather than as a file.
+func isImageFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package channels
+
+import (
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+func TestWithAttachmentNotice(t *testing.T) {
+	msg := bus.OutboundMessage{
+		Content: "Here is the report",
+		Attachments: []bus.Attachment{
+			{Path: "/ws/out/report.pdf"},
+			{Path: "/ws/out/c.png", Name: "chart.png"},
+		},
+	}
+	got := withAttachmentNotice(msg)
+	want := "Here is the report\n\n[attachments not supported on this channel: report.pdf, chart.png]"
+	if got.Content != want || got.Attachments != nil {
+		t.Errorf("got %q %v", got.Content, got.Attachments)
+	}
+
+	got = withAttachmentNotice(bus.OutboundMessage{Attachments: msg.Attachments[:1]})
+	if got.Content != "[attachments not supported on this channel: report.pdf]" {
+		t.Errorf("attachment-only message: got %q", got.Content)
+	}
+}
+
+func TestSupportsAttachments(t *testing.T) {
+	if !supportsAttachments(&TelegramChannel{}) || !supportsAttachments(&DiscordChannel{}) {
+		t.Error("telegram and discord upload attachments")
+	}
+	if supportsAttachments(&MaixCamChannel{}) {
+		t.Error("maixcam has no attachment support")
+	}
+}
@@ -3,6 +3,7 @@ package channels
 import (
 	"context"
 	"fmt"
+	"sync"
 	"sync/atomic"
 	"strings"
 	"github.com/sipeed/picoclaw/pkg/bus"
@@ -23,6 +24,7 @@ type BaseChannel struct {
 	running   atomic.Bool
 	name      string
 	allowList []string
+	allowMu   sync.RWMutex
 }
 
 func NewBaseChannel(name string, config interface{}, bus *bus.MessageBus, allowList []string) *BaseChannel {
@@ -43,7 +45,7 @@ func (c *BaseChannel) IsRunning() bool {
 }
 
 func (c *BaseChannel) IsAllowed(senderID string) bool {
-	if len(c.allowList) == 0 {
+	if len(c.allowEntries()) == 0 {
 		return true
 	}
 
@@ -77,7 +79,7 @@ func (c *BaseChannel) matchAllowEntry(senderID string) (string, bool) {
 		userPart = senderID[idx+1:]
 	}
 
-	for _, allowed := range c.allowList {
+	for _, allowed := range c.allowEntries() {
 		// Strip leading "@" and agent suffix for matching
 		trimmed := strings.TrimPrefix(allowed, "@")
 		bare := trimmed
@@ -106,6 +108,19 @@ func (c *BaseChannel) matchAllowEntry(senderID string) (string, bool) {
 	return "", false
 }
 
+// SetAllowList replaces allow_from on a running channel.
+func (c *BaseChannel) SetAllowList(allowList []string) {
+	c.allowMu.Lock()
+	defer c.allowMu.Unlock()
+	c.allowList = allowList
+}
+
+func (c *BaseChannel) allowEntries() []string {
+	c.allowMu.RLock()
+	defer c.allowMu.RUnlock()
+	return c.allowList
+}
+
 func (c *BaseChannel) HandleMessage(senderID, chatID, content string, media []string, metadata map[string]string) {
 	if !c.IsAllowed(senderID) {
 		return
@@ -176,3 +176,14 @@ func TestMatchAllowEntry_WithAgentSuffix(t *testing.T) {
 		})
 	}
 }
+
+func TestSetAllowList(t *testing.T) {
+	bc := NewBaseChannel("test", nil, nil, []string{"alice"})
+	bc.SetAllowList([]string{"bob"})
+	if bc.IsAllowed("alice") {
+		t.Error("alice should no longer be allowed")
+	}
+	if !bc.IsAllowed("bob") {
+		t.Error("bob should be allowed after reload")
+	}
+}
@@ -4,16 +4,27 @@
 package channels
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/open-dingtalk/dingtalk-stream-sdk-go/chatbot"
 	"github.com/open-dingtalk/dingtalk-stream-sdk-go/client"
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/utils"
+	"github.com/sipeed/picoclaw/pkg/voice"
 )
 
 // DingTalkChannel implements the Channel interface for DingTalk (钉钉)
@@ -28,6 +39,11 @@ type DingTalkChannel struct {
 	cancel         context.CancelFunc
 	// Map to store session webhooks for each chat
 	sessionWebhooks sync.Map // chatID -> sessionWebhook
+	groupChats      sync.Map // chatID -> true for group conversations
+	transcriber     voice.Transcriber
+	tokenMu         sync.Mutex
+	accessToken     string
+	tokenExpiry     time.Time
 }
 
 // NewDingTalkChannel creates a new DingTalk channel instance
@@ -46,6 +62,12 @@ func NewDingTalkChannel(cfg config.DingTalkConfig, messageBus *bus.MessageBus) (
 	}, nil
 }
 
+// SetTranscriber enables speech-to-text for audio messages. Without it,
+// DingTalk's own recognition text is used.
+func (c *DingTalkChannel) SetTranscriber(transcriber voice.Transcriber) {
+	c.transcriber = transcriber
+}
+
 // Start initializes the DingTalk channel with Stream Mode
 func (c *DingTalkChannel) Start(ctx context.Context) error {
 	log.Printf("Starting DingTalk channel (Stream Mode)...")
@@ -111,7 +133,125 @@ func (c *DingTalkChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 	log.Printf("DingTalk message to %s: %s", msg.ChatID, utils.Truncate(msg.Content, 100))
 
 	// Use the session webhook to send the reply
-	return c.SendDirectReply(sessionWebhook, msg.Content)
+	if strings.TrimSpace(msg.Content) != "" || len(msg.Attachments) == 0 {
+		if err := c.SendDirectReply(sessionWebhook, msg.Content); err != nil {
+			return err
+		}
+	}
+
+	// Session webhooks only carry text, so files go through the robot API
+	for _, a := range msg.Attachments {
+		if err := c.sendAttachment(ctx, msg.ChatID, a); err != nil {
+			return fmt.Errorf("failed to send attachment %s: %w", attachmentName(a), err)
+		}
+	}
+	return nil
+}
+
+// SupportsAttachments implements AttachmentChannel.
+func (c *DingTalkChannel) SupportsAttachments() bool {
+	return true
+}
+
+// sendAttachment uploads a file to DingTalk's media store and sends it as
+// an image or file message from the robot.
+func (c *DingTalkChannel) sendAttachment(ctx context.Context, chatID string, a bus.Attachment) error {
+	mediaType := "file"
+	if isImageFile(a.Path) {
+		mediaType = "image"
+	}
+	mediaID, err := c.uploadMedia(ctx, a, mediaType)
+	if err != nil {
+		return err
+	}
+
+	msgKey := "sampleFile"
+	param := map[string]string{
+		"mediaId":  mediaID,
+		"fileName": attachmentName(a),
+		"fileType": strings.TrimPrefix(strings.ToLower(filepath.Ext(a.Path)), "."),
+	}
+	if mediaType == "image" {
+		msgKey = "sampleImageMsg"
+		param = map[string]string{"photoURL": mediaID}
+	}
+	msgParam, _ := json.Marshal(param)
+
+	endpoint := "https://api.dingtalk.com/v1.0/robot/oToMessages/batchSend"
+	body := map[string]interface{}{
+		"robotCode": c.clientID,
+		"msgKey":    msgKey,
+		"msgParam":  string(msgParam),
+	}
+	if _, isGroup := c.groupChats.Load(chatID); isGroup {
+		endpoint = "https://api.dingtalk.com/v1.0/robot/groupMessages/send"
+		body["openConversationId"] = chatID
+	} else {
+		body["userIds"] = []string{chatID}
+	}
+
+	token, err := c.getAccessToken(ctx)
+	if err != nil {
+		return err
+	}
+	data, _ := json.Marshal(body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-acs-dingtalk-access-token", token)
+
+	var result map[string]interface{}
+	return doDingTalkJSON(req, &result)
+}
+
+// uploadMedia uploads a file and returns its media ID.
+func (c *DingTalkChannel) uploadMedia(ctx context.Context, a bus.Attachment, mediaType string) (string, error) {
+	token, err := c.getAccessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(a.Path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, err := writer.CreateFormFile("media", attachmentName(a))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, f); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	uploadURL := fmt.Sprintf("https://oapi.dingtalk.com/media/upload?access_token=%s&type=%s",
+		url.QueryEscape(token), mediaType)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, &buf)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+		MediaID string `json:"media_id"`
+	}
+	if err := doDingTalkJSON(req, &result); err != nil {
+		return "", err
+	}
+	if result.ErrCode != 0 || result.MediaID == "" {
+		return "", fmt.Errorf("dingtalk media upload failed: %d %s", result.ErrCode, result.ErrMsg)
+	}
+	return result.MediaID, nil
 }
 
 // onChatBotMessageReceived implements the IChatBotMessageHandler function signature
@@ -129,12 +269,21 @@ func (c *DingTalkChannel) onChatBotMessageReceived(ctx context.Context, data *ch
 		}
 	}
 
+	senderID := data.SenderStaffId
+	senderNick := data.SenderNick
+	if !c.IsAllowed(senderID) {
+		return nil, nil
+	}
+
+	var mediaPaths []string
+	if data.Msgtype == "audio" {
+		content, mediaPaths = c.handleAudio(ctx, data)
+	}
+
 	if content == "" {
 		return nil, nil // Ignore empty messages
 	}
 
-	senderID := data.SenderStaffId
-	senderNick := data.SenderNick
 	chatID := senderID
 	if data.ConversationType != "1" {
 		// For group chats
@@ -143,6 +292,9 @@ func (c *DingTalkChannel) onChatBotMessageReceived(ctx context.Context, data *ch
 
 	// Store the session webhook for this chat so we can reply later
 	c.sessionWebhooks.Store(chatID, data.SessionWebhook)
+	if data.ConversationType != "1" {
+		c.groupChats.Store(chatID, true)
+	}
 
 	metadata := map[string]string{
 		"sender_name":       senderNick,
@@ -155,7 +307,7 @@ func (c *DingTalkChannel) onChatBotMessageReceived(ctx context.Context, data *ch
 	log.Printf("DingTalk message from %s (%s): %s", senderNick, senderID, utils.Truncate(content, 50))
 
 	// Handle the message through the base channel
-	c.HandleMessage(senderID, chatID, content, nil, metadata)
+	c.HandleMessage(senderID, chatID, content, mediaPaths, metadata)
 
 	// Return nil to indicate we've handled the message asynchronously
 	// The response will be sent through the message bus
@@ -185,3 +337,142 @@ func (c *DingTalkChannel) SendDirectReply(sessionWebhook, content string) error
 	return nil
 }
 
+
+// handleAudio turns an audio message into content. The file is downloaded
+// and transcribed when a transcriber is set; DingTalk's recognition text is
+// the fallback.
+func (c *DingTalkChannel) handleAudio(ctx context.Context, data *chatbot.BotCallbackDataModel) (string, []string) {
+	contentMap, _ := data.Content.(map[string]interface{})
+	recognition, _ := contentMap["recognition"].(string)
+	downloadCode, _ := contentMap["downloadCode"].(string)
+
+	if c.transcriber != nil && c.transcriber.IsAvailable() && downloadCode != "" {
+		audioPath, err := c.downloadMessageFile(ctx, downloadCode, data.MsgId)
+		if err != nil {
+			log.Printf("DingTalk audio download failed: %v", err)
+		} else if recognition == "" {
+			return transcribeVoice(ctx, c.transcriber, audioPath, "voice", ""), []string{audioPath}
+		} else if text, err := transcribe(ctx, c.transcriber, audioPath, ""); err == nil {
+			return fmt.Sprintf("[voice transcription: %s]", text), []string{audioPath}
+		}
+	}
+
+	if recognition != "" {
+		return fmt.Sprintf("[voice transcription: %s]", recognition), nil
+	}
+	return "[voice message]", nil
+}
+
+// downloadMessageFile fetches a robot message attachment by download code.
+func (c *DingTalkChannel) downloadMessageFile(ctx context.Context, downloadCode, msgID string) (string, error) {
+	token, err := c.getAccessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	body, _ := json.Marshal(map[string]string{
+		"downloadCode": downloadCode,
+		"robotCode":    c.clientID,
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		"https://api.dingtalk.com/v1.0/robot/messageFiles/download", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-acs-dingtalk-access-token", token)
+
+	var result struct {
+		DownloadURL string `json:"downloadUrl"`
+	}
+	if err := doDingTalkJSON(req, &result); err != nil {
+		return "", err
+	}
+	if result.DownloadURL == "" {
+		return "", fmt.Errorf("empty download url")
+	}
+
+	fileReq, err := http.NewRequestWithContext(ctx, http.MethodGet, result.DownloadURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(fileReq)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+
+	ext := ".amr"
+	if ct := resp.Header.Get("Content-Type"); strings.Contains(ct, "ogg") || strings.Contains(ct, "opus") {
+		ext = ".ogg"
+	}
+	mediaDir := filepath.Join(os.TempDir(), "picoclaw_media")
+	if err := os.MkdirAll(mediaDir, 0755); err != nil {
+		return "", err
+	}
+	localPath := filepath.Join(mediaDir, "dingtalk_"+filepath.Base(msgID)+ext)
+	out, err := os.Create(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		return "", err
+	}
+	return localPath, nil
+}
+
+// getAccessToken returns a cached app access token, refreshing it shortly
+// before it expires.
+func (c *DingTalkChannel) getAccessToken(ctx context.Context) (string, error) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+
+	if c.accessToken != "" && time.Now().Before(c.tokenExpiry) {
+		return c.accessToken, nil
+	}
+
+	body, _ := json.Marshal(map[string]string{
+		"appKey":    c.clientID,
+		"appSecret": c.clientSecret,
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		"https://api.dingtalk.com/v1.0/oauth2/accessToken", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var result struct {
+		AccessToken string `json:"accessToken"`
+		ExpireIn    int    `json:"expireIn"`
+	}
+	if err := doDingTalkJSON(req, &result); err != nil {
+		return "", fmt.Errorf("failed to get access token: %w", err)
+	}
+
+	c.accessToken = result.AccessToken
+	c.tokenExpiry = time.Now().Add(time.Duration(result.ExpireIn)*time.Second - time.Minute)
+	return c.accessToken, nil
+}
+
+func doDingTalkJSON(req *http.Request, out interface{}) error {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("dingtalk api error (status %d): %s", resp.StatusCode, utils.Truncate(string(data), 200))
+	}
+	return json.Unmarshal(data, out)
+}
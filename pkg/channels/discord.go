@@ -5,14 +5,15 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/commands"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/utils"
@@ -23,7 +24,7 @@ type DiscordChannel struct {
 	*BaseChannel
 	session     *discordgo.Session
 	config      config.DiscordConfig
-	transcriber *voice.GroqTranscriber
+	transcriber voice.Transcriber
 }
 
 func NewDiscordChannel(cfg config.DiscordConfig, bus *bus.MessageBus) (*DiscordChannel, error) {
@@ -42,7 +43,7 @@ func NewDiscordChannel(cfg config.DiscordConfig, bus *bus.MessageBus) (*DiscordC
 	}, nil
 }
 
-func (c *DiscordChannel) SetTranscriber(transcriber *voice.GroqTranscriber) {
+func (c *DiscordChannel) SetTranscriber(transcriber voice.Transcriber) {
 	c.transcriber = transcriber
 }
 
@@ -50,6 +51,7 @@ func (c *DiscordChannel) Start(ctx context.Context) error {
 	logger.InfoC("discord", "Starting Discord bot")
 
 	c.session.AddHandler(c.handleMessage)
+	c.session.AddHandler(c.handleInteraction)
 
 	if err := c.session.Open(); err != nil {
 		return fmt.Errorf("failed to open discord session: %w", err)
@@ -66,9 +68,88 @@ func (c *DiscordChannel) Start(ctx context.Context) error {
 		"user_id":  botUser.ID,
 	})
 
+	c.registerCommands(botUser.ID)
+
 	return nil
 }
 
+// registerCommands publishes the built-in commands as global Discord slash
+// commands. Commands that take arguments get a single free-text option.
+func (c *DiscordChannel) registerCommands(appID string) {
+	cmds := make([]*discordgo.ApplicationCommand, 0, len(commands.Builtin))
+	for _, cmd := range commands.Builtin {
+		ac := &discordgo.ApplicationCommand{
+			Name:        cmd.Name,
+			Description: cmd.Description,
+		}
+		if cmd.Args != "" {
+			ac.Options = []*discordgo.ApplicationCommandOption{{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "args",
+				Description: cmd.Args,
+			}}
+		}
+		cmds = append(cmds, ac)
+	}
+	if _, err := c.session.ApplicationCommandBulkOverwrite(appID, "", cmds); err != nil {
+		logger.ErrorCF("discord", "Failed to register slash commands", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
+
+// handleInteraction turns a slash command invocation into a regular inbound
+// "/name args" message. The reply arrives as a normal channel message.
+func (c *DiscordChannel) handleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i == nil || i.Type != discordgo.InteractionApplicationCommand {
+		return
+	}
+
+	user := i.User
+	if i.Member != nil && i.Member.User != nil {
+		user = i.Member.User
+	}
+	if user == nil {
+		return
+	}
+
+	data := i.ApplicationCommandData()
+	content := "/" + data.Name
+	for _, opt := range data.Options {
+		if opt.Name == "args" {
+			content += " " + opt.StringValue()
+		}
+	}
+
+	respond := func(text string, flags discordgo.MessageFlags) {
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{Content: text, Flags: flags},
+		})
+		if err != nil {
+			logger.ErrorCF("discord", "Failed to respond to interaction", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	if !c.IsAllowed(user.ID) {
+		respond("You are not allowed to use this bot.", discordgo.MessageFlagsEphemeral)
+		return
+	}
+	respond(content, 0)
+
+	metadata := map[string]string{
+		"user_id":      user.ID,
+		"username":     user.Username,
+		"display_name": user.Username,
+		"guild_id":     i.GuildID,
+		"channel_id":   i.ChannelID,
+		"is_dm":        fmt.Sprintf("%t", i.GuildID == ""),
+	}
+	c.HandleMessage(user.ID, i.ChannelID, content, nil, metadata)
+}
+
 func (c *DiscordChannel) Stop(ctx context.Context) error {
 	logger.InfoC("discord", "Stopping Discord bot")
 	c.setRunning(false)
@@ -92,6 +173,25 @@ func (c *DiscordChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 
 	message := msg.Content
 
+	if msg.Metadata["type"] == "audio" && msg.Metadata["audio_path"] != "" {
+		audio := bus.Attachment{Path: msg.Metadata["audio_path"]}
+		content := message
+		if msg.Metadata["audio_only"] == "true" {
+			content = ""
+		}
+		err := c.sendFiles(channelID, content, []bus.Attachment{audio})
+		if err == nil {
+			return c.sendAttachments(channelID, "", msg.Attachments)
+		}
+		logger.ErrorCF("discord", "Failed to send voice reply, sending text", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	if len(msg.Attachments) > 0 {
+		return c.sendAttachments(channelID, message, msg.Attachments)
+	}
+
 	if _, err := c.session.ChannelMessageSend(channelID, message); err != nil {
 		return fmt.Errorf("failed to send discord message: %w", err)
 	}
@@ -99,6 +199,58 @@ func (c *DiscordChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 	return nil
 }
 
+// SupportsAttachments implements AttachmentChannel.
+func (c *DiscordChannel) SupportsAttachments() bool {
+	return true
+}
+
+// discordMaxFiles is the attachment limit of a single Discord message.
+const discordMaxFiles = 10
+
+// sendAttachments uploads files in batches of discordMaxFiles; content goes
+// with the first batch.
+func (c *DiscordChannel) sendAttachments(channelID, content string, attachments []bus.Attachment) error {
+	for start := 0; start < len(attachments); start += discordMaxFiles {
+		end := start + discordMaxFiles
+		if end > len(attachments) {
+			end = len(attachments)
+		}
+		if err := c.sendFiles(channelID, content, attachments[start:end]); err != nil {
+			return fmt.Errorf("failed to send discord attachments: %w", err)
+		}
+		content = ""
+	}
+	return nil
+}
+
+// sendFiles posts files as attachments of one message, with optional text.
+func (c *DiscordChannel) sendFiles(channelID, content string, attachments []bus.Attachment) error {
+	files := make([]*discordgo.File, 0, len(attachments))
+	for _, a := range attachments {
+		f, err := os.Open(a.Path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		contentType := mime.TypeByExtension(filepath.Ext(a.Path))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		files = append(files, &discordgo.File{
+			Name:        attachmentName(a),
+			ContentType: contentType,
+			Reader:      f,
+		})
+	}
+
+	_, err := c.session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content: content,
+		Files:   files,
+	})
+	return err
+}
+
 func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.MessageCreate) {
 	if m == nil || m.Author == nil {
 		return
@@ -125,22 +277,7 @@ func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.Messag
 			if localPath != "" {
 				mediaPaths = append(mediaPaths, localPath)
 
-				transcribedText := ""
-				if c.transcriber != nil && c.transcriber.IsAvailable() {
-					ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-					defer cancel()
-
-					result, err := c.transcriber.Transcribe(ctx, localPath)
-					if err != nil {
-						log.Printf("Voice transcription failed: %v", err)
-						transcribedText = fmt.Sprintf("[audio: %s (transcription failed)]", localPath)
-					} else {
-						transcribedText = fmt.Sprintf("[audio transcription: %s]", result.Text)
-						log.Printf("Audio transcribed successfully: %s", result.Text)
-					}
-				} else {
-					transcribedText = fmt.Sprintf("[audio: %s]", localPath)
-				}
+				transcribedText := transcribeVoice(context.Background(), c.transcriber, localPath, "audio", m.Author.Locale)
 
 				if content != "" {
 					content += "\n"
@@ -4,6 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,6 +19,7 @@ import (
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/utils"
+	"github.com/sipeed/picoclaw/pkg/voice"
 )
 
 type FeishuChannel struct {
@@ -24,8 +28,9 @@ type FeishuChannel struct {
 	client   *lark.Client
 	wsClient *larkws.Client
 
-	mu     sync.Mutex
-	cancel context.CancelFunc
+	mu          sync.Mutex
+	cancel      context.CancelFunc
+	transcriber voice.Transcriber
 }
 
 func NewFeishuChannel(cfg config.FeishuConfig, bus *bus.MessageBus) (*FeishuChannel, error) {
@@ -38,6 +43,10 @@ func NewFeishuChannel(cfg config.FeishuConfig, bus *bus.MessageBus) (*FeishuChan
 	}, nil
 }
 
+func (c *FeishuChannel) SetTranscriber(transcriber voice.Transcriber) {
+	c.transcriber = transcriber
+}
+
 func (c *FeishuChannel) Start(ctx context.Context) error {
 	if c.config.AppID == "" || c.config.AppSecret == "" {
 		return fmt.Errorf("feishu app_id or app_secret is empty")
@@ -95,7 +104,33 @@ func (c *FeishuChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 		return fmt.Errorf("chat ID is empty")
 	}
 
-	payload, err := json.Marshal(map[string]string{"text": msg.Content})
+	if strings.TrimSpace(msg.Content) != "" || len(msg.Attachments) == 0 {
+		if err := c.sendMessage(ctx, msg.ChatID, larkim.MsgTypeText, map[string]string{"text": msg.Content}); err != nil {
+			return err
+		}
+	}
+
+	for _, a := range msg.Attachments {
+		if err := c.sendAttachment(ctx, msg.ChatID, a); err != nil {
+			return fmt.Errorf("failed to send attachment %s: %w", attachmentName(a), err)
+		}
+	}
+
+	logger.DebugCF("feishu", "Feishu message sent", map[string]interface{}{
+		"chat_id":     msg.ChatID,
+		"attachments": len(msg.Attachments),
+	})
+
+	return nil
+}
+
+// SupportsAttachments implements AttachmentChannel.
+func (c *FeishuChannel) SupportsAttachments() bool {
+	return true
+}
+
+func (c *FeishuChannel) sendMessage(ctx context.Context, chatID, msgType string, content interface{}) error {
+	payload, err := json.Marshal(content)
 	if err != nil {
 		return fmt.Errorf("failed to marshal feishu content: %w", err)
 	}
@@ -103,8 +138,8 @@ func (c *FeishuChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 	req := larkim.NewCreateMessageReqBuilder().
 		ReceiveIdType(larkim.ReceiveIdTypeChatId).
 		Body(larkim.NewCreateMessageReqBodyBuilder().
-			ReceiveId(msg.ChatID).
-			MsgType(larkim.MsgTypeText).
+			ReceiveId(chatID).
+			MsgType(msgType).
 			Content(string(payload)).
 			Uuid(fmt.Sprintf("picoclaw-%d", time.Now().UnixNano())).
 			Build()).
@@ -118,15 +153,67 @@ func (c *FeishuChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 	if !resp.Success() {
 		return fmt.Errorf("feishu api error: code=%d msg=%s", resp.Code, resp.Msg)
 	}
+	return nil
+}
 
-	logger.DebugCF("feishu", "Feishu message sent", map[string]interface{}{
-		"chat_id": msg.ChatID,
-	})
+// sendAttachment uploads a file and posts it as an image or file message.
+func (c *FeishuChannel) sendAttachment(ctx context.Context, chatID string, a bus.Attachment) error {
+	f, err := os.Open(a.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if isImageFile(a.Path) {
+		req := larkim.NewCreateImageReqBuilder().
+			Body(larkim.NewCreateImageReqBodyBuilder().
+				ImageType(larkim.ImageTypeMessage).
+				Image(f).
+				Build()).
+			Build()
+		resp, err := c.client.Im.V1.Image.Create(ctx, req)
+		if err != nil {
+			return err
+		}
+		if !resp.Success() || resp.Data == nil || resp.Data.ImageKey == nil {
+			return fmt.Errorf("feishu image upload failed: code=%d msg=%s", resp.Code, resp.Msg)
+		}
+		return c.sendMessage(ctx, chatID, larkim.MsgTypeImage, map[string]string{"image_key": *resp.Data.ImageKey})
+	}
 
-	return nil
+	req := larkim.NewCreateFileReqBuilder().
+		Body(larkim.NewCreateFileReqBodyBuilder().
+			FileType(feishuFileType(a.Path)).
+			FileName(attachmentName(a)).
+			File(f).
+			Build()).
+		Build()
+	resp, err := c.client.Im.V1.File.Create(ctx, req)
+	if err != nil {
+		return err
+	}
+	if !resp.Success() || resp.Data == nil || resp.Data.FileKey == nil {
+		return fmt.Errorf("feishu file upload failed: code=%d msg=%s", resp.Code, resp.Msg)
+	}
+	return c.sendMessage(ctx, chatID, larkim.MsgTypeFile, map[string]string{"file_key": *resp.Data.FileKey})
 }
 
-func (c *FeishuChannel) handleMessageReceive(_ context.Context, event *larkim.P2MessageReceiveV1) error {
+// feishuFileType maps an extension to Feishu's upload file_type.
+func feishuFileType(path string) string {
+	switch ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), ".")); ext {
+	case "pdf", "doc", "xls", "ppt":
+		return ext
+	case "docx":
+		return "doc"
+	case "xlsx":
+		return "xls"
+	case "pptx":
+		return "ppt"
+	}
+	return larkim.FileTypeStream
+}
+
+func (c *FeishuChannel) handleMessageReceive(ctx context.Context, event *larkim.P2MessageReceiveV1) error {
 	if event == nil || event.Event == nil || event.Event.Message == nil {
 		return nil
 	}
@@ -143,8 +230,19 @@ func (c *FeishuChannel) handleMessageReceive(_ context.Context, event *larkim.P2
 	if senderID == "" {
 		senderID = "unknown"
 	}
+	if !c.IsAllowed(senderID) {
+		return nil
+	}
 
 	content := extractFeishuMessageContent(message)
+	var mediaPaths []string
+	if stringValue(message.MessageType) == larkim.MsgTypeAudio {
+		content = ""
+		if audioPath := c.downloadAudio(ctx, message); audioPath != "" {
+			mediaPaths = append(mediaPaths, audioPath)
+			content = transcribeVoice(ctx, c.transcriber, audioPath, "voice", "")
+		}
+	}
 	if content == "" {
 		content = "[empty message]"
 	}
@@ -169,10 +267,50 @@ func (c *FeishuChannel) handleMessageReceive(_ context.Context, event *larkim.P2
 		"preview":   utils.Truncate(content, 80),
 	})
 
-	c.HandleMessage(senderID, chatID, content, nil, metadata)
+	c.HandleMessage(senderID, chatID, content, mediaPaths, metadata)
 	return nil
 }
 
+// downloadAudio fetches the Opus file behind an audio message.
+func (c *FeishuChannel) downloadAudio(ctx context.Context, message *larkim.EventMessage) string {
+	var payload struct {
+		FileKey string `json:"file_key"`
+	}
+	if err := json.Unmarshal([]byte(stringValue(message.Content)), &payload); err != nil || payload.FileKey == "" {
+		return ""
+	}
+	messageID := stringValue(message.MessageId)
+
+	req := larkim.NewGetMessageResourceReqBuilder().
+		MessageId(messageID).
+		FileKey(payload.FileKey).
+		Type("file").
+		Build()
+	resp, err := c.client.Im.V1.MessageResource.Get(ctx, req)
+	if err != nil {
+		logger.ErrorCF("feishu", "Failed to download audio", map[string]interface{}{"error": err.Error()})
+		return ""
+	}
+	if !resp.Success() {
+		logger.ErrorCF("feishu", "Failed to download audio", map[string]interface{}{
+			"code": resp.Code,
+			"msg":  resp.Msg,
+		})
+		return ""
+	}
+
+	mediaDir := filepath.Join(os.TempDir(), "picoclaw_media")
+	if err := os.MkdirAll(mediaDir, 0755); err != nil {
+		return ""
+	}
+	localPath := filepath.Join(mediaDir, "feishu_"+filepath.Base(messageID)+".opus")
+	if err := resp.WriteFile(localPath); err != nil {
+		logger.ErrorCF("feishu", "Failed to save audio", map[string]interface{}{"error": err.Error()})
+		return ""
+	}
+	return localPath
+}
+
 func extractFeishuSenderID(sender *larkim.EventSender) string {
 	if sender == nil || sender.SenderId == nil {
 		return ""
@@ -2,22 +2,54 @@ package channels
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"net"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
+// maixcamHandshakeTimeout bounds how long a new client may take to send hello.
+const maixcamHandshakeTimeout = 10 * time.Second
+
+// maixcamDefaultDevice is the device ID used for clients that connect
+// without a hello when unauthenticated clients are allowed.
+const maixcamDefaultDevice = "default"
+
 type MaixCamChannel struct {
 	*BaseChannel
 	config     config.MaixCamConfig
 	listener   net.Listener
-	clients    map[net.Conn]bool
+	devices    map[string]*maixcamDevice
+	status     map[string]*MaixCamDeviceStatus
 	clientsMux sync.RWMutex
+	vision     *visionPipeline
+}
+
+// maixcamDevice is an authenticated connection. Each device ID has at most
+// one live connection; a reconnect replaces the previous one.
+type maixcamDevice struct {
+	id      string
+	name    string
+	conn    net.Conn
+	writeMu sync.Mutex
+}
+
+// MaixCamDeviceStatus is the last known state of a device.
+type MaixCamDeviceStatus struct {
+	ID         string                 `json:"id"`
+	Name       string                 `json:"name,omitempty"`
+	Connected  bool                   `json:"connected"`
+	RemoteAddr string                 `json:"remote_addr,omitempty"`
+	LastSeen   time.Time              `json:"last_seen"`
+	Status     map[string]interface{} `json:"status,omitempty"`
 }
 
 type MaixCamMessage struct {
@@ -30,11 +62,40 @@ type MaixCamMessage struct {
 func NewMaixCamChannel(cfg config.MaixCamConfig, bus *bus.MessageBus) (*MaixCamChannel, error) {
 	base := NewBaseChannel("maixcam", cfg, bus, cfg.AllowFrom)
 
-	return &MaixCamChannel{
+	seen := make(map[string]bool, len(cfg.Devices))
+	for _, d := range cfg.Devices {
+		if d.ID == "" || d.Token == "" {
+			return nil, fmt.Errorf("maixcam device entries need both id and token")
+		}
+		if seen[d.ID] {
+			return nil, fmt.Errorf("duplicate maixcam device id %q", d.ID)
+		}
+		seen[d.ID] = true
+	}
+
+	policies := []string{cfg.Vision.Policy}
+	for _, cls := range cfg.Vision.Classes {
+		policies = append(policies, cls.Policy)
+	}
+	for _, p := range policies {
+		if p != "" && p != visionPolicyAgent && p != visionPolicyNotify {
+			return nil, fmt.Errorf("invalid maixcam vision policy %q, want agent or notify", p)
+		}
+	}
+	for _, t := range cfg.Vision.NotifyTargets {
+		if channel, chatID, ok := strings.Cut(t, ":"); !ok || channel == "" || chatID == "" {
+			return nil, fmt.Errorf("invalid maixcam notify target %q, want channel:chat_id", t)
+		}
+	}
+
+	c := &MaixCamChannel{
 		BaseChannel: base,
 		config:      cfg,
-		clients:     make(map[net.Conn]bool),
-	}, nil
+		devices:     make(map[string]*maixcamDevice),
+		status:      make(map[string]*MaixCamDeviceStatus),
+	}
+	c.vision = newVisionPipeline(cfg.Vision, c.dispatchVisionEvent)
+	return c, nil
 }
 
 func (c *MaixCamChannel) Start(ctx context.Context) error {
@@ -49,9 +110,18 @@ func (c *MaixCamChannel) Start(ctx context.Context) error {
 	c.listener = listener
 	c.setRunning(true)
 
+	if len(c.config.Devices) == 0 {
+		if c.config.AllowUnauthenticated {
+			logger.WarnC("maixcam", "No devices configured; accepting unauthenticated MaixCam clients")
+		} else {
+			logger.WarnC("maixcam", "No devices configured; refusing all MaixCam clients")
+		}
+	}
+
 	logger.InfoCF("maixcam", "MaixCam server listening", map[string]interface{}{
-		"host": c.config.Host,
-		"port": c.config.Port,
+		"host":    c.config.Host,
+		"port":    c.config.Port,
+		"devices": len(c.config.Devices),
 	})
 
 	go c.acceptConnections(ctx)
@@ -82,10 +152,6 @@ func (c *MaixCamChannel) acceptConnections(ctx context.Context) {
 				"remote_addr": conn.RemoteAddr().String(),
 			})
 
-			c.clientsMux.Lock()
-			c.clients[conn] = true
-			c.clientsMux.Unlock()
-
 			go c.handleConnection(conn, ctx)
 		}
 	}
@@ -94,15 +160,29 @@ func (c *MaixCamChannel) acceptConnections(ctx context.Context) {
 func (c *MaixCamChannel) handleConnection(conn net.Conn, ctx context.Context) {
 	logger.DebugC("maixcam", "Handling MaixCam connection")
 
+	decoder := json.NewDecoder(conn)
+
+	dev, first, err := c.handshake(conn, decoder)
+	if err != nil {
+		logger.WarnCF("maixcam", "MaixCam handshake failed", map[string]interface{}{
+			"remote_addr": conn.RemoteAddr().String(),
+			"error":       err.Error(),
+		})
+		writeMaixCamJSON(conn, map[string]interface{}{"type": "error", "message": "authentication failed"})
+		conn.Close()
+		return
+	}
+
+	c.register(dev)
 	defer func() {
 		conn.Close()
-		c.clientsMux.Lock()
-		delete(c.clients, conn)
-		c.clientsMux.Unlock()
+		c.unregister(dev)
 		logger.DebugC("maixcam", "Connection closed")
 	}()
 
-	decoder := json.NewDecoder(conn)
+	if first != nil {
+		c.processMessage(*first, dev)
+	}
 
 	for {
 		select {
@@ -111,79 +191,278 @@ func (c *MaixCamChannel) handleConnection(conn net.Conn, ctx context.Context) {
 		default:
 			var msg MaixCamMessage
 			if err := decoder.Decode(&msg); err != nil {
-				if err.Error() != "EOF" {
+				if err.Error() != "EOF" && c.IsRunning() {
 					logger.ErrorCF("maixcam", "Failed to decode message", map[string]interface{}{
-						"error": err.Error(),
+						"device_id": dev.id,
+						"error":     err.Error(),
 					})
 				}
 				return
 			}
 
-			c.processMessage(msg, conn)
+			c.processMessage(msg, dev)
+		}
+	}
+}
+
+// handshake reads the first message from a new client, which must be
+// {"type":"hello","data":{"device_id":..,"token":..}} for a configured
+// device. Without devices, clients are refused unless allow_unauthenticated
+// is set; then hello is optional and the device ID defaults to "default",
+// and a non-hello first message is returned for normal processing.
+func (c *MaixCamChannel) handshake(conn net.Conn, decoder *json.Decoder) (*maixcamDevice, *MaixCamMessage, error) {
+	conn.SetReadDeadline(time.Now().Add(maixcamHandshakeTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	var msg MaixCamMessage
+	if err := decoder.Decode(&msg); err != nil {
+		return nil, nil, fmt.Errorf("reading hello: %w", err)
+	}
+
+	deviceID, _ := msg.Data["device_id"].(string)
+	token, _ := msg.Data["token"].(string)
+
+	if len(c.config.Devices) == 0 {
+		if !c.config.AllowUnauthenticated {
+			return nil, nil, fmt.Errorf("no devices configured")
 		}
+		dev := &maixcamDevice{id: maixcamDefaultDevice, conn: conn}
+		if msg.Type != "hello" {
+			return dev, &msg, nil
+		}
+		if deviceID != "" {
+			dev.id = deviceID
+		}
+		c.ackHello(dev)
+		return dev, nil, nil
+	}
+
+	if msg.Type != "hello" {
+		return nil, nil, fmt.Errorf("expected hello, got %q", msg.Type)
+	}
+	for _, d := range c.config.Devices {
+		if d.ID == deviceID && subtle.ConstantTimeCompare([]byte(d.Token), []byte(token)) == 1 {
+			dev := &maixcamDevice{id: d.ID, name: d.Name, conn: conn}
+			c.ackHello(dev)
+			return dev, nil, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("unknown device or bad token for %q", deviceID)
+}
+
+func (c *MaixCamChannel) ackHello(dev *maixcamDevice) {
+	dev.writeJSON(map[string]interface{}{
+		"type":      "hello_ack",
+		"device_id": dev.id,
+		"chat_id":   dev.id,
+	})
+}
+
+// register makes dev the live connection for its ID, closing any previous one.
+func (c *MaixCamChannel) register(dev *maixcamDevice) {
+	c.clientsMux.Lock()
+	old := c.devices[dev.id]
+	c.devices[dev.id] = dev
+	c.clientsMux.Unlock()
+
+	if old != nil && old.conn != dev.conn {
+		logger.InfoCF("maixcam", "Device reconnected, closing previous connection", map[string]interface{}{
+			"device_id": dev.id,
+		})
+		old.conn.Close()
+	}
+
+	c.handleStatusUpdate(MaixCamMessage{
+		Type: "connected",
+		Data: map[string]interface{}{"remote_addr": dev.conn.RemoteAddr().String()},
+	}, dev)
+}
+
+func (c *MaixCamChannel) unregister(dev *maixcamDevice) {
+	c.clientsMux.Lock()
+	current := c.devices[dev.id] == dev
+	if current {
+		delete(c.devices, dev.id)
+	}
+	c.clientsMux.Unlock()
+
+	// A replaced connection must not mark the device as offline.
+	if current {
+		c.handleStatusUpdate(MaixCamMessage{Type: "disconnected"}, dev)
 	}
 }
 
-func (c *MaixCamChannel) processMessage(msg MaixCamMessage, conn net.Conn) {
+func (c *MaixCamChannel) processMessage(msg MaixCamMessage, dev *maixcamDevice) {
 	switch msg.Type {
-	case "person_detected":
-		c.handlePersonDetection(msg)
-	case "heartbeat":
-		logger.DebugC("maixcam", "Received heartbeat")
-	case "status":
-		c.handleStatusUpdate(msg)
+	case "person_detected", "detection":
+		c.handleDetection(msg, dev)
+	case "heartbeat", "status":
+		c.handleStatusUpdate(msg, dev)
 	default:
 		logger.WarnCF("maixcam", "Unknown message type", map[string]interface{}{
-			"type": msg.Type,
+			"type":      msg.Type,
+			"device_id": dev.id,
 		})
 	}
 }
 
-func (c *MaixCamChannel) handlePersonDetection(msg MaixCamMessage) {
-	logger.InfoCF("maixcam", "", map[string]interface{}{
+// handleDetection feeds a detection into the vision pipeline, which emits
+// grouped events through dispatchVisionEvent.
+func (c *MaixCamChannel) handleDetection(msg MaixCamMessage, dev *maixcamDevice) {
+	logger.DebugCF("maixcam", "Detection from MaixCam", map[string]interface{}{
+		"device_id": dev.id,
 		"timestamp": msg.Timestamp,
-		"data":      msg.Data,
+		"class":     msg.Data["class_name"],
+		"score":     msg.Data["score"],
 	})
 
-	senderID := "maixcam"
-	chatID := "default"
+	c.touch(dev)
+	c.vision.Process(dev, msg)
+}
 
-	classInfo, ok := msg.Data["class_name"].(string)
-	if !ok {
-		classInfo = "person"
+// dispatchVisionEvent delivers a grouped detection event either as a
+// notification to the configured targets or to the agent via the device chat.
+func (c *MaixCamChannel) dispatchVisionEvent(ev *visionEvent) {
+	dev := ev.Device
+	if !c.IsAllowed(dev.id) {
+		return
 	}
 
-	score, _ := msg.Data["score"].(float64)
-	x, _ := msg.Data["x"].(float64)
-	y, _ := msg.Data["y"].(float64)
-	w, _ := msg.Data["w"].(float64)
-	h, _ := msg.Data["h"].(float64)
+	content := ev.Summary()
+	snapshots := ev.Snapshots()
 
-	content := fmt.Sprintf("📷 Person detected!\nClass: %s\nConfidence: %.2f%%\nPosition: (%.0f, %.0f)\nSize: %.0fx%.0f",
-		classInfo, score*100, x, y, w, h)
+	logger.InfoCF("maixcam", "Vision event", map[string]interface{}{
+		"device_id":  dev.id,
+		"detections": len(ev.Detections),
+		"snapshots":  len(snapshots),
+		"policy":     ev.Policy,
+	})
 
+	if ev.Policy == visionPolicyNotify {
+		if len(c.config.Vision.NotifyTargets) > 0 {
+			c.notifyVisionEvent(content, snapshots)
+			return
+		}
+		logger.WarnC("maixcam", "Vision policy is notify but no notify_targets are set; sending to agent")
+	}
+
+	classes := make([]string, 0, len(ev.Detections))
+	for _, d := range ev.Detections {
+		if !containsString(classes, d.Class) {
+			classes = append(classes, d.Class)
+		}
+	}
 	metadata := map[string]string{
-		"timestamp": fmt.Sprintf("%.0f", msg.Timestamp),
-		"class_id":  fmt.Sprintf("%.0f", msg.Data["class_id"]),
-		"score":     fmt.Sprintf("%.2f", score),
-		"x":         fmt.Sprintf("%.0f", x),
-		"y":         fmt.Sprintf("%.0f", y),
-		"w":         fmt.Sprintf("%.0f", w),
-		"h":         fmt.Sprintf("%.0f", h),
+		"device_id":  dev.id,
+		"event":      "detection",
+		"classes":    strings.Join(classes, ","),
+		"detections": fmt.Sprintf("%d", len(ev.Detections)),
+		"started_at": ev.Start.UTC().Format(time.RFC3339),
+	}
+	if dev.name != "" {
+		metadata["device_name"] = dev.name
 	}
 
-	c.HandleMessage(senderID, chatID, content, []string{}, metadata)
+	// Each device is its own sender and chat, so replies route back to it.
+	c.HandleMessage(dev.id, dev.id, content, snapshots, metadata)
 }
 
-func (c *MaixCamChannel) handleStatusUpdate(msg MaixCamMessage) {
-	logger.InfoCF("maixcam", "Status update from MaixCam", map[string]interface{}{
-		"status": msg.Data,
-	})
+// notifyVisionEvent sends the summary with its snapshots attached. Channels
+// that cannot upload files get a notice instead, never the local paths.
+func (c *MaixCamChannel) notifyVisionEvent(content string, snapshots []string) {
+	attachments := make([]bus.Attachment, 0, len(snapshots))
+	for _, path := range snapshots {
+		attachments = append(attachments, bus.Attachment{Path: path})
+	}
+	for _, target := range c.config.Vision.NotifyTargets {
+		channel, chatID, ok := strings.Cut(target, ":")
+		if !ok || channel == "" || chatID == "" {
+			logger.WarnCF("maixcam", "Invalid notify target", map[string]interface{}{"target": target})
+			continue
+		}
+		c.bus.PublishOutbound(bus.OutboundMessage{Channel: channel, ChatID: chatID, Content: content, Attachments: attachments})
+	}
+}
+
+// handleStatusUpdate records heartbeats, status reports and connection
+// changes for a device.
+func (c *MaixCamChannel) handleStatusUpdate(msg MaixCamMessage, dev *maixcamDevice) {
+	c.clientsMux.Lock()
+	st := c.statusFor(dev)
+	st.LastSeen = time.Now()
+	switch msg.Type {
+	case "connected":
+		st.Connected = true
+		st.RemoteAddr, _ = msg.Data["remote_addr"].(string)
+	case "disconnected":
+		st.Connected = false
+	case "status":
+		if st.Status == nil {
+			st.Status = make(map[string]interface{})
+		}
+		for k, v := range msg.Data {
+			st.Status[k] = v
+		}
+	}
+	c.clientsMux.Unlock()
+
+	fields := map[string]interface{}{
+		"device_id": dev.id,
+		"event":     msg.Type,
+	}
+	if len(msg.Data) > 0 {
+		fields["status"] = msg.Data
+	}
+	if msg.Type == "heartbeat" {
+		logger.DebugCF("maixcam", "Received heartbeat", fields)
+		return
+	}
+	logger.InfoCF("maixcam", "Status update from MaixCam", fields)
+}
+
+// statusFor returns the status entry for dev. Caller must hold clientsMux.
+func (c *MaixCamChannel) statusFor(dev *maixcamDevice) *MaixCamDeviceStatus {
+	st, ok := c.status[dev.id]
+	if !ok {
+		st = &MaixCamDeviceStatus{ID: dev.id}
+		c.status[dev.id] = st
+	}
+	if dev.name != "" {
+		st.Name = dev.name
+	}
+	return st
+}
+
+func (c *MaixCamChannel) touch(dev *maixcamDevice) {
+	c.clientsMux.Lock()
+	c.statusFor(dev).LastSeen = time.Now()
+	c.clientsMux.Unlock()
+}
+
+// Devices returns the known devices and their last reported state, sorted by ID.
+func (c *MaixCamChannel) Devices() []MaixCamDeviceStatus {
+	c.clientsMux.RLock()
+	defer c.clientsMux.RUnlock()
+
+	out := make([]MaixCamDeviceStatus, 0, len(c.status))
+	for _, st := range c.status {
+		cp := *st
+		if st.Status != nil {
+			cp.Status = make(map[string]interface{}, len(st.Status))
+			for k, v := range st.Status {
+				cp.Status[k] = v
+			}
+		}
+		out = append(out, cp)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
 }
 
 func (c *MaixCamChannel) Stop(ctx context.Context) error {
 	logger.InfoC("maixcam", "Stopping MaixCam channel")
 	c.setRunning(false)
+	c.vision.Stop()
 
 	if c.listener != nil {
 		c.listener.Close()
@@ -192,26 +471,30 @@ func (c *MaixCamChannel) Stop(ctx context.Context) error {
 	c.clientsMux.Lock()
 	defer c.clientsMux.Unlock()
 
-	for conn := range c.clients {
-		conn.Close()
+	for _, dev := range c.devices {
+		dev.conn.Close()
 	}
-	c.clients = make(map[net.Conn]bool)
+	c.devices = make(map[string]*maixcamDevice)
 
 	logger.InfoC("maixcam", "MaixCam channel stopped")
 	return nil
 }
 
+// Send delivers a command to the device whose ID is the message's chat ID.
 func (c *MaixCamChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 	if !c.IsRunning() {
 		return fmt.Errorf("maixcam channel not running")
 	}
 
 	c.clientsMux.RLock()
-	defer c.clientsMux.RUnlock()
+	dev, ok := c.devices[msg.ChatID]
+	c.clientsMux.RUnlock()
 
-	if len(c.clients) == 0 {
-		logger.WarnC("maixcam", "No MaixCam devices connected")
-		return fmt.Errorf("no connected MaixCam devices")
+	if !ok {
+		logger.WarnCF("maixcam", "MaixCam device not connected", map[string]interface{}{
+			"device_id": msg.ChatID,
+		})
+		return fmt.Errorf("maixcam device %q is not connected", msg.ChatID)
 	}
 
 	response := map[string]interface{}{
@@ -221,21 +504,33 @@ func (c *MaixCamChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 		"chat_id":   msg.ChatID,
 	}
 
-	data, err := json.Marshal(response)
-	if err != nil {
-		return fmt.Errorf("failed to marshal response: %w", err)
+	if err := dev.writeJSON(response); err != nil {
+		logger.ErrorCF("maixcam", "Failed to send to device", map[string]interface{}{
+			"device_id": dev.id,
+			"error":     err.Error(),
+		})
+		return err
 	}
+	return nil
+}
 
-	var sendErr error
-	for conn := range c.clients {
-		if _, err := conn.Write(data); err != nil {
-			logger.ErrorCF("maixcam", "Failed to send to client", map[string]interface{}{
-				"client": conn.RemoteAddr().String(),
-				"error":  err.Error(),
-			})
-			sendErr = err
-		}
+// writeJSON sends one newline-terminated JSON message, serializing writes
+// to the device connection.
+func (d *maixcamDevice) writeJSON(v interface{}) error {
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+	return writeMaixCamJSON(d.conn, v)
+}
+
+func writeMaixCamJSON(conn net.Conn, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response: %w", err)
 	}
+	data = append(data, '\n')
 
-	return sendErr
+	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	defer conn.SetWriteDeadline(time.Time{})
+	_, err = conn.Write(data)
+	return err
 }
@@ -0,0 +1,221 @@
+package channels
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+type fakeMaixCam struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialFakeMaixCam(t *testing.T, c *MaixCamChannel) *fakeMaixCam {
+	t.Helper()
+	conn, err := net.Dial("tcp", c.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &fakeMaixCam{t: t, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+func (f *fakeMaixCam) send(msgType string, data map[string]interface{}) {
+	f.t.Helper()
+	b, _ := json.Marshal(MaixCamMessage{Type: msgType, Data: data})
+	if _, err := f.conn.Write(append(b, '\n')); err != nil {
+		f.t.Fatalf("write: %v", err)
+	}
+}
+
+func (f *fakeMaixCam) read() map[string]interface{} {
+	f.t.Helper()
+	f.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := f.reader.ReadBytes('\n')
+	if err != nil {
+		f.t.Fatalf("read: %v", err)
+	}
+	var v map[string]interface{}
+	if err := json.Unmarshal(line, &v); err != nil {
+		f.t.Fatalf("unmarshal %q: %v", line, err)
+	}
+	return v
+}
+
+func (f *fakeMaixCam) hello(id, token string) map[string]interface{} {
+	f.t.Helper()
+	f.send("hello", map[string]interface{}{"device_id": id, "token": token})
+	return f.read()
+}
+
+func startTestMaixCam(t *testing.T, devices []config.MaixCamDeviceConfig) (*MaixCamChannel, *bus.MessageBus) {
+	t.Helper()
+	mb := bus.NewMessageBus()
+	cfg := config.MaixCamConfig{
+		Host:    "127.0.0.1",
+		Port:    0,
+		Devices: devices,
+		Vision:  config.MaixCamVisionConfig{BurstSeconds: 0.05},
+	}
+	c, err := NewMaixCamChannel(cfg, mb)
+	if err != nil {
+		t.Fatalf("NewMaixCamChannel: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := c.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		c.Stop(context.Background())
+	})
+	return c, mb
+}
+
+func waitForDevice(t *testing.T, c *MaixCamChannel, id string, connected bool) MaixCamDeviceStatus {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, st := range c.Devices() {
+			if st.ID == id && st.Connected == connected {
+				return st
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("device %q never reached connected=%v", id, connected)
+	return MaixCamDeviceStatus{}
+}
+
+func TestMaixCamRejectsBadToken(t *testing.T) {
+	c, _ := startTestMaixCam(t, []config.MaixCamDeviceConfig{{ID: "door", Token: "s3cret"}})
+
+	cam := dialFakeMaixCam(t, c)
+	if reply := cam.hello("door", "wrong"); reply["type"] != "error" {
+		t.Fatalf("expected error reply, got %v", reply)
+	}
+
+	cam = dialFakeMaixCam(t, c)
+	cam.send("person_detected", map[string]interface{}{"score": 0.9})
+	if reply := cam.read(); reply["type"] != "error" {
+		t.Fatalf("events before hello should be rejected, got %v", reply)
+	}
+	if len(c.Devices()) != 0 {
+		t.Errorf("no device should be registered, got %v", c.Devices())
+	}
+}
+
+func TestMaixCamWithoutDevices(t *testing.T) {
+	c, _ := startTestMaixCam(t, nil)
+	cam := dialFakeMaixCam(t, c)
+	if reply := cam.hello("door", ""); reply["type"] != "error" {
+		t.Fatalf("clients should be refused without devices, got %v", reply)
+	}
+
+	c.config.AllowUnauthenticated = true
+	cam = dialFakeMaixCam(t, c)
+	cam.send("heartbeat", nil)
+	waitForDevice(t, c, maixcamDefaultDevice, true)
+}
+
+func TestMaixCamPerDeviceRouting(t *testing.T) {
+	c, mb := startTestMaixCam(t, []config.MaixCamDeviceConfig{
+		{ID: "door", Token: "t1", Name: "Front door"},
+		{ID: "garage", Token: "t2"},
+	})
+
+	door := dialFakeMaixCam(t, c)
+	if reply := door.hello("door", "t1"); reply["type"] != "hello_ack" || reply["chat_id"] != "door" {
+		t.Fatalf("unexpected ack: %v", reply)
+	}
+	garage := dialFakeMaixCam(t, c)
+	if reply := garage.hello("garage", "t2"); reply["type"] != "hello_ack" {
+		t.Fatalf("unexpected ack: %v", reply)
+	}
+	waitForDevice(t, c, "garage", true)
+
+	door.send("person_detected", map[string]interface{}{"class_name": "person", "score": 0.8})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	msg, ok := mb.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("no inbound message")
+	}
+	if msg.SenderID != "door" || msg.ChatID != "door" || msg.Metadata["device_name"] != "Front door" {
+		t.Errorf("inbound not attributed to device: %+v", msg)
+	}
+
+	if err := c.Send(context.Background(), bus.OutboundMessage{Channel: "maixcam", ChatID: "garage", Content: "hi"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if reply := garage.read(); reply["message"] != "hi" {
+		t.Errorf("garage got %v", reply)
+	}
+	door.conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, err := door.reader.ReadBytes('\n'); err == nil {
+		t.Error("door should not receive the garage reply")
+	}
+
+	if err := c.Send(context.Background(), bus.OutboundMessage{Channel: "maixcam", ChatID: "attic", Content: "hi"}); err == nil {
+		t.Error("expected error for unknown device")
+	}
+}
+
+func TestMaixCamStatusTracking(t *testing.T) {
+	c, _ := startTestMaixCam(t, []config.MaixCamDeviceConfig{{ID: "door", Token: "t1"}})
+
+	cam := dialFakeMaixCam(t, c)
+	cam.hello("door", "t1")
+	cam.send("status", map[string]interface{}{"fps": 12.0})
+	cam.send("heartbeat", nil)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		st := waitForDevice(t, c, "door", true)
+		if st.Status["fps"] == 12.0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("status not recorded: %+v", st)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cam.conn.Close()
+	waitForDevice(t, c, "door", false)
+}
+
+func TestMaixCamNotifyAttachesSnapshots(t *testing.T) {
+	mb := bus.NewMessageBus()
+	cfg := config.MaixCamConfig{Vision: config.MaixCamVisionConfig{NotifyTargets: []string{"telegram:42"}}}
+	c, err := NewMaixCamChannel(cfg, mb)
+	if err != nil {
+		t.Fatalf("NewMaixCamChannel: %v", err)
+	}
+
+	snapshot := filepath.Join(t.TempDir(), "1.jpg")
+	c.notifyVisionEvent("Front door: person", []string{snapshot})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	out, ok := mb.SubscribeOutbound(ctx)
+	if !ok {
+		t.Fatal("no notification sent")
+	}
+	if out.Channel != "telegram" || out.ChatID != "42" || strings.Contains(out.Content, snapshot) {
+		t.Errorf("unexpected notification %+v", out)
+	}
+	if len(out.Attachments) != 1 || out.Attachments[0].Path != snapshot {
+		t.Errorf("snapshot should be attached, got %+v", out.Attachments)
+	}
+}
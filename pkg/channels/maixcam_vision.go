@@ -0,0 +1,407 @@
+package channels
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	visionPolicyAgent  = "agent"
+	visionPolicyNotify = "notify"
+)
+
+// visionDetection is one frame-level detection reported by a device.
+type visionDetection struct {
+	Class    string
+	Score    float64
+	X, Y     float64
+	W, H     float64
+	Zone     string
+	Time     time.Time
+	Snapshot string // saved frame, if any
+}
+
+// visionEvent groups the detections from one burst on one device.
+type visionEvent struct {
+	Device     *maixcamDevice
+	Detections []visionDetection
+	Start, End time.Time
+	Policy     string
+}
+
+// visionBurst collects detections until its window closes.
+type visionBurst struct {
+	event *visionEvent
+	timer *time.Timer
+}
+
+// visionPipeline filters, debounces and groups detections into events.
+// Detections whose class fired within the debounce window are dropped;
+// the rest join the device's open burst, which is emitted when its
+// window elapses.
+type visionPipeline struct {
+	cfg       config.MaixCamVisionConfig
+	mu        sync.Mutex
+	lastFired map[string]time.Time // deviceID|class -> last event
+	bursts    map[string]*visionBurst
+	emit      func(*visionEvent)
+	now       func() time.Time
+	mediaDir  string
+}
+
+func newVisionPipeline(cfg config.MaixCamVisionConfig, emit func(*visionEvent)) *visionPipeline {
+	if cfg.BurstSeconds <= 0 {
+		cfg.BurstSeconds = 3
+	}
+	if cfg.DebounceSeconds == 0 {
+		cfg.DebounceSeconds = 30
+	}
+	if cfg.MaxSnapshots == 0 {
+		cfg.MaxSnapshots = 3
+	}
+	if cfg.Policy == "" {
+		cfg.Policy = visionPolicyAgent
+	}
+	return &visionPipeline{
+		cfg:       cfg,
+		lastFired: make(map[string]time.Time),
+		bursts:    make(map[string]*visionBurst),
+		emit:      emit,
+		now:       time.Now,
+		mediaDir:  filepath.Join(os.TempDir(), "picoclaw_media", "maixcam"),
+	}
+}
+
+// parseDetection extracts a detection from a device message.
+func parseDetection(msg MaixCamMessage) visionDetection {
+	d := visionDetection{Class: "person"}
+	if name, ok := msg.Data["class_name"].(string); ok && name != "" {
+		d.Class = name
+	}
+	d.Score, _ = msg.Data["score"].(float64)
+	d.X, _ = msg.Data["x"].(float64)
+	d.Y, _ = msg.Data["y"].(float64)
+	d.W, _ = msg.Data["w"].(float64)
+	d.H, _ = msg.Data["h"].(float64)
+	return d
+}
+
+// Process handles one detection message from dev.
+func (p *visionPipeline) Process(dev *maixcamDevice, msg MaixCamMessage) {
+	d := parseDetection(msg)
+	d.Time = p.now()
+
+	cls := p.cfg.Classes[d.Class]
+	if cls.Ignore {
+		return
+	}
+	minScore := p.cfg.MinScore
+	if cls.MinScore != 0 {
+		minScore = cls.MinScore
+	}
+	if d.Score < minScore {
+		return
+	}
+	zone, ok := p.zoneFor(d)
+	if !ok {
+		logger.DebugCF("maixcam", "Detection outside zones", map[string]interface{}{
+			"device_id": dev.id,
+			"class":     d.Class,
+		})
+		return
+	}
+	d.Zone = zone
+
+	// Decoding and writing the frame happen outside p.mu, so a slow disk
+	// does not hold up other devices or the burst timers
+	p.mu.Lock()
+	if p.debounced(dev.id, d) {
+		p.mu.Unlock()
+		return
+	}
+	wantSnapshot := p.cfg.MaxSnapshots > 0
+	if b, open := p.bursts[dev.id]; open {
+		wantSnapshot = p.snapshotCount(b.event) < p.cfg.MaxSnapshots
+	}
+	p.mu.Unlock()
+
+	snapshot := ""
+	if wantSnapshot {
+		snapshot = p.saveSnapshot(dev.id, msg, d.Time)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// A flush may have started the debounce window while the frame was saved
+	if p.debounced(dev.id, d) {
+		if snapshot != "" {
+			os.Remove(snapshot)
+		}
+		return
+	}
+
+	b, open := p.bursts[dev.id]
+	if !open {
+		b = &visionBurst{event: &visionEvent{Device: dev, Start: d.Time}}
+		p.bursts[dev.id] = b
+		window := time.Duration(p.cfg.BurstSeconds * float64(time.Second))
+		b.timer = time.AfterFunc(window, func() { p.flush(dev.id, b) })
+	}
+
+	if snapshot != "" {
+		if p.snapshotCount(b.event) < p.cfg.MaxSnapshots {
+			d.Snapshot = snapshot
+		} else {
+			os.Remove(snapshot)
+		}
+	}
+	b.event.Detections = append(b.event.Detections, d)
+	b.event.End = d.Time
+}
+
+// debounced reports whether d falls in the debounce window of its class
+// on this device. Caller must hold p.mu.
+func (p *visionPipeline) debounced(deviceID string, d visionDetection) bool {
+	last, fired := p.lastFired[deviceID+"|"+d.Class]
+	return fired && d.Time.Sub(last) < p.debounceFor(d.Class)
+}
+
+// flush emits a burst and starts the debounce window for its classes.
+func (p *visionPipeline) flush(deviceID string, b *visionBurst) {
+	p.mu.Lock()
+	if p.bursts[deviceID] != b {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.bursts, deviceID)
+	ev := b.event
+	now := p.now()
+	for _, d := range ev.Detections {
+		p.lastFired[deviceID+"|"+d.Class] = now
+	}
+	ev.Policy = p.policyFor(ev)
+	p.mu.Unlock()
+
+	p.emit(ev)
+}
+
+// Stop discards open bursts.
+func (p *visionPipeline) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for id, b := range p.bursts {
+		b.timer.Stop()
+		delete(p.bursts, id)
+	}
+}
+
+func (p *visionPipeline) debounceFor(class string) time.Duration {
+	secs := p.cfg.DebounceSeconds
+	if c, ok := p.cfg.Classes[class]; ok && c.DebounceSeconds != 0 {
+		secs = c.DebounceSeconds
+	}
+	if secs < 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// policyFor sends an event to the agent if any of its classes asks for
+// the agent; otherwise it is a plain notification.
+func (p *visionPipeline) policyFor(ev *visionEvent) string {
+	policy := ""
+	for _, d := range ev.Detections {
+		classPolicy := p.cfg.Classes[d.Class].Policy
+		if classPolicy == "" {
+			classPolicy = p.cfg.Policy
+		}
+		if classPolicy == visionPolicyAgent {
+			return visionPolicyAgent
+		}
+		policy = classPolicy
+	}
+	if policy == "" {
+		policy = p.cfg.Policy
+	}
+	return policy
+}
+
+// zoneFor applies zone filters to the center of the detection box. It
+// returns the matching include zone name ("" when no include zones apply)
+// and false when the detection should be dropped.
+func (p *visionPipeline) zoneFor(d visionDetection) (string, bool) {
+	cx, cy := d.X+d.W/2, d.Y+d.H/2
+	hasInclude := false
+	match := ""
+	for _, z := range p.cfg.Zones {
+		if len(z.Classes) > 0 && !containsString(z.Classes, d.Class) {
+			continue
+		}
+		inside := cx >= z.X && cx <= z.X+z.W && cy >= z.Y && cy <= z.Y+z.H
+		if z.Exclude {
+			if inside {
+				return "", false
+			}
+			continue
+		}
+		hasInclude = true
+		if inside && match == "" {
+			match = z.Name
+		}
+	}
+	if hasInclude && match == "" {
+		return "", false
+	}
+	return match, true
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *visionPipeline) snapshotCount(ev *visionEvent) int {
+	n := 0
+	for _, d := range ev.Detections {
+		if d.Snapshot != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// saveSnapshot writes the base64 "image" field of a detection to disk and
+// returns its path, or "" when the message carries no frame.
+func (p *visionPipeline) saveSnapshot(deviceID string, msg MaixCamMessage, at time.Time) string {
+	encoded, _ := msg.Data["image"].(string)
+	if encoded == "" {
+		return ""
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		logger.WarnCF("maixcam", "Invalid snapshot encoding", map[string]interface{}{
+			"device_id": deviceID,
+			"error":     err.Error(),
+		})
+		return ""
+	}
+	format, _ := msg.Data["image_format"].(string)
+	ext, ok := snapshotExt(format)
+	if !ok {
+		logger.WarnCF("maixcam", "Unsupported snapshot format", map[string]interface{}{
+			"device_id": deviceID,
+			"format":    format,
+		})
+		return ""
+	}
+
+	dir := filepath.Join(p.mediaDir, snapshotDirName(deviceID))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		logger.ErrorCF("maixcam", "Failed to create snapshot directory", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return ""
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%d.%s", at.UnixNano(), ext))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		logger.ErrorCF("maixcam", "Failed to save snapshot", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return ""
+	}
+	return path
+}
+
+// snapshotExt returns the file extension for a device's image_format,
+// defaulting to jpg. Only JPEG and PNG are accepted.
+func snapshotExt(format string) (string, bool) {
+	switch strings.ToLower(strings.TrimPrefix(format, ".")) {
+	case "", "jpg", "jpeg":
+		return "jpg", true
+	case "png":
+		return "png", true
+	}
+	return "", false
+}
+
+// snapshotDirName returns the directory name for a device's snapshots. IDs
+// that are not a plain path element are replaced by their hash.
+func snapshotDirName(deviceID string) string {
+	if deviceID != "" && !strings.ContainsAny(deviceID, `/\`) && !strings.Contains(deviceID, "..") && deviceID != "." {
+		return deviceID
+	}
+	sum := sha256.Sum256([]byte(deviceID))
+	return "device-" + hex.EncodeToString(sum[:8])
+}
+
+// Snapshots returns the saved frames of an event.
+func (ev *visionEvent) Snapshots() []string {
+	var paths []string
+	for _, d := range ev.Detections {
+		if d.Snapshot != "" {
+			paths = append(paths, d.Snapshot)
+		}
+	}
+	return paths
+}
+
+// Summary renders the event as a chat message.
+func (ev *visionEvent) Summary() string {
+	type classStat struct {
+		count int
+		best  float64
+		zones []string
+	}
+	stats := make(map[string]*classStat)
+	for _, d := range ev.Detections {
+		st, ok := stats[d.Class]
+		if !ok {
+			st = &classStat{}
+			stats[d.Class] = st
+		}
+		st.count++
+		if d.Score > st.best {
+			st.best = d.Score
+		}
+		if d.Zone != "" && !containsString(st.zones, d.Zone) {
+			st.zones = append(st.zones, d.Zone)
+		}
+	}
+	classes := make([]string, 0, len(stats))
+	for c := range stats {
+		classes = append(classes, c)
+	}
+	sort.Strings(classes)
+
+	where := ev.Device.id
+	if ev.Device.name != "" {
+		where = ev.Device.name
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "📷 %s: %d detection(s) over %.1fs", where, len(ev.Detections), ev.End.Sub(ev.Start).Seconds())
+	for _, c := range classes {
+		st := stats[c]
+		fmt.Fprintf(&b, "\n- %s ×%d (best %.0f%%)", c, st.count, st.best*100)
+		if len(st.zones) > 0 {
+			fmt.Fprintf(&b, " in %s", strings.Join(st.zones, ", "))
+		}
+	}
+	return b.String()
+}
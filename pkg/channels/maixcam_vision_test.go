@@ -0,0 +1,158 @@
+package channels
+
+import (
+	"encoding/base64"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+type visionRecorder struct {
+	mu     sync.Mutex
+	events []*visionEvent
+}
+
+func (r *visionRecorder) emit(ev *visionEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev)
+}
+
+func (r *visionRecorder) wait(t *testing.T, n int) []*visionEvent {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		r.mu.Lock()
+		if len(r.events) >= n {
+			out := append([]*visionEvent(nil), r.events...)
+			r.mu.Unlock()
+			return out
+		}
+		r.mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("expected %d events", n)
+	return nil
+}
+
+func detection(class string, score, x, y float64) MaixCamMessage {
+	return MaixCamMessage{Type: "detection", Data: map[string]interface{}{
+		"class_name": class, "score": score, "x": x, "y": y, "w": 10.0, "h": 10.0,
+	}}
+}
+
+func newTestPipeline(cfg config.MaixCamVisionConfig) (*visionPipeline, *visionRecorder) {
+	rec := &visionRecorder{}
+	if cfg.BurstSeconds == 0 {
+		cfg.BurstSeconds = 0.05
+	}
+	return newVisionPipeline(cfg, rec.emit), rec
+}
+
+func TestVisionPipelineGroupsBurstAndDebounces(t *testing.T) {
+	p, rec := newTestPipeline(config.MaixCamVisionConfig{DebounceSeconds: 60})
+	dev := &maixcamDevice{id: "door", name: "Front door"}
+
+	for i := 0; i < 3; i++ {
+		p.Process(dev, detection("person", 0.9, 0, 0))
+	}
+	events := rec.wait(t, 1)
+	if len(events[0].Detections) != 3 {
+		t.Fatalf("burst should contain 3 detections, got %d", len(events[0].Detections))
+	}
+	if !strings.Contains(events[0].Summary(), "Front door") || !strings.Contains(events[0].Summary(), "person ×3") {
+		t.Errorf("unexpected summary: %s", events[0].Summary())
+	}
+
+	// Same class is debounced; a different class still fires.
+	p.Process(dev, detection("person", 0.9, 0, 0))
+	p.Process(dev, detection("cat", 0.9, 0, 0))
+	events = rec.wait(t, 2)
+	if len(events[1].Detections) != 1 || events[1].Detections[0].Class != "cat" {
+		t.Errorf("expected only cat in second event, got %+v", events[1].Detections)
+	}
+}
+
+func TestVisionPipelineFilters(t *testing.T) {
+	p, rec := newTestPipeline(config.MaixCamVisionConfig{
+		MinScore: 0.5,
+		Classes:  map[string]config.MaixCamClassConfig{"car": {Ignore: true}, "dog": {MinScore: 0.9}},
+		Zones: []config.MaixCamZoneConfig{
+			{Name: "porch", X: 0, Y: 0, W: 100, H: 100},
+			{Name: "street", X: 50, Y: 50, W: 50, H: 50, Exclude: true},
+		},
+	})
+	dev := &maixcamDevice{id: "door"}
+
+	p.Process(dev, detection("person", 0.4, 10, 10))  // below min score
+	p.Process(dev, detection("car", 0.9, 10, 10))     // ignored class
+	p.Process(dev, detection("dog", 0.8, 10, 10))     // below class min score
+	p.Process(dev, detection("person", 0.9, 200, 10)) // outside include zone
+	p.Process(dev, detection("person", 0.9, 70, 70))  // in exclude zone
+	p.Process(dev, detection("person", 0.9, 10, 10))  // kept
+
+	events := rec.wait(t, 1)
+	if len(events[0].Detections) != 1 || events[0].Detections[0].Zone != "porch" {
+		t.Fatalf("expected one porch detection, got %+v", events[0].Detections)
+	}
+}
+
+func TestVisionPipelinePolicyAndSnapshots(t *testing.T) {
+	p, rec := newTestPipeline(config.MaixCamVisionConfig{
+		Policy:       visionPolicyNotify,
+		MaxSnapshots: 1,
+		Classes:      map[string]config.MaixCamClassConfig{"stranger": {Policy: visionPolicyAgent}},
+	})
+	p.mediaDir = t.TempDir()
+	dev := &maixcamDevice{id: "door"}
+
+	frame := base64.StdEncoding.EncodeToString([]byte("jpeg-bytes"))
+	for i := 0; i < 2; i++ {
+		msg := detection("person", 0.9, 0, 0)
+		msg.Data["image"] = frame
+		p.Process(dev, msg)
+	}
+	events := rec.wait(t, 1)
+	if events[0].Policy != visionPolicyNotify {
+		t.Errorf("expected notify policy, got %q", events[0].Policy)
+	}
+	snaps := events[0].Snapshots()
+	if len(snaps) != 1 {
+		t.Fatalf("expected 1 snapshot, got %v", snaps)
+	}
+	if data, err := os.ReadFile(snaps[0]); err != nil || string(data) != "jpeg-bytes" {
+		t.Errorf("snapshot not saved: %v %q", err, data)
+	}
+
+	p.Process(dev, detection("stranger", 0.9, 0, 0))
+	events = rec.wait(t, 2)
+	if events[1].Policy != visionPolicyAgent {
+		t.Errorf("class policy should send to agent, got %q", events[1].Policy)
+	}
+}
+
+func TestVisionPipelineSnapshotPaths(t *testing.T) {
+	p, _ := newTestPipeline(config.MaixCamVisionConfig{})
+	p.mediaDir = t.TempDir()
+	frame := base64.StdEncoding.EncodeToString([]byte("png-bytes"))
+
+	msg := detection("person", 0.9, 0, 0)
+	msg.Data["image"] = frame
+	msg.Data["image_format"] = "PNG"
+	path := p.saveSnapshot("../../etc", msg, time.Now())
+	if path == "" || !strings.HasPrefix(path, p.mediaDir+string(os.PathSeparator)) || !strings.HasSuffix(path, ".png") {
+		t.Errorf("snapshot should stay in the media dir: %q", path)
+	}
+	if strings.Contains(path, "..") || strings.Contains(path, "etc") {
+		t.Errorf("device ID should be replaced: %q", path)
+	}
+
+	msg.Data["image_format"] = "sh/../../x"
+	if path := p.saveSnapshot("door", msg, time.Now()); path != "" {
+		t.Errorf("unsupported format should not be saved, got %q", path)
+	}
+}
@@ -9,11 +9,13 @@ package channels
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/voice"
 )
 
 type Manager struct {
@@ -21,6 +23,7 @@ type Manager struct {
 	bus          *bus.MessageBus
 	config       *config.Config
 	dispatchTask *asyncTask
+	transcriber  voice.Transcriber
 	mu           sync.RWMutex
 }
 
@@ -42,105 +45,116 @@ func NewManager(cfg *config.Config, messageBus *bus.MessageBus) (*Manager, error
 	return m, nil
 }
 
+// channelNames lists the built-in channels in initialization order.
+var channelNames = []string{"telegram", "whatsapp", "feishu", "discord", "maixcam", "qq", "dingtalk"}
+
 func (m *Manager) initChannels() error {
 	logger.InfoC("channels", "Initializing channel manager")
 
-	if m.config.Channels.Telegram.Enabled && m.config.Channels.Telegram.Token != "" {
-		logger.DebugC("channels", "Attempting to initialize Telegram channel")
-		telegram, err := NewTelegramChannel(m.config.Channels.Telegram, m.bus)
+	for _, name := range channelNames {
+		channel, err := m.newChannel(name)
 		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize Telegram channel", map[string]interface{}{
-				"error": err.Error(),
+			logger.ErrorCF("channels", "Failed to initialize channel", map[string]interface{}{
+				"channel": name,
+				"error":   err.Error(),
 			})
-		} else {
-			m.channels["telegram"] = telegram
-			logger.InfoC("channels", "Telegram channel enabled successfully")
+			continue
 		}
+		if channel == nil {
+			continue
+		}
+		m.channels[name] = channel
+		logger.InfoCF("channels", "Channel enabled successfully", map[string]interface{}{
+			"channel": name,
+		})
 	}
 
-	if m.config.Channels.WhatsApp.Enabled && m.config.Channels.WhatsApp.BridgeURL != "" {
+	logger.InfoCF("channels", "Channel initialization completed", map[string]interface{}{
+		"enabled_channels": len(m.channels),
+	})
+
+	return nil
+}
+
+// newChannel creates the named channel from the current config. It returns
+// nil without an error when the channel is disabled.
+func (m *Manager) newChannel(name string) (Channel, error) {
+	cfg := m.config.Channels
+	var (
+		channel Channel
+		err     error
+	)
+	switch name {
+	case "telegram":
+		if !cfg.Telegram.Enabled || cfg.Telegram.Token == "" {
+			return nil, nil
+		}
+		logger.DebugC("channels", "Attempting to initialize Telegram channel")
+		var ch *TelegramChannel
+		if ch, err = NewTelegramChannel(cfg.Telegram, m.bus); err == nil {
+			channel = ch
+		}
+	case "whatsapp":
+		if !cfg.WhatsApp.Enabled || cfg.WhatsApp.BridgeURL == "" {
+			return nil, nil
+		}
 		logger.DebugC("channels", "Attempting to initialize WhatsApp channel")
-		whatsapp, err := NewWhatsAppChannel(m.config.Channels.WhatsApp, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize WhatsApp channel", map[string]interface{}{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["whatsapp"] = whatsapp
-			logger.InfoC("channels", "WhatsApp channel enabled successfully")
+		var ch *WhatsAppChannel
+		if ch, err = NewWhatsAppChannel(cfg.WhatsApp, m.bus); err == nil {
+			channel = ch
+		}
+	case "feishu":
+		if !cfg.Feishu.Enabled {
+			return nil, nil
 		}
-	}
-
-	if m.config.Channels.Feishu.Enabled {
 		logger.DebugC("channels", "Attempting to initialize Feishu channel")
-		feishu, err := NewFeishuChannel(m.config.Channels.Feishu, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize Feishu channel", map[string]interface{}{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["feishu"] = feishu
-			logger.InfoC("channels", "Feishu channel enabled successfully")
+		var ch *FeishuChannel
+		if ch, err = NewFeishuChannel(cfg.Feishu, m.bus); err == nil {
+			channel = ch
+		}
+	case "discord":
+		if !cfg.Discord.Enabled || cfg.Discord.Token == "" {
+			return nil, nil
 		}
-	}
-
-	if m.config.Channels.Discord.Enabled && m.config.Channels.Discord.Token != "" {
 		logger.DebugC("channels", "Attempting to initialize Discord channel")
-		discord, err := NewDiscordChannel(m.config.Channels.Discord, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize Discord channel", map[string]interface{}{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["discord"] = discord
-			logger.InfoC("channels", "Discord channel enabled successfully")
+		var ch *DiscordChannel
+		if ch, err = NewDiscordChannel(cfg.Discord, m.bus); err == nil {
+			channel = ch
+		}
+	case "maixcam":
+		if !cfg.MaixCam.Enabled {
+			return nil, nil
 		}
-	}
-
-	if m.config.Channels.MaixCam.Enabled {
 		logger.DebugC("channels", "Attempting to initialize MaixCam channel")
-		maixcam, err := NewMaixCamChannel(m.config.Channels.MaixCam, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize MaixCam channel", map[string]interface{}{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["maixcam"] = maixcam
-			logger.InfoC("channels", "MaixCam channel enabled successfully")
+		var ch *MaixCamChannel
+		if ch, err = NewMaixCamChannel(cfg.MaixCam, m.bus); err == nil {
+			channel = ch
+		}
+	case "qq":
+		if !cfg.QQ.Enabled {
+			return nil, nil
 		}
-	}
-
-	if m.config.Channels.QQ.Enabled {
 		logger.DebugC("channels", "Attempting to initialize QQ channel")
-		qq, err := NewQQChannel(m.config.Channels.QQ, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize QQ channel", map[string]interface{}{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["qq"] = qq
-			logger.InfoC("channels", "QQ channel enabled successfully")
+		var ch *QQChannel
+		if ch, err = NewQQChannel(cfg.QQ, m.bus); err == nil {
+			channel = ch
+		}
+	case "dingtalk":
+		if !cfg.DingTalk.Enabled || cfg.DingTalk.ClientID == "" {
+			return nil, nil
 		}
-	}
-
-	if m.config.Channels.DingTalk.Enabled && m.config.Channels.DingTalk.ClientID != "" {
 		logger.DebugC("channels", "Attempting to initialize DingTalk channel")
-		dingtalk, err := NewDingTalkChannel(m.config.Channels.DingTalk, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize DingTalk channel", map[string]interface{}{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["dingtalk"] = dingtalk
-			logger.InfoC("channels", "DingTalk channel enabled successfully")
+		var ch *DingTalkChannel
+		if ch, err = NewDingTalkChannel(cfg.DingTalk, m.bus); err == nil {
+			channel = ch
 		}
+	default:
+		return nil, fmt.Errorf("unknown channel %q", name)
 	}
-
-	logger.InfoCF("channels", "Channel initialization completed", map[string]interface{}{
-		"enabled_channels": len(m.channels),
-	})
-
-	return nil
+	if err != nil {
+		return nil, err
+	}
+	return channel, nil
 }
 
 func (m *Manager) StartAll(ctx context.Context) error {
@@ -154,10 +168,7 @@ func (m *Manager) StartAll(ctx context.Context) error {
 
 	logger.InfoC("channels", "Starting all channels")
 
-	dispatchCtx, cancel := context.WithCancel(ctx)
-	m.dispatchTask = &asyncTask{cancel: cancel}
-
-	go m.dispatchOutbound(dispatchCtx)
+	m.startDispatcher(ctx)
 
 	for name, channel := range m.channels {
 		logger.InfoCF("channels", "Starting channel", map[string]interface{}{
@@ -175,6 +186,90 @@ func (m *Manager) StartAll(ctx context.Context) error {
 	return nil
 }
 
+// startDispatcher starts the outbound dispatcher unless it is running.
+// Callers hold m.mu.
+func (m *Manager) startDispatcher(ctx context.Context) {
+	if m.dispatchTask != nil {
+		return
+	}
+	dispatchCtx, cancel := context.WithCancel(ctx)
+	m.dispatchTask = &asyncTask{cancel: cancel}
+	go m.dispatchOutbound(dispatchCtx)
+}
+
+// Reload switches the manager to cfg, a freshly loaded config. Channels
+// whose allow_from changed are updated in place; channels listed in
+// changes.Channels are stopped and recreated, or started/stopped when they
+// were enabled or disabled. Other channels keep running untouched.
+func (m *Manager) Reload(ctx context.Context, cfg *config.Config, changes config.Changes) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.config = cfg
+
+	restart := make(map[string]bool, len(changes.Channels))
+	for _, name := range changes.Channels {
+		restart[name] = true
+	}
+
+	for _, name := range changes.AllowFrom {
+		if restart[name] {
+			continue
+		}
+		if channel, ok := m.channels[name]; ok {
+			if setter, ok := channel.(interface{ SetAllowList([]string) }); ok {
+				setter.SetAllowList(m.config.GetChannelAllowFrom(name))
+				logger.InfoCF("channels", "Updated allow_from", map[string]interface{}{
+					"channel": name,
+				})
+			}
+		}
+	}
+
+	for _, name := range changes.Channels {
+		if old, ok := m.channels[name]; ok {
+			logger.InfoCF("channels", "Stopping channel for reload", map[string]interface{}{
+				"channel": name,
+			})
+			if err := old.Stop(ctx); err != nil {
+				logger.ErrorCF("channels", "Error stopping channel", map[string]interface{}{
+					"channel": name,
+					"error":   err.Error(),
+				})
+			}
+			delete(m.channels, name)
+		}
+
+		channel, err := m.newChannel(name)
+		if err != nil {
+			logger.ErrorCF("channels", "Failed to initialize channel", map[string]interface{}{
+				"channel": name,
+				"error":   err.Error(),
+			})
+			continue
+		}
+		if channel == nil {
+			logger.InfoCF("channels", "Channel disabled", map[string]interface{}{
+				"channel": name,
+			})
+			continue
+		}
+		if vc, ok := channel.(voiceChannel); ok && m.transcriber != nil {
+			vc.SetTranscriber(m.transcriber)
+		}
+		if err := channel.Start(ctx); err != nil {
+			logger.ErrorCF("channels", "Failed to start channel", map[string]interface{}{
+				"channel": name,
+				"error":   err.Error(),
+			})
+		}
+		m.channels[name] = channel
+		m.startDispatcher(ctx)
+		logger.InfoCF("channels", "Channel restarted", map[string]interface{}{
+			"channel": name,
+		})
+	}
+}
+
 func (m *Manager) StopAll(ctx context.Context) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -227,6 +322,10 @@ func (m *Manager) dispatchOutbound(ctx context.Context) {
 				continue
 			}
 
+			if len(msg.Attachments) > 0 && !supportsAttachments(channel) {
+				msg = withAttachmentNotice(msg)
+			}
+
 			if err := channel.Send(ctx, msg); err != nil {
 				logger.ErrorCF("channels", "Error sending message to channel", map[string]interface{}{
 					"channel": msg.Channel,
@@ -269,6 +368,24 @@ func (m *Manager) GetEnabledChannels() []string {
 	return names
 }
 
+// SetTranscriber attaches a speech-to-text backend to every channel that
+// supports voice messages and returns their names.
+func (m *Manager) SetTranscriber(t voice.Transcriber) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.transcriber = t
+	var names []string
+	for name, channel := range m.channels {
+		if vc, ok := channel.(voiceChannel); ok {
+			vc.SetTranscriber(t)
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 func (m *Manager) RegisterChannel(name string, channel Channel) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -21,6 +21,7 @@ import (
 	tu "github.com/mymmrac/telego/telegoutil"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/commands"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/utils"
 	"github.com/sipeed/picoclaw/pkg/voice"
@@ -33,7 +34,7 @@ type TelegramChannel struct {
 	chatIDs       sync.Map // string -> int64
 	updates       <-chan telego.Update
 	cancelPolling context.CancelFunc
-	transcriber   *voice.GroqTranscriber
+	transcriber   voice.Transcriber
 	placeholders  sync.Map // chatID -> messageID
 	tempAllows    sync.Map // "chatID:username" -> time.Time (expiry)
 	botUsername   string
@@ -56,7 +57,7 @@ func NewTelegramChannel(cfg config.TelegramConfig, bus *bus.MessageBus) (*Telegr
 	}, nil
 }
 
-func (c *TelegramChannel) SetTranscriber(transcriber *voice.GroqTranscriber) {
+func (c *TelegramChannel) SetTranscriber(transcriber voice.Transcriber) {
 	c.transcriber = transcriber
 }
 
@@ -73,6 +74,8 @@ func (c *TelegramChannel) Start(ctx context.Context) error {
 	c.botID = botInfo.ID
 	log.Printf("Telegram bot @%s connected", botInfo.Username)
 
+	c.registerCommands(ctx)
+
 	pollCtx, cancel := context.WithCancel(ctx)
 	c.cancelPolling = cancel
 
@@ -107,6 +110,18 @@ func (c *TelegramChannel) Stop(ctx context.Context) error {
 	return nil
 }
 
+// registerCommands publishes the built-in slash commands to Telegram's
+// command menu.
+func (c *TelegramChannel) registerCommands(ctx context.Context) {
+	cmds := make([]telego.BotCommand, 0, len(commands.Builtin))
+	for _, cmd := range commands.Builtin {
+		cmds = append(cmds, telego.BotCommand{Command: cmd.Name, Description: cmd.Description})
+	}
+	if err := c.bot.SetMyCommands(ctx, &telego.SetMyCommandsParams{Commands: cmds}); err != nil {
+		log.Printf("Failed to register Telegram commands: %v", err)
+	}
+}
+
 // sendWithRetry retries a Telegram API call on rate limit (429) errors.
 func (c *TelegramChannel) sendWithRetry(fn func() error) error {
 	const maxRetries = 3
@@ -149,6 +164,31 @@ func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 		return nil
 	}
 
+	// Voice replies: send the audio, then the text unless audio_only is set
+	if msg.Metadata["type"] == "audio" && msg.Metadata["audio_path"] != "" {
+		if err := c.sendVoice(ctx, chatID, msg.Metadata["audio_path"]); err != nil {
+			log.Printf("Failed to send voice reply: %v", err)
+		} else if msg.Metadata["audio_only"] == "true" {
+			c.deletePlaceholder(ctx, chatID, msg.ChatID)
+			return nil
+		}
+	}
+
+	if len(msg.Attachments) > 0 {
+		if strings.TrimSpace(msg.Content) == "" {
+			c.deletePlaceholder(ctx, chatID, msg.ChatID)
+		} else if err := c.sendText(ctx, chatID, msg); err != nil {
+			return err
+		}
+		return c.sendAttachments(ctx, chatID, msg.Attachments)
+	}
+
+	return c.sendText(ctx, chatID, msg)
+}
+
+// sendText sends msg.Content, editing the "Thinking..." placeholder when
+// the reply fits in one message.
+func (c *TelegramChannel) sendText(ctx context.Context, chatID int64, msg bus.OutboundMessage) error {
 	htmlContent := markdownToTelegramHTML(msg.Content)
 
 	// Try to edit placeholder (only if message fits in one chunk)
@@ -259,21 +299,11 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, update telego.Updat
 		if voicePath != "" {
 			mediaPaths = append(mediaPaths, voicePath)
 
-			transcribedText := ""
-			if c.transcriber != nil && c.transcriber.IsAvailable() {
-				tctx, cancel := context.WithTimeout(ctx, 30*time.Second)
-				result, err := c.transcriber.Transcribe(tctx, voicePath)
-				cancel()
-				if err != nil {
-					log.Printf("Voice transcription failed: %v", err)
-					transcribedText = fmt.Sprintf("[voice: %s (transcription failed)]", voicePath)
-				} else {
-					transcribedText = fmt.Sprintf("[voice transcription: %s]", result.Text)
-					log.Printf("Voice transcribed successfully: %s", result.Text)
-				}
-			} else {
-				transcribedText = fmt.Sprintf("[voice: %s]", voicePath)
+			lang := ""
+			if message.From != nil {
+				lang = message.From.LanguageCode
 			}
+			transcribedText := transcribeVoice(ctx, c.transcriber, voicePath, "voice", lang)
 
 			if content != "" {
 				content += "\n"
@@ -398,6 +428,14 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, update telego.Updat
 					break
 				}
 			}
+			// "/command@botname" addresses this bot directly
+			if e.Type == "bot_command" {
+				cmd := extractEntityText(message.Text, e.Offset, e.Length)
+				if _, name, ok := strings.Cut(cmd, "@"); ok && strings.EqualFold(name, c.botUsername) {
+					mentioned = true
+					break
+				}
+			}
 		}
 		isReplyToBot := message.ReplyToMessage != nil &&
 			message.ReplyToMessage.From != nil &&
@@ -542,6 +580,84 @@ func (c *TelegramChannel) downloadFromURL(ctx context.Context, url, localPath st
 	return nil
 }
 
+func (c *TelegramChannel) deletePlaceholder(ctx context.Context, chatID int64, key string) {
+	if pID, ok := c.placeholders.LoadAndDelete(key); ok {
+		c.bot.DeleteMessage(ctx, &telego.DeleteMessageParams{
+			ChatID:    tu.ID(chatID),
+			MessageID: pID.(int),
+		})
+	}
+}
+
+// SupportsAttachments implements AttachmentChannel.
+func (c *TelegramChannel) SupportsAttachments() bool {
+	return true
+}
+
+// sendAttachments uploads images with sendPhoto and everything else with
+// sendDocument. Photos Telegram rejects (too large, odd dimensions) are
+// retried as documents.
+func (c *TelegramChannel) sendAttachments(ctx context.Context, chatID int64, attachments []bus.Attachment) error {
+	for _, a := range attachments {
+		if isImageFile(a.Path) {
+			err := c.uploadFile(a, func(file telego.InputFile) error {
+				_, e := c.bot.SendPhoto(ctx, &telego.SendPhotoParams{ChatID: tu.ID(chatID), Photo: file})
+				return e
+			})
+			if err == nil {
+				continue
+			}
+			log.Printf("Failed to send %s as photo, sending as document: %v", attachmentName(a), err)
+		}
+		err := c.uploadFile(a, func(file telego.InputFile) error {
+			_, e := c.bot.SendDocument(ctx, &telego.SendDocumentParams{ChatID: tu.ID(chatID), Document: file})
+			return e
+		})
+		if err != nil {
+			return fmt.Errorf("failed to send attachment %s: %w", attachmentName(a), err)
+		}
+	}
+	return nil
+}
+
+// uploadFile opens an attachment and calls send with retries, rewinding the
+// file before each attempt.
+func (c *TelegramChannel) uploadFile(a bus.Attachment, send func(telego.InputFile) error) error {
+	f, err := os.Open(a.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	file := tu.FileFromReader(f, attachmentName(a))
+	return c.sendWithRetry(func() error {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		return send(file)
+	})
+}
+
+// sendVoice uploads an audio file as a Telegram voice note.
+func (c *TelegramChannel) sendVoice(ctx context.Context, chatID int64, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return c.sendWithRetry(func() error {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		_, e := c.bot.SendVoice(ctx, &telego.SendVoiceParams{
+			ChatID: tu.ID(chatID),
+			Voice:  tu.File(f),
+		})
+		return e
+	})
+}
+
 func (c *TelegramChannel) downloadFile(ctx context.Context, fileID, ext string) string {
 	file, err := c.bot.GetFile(ctx, &telego.GetFileParams{FileID: fileID})
 	if err != nil {
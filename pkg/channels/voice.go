@@ -0,0 +1,46 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/voice"
+)
+
+// voiceChannel is implemented by channels that can transcribe voice messages.
+type voiceChannel interface {
+	SetTranscriber(t voice.Transcriber)
+}
+
+// transcribeVoice transcribes a downloaded voice or audio file and returns
+// the line to add to the message content, e.g. "[voice transcription: ...]".
+// kind is "voice" or "audio"; lang is an optional language hint.
+func transcribeVoice(ctx context.Context, t voice.Transcriber, path, kind, lang string) string {
+	if t == nil || !t.IsAvailable() {
+		return fmt.Sprintf("[%s: %s]", kind, path)
+	}
+
+	text, err := transcribe(ctx, t, path, lang)
+	if err != nil {
+		return fmt.Sprintf("[%s: %s (transcription failed)]", kind, path)
+	}
+	return fmt.Sprintf("[%s transcription: %s]", kind, text)
+}
+
+// transcribe runs t on path with a timeout and logs failures.
+func transcribe(ctx context.Context, t voice.Transcriber, path, lang string) (string, error) {
+	tctx, cancel := context.WithTimeout(voice.WithLanguageHint(ctx, lang), 60*time.Second)
+	defer cancel()
+
+	result, err := t.Transcribe(tctx, path)
+	if err != nil {
+		logger.ErrorCF("voice", "Voice transcription failed", map[string]interface{}{
+			"path":  path,
+			"error": err.Error(),
+		})
+		return "", err
+	}
+	return result.Text, nil
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -13,15 +14,17 @@ import (
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/utils"
+	"github.com/sipeed/picoclaw/pkg/voice"
 )
 
 type WhatsAppChannel struct {
 	*BaseChannel
-	conn      *websocket.Conn
-	config    config.WhatsAppConfig
-	url       string
-	mu        sync.Mutex
-	connected bool
+	conn        *websocket.Conn
+	config      config.WhatsAppConfig
+	url         string
+	mu          sync.Mutex
+	connected   bool
+	transcriber voice.Transcriber
 }
 
 func NewWhatsAppChannel(cfg config.WhatsAppConfig, bus *bus.MessageBus) (*WhatsAppChannel, error) {
@@ -35,6 +38,10 @@ func NewWhatsAppChannel(cfg config.WhatsAppConfig, bus *bus.MessageBus) (*WhatsA
 	}, nil
 }
 
+func (c *WhatsAppChannel) SetTranscriber(transcriber voice.Transcriber) {
+	c.transcriber = transcriber
+}
+
 func (c *WhatsAppChannel) Start(ctx context.Context) error {
 	log.Printf("Starting WhatsApp channel connecting to %s...", c.url)
 
@@ -86,12 +93,63 @@ func (c *WhatsAppChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 		return fmt.Errorf("whatsapp connection not established")
 	}
 
-	payload := map[string]interface{}{
-		"type":    "message",
-		"to":      msg.ChatID,
-		"content": msg.Content,
+	for _, payload := range whatsAppFrames(msg) {
+		if err := c.writePayload(payload); err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+// whatsAppFrames turns msg into bridge frames: a voice reply first, as a
+// push-to-talk audio message, then the text unless the reply is audio only,
+// then one media message per attachment. The bridge reads the files.
+func whatsAppFrames(msg bus.OutboundMessage) []map[string]interface{} {
+	media := func(path, mediaType, name string) map[string]interface{} {
+		return map[string]interface{}{
+			"type":       "message",
+			"to":         msg.ChatID,
+			"content":    "",
+			"media":      path,
+			"media_type": mediaType,
+			"file_name":  name,
+		}
+	}
+
+	var frames []map[string]interface{}
+	audioOnly := false
+	if path := msg.Metadata["audio_path"]; msg.Metadata["type"] == "audio" && path != "" {
+		frame := media(path, "audio", filepath.Base(path))
+		frame["ptt"] = true
+		frames = append(frames, frame)
+		audioOnly = msg.Metadata["audio_only"] == "true"
+	}
+
+	if !audioOnly && (msg.Content != "" || len(msg.Attachments) == 0) {
+		frames = append(frames, map[string]interface{}{
+			"type":    "message",
+			"to":      msg.ChatID,
+			"content": msg.Content,
+		})
+	}
+
+	for _, a := range msg.Attachments {
+		mediaType := "document"
+		if isImageFile(a.Path) {
+			mediaType = "image"
+		}
+		frames = append(frames, media(a.Path, mediaType, attachmentName(a)))
+	}
+	return frames
+}
+
+// SupportsAttachments implements AttachmentChannel.
+func (c *WhatsAppChannel) SupportsAttachments() bool {
+	return true
+}
 
+// writePayload sends one JSON frame to the bridge. Callers hold c.mu.
+func (c *WhatsAppChannel) writePayload(payload map[string]interface{}) error {
 	data, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
@@ -100,7 +158,6 @@ func (c *WhatsAppChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 	if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
 		return fmt.Errorf("failed to send message: %w", err)
 	}
-
 	return nil
 }
 
@@ -156,6 +213,10 @@ func (c *WhatsAppChannel) handleIncomingMessage(msg map[string]interface{}) {
 		return
 	}
 
+	if !c.IsAllowed(senderID) {
+		return
+	}
+
 	chatID, ok := msg["chat"].(string)
 	if !ok {
 		chatID = senderID
@@ -184,7 +245,34 @@ func (c *WhatsAppChannel) handleIncomingMessage(msg map[string]interface{}) {
 		metadata["user_name"] = userName
 	}
 
+	// Voice notes arrive from the bridge as downloaded OGG/Opus files.
+	// Transcription is slow, so it runs off the read loop.
+	for _, path := range mediaPaths {
+		if voice.IsAudioFile(path) {
+			go c.handleVoiceMessage(senderID, chatID, content, mediaPaths, metadata)
+			return
+		}
+	}
+
 	log.Printf("WhatsApp message from %s: %s", senderID, utils.Truncate(content, 50))
 
 	c.HandleMessage(senderID, chatID, content, mediaPaths, metadata)
 }
+
+// handleVoiceMessage adds the transcriptions of the audio files in
+// mediaPaths to content and hands the message on.
+func (c *WhatsAppChannel) handleVoiceMessage(senderID, chatID, content string, mediaPaths []string, metadata map[string]string) {
+	for _, path := range mediaPaths {
+		if !voice.IsAudioFile(path) {
+			continue
+		}
+		if content != "" {
+			content += "\n"
+		}
+		content += transcribeVoice(context.Background(), c.transcriber, path, "voice", "")
+	}
+
+	log.Printf("WhatsApp voice message from %s: %s", senderID, utils.Truncate(content, 50))
+
+	c.HandleMessage(senderID, chatID, content, mediaPaths, metadata)
+}
@@ -0,0 +1,77 @@
+package channels
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/voice"
+)
+
+type countingTranscriber struct {
+	calls atomic.Int32
+}
+
+func (t *countingTranscriber) Transcribe(ctx context.Context, path string) (*voice.TranscriptionResponse, error) {
+	t.calls.Add(1)
+	return &voice.TranscriptionResponse{Text: "hello there"}, nil
+}
+
+func (t *countingTranscriber) IsAvailable() bool {
+	return true
+}
+
+func TestWhatsAppVoiceChecksAllowListFirst(t *testing.T) {
+	mb := bus.NewMessageBus()
+	c, err := NewWhatsAppChannel(config.WhatsAppConfig{AllowFrom: []string{"alice"}}, mb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := &countingTranscriber{}
+	c.SetTranscriber(tr)
+
+	voiceNote := func(from string) map[string]interface{} {
+		return map[string]interface{}{"from": from, "media": []interface{}{"/tmp/note.ogg"}}
+	}
+	c.handleIncomingMessage(voiceNote("mallory"))
+	c.handleIncomingMessage(voiceNote("alice"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	msg, ok := mb.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("no inbound message")
+	}
+	if msg.SenderID != "alice" || msg.Content != "[voice transcription: hello there]" {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+	if n := tr.calls.Load(); n != 1 {
+		t.Errorf("only the allowed sender should be transcribed, got %d calls", n)
+	}
+}
+
+func TestWhatsAppFramesVoiceReply(t *testing.T) {
+	msg := bus.OutboundMessage{
+		ChatID:   "alice",
+		Content:  "It is sunny.",
+		Metadata: map[string]string{"type": "audio", "audio_path": "/tmp/picoclaw_media/tts/reply-1.ogg"},
+	}
+	frames := whatsAppFrames(msg)
+	if len(frames) != 2 {
+		t.Fatalf("expected audio and text frames, got %v", frames)
+	}
+	if frames[0]["media"] != "/tmp/picoclaw_media/tts/reply-1.ogg" || frames[0]["media_type"] != "audio" || frames[0]["ptt"] != true {
+		t.Errorf("unexpected audio frame %v", frames[0])
+	}
+	if frames[1]["content"] != "It is sunny." {
+		t.Errorf("unexpected text frame %v", frames[1])
+	}
+
+	msg.Metadata["audio_only"] = "true"
+	if frames := whatsAppFrames(msg); len(frames) != 1 || frames[0]["media_type"] != "audio" {
+		t.Errorf("audio-only reply should send just the audio, got %v", frames)
+	}
+}
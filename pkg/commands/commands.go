@@ -0,0 +1,78 @@
+// Package commands defines the chat slash commands that are answered without
+// calling the LLM. The agent loop implements them; channels use the
+// definitions to register commands natively (Telegram menu, Discord slash
+// commands).
+package commands
+
+import "strings"
+
+// Command describes a built-in slash command.
+type Command struct {
+	Name        string // without the leading slash
+	Args        string // usage hint, e.g. "<id>"
+	Description string
+	Admin       bool // restricted to commands.admins
+}
+
+// Builtin lists the commands handled by the agent loop, in help order.
+var Builtin = []Command{
+	{Name: "help", Description: "Show available commands"},
+	{Name: "new", Description: "Summarize and start a new conversation"},
+	{Name: "agent", Args: "[id|auto]", Description: "Show or switch the agent for this chat", Admin: true},
+	{Name: "model", Args: "[name|default]", Description: "Show or switch the model for this chat"},
+	{Name: "voice", Args: "[on|off|auto|default]", Description: "Show or switch spoken replies for this chat"},
+	{Name: "memory", Args: "search <query>", Description: "Search stored memories"},
+	{Name: "forget", Args: "<key>", Description: "Delete a stored memory", Admin: true},
+	{Name: "cost", Description: "Show API usage costs", Admin: true},
+	{Name: "jobs", Description: "List scheduled jobs", Admin: true},
+	{Name: "approve", Args: "[id]", Description: "Send held content that contained credentials", Admin: true},
+	{Name: "deny", Args: "<id>", Description: "Discard held content", Admin: true},
+}
+
+// Lookup returns the built-in command with the given name.
+func Lookup(name string) (Command, bool) {
+	for _, c := range Builtin {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Command{}, false
+}
+
+// Parse splits "/name@bot args" into the lowercased command name and its
+// arguments. It reports false for text that is not a slash command.
+func Parse(text string) (name, args string, ok bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") {
+		return "", "", false
+	}
+	head, rest, _ := strings.Cut(text[1:], " ")
+	if i := strings.IndexAny(head, "\n\t"); i >= 0 {
+		rest = head[i:] + " " + rest
+		head = head[:i]
+	}
+	head, _, _ = strings.Cut(head, "@")
+	if head == "" {
+		return "", "", false
+	}
+	return strings.ToLower(head), strings.TrimSpace(rest), true
+}
+
+// IsAdmin reports whether a sender on channel matches an entry of the admin
+// list. Entries are "channel:id" or "channel:username", so an entry only
+// matches on its own channel; sender IDs may use the "id|username" form. An
+// empty list has no admins.
+func IsAdmin(admins []string, channel, senderID string) bool {
+	id, user, _ := strings.Cut(senderID, "|")
+	for _, a := range admins {
+		ch, who, ok := strings.Cut(strings.TrimSpace(a), ":")
+		who = strings.TrimPrefix(who, "@")
+		if !ok || ch != channel || who == "" {
+			continue
+		}
+		if who == senderID || who == id || (user != "" && who == user) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package commands
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text     string
+		wantName string
+		wantArgs string
+		wantOK   bool
+	}{
+		{"/help", "help", "", true},
+		{"  /Memory search  coffee beans ", "memory", "search  coffee beans", true},
+		{"/new@picobot", "new", "", true},
+		{"/forget\nold_key", "forget", "old_key", true},
+		{"hello /help", "", "", false},
+		{"/", "", "", false},
+		{"/@bot", "", "", false},
+	}
+	for _, tt := range tests {
+		name, args, ok := Parse(tt.text)
+		if name != tt.wantName || args != tt.wantArgs || ok != tt.wantOK {
+			t.Errorf("Parse(%q) = (%q, %q, %v), want (%q, %q, %v)", tt.text, name, args, ok, tt.wantName, tt.wantArgs, tt.wantOK)
+		}
+	}
+}
+
+func TestIsAdmin(t *testing.T) {
+	admins := []string{"telegram:111", "telegram:@alice", "discord:333", "bob"}
+	tests := []struct {
+		channel string
+		sender  string
+		want    bool
+	}{
+		{"telegram", "111", true},
+		{"telegram", "111|bob", true},
+		{"telegram", "222|alice", true},
+		{"discord", "333", true},
+		{"discord", "111", false},
+		{"discord", "444|alice", false},
+		{"telegram", "333", false},
+		{"telegram", "555|bob", false},
+	}
+	for _, tt := range tests {
+		if got := IsAdmin(admins, tt.channel, tt.sender); got != tt.want {
+			t.Errorf("IsAdmin(%q, %q) = %v, want %v", tt.channel, tt.sender, got, tt.want)
+		}
+	}
+	if IsAdmin(nil, "telegram", "anyone") {
+		t.Error("empty admin list should have no admins")
+	}
+}
+
+func TestBuiltinNamesAreValidTelegramCommands(t *testing.T) {
+	for _, c := range Builtin {
+		if c.Name == "" || len(c.Name) > 32 || c.Description == "" {
+			t.Errorf("invalid command definition: %+v", c)
+		}
+		for _, r := range c.Name {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_') {
+				t.Errorf("command %q has invalid character %q", c.Name, r)
+			}
+		}
+	}
+}
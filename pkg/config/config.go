@@ -27,9 +27,118 @@ type Config struct {
 	Cost      CostConfig      `json:"cost"`
 	Secrets   SecretsConfig   `json:"secrets"`
 	Security  SecurityConfig  `json:"security"`
+	Commands  CommandsConfig  `json:"commands"`
+	Voice     VoiceConfig     `json:"voice"`
+	Tracing   TracingConfig   `json:"tracing"`
+	Log       LogConfig       `json:"log"`
+	Cassette  CassetteConfig  `json:"cassette"`
+	dir       string // directory the config was loaded from
 	mu        sync.RWMutex
 }
 
+// TracingConfig records a span tree per agent turn and exports it to an
+// OTLP/HTTP collector, a JSONL file, or both.
+type TracingConfig struct {
+	Enabled     bool   `json:"enabled" env:"PICOCLAW_TRACING_ENABLED"`
+	ServiceName string `json:"service_name,omitempty"`
+	// OTLPEndpoint is the collector's base URL, e.g. "http://localhost:4318".
+	OTLPEndpoint string            `json:"otlp_endpoint,omitempty" env:"PICOCLAW_TRACING_OTLP_ENDPOINT"`
+	OTLPHeaders  map[string]string `json:"otlp_headers,omitempty"`
+	// File receives one JSON span per line; relative paths are resolved
+	// against the workspace.
+	File string `json:"file,omitempty" env:"PICOCLAW_TRACING_FILE"`
+}
+
+// CassetteConfig records LLM requests and responses to a cassette file or
+// replays them from it, for deterministic runs in CI and demos.
+type CassetteConfig struct {
+	// Mode is "off", "record" (call the LLM and save every exchange,
+	// replacing the file), "replay" (serve saved responses only; no API
+	// keys needed) or "auto" (replay what is saved, record the rest).
+	Mode string `json:"mode" env:"PICOCLAW_CASSETTE_MODE"`
+	// Path is the JSONL cassette file; relative paths are resolved against
+	// the config directory, outside the workspace.
+	Path string `json:"path" env:"PICOCLAW_CASSETTE_PATH"`
+	// Match is how strictly a request must equal a recorded one: "exact",
+	// "normal" (ignores the system prompt, tool call IDs and options) or
+	// "loose" (model and user messages only).
+	Match string `json:"match" env:"PICOCLAW_CASSETTE_MATCH"`
+}
+
+// LogConfig controls log levels and the JSON log file. Components sets
+// per-component levels, e.g. {"agent": "debug", "channels": "warn"}; a
+// level for "agent" also applies to "agent.delegate".
+type LogConfig struct {
+	Level      string            `json:"level" env:"PICOCLAW_LOG_LEVEL"`
+	Components map[string]string `json:"components,omitempty"`
+	// File receives one JSON entry per line; relative paths are resolved
+	// against the config directory, outside the workspace. Empty turns file
+	// logging off.
+	File string `json:"file" env:"PICOCLAW_LOG_FILE"`
+	// MaxSizeMB rotates the file before it grows past this size.
+	MaxSizeMB int `json:"max_size_mb"`
+	// Rotate also rotates the file "daily" or "hourly"; empty rotates by
+	// size only.
+	Rotate     string `json:"rotate"`
+	MaxBackups int    `json:"max_backups"`
+	MaxAgeDays int    `json:"max_age_days"`
+	// Caller records the source file and line of each entry.
+	Caller bool `json:"caller"`
+}
+
+// CommandsConfig controls in-chat slash commands. Admins lists the senders
+// allowed to run admin commands as "channel:id" or "channel:username"; empty
+// leaves admin commands to the local CLI.
+type CommandsConfig struct {
+	Admins []string `json:"admins,omitempty" env:"PICOCLAW_COMMANDS_ADMINS"`
+}
+
+// VoiceConfig configures speech handling for voice messages.
+type VoiceConfig struct {
+	Transcription TranscriptionConfig `json:"transcription"`
+	TTS           TTSConfig           `json:"tts"`
+}
+
+// TTSConfig turns final responses into voice replies. Mode is "off",
+// "always" or "when_voice" (reply with voice when the user sent a voice
+// message); Channels overrides it per channel and /voice per session.
+// Backend "openai" uses an OpenAI-compatible /audio/speech endpoint;
+// "command" runs a local engine, replacing {output} and {text} in Command
+// (text is passed on stdin when {text} is absent).
+type TTSConfig struct {
+	Mode           string            `json:"mode" env:"PICOCLAW_VOICE_TTS_MODE"`
+	Channels       map[string]string `json:"channels,omitempty"`
+	Backend        string            `json:"backend" env:"PICOCLAW_VOICE_TTS_BACKEND"`
+	Provider       string            `json:"provider,omitempty"`
+	APIBase        string            `json:"api_base,omitempty"`
+	APIKey         string            `json:"api_key,omitempty" env:"PICOCLAW_VOICE_TTS_API_KEY"`
+	Model          string            `json:"model,omitempty"`
+	Voice          string            `json:"voice,omitempty"`
+	Format         string            `json:"format,omitempty"` // "opus", "mp3" or "wav"
+	Speed          float64           `json:"speed,omitempty"`
+	Command        []string          `json:"command,omitempty"`
+	MaxChars       int               `json:"max_chars,omitempty"`
+	IncludeText    bool              `json:"include_text"`
+	TimeoutSeconds int               `json:"timeout_seconds,omitempty"`
+}
+
+// TranscriptionConfig selects the speech-to-text backend. Any server that
+// implements the OpenAI /audio/transcriptions API works, including local
+// faster-whisper or whisper.cpp servers. Provider names an entry in
+// providers for credentials ("" uses groq when it has a key, "none"
+// disables transcription); APIBase and APIKey override it.
+type TranscriptionConfig struct {
+	Provider       string `json:"provider" env:"PICOCLAW_VOICE_TRANSCRIPTION_PROVIDER"`
+	APIBase        string `json:"api_base,omitempty" env:"PICOCLAW_VOICE_TRANSCRIPTION_API_BASE"`
+	APIKey         string `json:"api_key,omitempty" env:"PICOCLAW_VOICE_TRANSCRIPTION_API_KEY"`
+	Model          string `json:"model,omitempty" env:"PICOCLAW_VOICE_TRANSCRIPTION_MODEL"`
+	Language       string `json:"language,omitempty" env:"PICOCLAW_VOICE_TRANSCRIPTION_LANGUAGE"`
+	Prompt         string `json:"prompt,omitempty"`
+	ConvertTo      string `json:"convert_to,omitempty"` // "wav" or "mp3"; converts OGG/Opus input with ffmpeg
+	FFmpegPath     string `json:"ffmpeg_path,omitempty"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
+}
+
 type SecurityConfig struct {
 	PromptGuard      PromptGuardConfig      `json:"prompt_guard"`
 	LeakDetector     LeakDetectorConfig     `json:"leak_detector"`
@@ -40,11 +149,43 @@ type PromptGuardConfig struct {
 	Enabled     bool    `json:"enabled" env:"PICOCLAW_SECURITY_PROMPT_GUARD_ENABLED"`
 	Action      string  `json:"action" env:"PICOCLAW_SECURITY_PROMPT_GUARD_ACTION"`
 	Sensitivity float64 `json:"sensitivity" env:"PICOCLAW_SECURITY_PROMPT_GUARD_SENSITIVITY"`
+	// RulesFile adds custom rules, allowlisted phrases and disabled
+	// built-in rules; relative paths are resolved against the workspace.
+	RulesFile string `json:"rules_file,omitempty" env:"PICOCLAW_SECURITY_PROMPT_GUARD_RULES_FILE"`
+	// Languages limits localized rules (e.g. "zh", "vi"); empty enables all.
+	Languages []string `json:"languages,omitempty"`
+	// Actions overrides Action per "group", "dm", channel name, or
+	// "channel:group" / "channel:dm"; the most specific key wins.
+	Actions map[string]string `json:"actions,omitempty"`
+	// ToolResultAction is "log", "sanitize" (strip matched text) or
+	// "quarantine" (withhold the result and save it for review).
+	ToolResultAction string                      `json:"tool_result_action" env:"PICOCLAW_SECURITY_PROMPT_GUARD_TOOL_RESULT_ACTION"`
+	Classifier       PromptGuardClassifierConfig `json:"classifier"`
+}
+
+// PromptGuardClassifierConfig sends content whose pattern score falls in
+// [MinScore, MaxScore) to an LLM, whose verdict replaces the pattern one.
+// Model defaults to the default agent's model.
+type PromptGuardClassifierConfig struct {
+	Enabled  bool    `json:"enabled" env:"PICOCLAW_SECURITY_PROMPT_GUARD_CLASSIFIER_ENABLED"`
+	Model    string  `json:"model,omitempty"`
+	MinScore float64 `json:"min_score"`
+	MaxScore float64 `json:"max_score"`
 }
 
+// LeakDetectorConfig scans everything that leaves the agent: replies,
+// delegate results, cron deliveries and the arguments of ScanTools.
 type LeakDetectorConfig struct {
 	Enabled     bool    `json:"enabled" env:"PICOCLAW_SECURITY_LEAK_DETECTOR_ENABLED"`
 	Sensitivity float64 `json:"sensitivity" env:"PICOCLAW_SECURITY_LEAK_DETECTOR_SENSITIVITY"`
+	// Action is "redact", "block" or "approve" (hold until an admin runs
+	// /approve). Delegate results are blocked instead of held.
+	Action string `json:"action" env:"PICOCLAW_SECURITY_LEAK_DETECTOR_ACTION"`
+	// ScanTools lists the tools whose arguments are scanned before they run.
+	ScanTools []string `json:"scan_tools"`
+	// Secrets are extra values redacted wherever they appear. Secrets from
+	// this config (API keys, tokens) are always included.
+	Secrets []string `json:"secrets,omitempty"`
 }
 
 type PromptLeakGuardConfig struct {
@@ -64,6 +205,31 @@ type CostConfig struct {
 	MonthlyLimitUSD float64                    `json:"monthly_limit_usd" env:"PICOCLAW_COST_MONTHLY_LIMIT_USD"`
 	WarnAtPercent  float64                     `json:"warn_at_percent" env:"PICOCLAW_COST_WARN_AT_PERCENT"`
 	Prices         map[string]ModelPriceConfig `json:"prices"`
+	Quotas         QuotaConfig                 `json:"quotas"`
+}
+
+// QuotaConfig limits usage per owner (the identity resolveOwner derives,
+// falling back to the sender ID) and per chat. Zero fields inherit from the
+// next broader level; negative values mean unlimited.
+type QuotaConfig struct {
+	Enabled   bool                          `json:"enabled" env:"PICOCLAW_COST_QUOTAS_ENABLED"`
+	Owner     QuotaLimits                   `json:"owner"`
+	Chat      QuotaLimits                   `json:"chat"`
+	Channels  map[string]ChannelQuotaConfig `json:"channels,omitempty"`
+	AllowFrom map[string]QuotaLimits        `json:"allow_from,omitempty"` // keyed by allow_from user ID or username
+}
+
+// ChannelQuotaConfig overrides owner and chat quotas for one channel.
+type ChannelQuotaConfig struct {
+	Owner QuotaLimits `json:"owner"`
+	Chat  QuotaLimits `json:"chat"`
+}
+
+// QuotaLimits caps message rate, daily tokens and monthly spend.
+type QuotaLimits struct {
+	MessagesPerMinute int     `json:"messages_per_minute,omitempty"`
+	TokensPerDay      int     `json:"tokens_per_day,omitempty"`
+	USDPerMonth       float64 `json:"usd_per_month,omitempty"`
 }
 
 type MemoryRetentionConfig struct {
@@ -81,14 +247,66 @@ type MemoryConfig struct {
 }
 
 type HeartbeatConfig struct {
-	Enabled         bool   `json:"enabled" env:"PICOCLAW_HEARTBEAT_ENABLED"`
-	IntervalSeconds int    `json:"interval_seconds" env:"PICOCLAW_HEARTBEAT_INTERVAL_SECONDS"`
-	Channel         string `json:"channel" env:"PICOCLAW_HEARTBEAT_CHANNEL"`
+	Enabled            bool                  `json:"enabled" env:"PICOCLAW_HEARTBEAT_ENABLED"`
+	IntervalSeconds    int                   `json:"interval_seconds" env:"PICOCLAW_HEARTBEAT_INTERVAL_SECONDS"`
+	Channel            string                `json:"channel" env:"PICOCLAW_HEARTBEAT_CHANNEL"`
+	DedupWindowMinutes int                   `json:"dedup_window_minutes" env:"PICOCLAW_HEARTBEAT_DEDUP_WINDOW_MINUTES"`
+	Items              []HeartbeatItemConfig `json:"items,omitempty"`
+}
+
+// HeartbeatItemConfig is one entry of the heartbeat checklist. Items without
+// targets deliver to the heartbeat channel's first allow_from entry.
+type HeartbeatItemConfig struct {
+	Name            string                 `json:"name"`
+	Prompt          string                 `json:"prompt,omitempty"`
+	IntervalSeconds int                    `json:"interval_seconds,omitempty"`
+	Agent           string                 `json:"agent,omitempty"`
+	Targets         []string               `json:"targets,omitempty"` // "channel:chat_id"
+	Checks          []HeartbeatCheckConfig `json:"checks,omitempty"`
+	OnlyOnFailure   bool                   `json:"only_on_failure,omitempty"`
+}
+
+// HeartbeatCheckConfig is a probe run before the agent sees a heartbeat item.
+type HeartbeatCheckConfig struct {
+	Type           string  `json:"type"`                       // "disk" or "http"
+	Path           string  `json:"path,omitempty"`             // disk: mount point to check
+	MaxUsedPercent float64 `json:"max_used_percent,omitempty"` // disk: fail above this usage
+	URL            string  `json:"url,omitempty"`              // http: URL to probe
+	ExpectStatus   int     `json:"expect_status,omitempty"`    // http: required status code
+	TimeoutSeconds int     `json:"timeout_seconds,omitempty"`
 }
 
 type AgentsConfig struct {
 	Defaults AgentDefaults `json:"defaults"`
 	List     []AgentConfig `json:"list,omitempty"`
+	Routing  RoutingConfig `json:"routing,omitempty"`
+}
+
+// RoutingConfig selects an agent per inbound message. Rules are evaluated
+// in order and the first match wins; the classifier is only consulted when
+// no rule and no allow_from ":agent" suffix applies.
+type RoutingConfig struct {
+	Rules      []RoutingRule           `json:"rules,omitempty"`
+	Classifier RoutingClassifierConfig `json:"classifier"`
+}
+
+// RoutingRule matches inbound messages to an agent. Empty fields match
+// anything; all non-empty fields must match.
+type RoutingRule struct {
+	Agent    string   `json:"agent"`
+	Channel  string   `json:"channel,omitempty"`
+	ChatIDs  []string `json:"chat_ids,omitempty"`
+	ChatType string   `json:"chat_type,omitempty"` // "group" or "dm"
+	Prefix   string   `json:"prefix,omitempty"`    // e.g. "/code", stripped from the message
+	Pattern  string   `json:"pattern,omitempty"`   // regex on the message text
+}
+
+// RoutingClassifierConfig enables an LLM fallback that picks an agent from
+// their descriptions.
+type RoutingClassifierConfig struct {
+	Enabled bool     `json:"enabled"`
+	Model   string   `json:"model,omitempty"`
+	Agents  []string `json:"agents,omitempty"`
 }
 
 type AgentConfig struct {
@@ -107,6 +325,9 @@ type AgentConfig struct {
 	Skills            []string         `json:"skills,omitempty"`
 	DeniedTools       []string         `json:"denied_tools,omitempty"`
 	Subagents         *SubagentsConfig `json:"subagents,omitempty"`
+	// HTTPAllowedHosts lets this agent's http_request tool reach internal
+	// hosts, on top of tools.http.allowed_hosts.
+	HTTPAllowedHosts []string `json:"http_allowed_hosts,omitempty"`
 }
 
 type SubagentsConfig struct {
@@ -161,10 +382,59 @@ type DiscordConfig struct {
 }
 
 type MaixCamConfig struct {
-	Enabled   bool     `json:"enabled" env:"PICOCLAW_CHANNELS_MAIXCAM_ENABLED"`
-	Host      string   `json:"host" env:"PICOCLAW_CHANNELS_MAIXCAM_HOST"`
-	Port      int      `json:"port" env:"PICOCLAW_CHANNELS_MAIXCAM_PORT"`
-	AllowFrom []string `json:"allow_from" env:"PICOCLAW_CHANNELS_MAIXCAM_ALLOW_FROM"`
+	Enabled   bool                  `json:"enabled" env:"PICOCLAW_CHANNELS_MAIXCAM_ENABLED"`
+	Host      string                `json:"host" env:"PICOCLAW_CHANNELS_MAIXCAM_HOST"`
+	Port      int                   `json:"port" env:"PICOCLAW_CHANNELS_MAIXCAM_PORT"`
+	AllowFrom []string              `json:"allow_from" env:"PICOCLAW_CHANNELS_MAIXCAM_ALLOW_FROM"`
+	Devices   []MaixCamDeviceConfig `json:"devices,omitempty"`
+	Vision    MaixCamVisionConfig   `json:"vision"`
+	// AllowUnauthenticated accepts clients without a hello when no
+	// devices are configured, as earlier versions did.
+	AllowUnauthenticated bool `json:"allow_unauthenticated,omitempty" env:"PICOCLAW_CHANNELS_MAIXCAM_ALLOW_UNAUTHENTICATED"`
+}
+
+// MaixCamVisionConfig controls how detections become events: filtering by
+// score and zone, per-class debouncing, grouping bursts, and whether an
+// event is sent as a plain notification or handed to the agent.
+type MaixCamVisionConfig struct {
+	MinScore        float64                       `json:"min_score"`
+	DebounceSeconds int                           `json:"debounce_seconds"`
+	BurstSeconds    float64                       `json:"burst_seconds"`
+	MaxSnapshots    int                           `json:"max_snapshots"`
+	Policy          string                        `json:"policy"`                   // "agent" or "notify"
+	NotifyTargets   []string                      `json:"notify_targets,omitempty"` // "channel:chat_id"
+	Classes         map[string]MaixCamClassConfig `json:"classes,omitempty"`
+	Zones           []MaixCamZoneConfig           `json:"zones,omitempty"`
+}
+
+// MaixCamClassConfig overrides vision settings for one detection class.
+// Zero values inherit from MaixCamVisionConfig.
+type MaixCamClassConfig struct {
+	MinScore        float64 `json:"min_score,omitempty"`
+	DebounceSeconds int     `json:"debounce_seconds,omitempty"`
+	Policy          string  `json:"policy,omitempty"`
+	Ignore          bool    `json:"ignore,omitempty"`
+}
+
+// MaixCamZoneConfig is a rectangle in frame pixels. A detection is in the
+// zone when the center of its box is inside. If any include zones exist,
+// detections must fall in one of them; exclude zones always drop.
+type MaixCamZoneConfig struct {
+	Name    string   `json:"name"`
+	X       float64  `json:"x"`
+	Y       float64  `json:"y"`
+	W       float64  `json:"w"`
+	H       float64  `json:"h"`
+	Classes []string `json:"classes,omitempty"` // empty applies to all classes
+	Exclude bool     `json:"exclude,omitempty"`
+}
+
+// MaixCamDeviceConfig registers a camera. When any devices are configured,
+// clients must complete a hello handshake with a matching ID and token.
+type MaixCamDeviceConfig struct {
+	ID    string `json:"id"`
+	Token string `json:"token"`
+	Name  string `json:"name,omitempty"`
 }
 
 type QQConfig struct {
@@ -272,6 +542,8 @@ func (c *Config) ProviderNames() []string {
 type GatewayConfig struct {
 	Host string `json:"host" env:"PICOCLAW_GATEWAY_HOST"`
 	Port int    `json:"port" env:"PICOCLAW_GATEWAY_PORT"`
+	// Metrics serves Prometheus metrics at /metrics on the gateway port.
+	Metrics bool `json:"metrics" env:"PICOCLAW_GATEWAY_METRICS"`
 }
 
 type WebSearchConfig struct {
@@ -284,13 +556,54 @@ type OllamaConfig struct {
 	MaxResults int    `json:"max_results" env:"PICOCLAW_TOOLS_WEB_OLLAMA_MAX_RESULTS"`
 }
 
+// WebCacheConfig controls the on-disk response cache used by web_search and
+// web_fetch. Entries are stored under <workspace>/cache/web.
+type WebCacheConfig struct {
+	Enabled    bool `json:"enabled" env:"PICOCLAW_TOOLS_WEB_CACHE_ENABLED"`
+	TTLMinutes int  `json:"ttl_minutes" env:"PICOCLAW_TOOLS_WEB_CACHE_TTL_MINUTES"`
+	MaxSizeMB  int  `json:"max_size_mb" env:"PICOCLAW_TOOLS_WEB_CACHE_MAX_SIZE_MB"`
+}
+
 type WebToolsConfig struct {
 	Search WebSearchConfig `json:"search"`
 	Ollama OllamaConfig    `json:"ollama"`
+	Cache  WebCacheConfig  `json:"cache"`
+	// SearchBackends orders the web_search backends ("brave", "ollama",
+	// "duckduckgo"). Empty means every backend with credentials, then
+	// DuckDuckGo.
+	SearchBackends []string `json:"search_backends,omitempty"`
+	// SearchStrategy is "fallback" (stop once enough results arrive) or
+	// "fusion" (query every backend and merge rankings).
+	SearchStrategy string `json:"search_strategy" env:"PICOCLAW_TOOLS_WEB_SEARCH_STRATEGY"`
+}
+
+// HTTPCredential is a named secret the http_request tool can apply without
+// the value ever reaching the model. Type is "bearer" (Token), "basic"
+// (Username/Password) or "header" (Header/Value). Hosts, when set, limits
+// which hosts the credential may be sent to.
+type HTTPCredential struct {
+	Type     string   `json:"type"`
+	Token    string   `json:"token,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	Header   string   `json:"header,omitempty"`
+	Value    string   `json:"value,omitempty"`
+	Hosts    []string `json:"hosts,omitempty"`
+}
+
+type HTTPToolConfig struct {
+	Enabled        bool `json:"enabled" env:"PICOCLAW_TOOLS_HTTP_ENABLED"`
+	TimeoutSeconds int  `json:"timeout_seconds" env:"PICOCLAW_TOOLS_HTTP_TIMEOUT_SECONDS"`
+	MaxChars       int  `json:"max_chars" env:"PICOCLAW_TOOLS_HTTP_MAX_CHARS"`
+	// AllowedHosts are private/internal hosts every agent may reach:
+	// host names, "*.suffix" wildcards, IPs or CIDR ranges.
+	AllowedHosts []string                   `json:"allowed_hosts,omitempty"`
+	Credentials  map[string]*HTTPCredential `json:"credentials,omitempty"`
 }
 
 type ToolsConfig struct {
 	Web                WebToolsConfig `json:"web"`
+	HTTP               HTTPToolConfig `json:"http"`
 	RestrictToWorkspace *bool         `json:"restrict_to_workspace" env:"PICOCLAW_TOOLS_RESTRICT_TO_WORKSPACE"`
 }
 
@@ -334,6 +647,12 @@ func DefaultConfig() *Config {
 				Host:      "0.0.0.0",
 				Port:      18790,
 				AllowFrom: []string{},
+				Vision: MaixCamVisionConfig{
+					DebounceSeconds: 30,
+					BurstSeconds:    3,
+					MaxSnapshots:    3,
+					Policy:          "agent",
+				},
 			},
 			QQ: QQConfig{
 				Enabled:   false,
@@ -359,13 +678,15 @@ func DefaultConfig() *Config {
 			"nvidia":     &ProviderConfig{},
 		},
 		Gateway: GatewayConfig{
-			Host: "0.0.0.0",
-			Port: 18790,
+			Host:    "0.0.0.0",
+			Port:    18791,
+			Metrics: false,
 		},
 		Heartbeat: HeartbeatConfig{
-			Enabled:         false,
-			IntervalSeconds: 1800,
-			Channel:         "telegram",
+			Enabled:            false,
+			IntervalSeconds:    1800,
+			Channel:            "telegram",
+			DedupWindowMinutes: 360,
 		},
 		Tools: ToolsConfig{
 			Web: WebToolsConfig{
@@ -377,6 +698,17 @@ func DefaultConfig() *Config {
 					APIKey:     "",
 					MaxResults: 5,
 				},
+				Cache: WebCacheConfig{
+					Enabled:    true,
+					TTLMinutes: 60,
+					MaxSizeMB:  100,
+				},
+				SearchStrategy: "fallback",
+			},
+			HTTP: HTTPToolConfig{
+				Enabled:        false,
+				TimeoutSeconds: 30,
+				MaxChars:       50000,
 			},
 		},
 		Memory: MemoryConfig{
@@ -400,15 +732,45 @@ func DefaultConfig() *Config {
 		Secrets: SecretsConfig{
 			Encrypt: false,
 		},
+		Log: LogConfig{
+			Level:      "info",
+			File:       "logs/picoclaw.log",
+			MaxSizeMB:  10,
+			Rotate:     "daily",
+			MaxBackups: 7,
+			MaxAgeDays: 30,
+		},
+		Cassette: CassetteConfig{
+			Mode:  "off",
+			Path:  "cassettes/llm.jsonl",
+			Match: "normal",
+		},
+		Voice: VoiceConfig{
+			TTS: TTSConfig{
+				Mode:        "off",
+				Backend:     "openai",
+				Format:      "opus",
+				MaxChars:    1000,
+				IncludeText: true,
+			},
+		},
 		Security: SecurityConfig{
 			PromptGuard: PromptGuardConfig{
-				Enabled:     false,
-				Action:      "warn",
-				Sensitivity: 0.5,
+				Enabled:          false,
+				Action:           "warn",
+				Sensitivity:      0.5,
+				RulesFile:        "prompt_guard_rules.json",
+				ToolResultAction: "log",
+				Classifier: PromptGuardClassifierConfig{
+					MinScore: 0.05,
+					MaxScore: 0.5,
+				},
 			},
 			LeakDetector: LeakDetectorConfig{
 				Enabled:     false,
 				Sensitivity: 0.7,
+				Action:      "redact",
+				ScanTools:   []string{"web_fetch", "web_search", "http_request", "exec", "message"},
 			},
 			PromptLeakGuard: PromptLeakGuardConfig{
 				Enabled:   false,
@@ -432,6 +794,25 @@ func sensitiveFields(cfg *Config) []*string {
 		&cfg.Channels.DingTalk.ClientSecret,
 		&cfg.Tools.Web.Search.APIKey,
 		&cfg.Tools.Web.Ollama.APIKey,
+		&cfg.Voice.Transcription.APIKey,
+		&cfg.Voice.TTS.APIKey,
+	}
+	for i := range cfg.Channels.MaixCam.Devices {
+		fields = append(fields, &cfg.Channels.MaixCam.Devices[i].Token)
+	}
+	for i := range cfg.Security.LeakDetector.Secrets {
+		fields = append(fields, &cfg.Security.LeakDetector.Secrets[i])
+	}
+	credNames := make([]string, 0, len(cfg.Tools.HTTP.Credentials))
+	for name, cred := range cfg.Tools.HTTP.Credentials {
+		if cred != nil {
+			credNames = append(credNames, name)
+		}
+	}
+	sort.Strings(credNames)
+	for _, name := range credNames {
+		cred := cfg.Tools.HTTP.Credentials[name]
+		fields = append(fields, &cred.Token, &cred.Password, &cred.Value)
 	}
 	// Collect provider API keys in sorted order for deterministic encryption
 	names := make([]string, 0, len(cfg.Providers))
@@ -445,20 +826,68 @@ func sensitiveFields(cfg *Config) []*string {
 	return fields
 }
 
+// SecretValues returns the non-empty secret values of the config, for the
+// leak detector to redact by value.
+func (c *Config) SecretValues() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var values []string
+	for _, fp := range sensitiveFields(c) {
+		if *fp != "" && !secrets.IsEncrypted(*fp) {
+			values = append(values, *fp)
+		}
+	}
+	return values
+}
+
 func LoadConfig(path string) (*Config, error) {
+	cfg, hasPlaintext, err := loadConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fill in builtin defaults for known providers
+	mergeProviderDefaults(cfg.Providers)
+
+	// Auto-encrypt: if encrypt is enabled and any sensitive field was plaintext, save back encrypted
+	if cfg.Secrets.Encrypt && hasPlaintext {
+		if err := SaveConfig(path, cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to auto-encrypt config secrets: %v\n", err)
+		}
+	}
+
+	if err := env.Parse(cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// LoadRawConfig reads the config file without provider defaults or
+// environment overrides, so it can be edited and saved back without
+// persisting PICOCLAW_* values.
+func LoadRawConfig(path string) (*Config, error) {
+	cfg, _, err := loadConfigFile(path)
+	return cfg, err
+}
+
+// loadConfigFile reads path over the defaults and decrypts its secrets. It
+// also reports whether any secret was stored in plaintext.
+func loadConfigFile(path string) (*Config, bool, error) {
 	cfg := DefaultConfig()
+	cfg.dir = filepath.Dir(path)
 
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			fmt.Fprintf(os.Stderr, "Warning: config file not found at %s, using defaults\n", path)
-			return cfg, nil
+			return cfg, false, nil
 		}
-		return nil, err
+		return nil, false, err
 	}
 
 	if err := json.Unmarshal(data, cfg); err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	// Ensure providers map is initialized (in case JSON had no providers section)
@@ -466,9 +895,6 @@ func LoadConfig(path string) (*Config, error) {
 		cfg.Providers = make(ProvidersConfig)
 	}
 
-	// Fill in builtin defaults for known providers
-	mergeProviderDefaults(cfg.Providers)
-
 	// Check for encrypted and unencrypted sensitive fields
 	hasEncrypted := false
 	hasPlaintext := false
@@ -488,29 +914,18 @@ func LoadConfig(path string) (*Config, error) {
 		keyPath := filepath.Join(filepath.Dir(path), ".secret_key")
 		store, err := secrets.NewSecretStore(keyPath)
 		if err != nil {
-			return nil, fmt.Errorf("config: init secret store: %w", err)
+			return nil, false, fmt.Errorf("config: init secret store: %w", err)
 		}
 		for _, fp := range sensitiveFields(cfg) {
 			decrypted, err := store.Decrypt(*fp)
 			if err != nil {
-				return nil, fmt.Errorf("config: decrypt field: %w", err)
+				return nil, false, fmt.Errorf("config: decrypt field: %w", err)
 			}
 			*fp = decrypted
 		}
 	}
 
-	// Auto-encrypt: if encrypt is enabled and any sensitive field was plaintext, save back encrypted
-	if cfg.Secrets.Encrypt && hasPlaintext {
-		if err := SaveConfig(path, cfg); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to auto-encrypt config secrets: %v\n", err)
-		}
-	}
-
-	if err := env.Parse(cfg); err != nil {
-		return nil, err
-	}
-
-	return cfg, nil
+	return cfg, hasPlaintext, nil
 }
 
 func SaveConfig(path string, cfg *Config) error {
@@ -600,6 +1015,34 @@ func (c *Config) IsRestrictToWorkspace() bool {
 	return *c.Tools.RestrictToWorkspace
 }
 
+// Dir returns the directory holding the config file, ~/.picoclaw for a
+// config that was not loaded from a file. Unlike the workspace, the agent's
+// file tools cannot reach it.
+func (c *Config) Dir() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.dir == "" {
+		return expandHome("~/.picoclaw")
+	}
+	return c.dir
+}
+
+// SetDir overrides the directory returned by Dir.
+func (c *Config) SetDir(dir string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dir = dir
+}
+
+// ResolvePath expands ~ in path and makes a relative path relative to Dir.
+func (c *Config) ResolvePath(path string) string {
+	path = expandHome(path)
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(c.Dir(), path)
+}
+
 func expandHome(path string) string {
 	if path == "" {
 		return path
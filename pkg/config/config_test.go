@@ -210,6 +210,29 @@ func TestSensitiveFields_MutatesProviderKey(t *testing.T) {
 	}
 }
 
+func TestSensitiveFields_IncludesHTTPCredentials(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Tools.HTTP.Credentials = map[string]*HTTPCredential{
+		"grafana": {Type: "bearer", Token: "grafana-token"},
+		"nas":     {Type: "basic", Username: "admin", Password: "nas-pass"},
+	}
+
+	want := map[string]bool{"grafana-token": false, "nas-pass": false}
+	for _, fp := range sensitiveFields(cfg) {
+		if _, ok := want[*fp]; ok {
+			want[*fp] = true
+		}
+		if *fp == "admin" {
+			t.Error("usernames should not be treated as secrets")
+		}
+	}
+	for secret, found := range want {
+		if !found {
+			t.Errorf("sensitiveFields missing %q", secret)
+		}
+	}
+}
+
 func TestLoadConfig_MergesDefaults(t *testing.T) {
 	dir := t.TempDir()
 	cfgPath := filepath.Join(dir, "config.json")
@@ -269,6 +292,27 @@ func TestLoadConfig_CustomProviderPreserved(t *testing.T) {
 	}
 }
 
+func TestLoadRawConfig_NoEnvOrDefaults(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.json")
+	data := `{"providers":{"anthropic":{"api_key":"sk-test"}}}`
+	if err := os.WriteFile(cfgPath, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PICOCLAW_CHANNELS_TELEGRAM_TOKEN", "from-env")
+
+	cfg, err := LoadRawConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("LoadRawConfig: %v", err)
+	}
+	if cfg.Channels.Telegram.Token != "" {
+		t.Errorf("env override leaked into raw config: %q", cfg.Channels.Telegram.Token)
+	}
+	if p := cfg.Providers["anthropic"]; p == nil || p.APIKey != "sk-test" || p.APIBase != "" {
+		t.Errorf("raw anthropic provider: %+v", p)
+	}
+}
+
 func TestLoadConfig_MissingFile_ReturnsDefaults(t *testing.T) {
 	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "nonexistent.json"))
 	if err != nil {
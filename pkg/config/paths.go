@@ -0,0 +1,279 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maskedSecret replaces secret values in Values and DiffDefaults output.
+const maskedSecret = "********"
+
+// Values returns the config as generic JSON values keyed like config.json.
+// Secrets are masked unless reveal is set.
+func (c *Config) Values(reveal bool) (map[string]interface{}, error) {
+	c.mu.RLock()
+	data, err := json.Marshal(c)
+	c.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	if !reveal {
+		var clone Config
+		if err := json.Unmarshal(data, &clone); err != nil {
+			return nil, err
+		}
+		for _, fp := range sensitiveFields(&clone) {
+			if *fp != "" {
+				*fp = maskedSecret
+			}
+		}
+		if data, err = json.Marshal(&clone); err != nil {
+			return nil, err
+		}
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// splitPath turns "agents.list[0].model" (or "agents.list.0.model") into
+// its segments.
+func splitPath(path string) ([]string, error) {
+	path = strings.ReplaceAll(path, "[", ".")
+	path = strings.ReplaceAll(path, "]", "")
+	segments := strings.Split(strings.Trim(path, "."), ".")
+	for _, s := range segments {
+		if s == "" {
+			return nil, fmt.Errorf("invalid path %q", path)
+		}
+	}
+	return segments, nil
+}
+
+// LookupPath returns the value at path in values returned by Values.
+func LookupPath(values map[string]interface{}, path string) (interface{}, error) {
+	segments, err := splitPath(path)
+	if err != nil {
+		return nil, err
+	}
+	var cur interface{} = values
+	for i, seg := range segments {
+		switch node := cur.(type) {
+		case map[string]interface{}:
+			next, ok := node[seg]
+			if !ok {
+				return nil, fmt.Errorf("%s: not found", strings.Join(segments[:i+1], "."))
+			}
+			cur = next
+		case []interface{}:
+			idx, err := strconv.Atoi(seg)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil, fmt.Errorf("%s: index out of range", strings.Join(segments[:i+1], "."))
+			}
+			cur = node[idx]
+		default:
+			return nil, fmt.Errorf("%s: not an object or list", strings.Join(segments[:i], "."))
+		}
+	}
+	return cur, nil
+}
+
+// setPath stores value at path, creating missing objects along the way. An
+// index equal to a list's length appends.
+func setPath(node interface{}, segments []string, value interface{}) (interface{}, error) {
+	if len(segments) == 0 {
+		return value, nil
+	}
+	seg := segments[0]
+	switch n := node.(type) {
+	case nil:
+		child, err := setPath(nil, segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		// A numeric segment under an omitted field starts a new list
+		if idx, err := strconv.Atoi(seg); err == nil {
+			if idx != 0 {
+				return nil, fmt.Errorf("index %s out of range", seg)
+			}
+			return []interface{}{child}, nil
+		}
+		return map[string]interface{}{seg: child}, nil
+	case map[string]interface{}:
+		child, err := setPath(n[seg], segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		n[seg] = child
+		return n, nil
+	case []interface{}:
+		idx, err := strconv.Atoi(seg)
+		if err != nil || idx < 0 || idx > len(n) {
+			return nil, fmt.Errorf("index %s out of range", seg)
+		}
+		if idx == len(n) {
+			n = append(n, nil)
+		}
+		child, err := setPath(n[idx], segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		n[idx] = child
+		return n, nil
+	default:
+		return nil, fmt.Errorf("parent of %q is not an object or list", seg)
+	}
+}
+
+// SetPath sets the value at path. value is parsed as JSON when possible
+// (numbers, booleans, lists, objects) and used as a plain string otherwise.
+// Unknown paths are rejected.
+func (c *Config) SetPath(path, value string) error {
+	segments, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+	var parsed interface{}
+	isJSON := json.Unmarshal([]byte(value), &parsed) == nil
+	if !isJSON {
+		parsed = value
+	}
+
+	next, err := c.withValue(segments, parsed)
+	if err != nil && isJSON {
+		// e.g. a numeric-looking model name for a string field
+		if asString, strErr := c.withValue(segments, value); strErr == nil {
+			next, err = asString, nil
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	c.ReplaceWith(next)
+	return nil
+}
+
+// withValue returns a copy of c with value stored at segments.
+func (c *Config) withValue(segments []string, value interface{}) (*Config, error) {
+	values, err := c.Values(true)
+	if err != nil {
+		return nil, err
+	}
+	root, err := setPath(map[string]interface{}(values), segments, value)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(root)
+	if err != nil {
+		return nil, err
+	}
+	next := &Config{}
+	if err := json.Unmarshal(data, next); err != nil {
+		return nil, err
+	}
+
+	// Fields that do not exist in Config are dropped by Unmarshal
+	check, err := next.Values(true)
+	if err != nil {
+		return nil, err
+	}
+	got, err := LookupPath(check, strings.Join(segments, "."))
+	if err != nil {
+		// omitempty fields disappear when cleared
+		if isEmptyValue(value) {
+			if _, parentErr := LookupPath(check, strings.Join(segments[:len(segments)-1], ".")); parentErr == nil || len(segments) == 1 {
+				return next, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown config path")
+	}
+	if !sameJSON(got, value) {
+		return nil, fmt.Errorf("value does not fit this setting")
+	}
+	return next, nil
+}
+
+// isEmptyValue reports whether v is a JSON zero value.
+func isEmptyValue(v interface{}) bool {
+	switch x := v.(type) {
+	case nil:
+		return true
+	case string:
+		return x == ""
+	case bool:
+		return !x
+	case float64:
+		return x == 0
+	case []interface{}:
+		return len(x) == 0
+	case map[string]interface{}:
+		return len(x) == 0
+	}
+	return false
+}
+
+// PathChange is a setting that differs between two configs.
+type PathChange struct {
+	Path     string
+	Default  interface{}
+	Current  interface{}
+	Modified bool // present in both; otherwise only in one of them
+}
+
+// DiffDefaults lists the settings that differ from DefaultConfig (with
+// built-in provider defaults applied, as LoadConfig does), with secrets
+// masked.
+func (c *Config) DiffDefaults() ([]PathChange, error) {
+	base := DefaultConfig()
+	mergeProviderDefaults(base.Providers)
+	defaults, err := base.Values(false)
+	if err != nil {
+		return nil, err
+	}
+	current, err := c.Values(false)
+	if err != nil {
+		return nil, err
+	}
+	def, cur := map[string]interface{}{}, map[string]interface{}{}
+	flatten("", defaults, def)
+	flatten("", current, cur)
+
+	var changes []PathChange
+	for path, d := range def {
+		v, ok := cur[path]
+		if !ok {
+			changes = append(changes, PathChange{Path: path, Default: d})
+		} else if !sameJSON(d, v) {
+			changes = append(changes, PathChange{Path: path, Default: d, Current: v, Modified: true})
+		}
+	}
+	for path, v := range cur {
+		if _, ok := def[path]; !ok {
+			changes = append(changes, PathChange{Path: path, Current: v})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// flatten records every non-object value under its dotted path. Lists are
+// kept whole.
+func flatten(prefix string, v interface{}, out map[string]interface{}) {
+	obj, ok := v.(map[string]interface{})
+	if !ok || (len(obj) == 0 && prefix != "") {
+		out[prefix] = v
+		return
+	}
+	for k, child := range obj {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		flatten(path, child, out)
+	}
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValues_MasksSecrets(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Channels.Telegram.Token = "123:secret"
+
+	masked, err := cfg.Values(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := LookupPath(masked, "channels.telegram.token"); v != maskedSecret {
+		t.Errorf("token = %v, want masked", v)
+	}
+	revealed, _ := cfg.Values(true)
+	if v, _ := LookupPath(revealed, "channels.telegram.token"); v != "123:secret" {
+		t.Errorf("revealed token = %v", v)
+	}
+	if cfg.Channels.Telegram.Token != "123:secret" {
+		t.Error("Values modified the config")
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Agents.List = []AgentConfig{{ID: "main"}}
+
+	for path, value := range map[string]string{
+		"agents.defaults.max_tokens":  "4096",
+		"agents.list[0].model":        "gpt-4o",
+		"agents.list.0.denied_tools":  `["exec"]`,
+		"channels.telegram.enabled":   "true",
+		"providers.custom.api_base":   "http://localhost:8000/v1",
+		"agents.defaults.model":       "12345",
+		"tools.web.search_backends.0": "duckduckgo",
+	} {
+		if err := cfg.SetPath(path, value); err != nil {
+			t.Errorf("SetPath(%s): %v", path, err)
+		}
+	}
+	if cfg.Agents.Defaults.MaxTokens != 4096 || cfg.Agents.List[0].Model != "gpt-4o" ||
+		len(cfg.Agents.List[0].DeniedTools) != 1 || !cfg.Channels.Telegram.Enabled ||
+		cfg.Providers["custom"] == nil || cfg.Agents.Defaults.Model != "12345" ||
+		len(cfg.Tools.Web.SearchBackends) != 1 {
+		t.Errorf("values not applied: %+v", cfg.Agents)
+	}
+
+	for _, path := range []string{"agents.defaults.bogus", "agents.list[5].model", "gateway.port.x"} {
+		if err := cfg.SetPath(path, "1"); err == nil {
+			t.Errorf("SetPath(%s) should fail", path)
+		}
+	}
+	if err := cfg.SetPath("gateway.port", "not-a-number"); err == nil {
+		t.Error("expected type error")
+	}
+}
+
+func TestSetPath_SaveKeepsEncryption(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	cfg := DefaultConfig()
+	cfg.Secrets.Encrypt = true
+	cfg.Channels.Telegram.Token = "123:secret"
+	if err := SaveConfig(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.SetPath("channels.telegram.enabled", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveConfig(path, loaded); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "123:secret") {
+		t.Error("secret written in plaintext")
+	}
+}
+
+func TestDiffDefaults(t *testing.T) {
+	cfg := DefaultConfig()
+	mergeProviderDefaults(cfg.Providers)
+	cfg.Gateway.Port = 9000
+	cfg.Providers["openai"].APIKey = "sk-test"
+
+	changes, err := cfg.DiffDefaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]PathChange{}
+	for _, c := range changes {
+		got[c.Path] = c
+	}
+	if len(got) != 2 {
+		t.Errorf("changes = %+v", changes)
+	}
+	if c := got["gateway.port"]; !c.Modified || c.Current != float64(9000) {
+		t.Errorf("gateway.port = %+v", c)
+	}
+	if c := got["providers.openai.api_key"]; c.Current != maskedSecret {
+		t.Errorf("api_key not masked: %+v", c)
+	}
+}
@@ -0,0 +1,265 @@
+package config
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// reloadDebounce coalesces the burst of events editors produce when saving.
+const reloadDebounce = 500 * time.Millisecond
+
+// Changes describes how a reloaded config differs from the running one.
+type Changes struct {
+	// Agents is set when agent definitions, providers or tools changed;
+	// agent instances are rebuilt.
+	Agents bool
+	// Routing is set when agents.routing changed.
+	Routing  bool
+	Security bool
+	Cost     bool
+	Commands bool
+	// Log is set when log levels or the log file changed; they are applied
+	// without a restart.
+	Log bool
+	// AllowFrom lists channels whose allow_from changed.
+	AllowFrom []string
+	// Channels lists channels whose other settings changed and that must
+	// be restarted (or started/stopped when enabled was toggled).
+	Channels []string
+	// RestartRequired lists sections that only take effect after the
+	// gateway restarts.
+	RestartRequired []string
+}
+
+// Empty reports whether nothing changed.
+func (c Changes) Empty() bool {
+	return !c.Agents && !c.Routing && !c.Security && !c.Cost && !c.Commands && !c.Log &&
+		len(c.AllowFrom) == 0 && len(c.Channels) == 0 && len(c.RestartRequired) == 0
+}
+
+// Sections returns the changed sections, for logging.
+func (c Changes) Sections() []string {
+	var s []string
+	if c.Agents {
+		s = append(s, "agents")
+	}
+	if c.Routing {
+		s = append(s, "routing")
+	}
+	if c.Security {
+		s = append(s, "security")
+	}
+	if c.Cost {
+		s = append(s, "cost")
+	}
+	if c.Commands {
+		s = append(s, "commands")
+	}
+	if c.Log {
+		s = append(s, "log")
+	}
+	for _, ch := range c.AllowFrom {
+		s = append(s, "channels."+ch+".allow_from")
+	}
+	for _, ch := range c.Channels {
+		s = append(s, "channels."+ch)
+	}
+	return append(s, c.RestartRequired...)
+}
+
+// Diff compares the running config with a freshly loaded one.
+func Diff(old, cur *Config) Changes {
+	old.mu.RLock()
+	defer old.mu.RUnlock()
+	cur.mu.RLock()
+	defer cur.mu.RUnlock()
+
+	var ch Changes
+
+	oldAgents, curAgents := old.Agents, cur.Agents
+	oldAgents.Routing, curAgents.Routing = RoutingConfig{}, RoutingConfig{}
+	ch.Agents = !sameJSON(oldAgents, curAgents) || !sameJSON(old.Providers, cur.Providers) || !sameJSON(old.Tools, cur.Tools)
+	ch.Routing = !sameJSON(old.Agents.Routing, cur.Agents.Routing)
+	ch.Security = !sameJSON(old.Security, cur.Security)
+	ch.Cost = !sameJSON(old.Cost, cur.Cost)
+	ch.Commands = !sameJSON(old.Commands, cur.Commands)
+	ch.Log = !sameJSON(old.Log, cur.Log)
+
+	oldChannels, curChannels := channelSettings(old.Channels), channelSettings(cur.Channels)
+	for name, o := range oldChannels {
+		c := curChannels[name]
+		if !sameJSON(o["allow_from"], c["allow_from"]) {
+			ch.AllowFrom = append(ch.AllowFrom, name)
+		}
+		delete(o, "allow_from")
+		delete(c, "allow_from")
+		if !sameJSON(o, c) {
+			ch.Channels = append(ch.Channels, name)
+		}
+	}
+	sort.Strings(ch.AllowFrom)
+	sort.Strings(ch.Channels)
+
+	if old.Agents.Defaults.Workspace != cur.Agents.Defaults.Workspace {
+		ch.RestartRequired = append(ch.RestartRequired, "agents.defaults.workspace")
+	}
+	if old.Cost.Enabled != cur.Cost.Enabled || old.Cost.Quotas.Enabled != cur.Cost.Quotas.Enabled {
+		ch.RestartRequired = append(ch.RestartRequired, "cost.enabled")
+	}
+	for _, s := range []struct {
+		name     string
+		old, cur interface{}
+	}{
+		{"gateway", old.Gateway, cur.Gateway},
+		{"heartbeat", old.Heartbeat, cur.Heartbeat},
+		{"memory", old.Memory, cur.Memory},
+		{"secrets", old.Secrets, cur.Secrets},
+		{"voice", old.Voice, cur.Voice},
+		{"tracing", old.Tracing, cur.Tracing},
+		{"cassette", old.Cassette, cur.Cassette},
+	} {
+		if !sameJSON(s.old, s.cur) {
+			ch.RestartRequired = append(ch.RestartRequired, s.name)
+		}
+	}
+	return ch
+}
+
+// channelSettings splits ChannelsConfig into per-channel JSON objects.
+func channelSettings(c ChannelsConfig) map[string]map[string]interface{} {
+	data, _ := json.Marshal(c)
+	var raw map[string]map[string]interface{}
+	json.Unmarshal(data, &raw)
+	return raw
+}
+
+func sameJSON(a, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
+
+// ReplaceWith updates c in place with other's values. It is not safe while
+// other goroutines read c; a running gateway swaps in a new *Config instead.
+func (c *Config) ReplaceWith(other *Config) {
+	other.mu.RLock()
+	defer other.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Agents = other.Agents
+	c.Channels = other.Channels
+	c.Providers = other.Providers
+	c.Gateway = other.Gateway
+	c.Tools = other.Tools
+	c.Heartbeat = other.Heartbeat
+	c.Memory = other.Memory
+	c.Cost = other.Cost
+	c.Secrets = other.Secrets
+	c.Security = other.Security
+	c.Commands = other.Commands
+	c.Voice = other.Voice
+	c.Tracing = other.Tracing
+	c.Log = other.Log
+	c.Cassette = other.Cassette
+}
+
+// Clone returns a deep copy of c.
+func (c *Config) Clone() (*Config, error) {
+	c.mu.RLock()
+	data, err := json.Marshal(c)
+	dir := c.dir
+	c.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	clone := &Config{dir: dir}
+	if err := json.Unmarshal(data, clone); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
+
+// Watcher calls a function whenever the config file is saved.
+type Watcher struct {
+	fsw      *fsnotify.Watcher
+	path     string
+	onChange func()
+	mu       sync.Mutex
+	timer    *time.Timer
+	done     chan struct{}
+}
+
+// WatchFile watches path and calls onChange after each save, debounced.
+// The directory is watched so editors that replace the file on save are
+// handled.
+func WatchFile(path string, onChange func()) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	if err := fsw.Add(filepath.Dir(abs)); err != nil {
+		fsw.Close()
+		return nil, err
+	}
+	w := &Watcher{
+		fsw:      fsw,
+		path:     abs,
+		onChange: onChange,
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+func (w *Watcher) run() {
+	for {
+		select {
+		case <-w.done:
+			return
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(ev.Name) != w.path {
+				continue
+			}
+			if !ev.Has(fsnotify.Write) && !ev.Has(fsnotify.Create) && !ev.Has(fsnotify.Rename) {
+				continue
+			}
+			w.mu.Lock()
+			if w.timer != nil {
+				w.timer.Stop()
+			}
+			w.timer = time.AfterFunc(reloadDebounce, w.onChange)
+			w.mu.Unlock()
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			logger.WarnCF("config", "Config watcher error", map[string]interface{}{"error": err.Error()})
+		}
+	}
+}
+
+// Close stops watching.
+func (w *Watcher) Close() {
+	w.mu.Lock()
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.mu.Unlock()
+	close(w.done)
+	w.fsw.Close()
+}
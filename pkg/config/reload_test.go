@@ -0,0 +1,102 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiff_NoChanges(t *testing.T) {
+	if ch := Diff(DefaultConfig(), DefaultConfig()); !ch.Empty() {
+		t.Errorf("Diff of identical configs = %+v", ch)
+	}
+}
+
+func TestDiff_ClassifiesSections(t *testing.T) {
+	old := DefaultConfig()
+	cur := DefaultConfig()
+	cur.Agents.Routing.Rules = []RoutingRule{{Agent: "main", Prefix: "/m"}}
+	cur.Channels.Telegram.AllowFrom = []string{"alice"}
+	cur.Channels.Discord.Enabled = true
+	cur.Security.PromptGuard.Enabled = !old.Security.PromptGuard.Enabled
+	cur.Gateway.Port = old.Gateway.Port + 1
+	cur.Log.Components = map[string]string{"agent": "debug"}
+
+	ch := Diff(old, cur)
+	if ch.Agents || !ch.Routing || !ch.Security || ch.Cost || ch.Commands || !ch.Log {
+		t.Errorf("section flags = %+v", ch)
+	}
+	if !reflect.DeepEqual(ch.AllowFrom, []string{"telegram"}) {
+		t.Errorf("AllowFrom = %v", ch.AllowFrom)
+	}
+	if !reflect.DeepEqual(ch.Channels, []string{"discord"}) {
+		t.Errorf("Channels = %v", ch.Channels)
+	}
+	if !reflect.DeepEqual(ch.RestartRequired, []string{"gateway"}) {
+		t.Errorf("RestartRequired = %v", ch.RestartRequired)
+	}
+}
+
+func TestDiff_AgentsIncludesToolsAndProviders(t *testing.T) {
+	old := DefaultConfig()
+	cur := DefaultConfig()
+	cur.Tools.Web.SearchStrategy = "fusion"
+	if ch := Diff(old, cur); !ch.Agents || ch.Routing {
+		t.Errorf("tools change: %+v", ch)
+	}
+}
+
+func TestReplaceWith(t *testing.T) {
+	cfg := DefaultConfig()
+	other := DefaultConfig()
+	other.Agents.Defaults.Model = "new-model"
+	other.Channels.Telegram.AllowFrom = []string{"bob"}
+
+	cfg.ReplaceWith(other)
+	if cfg.Agents.Defaults.Model != "new-model" || len(cfg.Channels.Telegram.AllowFrom) != 1 {
+		t.Errorf("ReplaceWith did not copy values: %+v", cfg.Agents.Defaults)
+	}
+}
+
+func TestClone(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Channels.Telegram.AllowFrom = []string{"alice"}
+
+	clone, err := cfg.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone.Channels.Telegram.AllowFrom[0] = "mallory"
+	clone.Agents.Defaults.Workspace = "/tmp/elsewhere"
+	if cfg.Channels.Telegram.AllowFrom[0] != "alice" || cfg.Agents.Defaults.Workspace == "/tmp/elsewhere" {
+		t.Error("changing the clone changed the original")
+	}
+	if !sameJSON(cfg.Providers, clone.Providers) {
+		t.Error("providers not copied")
+	}
+}
+
+func TestWatchFile_CallsOnSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	changed := make(chan struct{}, 4)
+	w, err := WatchFile(path, func() { changed <- struct{}{} })
+	if err != nil {
+		t.Fatalf("WatchFile: %v", err)
+	}
+	defer w.Close()
+
+	// Unrelated files in the same directory are ignored
+	os.WriteFile(filepath.Join(filepath.Dir(path), "other.json"), []byte(`{}`), 0600)
+	os.WriteFile(path, []byte(`{"agents":{}}`), 0600)
+
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("onChange not called after save")
+	}
+}
@@ -0,0 +1,492 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// ValidationError is a problem found by Validate, located by the JSON path
+// of the offending value (e.g. "agents.list[1].subagents.allow_agents[0]").
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// knownTools lists the built-in tool names accepted in denied_tools and
+// security.leak_detector.scan_tools.
+var knownTools = []string{
+	"append_file", "cost_summary", "cron", "delegate", "edit_file", "exec",
+	"grep_files", "http_request", "list_dir", "memory_forget", "memory_search",
+	"memory_store", "message", "message_history", "read_file",
+	"session_messages", "spawn", "web_fetch", "web_search", "write_file",
+}
+
+// knownChannels lists the channel names used in routing, heartbeat and
+// notification targets.
+var knownChannels = []string{"dingtalk", "discord", "feishu", "maixcam", "qq", "telegram", "whatsapp"}
+
+type validator struct {
+	errs []ValidationError
+}
+
+func (v *validator) add(path, format string, args ...interface{}) {
+	v.errs = append(v.errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// oneOf reports value at path unless it is empty or one of allowed.
+func (v *validator) oneOf(path, value string, allowed ...string) {
+	if value == "" || contains(allowed, value) {
+		return
+	}
+	v.add(path, "must be one of %s, got %q", strings.Join(quoteAll(allowed), ", "), value)
+}
+
+func (v *validator) agentRef(path, id string, agents []string) {
+	if id != "" && !contains(agents, id) {
+		v.add(path, "unknown agent %q (known: %s)", id, strings.Join(agents, ", "))
+	}
+}
+
+// Validate checks the config for references and values that would only
+// fail at runtime. It returns every problem found, ordered by path.
+func (c *Config) Validate() []ValidationError {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	v := &validator{}
+	agents := c.validateAgents(v)
+	c.validateRouting(v, agents)
+	c.validateChannels(v, agents)
+	c.validateTools(v)
+	c.validateMisc(v, agents)
+
+	sort.SliceStable(v.errs, func(i, j int) bool { return v.errs[i].Path < v.errs[j].Path })
+	return v.errs
+}
+
+// validateAgents checks agents.defaults and agents.list and returns the
+// configured agent IDs.
+func (c *Config) validateAgents(v *validator) []string {
+	var ids []string
+	if len(c.Agents.List) == 0 {
+		ids = []string{"main"}
+	}
+	defaults := 0
+	for i, a := range c.Agents.List {
+		path := fmt.Sprintf("agents.list[%d]", i)
+		switch {
+		case a.ID == "":
+			v.add(path+".id", "is required")
+		case contains(ids, a.ID):
+			v.add(path+".id", "duplicate agent id %q", a.ID)
+		default:
+			ids = append(ids, a.ID)
+		}
+		if a.Default {
+			defaults++
+			if defaults > 1 {
+				v.add(path+".default", "only one agent can be the default")
+			}
+		}
+	}
+
+	c.validateModel(v, "agents.defaults", c.Agents.Defaults.Model, c.Agents.Defaults.Provider)
+	if c.Agents.Defaults.MaxTokens < 0 {
+		v.add("agents.defaults.max_tokens", "must not be negative")
+	}
+	if t := c.Agents.Defaults.Temperature; t < 0 || t > 2 {
+		v.add("agents.defaults.temperature", "must be between 0 and 2, got %g", t)
+	}
+
+	for i, a := range c.Agents.List {
+		path := fmt.Sprintf("agents.list[%d]", i)
+		if a.Model != "" || a.Provider != "" {
+			model := a.Model
+			if model == "" {
+				model = c.Agents.Defaults.Model
+			}
+			c.validateModel(v, path, model, a.Provider)
+		}
+		if a.Temperature != nil && (*a.Temperature < 0 || *a.Temperature > 2) {
+			v.add(path+".temperature", "must be between 0 and 2, got %g", *a.Temperature)
+		}
+		for j, tool := range a.DeniedTools {
+			if !contains(knownTools, tool) {
+				v.add(fmt.Sprintf("%s.denied_tools[%d]", path, j), "unknown tool %q", tool)
+			}
+		}
+		if a.Subagents != nil {
+			for j, id := range a.Subagents.AllowAgents {
+				p := fmt.Sprintf("%s.subagents.allow_agents[%d]", path, j)
+				if id == a.ID {
+					v.add(p, "agent cannot delegate to itself")
+					continue
+				}
+				v.agentRef(p, id, ids)
+			}
+		}
+	}
+	return ids
+}
+
+// validateModel checks that a model resolves to a provider, mirroring the
+// lookup in providers.CreateProviderForModel.
+func (c *Config) validateModel(v *validator, path, model, provider string) {
+	if provider != "" {
+		p, ok := c.Providers[strings.ToLower(provider)]
+		if !ok || p == nil {
+			v.add(path+".provider", "unknown provider %q", provider)
+		} else if p.APIBase == "" {
+			v.add(path+".provider", "provider %q has no api_base", provider)
+		}
+		return
+	}
+	if model == "" {
+		v.add(path+".model", "is required")
+		return
+	}
+	// Replayed responses need no provider
+	if c.Cassette.Mode == "replay" {
+		return
+	}
+	if c.providerForModel(model) == "" {
+		v.add(path+".model", "no configured provider matches model %q", model)
+	}
+}
+
+// providerForModel returns the provider a model resolves to, or "".
+func (c *Config) providerForModel(model string) string {
+	lower := strings.ToLower(model)
+	names := make([]string, 0, len(c.Providers))
+	for name := range c.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		p := c.Providers[name]
+		if p == nil || (p.APIKey == "" && p.APIBase == "") {
+			continue
+		}
+		for _, pattern := range p.ModelPatterns {
+			if strings.HasSuffix(pattern, "/") && strings.HasPrefix(model, pattern) {
+				return name
+			}
+		}
+	}
+	for _, name := range names {
+		p := c.Providers[name]
+		if p == nil || p.APIKey == "" {
+			continue
+		}
+		for _, pattern := range p.ModelPatterns {
+			if !strings.HasSuffix(pattern, "/") && strings.Contains(lower, strings.ToLower(pattern)) {
+				return name
+			}
+		}
+	}
+	for _, name := range names {
+		if p := c.Providers[name]; p != nil && p.Fallback && p.APIKey != "" {
+			return name
+		}
+	}
+	for _, name := range names {
+		if p := c.Providers[name]; p != nil && p.APIBase != "" && len(p.ModelPatterns) == 0 {
+			return name
+		}
+	}
+	return ""
+}
+
+func (c *Config) validateRouting(v *validator, agents []string) {
+	for i, r := range c.Agents.Routing.Rules {
+		path := fmt.Sprintf("agents.routing.rules[%d]", i)
+		if r.Agent == "" {
+			v.add(path+".agent", "is required")
+		}
+		v.agentRef(path+".agent", r.Agent, agents)
+		v.oneOf(path+".channel", r.Channel, knownChannels...)
+		v.oneOf(path+".chat_type", r.ChatType, "group", "dm")
+		if r.Pattern != "" {
+			if _, err := regexp.Compile(r.Pattern); err != nil {
+				v.add(path+".pattern", "invalid regexp: %v", err)
+			}
+		}
+	}
+	for i, id := range c.Agents.Routing.Classifier.Agents {
+		v.agentRef(fmt.Sprintf("agents.routing.classifier.agents[%d]", i), id, agents)
+	}
+}
+
+func (c *Config) validateChannels(v *validator, agents []string) {
+	ch := c.Channels
+	required := []struct {
+		path    string
+		enabled bool
+		value   string
+	}{
+		{"channels.telegram.token", ch.Telegram.Enabled, ch.Telegram.Token},
+		{"channels.discord.token", ch.Discord.Enabled, ch.Discord.Token},
+		{"channels.whatsapp.bridge_url", ch.WhatsApp.Enabled, ch.WhatsApp.BridgeURL},
+		{"channels.feishu.app_id", ch.Feishu.Enabled, ch.Feishu.AppID},
+		{"channels.qq.app_id", ch.QQ.Enabled, ch.QQ.AppID},
+		{"channels.dingtalk.client_id", ch.DingTalk.Enabled, ch.DingTalk.ClientID},
+	}
+	for _, r := range required {
+		if r.enabled && r.value == "" {
+			v.add(r.path, "is required when the channel is enabled")
+		}
+	}
+
+	v.agentRef("channels.telegram.temp_allow_agent", ch.Telegram.TempAllowAgent, agents)
+	for _, name := range knownChannels {
+		for i, entry := range c.channelAllowFrom(name) {
+			trimmed := strings.TrimPrefix(entry, "@")
+			if idx := strings.LastIndex(trimmed, ":"); idx > 0 {
+				v.agentRef(fmt.Sprintf("channels.%s.allow_from[%d]", name, i), trimmed[idx+1:], agents)
+			}
+		}
+	}
+
+	if ch.MaixCam.Enabled && len(ch.MaixCam.Devices) == 0 && !ch.MaixCam.AllowUnauthenticated {
+		v.add("channels.maixcam.devices", "are required unless allow_unauthenticated is set")
+	}
+	if ch.MaixCam.Port < 0 || ch.MaixCam.Port > 65535 {
+		v.add("channels.maixcam.port", "must be a valid port, got %d", ch.MaixCam.Port)
+	}
+	vision := ch.MaixCam.Vision
+	v.oneOf("channels.maixcam.vision.policy", vision.Policy, "agent", "notify")
+	for i, t := range vision.NotifyTargets {
+		v.target(fmt.Sprintf("channels.maixcam.vision.notify_targets[%d]", i), t)
+	}
+	for name, class := range vision.Classes {
+		v.oneOf("channels.maixcam.vision.classes."+name+".policy", class.Policy, "agent", "notify")
+	}
+}
+
+// channelAllowFrom returns a channel's allow_from without taking the lock.
+func (c *Config) channelAllowFrom(name string) []string {
+	switch name {
+	case "whatsapp":
+		return c.Channels.WhatsApp.AllowFrom
+	case "telegram":
+		return c.Channels.Telegram.AllowFrom
+	case "feishu":
+		return c.Channels.Feishu.AllowFrom
+	case "discord":
+		return c.Channels.Discord.AllowFrom
+	case "maixcam":
+		return c.Channels.MaixCam.AllowFrom
+	case "qq":
+		return c.Channels.QQ.AllowFrom
+	case "dingtalk":
+		return c.Channels.DingTalk.AllowFrom
+	}
+	return nil
+}
+
+// target checks a "channel:chat_id" delivery target.
+func (v *validator) target(path, t string) {
+	channel, chatID, ok := strings.Cut(t, ":")
+	if !ok || chatID == "" {
+		v.add(path, "must be \"channel:chat_id\", got %q", t)
+		return
+	}
+	v.oneOf(path, channel, knownChannels...)
+}
+
+func (c *Config) validateTools(v *validator) {
+	web := c.Tools.Web
+	for i, name := range web.SearchBackends {
+		v.oneOf(fmt.Sprintf("tools.web.search_backends[%d]", i), strings.ToLower(name), "brave", "ollama", "duckduckgo")
+	}
+	v.oneOf("tools.web.search_strategy", web.SearchStrategy, "fallback", "fusion")
+
+	names := make([]string, 0, len(c.Tools.HTTP.Credentials))
+	for name := range c.Tools.HTTP.Credentials {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cred := c.Tools.HTTP.Credentials[name]
+		path := "tools.http.credentials." + name
+		if cred == nil {
+			v.add(path, "must be an object")
+			continue
+		}
+		switch cred.Type {
+		case "bearer":
+			if cred.Token == "" {
+				v.add(path+".token", "is required for bearer credentials")
+			}
+		case "basic":
+			if cred.Username == "" {
+				v.add(path+".username", "is required for basic credentials")
+			}
+		case "header":
+			if cred.Header == "" {
+				v.add(path+".header", "is required for header credentials")
+			}
+		default:
+			v.add(path+".type", "must be one of \"bearer\", \"basic\", \"header\", got %q", cred.Type)
+		}
+	}
+}
+
+func (c *Config) validateMisc(v *validator, agents []string) {
+	if p := c.Gateway.Port; p <= 0 || p > 65535 {
+		v.add("gateway.port", "must be a valid port, got %d", p)
+	}
+	for i, admin := range c.Commands.Admins {
+		path := fmt.Sprintf("commands.admins[%d]", i)
+		channel, who, ok := strings.Cut(admin, ":")
+		if !ok || strings.TrimPrefix(who, "@") == "" {
+			v.add(path, "must be \"channel:id\" or \"channel:username\", got %q", admin)
+			continue
+		}
+		v.oneOf(path, channel, append([]string{"cli"}, knownChannels...)...)
+	}
+	if m := c.Channels.MaixCam; m.Enabled && m.Port == c.Gateway.Port && hostsOverlap(m.Host, c.Gateway.Host) {
+		v.add("gateway.port", "clashes with channels.maixcam (%s:%d)", m.Host, m.Port)
+	}
+
+	hb := c.Heartbeat
+	if hb.Enabled {
+		v.oneOf("heartbeat.channel", hb.Channel, knownChannels...)
+	}
+	for i, item := range hb.Items {
+		path := fmt.Sprintf("heartbeat.items[%d]", i)
+		if item.Name == "" {
+			v.add(path+".name", "is required")
+		}
+		v.agentRef(path+".agent", item.Agent, agents)
+		for j, t := range item.Targets {
+			v.target(fmt.Sprintf("%s.targets[%d]", path, j), t)
+		}
+		for j, check := range item.Checks {
+			p := fmt.Sprintf("%s.checks[%d]", path, j)
+			switch check.Type {
+			case "disk":
+			case "http":
+				if check.URL == "" {
+					v.add(p+".url", "is required for http checks")
+				}
+			default:
+				v.add(p+".type", "must be one of \"disk\", \"http\", got %q", check.Type)
+			}
+		}
+	}
+
+	sec := c.Security
+	v.oneOf("security.prompt_guard.action", sec.PromptGuard.Action, "warn", "block")
+	v.oneOf("security.prompt_leak_guard.action", sec.PromptLeakGuard.Action, "warn", "block")
+	v.oneOf("security.leak_detector.action", sec.LeakDetector.Action, "redact", "block", "approve")
+	for i, name := range sec.LeakDetector.ScanTools {
+		if !contains(knownTools, name) {
+			v.add(fmt.Sprintf("security.leak_detector.scan_tools[%d]", i), "unknown tool %q", name)
+		}
+	}
+	v.oneOf("security.prompt_guard.tool_result_action", sec.PromptGuard.ToolResultAction, "log", "sanitize", "quarantine")
+	for key, action := range sec.PromptGuard.Actions {
+		v.oneOf("security.prompt_guard.actions."+key, action, "warn", "block")
+	}
+	if cls := sec.PromptGuard.Classifier; cls.Enabled && cls.MaxScore <= cls.MinScore {
+		v.add("security.prompt_guard.classifier.max_score", "must be greater than min_score")
+	}
+	for path, s := range map[string]float64{
+		"security.prompt_guard.sensitivity":    sec.PromptGuard.Sensitivity,
+		"security.leak_detector.sensitivity":   sec.LeakDetector.Sensitivity,
+		"security.prompt_leak_guard.threshold": sec.PromptLeakGuard.Threshold,
+	} {
+		if s < 0 || s > 1 {
+			v.add(path, "must be between 0 and 1, got %g", s)
+		}
+	}
+
+	tts := c.Voice.TTS
+	v.oneOf("voice.tts.mode", tts.Mode, "off", "always", "when_voice")
+	v.oneOf("voice.tts.backend", tts.Backend, "openai", "command")
+	v.oneOf("voice.tts.format", tts.Format, "opus", "mp3", "wav")
+	for name, mode := range tts.Channels {
+		v.oneOf("voice.tts.channels."+name, mode, "off", "always", "when_voice")
+	}
+	if tts.Mode != "" && tts.Mode != "off" && tts.Backend == "command" && len(tts.Command) == 0 {
+		v.add("voice.tts.command", "is required for the command backend")
+	}
+	v.oneOf("voice.transcription.convert_to", c.Voice.Transcription.ConvertTo, "wav", "mp3")
+
+	c.validateLog(v)
+
+	cc := c.Cassette
+	v.oneOf("cassette.mode", cc.Mode, "off", "record", "replay", "auto")
+	v.oneOf("cassette.match", cc.Match, "exact", "normal", "loose")
+	if cc.Mode != "" && cc.Mode != "off" && cc.Path == "" {
+		v.add("cassette.path", "is required when the cassette is on")
+	}
+
+	if tr := c.Tracing; tr.Enabled {
+		if tr.OTLPEndpoint == "" && tr.File == "" {
+			v.add("tracing", "needs otlp_endpoint or file when enabled")
+		}
+		if tr.OTLPEndpoint != "" && !strings.HasPrefix(tr.OTLPEndpoint, "http://") && !strings.HasPrefix(tr.OTLPEndpoint, "https://") {
+			v.add("tracing.otlp_endpoint", "must be an http:// or https:// URL, got %q", tr.OTLPEndpoint)
+		}
+	}
+}
+
+func (c *Config) validateLog(v *validator) {
+	lc := c.Log
+	if lc.Level != "" {
+		if _, err := logger.ParseLevel(lc.Level); err != nil {
+			v.add("log.level", "%v", err)
+		}
+	}
+	components := make([]string, 0, len(lc.Components))
+	for name := range lc.Components {
+		components = append(components, name)
+	}
+	sort.Strings(components)
+	for _, name := range components {
+		if _, err := logger.ParseLevel(lc.Components[name]); err != nil {
+			v.add("log.components."+name, "%v", err)
+		}
+	}
+	v.oneOf("log.rotate", lc.Rotate, "daily", "hourly")
+	for path, n := range map[string]int{"log.max_size_mb": lc.MaxSizeMB, "log.max_backups": lc.MaxBackups, "log.max_age_days": lc.MaxAgeDays} {
+		if n < 0 {
+			v.add(path, "must not be negative, got %d", n)
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func quoteAll(list []string) []string {
+	quoted := make([]string, len(list))
+	for i, s := range list {
+		quoted[i] = fmt.Sprintf("%q", s)
+	}
+	return quoted
+}
+
+// hostsOverlap reports whether listeners on a and b at the same port would
+// conflict, treating empty and wildcard hosts as every address.
+func hostsOverlap(a, b string) bool {
+	wildcard := func(h string) bool { return h == "" || h == "0.0.0.0" || h == "::" }
+	return a == b || wildcard(a) || wildcard(b)
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func validConfig() *Config {
+	cfg := DefaultConfig()
+	cfg.Providers["zhipu"].APIKey = "key"
+	mergeProviderDefaults(cfg.Providers)
+	return cfg
+}
+
+func errorPaths(errs []ValidationError) map[string]string {
+	paths := make(map[string]string, len(errs))
+	for _, e := range errs {
+		paths[e.Path] = e.Message
+	}
+	return paths
+}
+
+func TestValidate_DefaultsWithProviderKey(t *testing.T) {
+	if errs := validConfig().Validate(); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestValidate_ReportsEveryProblemWithPath(t *testing.T) {
+	cfg := validConfig()
+	cfg.Agents.List = []AgentConfig{
+		{ID: "main", Default: true, DeniedTools: []string{"exce"}},
+		{ID: "coder", Model: "mystery-model", Subagents: &SubagentsConfig{AllowAgents: []string{"main", "ghost"}}},
+		{ID: "main"},
+	}
+	cfg.Agents.Routing.Rules = []RoutingRule{{Agent: "writer", ChatType: "channel", Pattern: "("}}
+	cfg.Channels.Telegram.TempAllowAgent = "guest"
+	cfg.Channels.Discord.AllowFrom = []string{"alice:nobody", "bob"}
+	cfg.Tools.HTTP.Credentials = map[string]*HTTPCredential{"gh": {Type: "oauth"}}
+	cfg.Heartbeat.Items = []HeartbeatItemConfig{{Name: "x", Targets: []string{"telegram"}}}
+	cfg.Security.LeakDetector.Action = "quarantine"
+	cfg.Security.LeakDetector.ScanTools = []string{"web_fetch", "curl"}
+	cfg.Log.Level = "verbose"
+	cfg.Log.Components = map[string]string{"agent": "debug", "channels": "loud"}
+	cfg.Log.Rotate = "weekly"
+	cfg.Cassette.Mode = "rewind"
+	cfg.Cassette.Match = "fuzzy"
+	cfg.Commands.Admins = []string{"telegram:123", "alice"}
+
+	got := errorPaths(cfg.Validate())
+	for _, path := range []string{
+		"agents.list[0].denied_tools[0]",
+		"agents.list[1].model",
+		"agents.list[1].subagents.allow_agents[1]",
+		"agents.list[2].id",
+		"agents.routing.rules[0].agent",
+		"agents.routing.rules[0].chat_type",
+		"agents.routing.rules[0].pattern",
+		"channels.telegram.temp_allow_agent",
+		"channels.discord.allow_from[0]",
+		"tools.http.credentials.gh.type",
+		"heartbeat.items[0].targets[0]",
+		"security.leak_detector.action",
+		"security.leak_detector.scan_tools[1]",
+		"log.level",
+		"log.components.channels",
+		"log.rotate",
+		"cassette.mode",
+		"cassette.match",
+		"commands.admins[1]",
+	} {
+		if _, ok := got[path]; !ok {
+			t.Errorf("missing error for %s; got %v", path, got)
+		}
+	}
+	if _, ok := got["commands.admins[0]"]; ok {
+		t.Error("valid admin entry reported")
+	}
+	if _, ok := got["log.components.agent"]; ok {
+		t.Error("valid component level reported")
+	}
+	if _, ok := got["agents.list[1].subagents.allow_agents[0]"]; ok {
+		t.Error("known agent reported as unknown")
+	}
+	if !strings.Contains(got["channels.telegram.temp_allow_agent"], `"guest"`) {
+		t.Errorf("message = %q", got["channels.telegram.temp_allow_agent"])
+	}
+}
+
+func TestValidate_CassetteReplayNeedsNoProvider(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Cassette.Mode = "replay"
+	if errs := cfg.Validate(); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	cfg.Cassette.Mode = "record"
+	if _, ok := errorPaths(cfg.Validate())["agents.defaults.model"]; !ok {
+		t.Error("record mode still needs a provider")
+	}
+}
+
+func TestValidate_ExplicitProvider(t *testing.T) {
+	cfg := validConfig()
+	cfg.Agents.List = []AgentConfig{{ID: "a", Provider: "nope"}}
+	if _, ok := errorPaths(cfg.Validate())["agents.list[0].provider"]; !ok {
+		t.Error("expected unknown provider error")
+	}
+}
+
+func TestValidate_GatewayPortClash(t *testing.T) {
+	cfg := validConfig()
+	cfg.Channels.MaixCam.Enabled = true
+	if _, ok := errorPaths(cfg.Validate())["gateway.port"]; ok {
+		t.Error("default ports should not clash")
+	}
+	cfg.Gateway.Port = cfg.Channels.MaixCam.Port
+	if _, ok := errorPaths(cfg.Validate())["gateway.port"]; !ok {
+		t.Error("expected clash with channels.maixcam")
+	}
+	cfg.Gateway.Host = "127.0.0.1"
+	cfg.Channels.MaixCam.Host = "192.168.1.2"
+	if _, ok := errorPaths(cfg.Validate())["gateway.port"]; ok {
+		t.Error("different hosts should not clash")
+	}
+}
@@ -0,0 +1,281 @@
+package cost
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// QuotaTracker enforces per-owner and per-chat limits on message rate,
+// daily tokens and monthly spend. Token and spend counters are persisted
+// so they survive restarts; the per-minute message window is in memory.
+type QuotaTracker struct {
+	cfg         config.QuotaConfig
+	storagePath string
+	prices      map[string]ModelPrice
+	mu          sync.Mutex
+	usage       map[string]*quotaUsage
+	now         func() time.Time
+}
+
+type quotaUsage struct {
+	Day        string      `json:"day"`
+	DayTokens  int         `json:"day_tokens"`
+	Month      string      `json:"month"`
+	MonthUSD   float64     `json:"month_usd"`
+	recentMsgs []time.Time // timestamps within the last minute
+}
+
+// QuotaCheck is the result of QuotaTracker.Allow.
+type QuotaCheck struct {
+	Allowed bool
+	Message string // user-facing explanation when not allowed
+}
+
+// NewQuotaTracker creates a quota tracker. Returns (nil, nil) when quotas
+// are disabled; all methods are safe to call on a nil tracker.
+func NewQuotaTracker(cfg *config.CostConfig, workspace string) (*QuotaTracker, error) {
+	if cfg == nil || !cfg.Quotas.Enabled {
+		return nil, nil
+	}
+
+	storagePath := filepath.Join(workspace, "state", "quotas.json")
+	if err := os.MkdirAll(filepath.Dir(storagePath), 0755); err != nil {
+		return nil, err
+	}
+
+	prices := make(map[string]ModelPrice, len(cfg.Prices))
+	for k, v := range cfg.Prices {
+		prices[k] = ModelPrice{Input: v.Input, Output: v.Output}
+	}
+
+	qt := &QuotaTracker{
+		cfg:         cfg.Quotas,
+		storagePath: storagePath,
+		prices:      prices,
+		usage:       make(map[string]*quotaUsage),
+		now:         time.Now,
+	}
+	qt.load()
+	return qt, nil
+}
+
+// SetConfig applies reloaded quota limits and price overrides. Counters
+// are kept.
+func (qt *QuotaTracker) SetConfig(cfg config.CostConfig) {
+	if qt == nil {
+		return
+	}
+	prices := make(map[string]ModelPrice, len(cfg.Prices))
+	for k, v := range cfg.Prices {
+		prices[k] = ModelPrice{Input: v.Input, Output: v.Output}
+	}
+
+	qt.mu.Lock()
+	defer qt.mu.Unlock()
+	qt.cfg = cfg.Quotas
+	qt.prices = prices
+}
+
+// Allow checks the owner and chat quotas for an inbound message and, when
+// allowed, counts it against the per-minute message limits.
+func (qt *QuotaTracker) Allow(channel, chatID, senderID, owner string) QuotaCheck {
+	if qt == nil {
+		return QuotaCheck{Allowed: true}
+	}
+
+	ownerLimits, chatLimits := qt.limitsFor(channel, senderID, owner)
+	now := qt.now().UTC()
+
+	qt.mu.Lock()
+	defer qt.mu.Unlock()
+
+	ownerUsage := qt.usageFor(ownerKey(senderID, owner), now)
+	chatUsage := qt.usageFor(chatKey(channel, chatID), now)
+
+	if msg := checkLimits(ownerUsage, ownerLimits, now, "You have", "your"); msg != "" {
+		return QuotaCheck{Message: msg}
+	}
+	if msg := checkLimits(chatUsage, chatLimits, now, "This chat has", "its"); msg != "" {
+		return QuotaCheck{Message: msg}
+	}
+
+	ownerUsage.recentMsgs = append(ownerUsage.recentMsgs, now)
+	chatUsage.recentMsgs = append(chatUsage.recentMsgs, now)
+	return QuotaCheck{Allowed: true}
+}
+
+// RecordUsage adds an LLM call's tokens and cost to the owner and chat
+// counters and persists them.
+func (qt *QuotaTracker) RecordUsage(channel, chatID, senderID, owner, model string, inputTokens, outputTokens int) {
+	if qt == nil {
+		return
+	}
+
+	qt.mu.Lock()
+	defer qt.mu.Unlock()
+
+	price := PriceForModel(model, qt.prices)
+	usage := NewTokenUsage(model, inputTokens, outputTokens, price.Input, price.Output)
+	now := qt.now().UTC()
+
+	for _, key := range []string{ownerKey(senderID, owner), chatKey(channel, chatID)} {
+		u := qt.usageFor(key, now)
+		u.DayTokens += usage.TotalTokens
+		u.MonthUSD += usage.CostUSD
+	}
+
+	if err := qt.save(); err != nil {
+		logger.ErrorCF("cost", "Failed to save quota state",
+			map[string]interface{}{"error": err.Error()})
+	}
+}
+
+func ownerKey(senderID, owner string) string {
+	if owner == "" {
+		owner = senderID
+	}
+	return "owner:" + owner
+}
+
+func chatKey(channel, chatID string) string {
+	return "chat:" + channel + ":" + chatID
+}
+
+// limitsFor resolves effective limits: global, then channel, then the
+// matching allow_from entry (owner limits only).
+func (qt *QuotaTracker) limitsFor(channel, senderID, owner string) (config.QuotaLimits, config.QuotaLimits) {
+	ownerLimits := qt.cfg.Owner
+	chatLimits := qt.cfg.Chat
+	if ch, ok := qt.cfg.Channels[channel]; ok {
+		ownerLimits = mergeLimits(ownerLimits, ch.Owner)
+		chatLimits = mergeLimits(chatLimits, ch.Chat)
+	}
+	entries := make([]string, 0, len(qt.cfg.AllowFrom))
+	for entry := range qt.cfg.AllowFrom {
+		entries = append(entries, entry)
+	}
+	sort.Strings(entries)
+	for _, entry := range entries {
+		if matchesSender(entry, senderID, owner) {
+			ownerLimits = mergeLimits(ownerLimits, qt.cfg.AllowFrom[entry])
+			break
+		}
+	}
+	return ownerLimits, chatLimits
+}
+
+func mergeLimits(base, override config.QuotaLimits) config.QuotaLimits {
+	if override.MessagesPerMinute != 0 {
+		base.MessagesPerMinute = override.MessagesPerMinute
+	}
+	if override.TokensPerDay != 0 {
+		base.TokensPerDay = override.TokensPerDay
+	}
+	if override.USDPerMonth != 0 {
+		base.USDPerMonth = override.USDPerMonth
+	}
+	return base
+}
+
+// matchesSender reports whether an allow_from style entry ("id", "@user",
+// "id|user") refers to the sender.
+func matchesSender(entry, senderID, owner string) bool {
+	entry = strings.TrimPrefix(strings.TrimSpace(entry), "@")
+	if entry == "" {
+		return false
+	}
+	entryID, entryUser, _ := strings.Cut(entry, "|")
+	id, user, _ := strings.Cut(senderID, "|")
+	for _, candidate := range []string{owner, senderID, id, user} {
+		if candidate != "" && (candidate == entry || candidate == entryID || candidate == entryUser) {
+			return true
+		}
+	}
+	return false
+}
+
+// usageFor returns the counters for key, resetting periods that have
+// rolled over. Caller must hold qt.mu.
+func (qt *QuotaTracker) usageFor(key string, now time.Time) *quotaUsage {
+	u, ok := qt.usage[key]
+	if !ok {
+		u = &quotaUsage{}
+		qt.usage[key] = u
+	}
+	day := now.Format("2006-01-02")
+	month := now.Format("2006-01")
+	if u.Day != day {
+		u.Day = day
+		u.DayTokens = 0
+	}
+	if u.Month != month {
+		u.Month = month
+		u.MonthUSD = 0
+	}
+
+	cutoff := now.Add(-time.Minute)
+	kept := u.recentMsgs[:0]
+	for _, t := range u.recentMsgs {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	u.recentMsgs = kept
+	return u
+}
+
+// checkLimits returns a friendly message for the first exceeded limit, or
+// "" when the usage is within limits.
+func checkLimits(u *quotaUsage, limits config.QuotaLimits, now time.Time, subject, possessive string) string {
+	if limits.MessagesPerMinute > 0 && len(u.recentMsgs) >= limits.MessagesPerMinute {
+		wait := u.recentMsgs[0].Add(time.Minute).Sub(now).Round(time.Second)
+		if wait < time.Second {
+			wait = time.Second
+		}
+		return fmt.Sprintf("%s sent a lot of messages (limit %d per minute). Please try again in %s.",
+			subject, limits.MessagesPerMinute, wait)
+	}
+	if limits.TokensPerDay > 0 && u.DayTokens >= limits.TokensPerDay {
+		return fmt.Sprintf("%s used %s daily allowance of %d tokens. It resets at midnight UTC.",
+			subject, possessive, limits.TokensPerDay)
+	}
+	if limits.USDPerMonth > 0 && u.MonthUSD >= limits.USDPerMonth {
+		return fmt.Sprintf("%s used %s monthly budget of $%.2f. It resets on the 1st.",
+			subject, possessive, limits.USDPerMonth)
+	}
+	return ""
+}
+
+func (qt *QuotaTracker) load() {
+	data, err := os.ReadFile(qt.storagePath)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &qt.usage); err != nil {
+		logger.ErrorCF("cost", "Failed to parse quota state, starting fresh",
+			map[string]interface{}{"error": err.Error()})
+		qt.usage = make(map[string]*quotaUsage)
+	}
+}
+
+// save writes the counters atomically. Caller must hold qt.mu.
+func (qt *QuotaTracker) save() error {
+	data, err := json.MarshalIndent(qt.usage, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := qt.storagePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, qt.storagePath)
+}
@@ -0,0 +1,110 @@
+package cost
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func newTestQuotaTracker(t *testing.T, workspace string, quotas config.QuotaConfig, now *time.Time) *QuotaTracker {
+	t.Helper()
+	quotas.Enabled = true
+	qt, err := NewQuotaTracker(&config.CostConfig{Quotas: quotas}, workspace)
+	if err != nil {
+		t.Fatalf("NewQuotaTracker: %v", err)
+	}
+	qt.now = func() time.Time { return *now }
+	return qt
+}
+
+func TestQuotaTrackerDisabled(t *testing.T) {
+	qt, err := NewQuotaTracker(&config.CostConfig{}, t.TempDir())
+	if err != nil || qt != nil {
+		t.Fatalf("expected nil tracker, got %v, %v", qt, err)
+	}
+	if !qt.Allow("telegram", "1", "1", "").Allowed {
+		t.Error("nil tracker should allow everything")
+	}
+	qt.RecordUsage("telegram", "1", "1", "", "gpt-4o", 10, 10)
+}
+
+func TestQuotaMessagesPerMinute(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	qt := newTestQuotaTracker(t, t.TempDir(), config.QuotaConfig{
+		Owner: config.QuotaLimits{MessagesPerMinute: 2},
+	}, &now)
+
+	for i := 0; i < 2; i++ {
+		if !qt.Allow("telegram", "1", "42|alice", "alice").Allowed {
+			t.Fatalf("message %d should be allowed", i)
+		}
+	}
+	check := qt.Allow("telegram", "1", "42|alice", "alice")
+	if check.Allowed || !strings.Contains(check.Message, "per minute") {
+		t.Fatalf("third message should be rate limited, got %+v", check)
+	}
+	if !qt.Allow("telegram", "1", "43|bob", "bob").Allowed {
+		t.Error("other owner should not be limited")
+	}
+
+	now = now.Add(61 * time.Second)
+	if !qt.Allow("telegram", "1", "42|alice", "alice").Allowed {
+		t.Error("window should have expired")
+	}
+}
+
+func TestQuotaTokensPersistAcrossRestart(t *testing.T) {
+	workspace := t.TempDir()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	quotas := config.QuotaConfig{Chat: config.QuotaLimits{TokensPerDay: 1000}}
+
+	qt := newTestQuotaTracker(t, workspace, quotas, &now)
+	qt.RecordUsage("discord", "c1", "7", "", "gpt-4o", 800, 300)
+
+	qt = newTestQuotaTracker(t, workspace, quotas, &now)
+	check := qt.Allow("discord", "c1", "8", "")
+	if check.Allowed || !strings.Contains(check.Message, "This chat") {
+		t.Fatalf("chat over daily tokens after reload, got %+v", check)
+	}
+	if !qt.Allow("discord", "c2", "8", "").Allowed {
+		t.Error("other chat should be allowed")
+	}
+
+	now = now.Add(24 * time.Hour)
+	if !qt.Allow("discord", "c1", "8", "").Allowed {
+		t.Error("daily tokens should reset the next day")
+	}
+}
+
+func TestQuotaLimitResolution(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	qt := newTestQuotaTracker(t, t.TempDir(), config.QuotaConfig{
+		Owner: config.QuotaLimits{MessagesPerMinute: 1, USDPerMonth: 5},
+		Channels: map[string]config.ChannelQuotaConfig{
+			"discord": {Owner: config.QuotaLimits{MessagesPerMinute: 3}},
+		},
+		AllowFrom: map[string]config.QuotaLimits{
+			"@alice": {MessagesPerMinute: -1},
+		},
+	}, &now)
+
+	owner, _ := qt.limitsFor("telegram", "1|carol", "carol")
+	if owner.MessagesPerMinute != 1 || owner.USDPerMonth != 5 {
+		t.Errorf("global limits: got %+v", owner)
+	}
+	owner, _ = qt.limitsFor("discord", "2", "2")
+	if owner.MessagesPerMinute != 3 || owner.USDPerMonth != 5 {
+		t.Errorf("channel override should inherit unset fields: got %+v", owner)
+	}
+	owner, _ = qt.limitsFor("telegram", "3|alice", "alice")
+	if owner.MessagesPerMinute != -1 {
+		t.Errorf("allow_from override: got %+v", owner)
+	}
+	for i := 0; i < 5; i++ {
+		if !qt.Allow("telegram", "1", "3|alice", "alice").Allowed {
+			t.Fatal("negative limit should mean unlimited")
+		}
+	}
+}
@@ -55,6 +55,22 @@ func NewCostTracker(cfg *config.CostConfig, workspace string) (*CostTracker, err
 	return ct, nil
 }
 
+// SetConfig applies reloaded limits and price overrides.
+func (ct *CostTracker) SetConfig(cfg config.CostConfig) {
+	if ct == nil {
+		return
+	}
+	overrides := make(map[string]ModelPrice, len(cfg.Prices))
+	for k, v := range cfg.Prices {
+		overrides[k] = ModelPrice{Input: v.Input, Output: v.Output}
+	}
+
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	ct.cfg = cfg
+	ct.priceOverrides = overrides
+}
+
 func newID() string {
 	b := make([]byte, 8)
 	rand.Read(b)
@@ -67,6 +83,9 @@ func (ct *CostTracker) RecordUsage(model string, inputTokens, outputTokens int)
 		return
 	}
 
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
 	price := PriceForModel(model, ct.priceOverrides)
 	usage := NewTokenUsage(model, inputTokens, outputTokens, price.Input, price.Output)
 	record := CostRecord{
@@ -74,9 +93,6 @@ func (ct *CostTracker) RecordUsage(model string, inputTokens, outputTokens int)
 		Usage: usage,
 	}
 
-	ct.mu.Lock()
-	defer ct.mu.Unlock()
-
 	// Append to JSONL file
 	if err := ct.appendRecord(record); err != nil {
 		logger.ErrorCF("cost", "Failed to write cost record",
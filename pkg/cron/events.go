@@ -0,0 +1,316 @@
+package cron
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// Event sources supported by event-triggered jobs.
+const (
+	EventSourceFile    = "file"
+	EventSourceWebhook = "webhook"
+	EventSourceMemory  = "memory"
+	EventSourceMessage = "message"
+)
+
+// maxEventPayload caps the event payload injected into the job prompt.
+const maxEventPayload = 4000
+
+// minSelfTriggerCooldownS is the shortest cooldown of memory and message
+// triggers. A job's own run can store a matching memory or send a matching
+// message, which would otherwise re-trigger it without limit.
+const minSelfTriggerCooldownS = 60
+
+// EventTrigger describes which events start a job with Kind "event".
+// Only the fields relevant to Source are used.
+type EventTrigger struct {
+	Source    string `json:"source"`
+	Path      string `json:"path,omitempty"`      // file: absolute directory to watch
+	Pattern   string `json:"pattern,omitempty"`   // file: glob on file name; memory: regex on key; message: regex on content
+	Name      string `json:"name,omitempty"`      // webhook: served at /hooks/<name>
+	Secret    string `json:"secret,omitempty"`    // webhook: required X-Webhook-Secret header
+	Category  string `json:"category,omitempty"`  // memory: category filter
+	Channel   string `json:"channel,omitempty"`   // message: channel filter
+	ChatID    string `json:"chatId,omitempty"`    // message: chat filter
+	CooldownS int    `json:"cooldownS,omitempty"` // minimum seconds between two runs
+}
+
+// Event is something that happened outside the scheduler and may start
+// event-triggered jobs.
+type Event struct {
+	Source  string
+	Attrs   map[string]string // source-specific attributes used for matching
+	Payload string            // content handed to the agent in the prompt
+}
+
+// Validate checks that the trigger has the fields its source needs.
+func (t *EventTrigger) Validate() error {
+	switch t.Source {
+	case EventSourceFile:
+		if t.Path == "" {
+			return fmt.Errorf("file trigger requires a path")
+		}
+		if t.Pattern != "" {
+			if _, err := filepath.Match(t.Pattern, ""); err != nil {
+				return fmt.Errorf("invalid file pattern: %w", err)
+			}
+		}
+	case EventSourceWebhook:
+		if t.Name == "" || strings.ContainsAny(t.Name, "/?#") {
+			return fmt.Errorf("webhook trigger requires a name without '/', '?' or '#'")
+		}
+	case EventSourceMemory:
+		if t.Pattern != "" {
+			if _, err := regexp.Compile(t.Pattern); err != nil {
+				return fmt.Errorf("invalid key pattern: %w", err)
+			}
+		}
+	case EventSourceMessage:
+		if t.Pattern == "" {
+			return fmt.Errorf("message trigger requires a pattern")
+		}
+		if _, err := regexp.Compile(t.Pattern); err != nil {
+			return fmt.Errorf("invalid message pattern: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown event source: %q", t.Source)
+	}
+	return nil
+}
+
+// String returns a short human-readable description of the trigger.
+func (t *EventTrigger) String() string {
+	switch t.Source {
+	case EventSourceFile:
+		if t.Pattern != "" {
+			return fmt.Sprintf("on file %s/%s", t.Path, t.Pattern)
+		}
+		return fmt.Sprintf("on file in %s", t.Path)
+	case EventSourceWebhook:
+		return fmt.Sprintf("on webhook /hooks/%s", t.Name)
+	case EventSourceMemory:
+		desc := "on memory store"
+		if t.Category != "" {
+			desc += " (" + t.Category + ")"
+		}
+		if t.Pattern != "" {
+			desc += " key~" + t.Pattern
+		}
+		return desc
+	case EventSourceMessage:
+		desc := "on message ~" + t.Pattern
+		if t.Channel != "" {
+			desc += " in " + t.Channel
+			if t.ChatID != "" {
+				desc += ":" + t.ChatID
+			}
+		}
+		return desc
+	default:
+		return "on unknown event"
+	}
+}
+
+// cooldown returns the minimum time between two runs of the trigger.
+func (t *EventTrigger) cooldown() time.Duration {
+	seconds := t.CooldownS
+	if (t.Source == EventSourceMemory || t.Source == EventSourceMessage) && seconds < minSelfTriggerCooldownS {
+		seconds = minSelfTriggerCooldownS
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// matches reports whether ev should trigger a job with this trigger.
+func (cs *CronService) matches(t *EventTrigger, ev Event) bool {
+	if t.Source != ev.Source {
+		return false
+	}
+
+	switch t.Source {
+	case EventSourceFile:
+		path := ev.Attrs["path"]
+		if filepath.Clean(filepath.Dir(path)) != filepath.Clean(t.Path) {
+			return false
+		}
+		if t.Pattern == "" {
+			return true
+		}
+		ok, _ := filepath.Match(t.Pattern, filepath.Base(path))
+		return ok
+	case EventSourceWebhook:
+		if ev.Attrs["name"] != t.Name {
+			return false
+		}
+		return t.Secret == "" ||
+			subtle.ConstantTimeCompare([]byte(t.Secret), []byte(ev.Attrs["secret"])) == 1
+	case EventSourceMemory:
+		if t.Category != "" && ev.Attrs["category"] != t.Category {
+			return false
+		}
+		if t.Pattern == "" {
+			return true
+		}
+		re := cs.compilePattern(t.Pattern)
+		return re != nil && re.MatchString(ev.Attrs["key"])
+	case EventSourceMessage:
+		if t.Channel != "" && ev.Attrs["channel"] != t.Channel {
+			return false
+		}
+		if t.ChatID != "" && ev.Attrs["chat_id"] != t.ChatID {
+			return false
+		}
+		re := cs.compilePattern(t.Pattern)
+		return re != nil && re.MatchString(ev.Payload)
+	}
+	return false
+}
+
+// compilePattern returns a cached compiled regex, or nil if it is invalid.
+// Caller must hold cs.mu.
+func (cs *CronService) compilePattern(pattern string) *regexp.Regexp {
+	if re, ok := cs.patterns[pattern]; ok {
+		return re
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		log.Printf("[cron] invalid event pattern '%s': %v", pattern, err)
+	}
+	cs.patterns[pattern] = re
+	return re
+}
+
+// Emit delivers an event to all enabled event-triggered jobs that match it.
+// Matching jobs run in the background; Emit returns the number started.
+func (cs *CronService) Emit(ev Event) int {
+	cs.mu.Lock()
+
+	if !cs.running {
+		cs.mu.Unlock()
+		return 0
+	}
+
+	now := time.Now().UnixMilli()
+	var triggered []*CronJob
+	for i := range cs.store.Jobs {
+		job := &cs.store.Jobs[i]
+		if !job.Enabled || job.Schedule.Kind != "event" || job.Schedule.Event == nil {
+			continue
+		}
+		trigger := job.Schedule.Event
+		if !cs.matches(trigger, ev) {
+			continue
+		}
+		if cooldown := trigger.cooldown(); cooldown > 0 && job.State.LastRunAtMS != nil &&
+			now-*job.State.LastRunAtMS < cooldown.Milliseconds() {
+			continue
+		}
+		// Claim the run now so bursts within the cooldown are dropped
+		job.State.LastRunAtMS = &now
+
+		jobCopy := *job
+		jobCopy.Payload.Message = buildEventMessage(job.Payload.Message, ev)
+		triggered = append(triggered, &jobCopy)
+	}
+	if len(triggered) > 0 {
+		// Persist the claims so a restart does not reset the cooldowns
+		if err := cs.saveStoreUnsafe(); err != nil {
+			log.Printf("[cron] failed to save store: %v", err)
+		}
+	}
+
+	cs.mu.Unlock()
+
+	for _, job := range triggered {
+		go cs.executeJob(job)
+	}
+	return len(triggered)
+}
+
+// buildEventMessage appends the event details to the job's task message.
+func buildEventMessage(message string, ev Event) string {
+	var sb strings.Builder
+	sb.WriteString(message)
+	sb.WriteString("\n\n[Event: ")
+	sb.WriteString(ev.Source)
+	for _, key := range []string{"name", "path", "op", "key", "category", "channel", "chat_id", "sender"} {
+		if v := ev.Attrs[key]; v != "" {
+			sb.WriteString(fmt.Sprintf(" %s=%s", key, v))
+		}
+	}
+	sb.WriteString("]")
+	if ev.Payload != "" {
+		sb.WriteString("\n")
+		sb.WriteString(utils.Truncate(ev.Payload, maxEventPayload))
+	}
+	return sb.String()
+}
+
+// watchedDirs returns the directories watched by enabled file-triggered jobs.
+// Caller must hold cs.mu.
+func (cs *CronService) watchedDirs() []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, job := range cs.store.Jobs {
+		if !job.Enabled || job.Schedule.Kind != "event" || job.Schedule.Event == nil {
+			continue
+		}
+		if job.Schedule.Event.Source != EventSourceFile {
+			continue
+		}
+		dir := filepath.Clean(job.Schedule.Event.Path)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// syncWatchesUnsafe updates the file watcher to match the current jobs.
+// Caller must hold cs.mu.
+func (cs *CronService) syncWatchesUnsafe() {
+	if cs.watcher == nil {
+		return
+	}
+	cs.watcher.sync(cs.watchedDirs())
+}
+
+// SetOnWebhookJob sets a function called when a webhook job is added or
+// enabled, so the HTTP server serving WebhookHandler can start on demand.
+func (cs *CronService) SetOnWebhookJob(fn func()) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.onWebhook = fn
+}
+
+// HasWebhookJobs reports whether any enabled job has a webhook trigger.
+func (cs *CronService) HasWebhookJobs() bool {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	for i := range cs.store.Jobs {
+		if isWebhookJob(&cs.store.Jobs[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWebhookJob(job *CronJob) bool {
+	return job.Enabled && job.Schedule.Kind == "event" && job.Schedule.Event != nil &&
+		job.Schedule.Event.Source == EventSourceWebhook
+}
+
+// notifyWebhookUnsafe calls the webhook hook if job is an enabled webhook
+// job. Caller must hold cs.mu.
+func (cs *CronService) notifyWebhookUnsafe(job *CronJob) {
+	if cs.onWebhook != nil && isWebhookJob(job) {
+		go cs.onWebhook()
+	}
+}
@@ -0,0 +1,292 @@
+package cron
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestService returns a running service whose jobs report their
+// payload message on the returned channel.
+func newTestService(t *testing.T) (*CronService, chan string) {
+	t.Helper()
+	ran := make(chan string, 10)
+	cs := NewCronService(filepath.Join(t.TempDir(), "jobs.json"), func(job *CronJob) (string, error) {
+		ran <- job.Payload.Message
+		return "ok", nil
+	})
+	if err := cs.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	t.Cleanup(cs.Stop)
+	return cs, ran
+}
+
+func addEventJob(t *testing.T, cs *CronService, trigger *EventTrigger) *CronJob {
+	t.Helper()
+	job, err := cs.AddJob("test", CronSchedule{Kind: "event", Event: trigger}, "handle it", false, "telegram", "42")
+	if err != nil {
+		t.Fatalf("add job: %v", err)
+	}
+	return job
+}
+
+func waitRun(t *testing.T, ran chan string) string {
+	t.Helper()
+	select {
+	case msg := <-ran:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("job did not run")
+		return ""
+	}
+}
+
+// --- Validate ---
+
+func TestEventTriggerValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		trigger EventTrigger
+		wantErr bool
+	}{
+		{"file ok", EventTrigger{Source: EventSourceFile, Path: "/tmp", Pattern: "*.csv"}, false},
+		{"file no path", EventTrigger{Source: EventSourceFile}, true},
+		{"file bad glob", EventTrigger{Source: EventSourceFile, Path: "/tmp", Pattern: "["}, true},
+		{"webhook ok", EventTrigger{Source: EventSourceWebhook, Name: "deploy"}, false},
+		{"webhook slash", EventTrigger{Source: EventSourceWebhook, Name: "a/b"}, true},
+		{"memory any", EventTrigger{Source: EventSourceMemory}, false},
+		{"message no pattern", EventTrigger{Source: EventSourceMessage}, true},
+		{"message bad regex", EventTrigger{Source: EventSourceMessage, Pattern: "("}, true},
+		{"unknown", EventTrigger{Source: "mqtt"}, true},
+	}
+	for _, tc := range cases {
+		err := tc.trigger.Validate()
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: got err=%v, wantErr=%v", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestAddJob_RejectsInvalidTrigger(t *testing.T) {
+	cs := NewCronService(filepath.Join(t.TempDir(), "jobs.json"), nil)
+	if _, err := cs.AddJob("x", CronSchedule{Kind: "event"}, "m", false, "", ""); err == nil {
+		t.Error("expected error for event schedule without trigger")
+	}
+	if _, err := cs.AddJob("x", CronSchedule{Kind: "event", Event: &EventTrigger{Source: "nope"}}, "m", false, "", ""); err == nil {
+		t.Error("expected error for unknown source")
+	}
+}
+
+func TestAddJob_EventJobsNeverDeliver(t *testing.T) {
+	cs := NewCronService(filepath.Join(t.TempDir(), "jobs.json"), nil)
+	job, err := cs.AddJob("x", CronSchedule{Kind: "event", Event: &EventTrigger{Source: EventSourceWebhook, Name: "deploy"}}, "m", true, "telegram", "42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Payload.Deliver {
+		t.Error("event job should run as an agent task, not deliver its payload directly")
+	}
+}
+
+// --- Emit ---
+
+func TestEmit_MessageTrigger(t *testing.T) {
+	cs, ran := newTestService(t)
+	addEventJob(t, cs, &EventTrigger{Source: EventSourceMessage, Pattern: `(?i)^deploy\b`, Channel: "telegram", ChatID: "42"})
+
+	if n := cs.Emit(Event{Source: EventSourceMessage, Attrs: map[string]string{"channel": "telegram", "chat_id": "7"}, Payload: "deploy now"}); n != 0 {
+		t.Errorf("other chat should not trigger, got %d", n)
+	}
+	if n := cs.Emit(Event{Source: EventSourceMessage, Attrs: map[string]string{"channel": "telegram", "chat_id": "42"}, Payload: "hello"}); n != 0 {
+		t.Errorf("non-matching text should not trigger, got %d", n)
+	}
+	if n := cs.Emit(Event{Source: EventSourceMessage, Attrs: map[string]string{"channel": "telegram", "chat_id": "42"}, Payload: "Deploy v2"}); n != 1 {
+		t.Fatalf("expected 1 triggered job, got %d", n)
+	}
+
+	msg := waitRun(t, ran)
+	if !strings.HasPrefix(msg, "handle it") {
+		t.Errorf("expected task message first, got %q", msg)
+	}
+	if !strings.Contains(msg, "[Event: message") || !strings.Contains(msg, "Deploy v2") {
+		t.Errorf("expected event details in message, got %q", msg)
+	}
+}
+
+func TestEmit_MemoryTrigger(t *testing.T) {
+	cs, ran := newTestService(t)
+	addEventJob(t, cs, &EventTrigger{Source: EventSourceMemory, Category: "core", Pattern: "^todo_"})
+
+	if n := cs.Emit(Event{Source: EventSourceMemory, Attrs: map[string]string{"key": "todo_milk", "category": "daily"}}); n != 0 {
+		t.Errorf("other category should not trigger, got %d", n)
+	}
+	if n := cs.Emit(Event{Source: EventSourceMemory, Attrs: map[string]string{"key": "todo_milk", "category": "core"}, Payload: "buy milk"}); n != 1 {
+		t.Fatalf("expected 1 triggered job, got %d", n)
+	}
+	if msg := waitRun(t, ran); !strings.Contains(msg, "key=todo_milk") {
+		t.Errorf("expected key in message, got %q", msg)
+	}
+}
+
+func TestEmit_Cooldown(t *testing.T) {
+	cs, ran := newTestService(t)
+	addEventJob(t, cs, &EventTrigger{Source: EventSourceMemory, CooldownS: 60})
+
+	ev := Event{Source: EventSourceMemory, Attrs: map[string]string{"key": "k"}}
+	if n := cs.Emit(ev); n != 1 {
+		t.Fatalf("first event should trigger, got %d", n)
+	}
+	waitRun(t, ran)
+	if n := cs.Emit(ev); n != 0 {
+		t.Errorf("second event within cooldown should be dropped, got %d", n)
+	}
+}
+
+func TestEmit_SelfTriggerCooldown(t *testing.T) {
+	cs, ran := newTestService(t)
+	addEventJob(t, cs, &EventTrigger{Source: EventSourceMessage, Pattern: "report"})
+
+	// A run that sends a matching message must not trigger itself again
+	ev := Event{Source: EventSourceMessage, Payload: "daily report"}
+	if n := cs.Emit(ev); n != 1 {
+		t.Fatalf("first event should trigger, got %d", n)
+	}
+	waitRun(t, ran)
+	if n := cs.Emit(ev); n != 0 {
+		t.Errorf("message trigger without cooldown should get the minimum, got %d", n)
+	}
+}
+
+func TestEmit_PersistsClaim(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	release := make(chan struct{})
+	cs := NewCronService(path, func(job *CronJob) (string, error) {
+		<-release
+		return "ok", nil
+	})
+	if err := cs.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer cs.Stop()
+	defer close(release)
+	addEventJob(t, cs, &EventTrigger{Source: EventSourceMemory})
+
+	if n := cs.Emit(Event{Source: EventSourceMemory}); n != 1 {
+		t.Fatalf("expected 1 triggered job, got %d", n)
+	}
+	// The job is still running, so only Emit can have saved the claim
+	jobs := NewCronService(path, nil).ListJobs(true)
+	if len(jobs) != 1 || jobs[0].State.LastRunAtMS == nil {
+		t.Errorf("claimed run not persisted: %+v", jobs)
+	}
+}
+
+func TestEmit_DisabledAndNotRunning(t *testing.T) {
+	cs := NewCronService(filepath.Join(t.TempDir(), "jobs.json"), nil)
+	addEventJob(t, cs, &EventTrigger{Source: EventSourceMemory})
+	if n := cs.Emit(Event{Source: EventSourceMemory}); n != 0 {
+		t.Errorf("stopped service should not trigger, got %d", n)
+	}
+
+	cs2, _ := newTestService(t)
+	job := addEventJob(t, cs2, &EventTrigger{Source: EventSourceMemory})
+	cs2.EnableJob(job.ID, false)
+	if n := cs2.Emit(Event{Source: EventSourceMemory}); n != 0 {
+		t.Errorf("disabled job should not trigger, got %d", n)
+	}
+}
+
+func TestEventJob_HasNoNextRun(t *testing.T) {
+	cs, _ := newTestService(t)
+	job := addEventJob(t, cs, &EventTrigger{Source: EventSourceMemory})
+	if job.State.NextRunAtMS != nil {
+		t.Error("event jobs should not be scheduled by time")
+	}
+}
+
+// --- file watcher ---
+
+func TestFileTrigger(t *testing.T) {
+	cs, ran := newTestService(t)
+	dir := t.TempDir()
+	addEventJob(t, cs, &EventTrigger{Source: EventSourceFile, Path: dir, Pattern: "*.csv"})
+
+	writeFile(t, filepath.Join(dir, "ignored.txt"))
+	writeFile(t, filepath.Join(dir, "report.csv"))
+
+	msg := waitRun(t, ran)
+	if !strings.Contains(msg, "report.csv") {
+		t.Errorf("expected csv path in message, got %q", msg)
+	}
+	select {
+	case extra := <-ran:
+		t.Errorf("unexpected extra run: %q", extra)
+	case <-time.After(fileDebounce + 300*time.Millisecond):
+	}
+}
+
+func writeFile(t *testing.T, path string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("a,b\n"), 0644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+// --- webhook ---
+
+func TestHasWebhookJobs(t *testing.T) {
+	cs := NewCronService(filepath.Join(t.TempDir(), "jobs.json"), nil)
+	notified := make(chan struct{}, 1)
+	cs.SetOnWebhookJob(func() { notified <- struct{}{} })
+
+	addEventJob(t, cs, &EventTrigger{Source: EventSourceMemory})
+	if cs.HasWebhookJobs() {
+		t.Error("memory job should not count as webhook job")
+	}
+	addEventJob(t, cs, &EventTrigger{Source: EventSourceWebhook, Name: "deploy"})
+	if !cs.HasWebhookJobs() {
+		t.Error("expected webhook job")
+	}
+	select {
+	case <-notified:
+	case <-time.After(2 * time.Second):
+		t.Error("adding a webhook job should call the hook")
+	}
+}
+
+func TestWebhookHandler(t *testing.T) {
+	cs, ran := newTestService(t)
+	addEventJob(t, cs, &EventTrigger{Source: EventSourceWebhook, Name: "deploy", Secret: "s3cret"})
+
+	handler := cs.WebhookHandler()
+	do := func(method, path, secret, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if secret != "" {
+			req.Header.Set("X-Webhook-Secret", secret)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := do(http.MethodGet, "/hooks/deploy", "s3cret", ""); code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: got %d", code)
+	}
+	if code := do(http.MethodPost, "/hooks/deploy", "wrong", ""); code != http.StatusNotFound {
+		t.Errorf("bad secret: got %d", code)
+	}
+	if code := do(http.MethodPost, "/hooks/other", "s3cret", ""); code != http.StatusNotFound {
+		t.Errorf("unknown hook: got %d", code)
+	}
+	if code := do(http.MethodPost, "/hooks/deploy", "s3cret", `{"ref":"main"}`); code != http.StatusAccepted {
+		t.Fatalf("valid call: got %d", code)
+	}
+	if msg := waitRun(t, ran); !strings.Contains(msg, `{"ref":"main"}`) {
+		t.Errorf("expected body in message, got %q", msg)
+	}
+}
@@ -8,18 +8,38 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sync"
 	"time"
 
 	"github.com/adhocore/gronx"
+
+	"github.com/sipeed/picoclaw/pkg/metrics"
 )
 
 type CronSchedule struct {
-	Kind    string `json:"kind"`
-	AtMS    *int64 `json:"atMs,omitempty"`
-	EveryMS *int64 `json:"everyMs,omitempty"`
-	Expr    string `json:"expr,omitempty"`
-	TZ      string `json:"tz,omitempty"`
+	Kind    string        `json:"kind"`
+	AtMS    *int64        `json:"atMs,omitempty"`
+	EveryMS *int64        `json:"everyMs,omitempty"`
+	Expr    string        `json:"expr,omitempty"`
+	TZ      string        `json:"tz,omitempty"`
+	Event   *EventTrigger `json:"event,omitempty"`
+}
+
+// String returns a short human-readable description of the schedule.
+func (s CronSchedule) String() string {
+	switch {
+	case s.Kind == "every" && s.EveryMS != nil:
+		return fmt.Sprintf("every %ds", *s.EveryMS/1000)
+	case s.Kind == "cron":
+		return s.Expr
+	case s.Kind == "at":
+		return "one-time"
+	case s.Kind == "event" && s.Event != nil:
+		return s.Event.String()
+	default:
+		return "unknown"
+	}
 }
 
 type CronPayload struct {
@@ -64,6 +84,9 @@ type CronService struct {
 	running   bool
 	stopChan  chan struct{}
 	gronx     *gronx.Gronx
+	watcher   *fileWatcher
+	patterns  map[string]*regexp.Regexp
+	onWebhook func()
 }
 
 func NewCronService(storePath string, onJob JobHandler) *CronService {
@@ -72,6 +95,7 @@ func NewCronService(storePath string, onJob JobHandler) *CronService {
 		onJob:     onJob,
 		stopChan:  make(chan struct{}),
 		gronx:     gronx.New(),
+		patterns:  make(map[string]*regexp.Regexp),
 	}
 	// Initialize and load store on creation
 	cs.loadStore()
@@ -95,6 +119,14 @@ func (cs *CronService) Start() error {
 		return fmt.Errorf("failed to save store: %w", err)
 	}
 
+	watcher, err := newFileWatcher(func(ev Event) { cs.Emit(ev) })
+	if err != nil {
+		log.Printf("[cron] file watcher unavailable, file triggers disabled: %v", err)
+	} else {
+		cs.watcher = watcher
+		cs.syncWatchesUnsafe()
+	}
+
 	cs.running = true
 	go cs.runLoop()
 
@@ -111,6 +143,10 @@ func (cs *CronService) Stop() {
 
 	cs.running = false
 	close(cs.stopChan)
+	if cs.watcher != nil {
+		cs.watcher.close()
+		cs.watcher = nil
+	}
 }
 
 func (cs *CronService) runLoop() {
@@ -180,6 +216,7 @@ func (cs *CronService) executeJob(job *CronJob) {
 	if cs.onJob != nil {
 		_, err = cs.onJob(job)
 	}
+	metrics.CronJobs.Inc(job.Schedule.Kind, metrics.Status(err))
 
 	// Now acquire lock to update state
 	cs.mu.Lock()
@@ -325,6 +362,18 @@ func (cs *CronService) AddJob(name string, schedule CronSchedule, message string
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
 
+	if schedule.Kind == "event" {
+		if schedule.Event == nil {
+			return nil, fmt.Errorf("event schedule requires a trigger")
+		}
+		if err := schedule.Event.Validate(); err != nil {
+			return nil, err
+		}
+		// Event payloads come from outside (webhooks, files, chats), so
+		// they always go through an agent task instead of straight to chat
+		deliver = false
+	}
+
 	now := time.Now().UnixMilli()
 
 	// One-time tasks (at) should be deleted after execution
@@ -354,6 +403,8 @@ func (cs *CronService) AddJob(name string, schedule CronSchedule, message string
 	if err := cs.saveStoreUnsafe(); err != nil {
 		return nil, err
 	}
+	cs.syncWatchesUnsafe()
+	cs.notifyWebhookUnsafe(&job)
 
 	return &job, nil
 }
@@ -380,6 +431,7 @@ func (cs *CronService) removeJobUnsafe(jobID string) bool {
 		if err := cs.saveStoreUnsafe(); err != nil {
 			log.Printf("[cron] failed to save store after remove: %v", err)
 		}
+		cs.syncWatchesUnsafe()
 	}
 
 	return removed
@@ -404,6 +456,8 @@ func (cs *CronService) EnableJob(jobID string, enabled bool) *CronJob {
 			if err := cs.saveStoreUnsafe(); err != nil {
 				log.Printf("[cron] failed to save store after enable: %v", err)
 			}
+			cs.syncWatchesUnsafe()
+			cs.notifyWebhookUnsafe(job)
 			return job
 		}
 	}
@@ -0,0 +1,136 @@
+package cron
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// fileDebounce coalesces the burst of write events editors produce when
+// saving a file into a single event.
+const fileDebounce = 500 * time.Millisecond
+
+// fileWatcher watches directories for file-triggered jobs and emits an
+// event for every created or modified file. Directories are not watched
+// recursively.
+type fileWatcher struct {
+	fsw     *fsnotify.Watcher
+	emit    func(Event)
+	mu      sync.Mutex
+	dirs    map[string]bool
+	pending map[string]*pendingEvent
+	done    chan struct{}
+}
+
+type pendingEvent struct {
+	timer *time.Timer
+	op    string
+}
+
+func newFileWatcher(emit func(Event)) (*fileWatcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &fileWatcher{
+		fsw:     fsw,
+		emit:    emit,
+		dirs:    make(map[string]bool),
+		pending: make(map[string]*pendingEvent),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// sync adds and removes watches so exactly dirs are watched.
+func (w *fileWatcher) sync(dirs []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	want := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		want[dir] = true
+		if w.dirs[dir] {
+			continue
+		}
+		if err := w.fsw.Add(dir); err != nil {
+			log.Printf("[cron] failed to watch %s: %v", dir, err)
+			continue
+		}
+		w.dirs[dir] = true
+	}
+	for dir := range w.dirs {
+		if !want[dir] {
+			w.fsw.Remove(dir)
+			delete(w.dirs, dir)
+		}
+	}
+}
+
+func (w *fileWatcher) run() {
+	for {
+		select {
+		case <-w.done:
+			return
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			var op string
+			switch {
+			case ev.Has(fsnotify.Create):
+				op = "create"
+			case ev.Has(fsnotify.Write):
+				op = "write"
+			default:
+				continue
+			}
+			w.schedule(ev.Name, op)
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("[cron] file watcher error: %v", err)
+		}
+	}
+}
+
+// schedule emits an event for path once no further events have arrived for
+// fileDebounce. A create followed by writes is reported as a create.
+func (w *fileWatcher) schedule(path, op string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if p, ok := w.pending[path]; ok && p.timer.Stop() && p.op == "create" {
+		op = "create"
+	}
+	timer := time.AfterFunc(fileDebounce, func() {
+		w.mu.Lock()
+		delete(w.pending, path)
+		w.mu.Unlock()
+
+		w.emit(Event{
+			Source: EventSourceFile,
+			Attrs: map[string]string{
+				"path": path,
+				"op":   op,
+			},
+		})
+	})
+	w.pending[path] = &pendingEvent{timer: timer, op: op}
+}
+
+func (w *fileWatcher) close() {
+	w.mu.Lock()
+	for _, p := range w.pending {
+		p.timer.Stop()
+	}
+	w.pending = make(map[string]*pendingEvent)
+	w.mu.Unlock()
+
+	close(w.done)
+	w.fsw.Close()
+}
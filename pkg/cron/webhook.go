@@ -0,0 +1,55 @@
+package cron
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// WebhookPathPrefix is the gateway path under which webhook triggers are served.
+const WebhookPathPrefix = "/hooks/"
+
+// maxWebhookBody caps the request body accepted from a webhook caller.
+const maxWebhookBody = 64 * 1024
+
+// WebhookHandler returns an HTTP handler that turns POST /hooks/<name>
+// requests into webhook events. A job's secret, if set, must be sent in the
+// X-Webhook-Secret header.
+func (cs *CronService) WebhookHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		name := strings.TrimPrefix(r.URL.Path, WebhookPathPrefix)
+		if name == "" || strings.Contains(name, "/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+
+		n := cs.Emit(Event{
+			Source: EventSourceWebhook,
+			Attrs: map[string]string{
+				"name":   name,
+				"secret": r.Header.Get("X-Webhook-Secret"),
+			},
+			Payload: string(body),
+		})
+		if n == 0 {
+			// Unknown hooks and bad secrets are indistinguishable to the caller
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, "{\"triggered\":%d}\n", n)
+	})
+}
@@ -0,0 +1,243 @@
+// Package eval replays conversations through AgentLoop and checks the
+// answers, so prompt, model and skill changes can be tested before they
+// ship. Cases come from YAML (or JSON) files or from recorded sessions.
+// LLM calls are either live or served from fixture responses, which makes
+// a case an offline test of agent behavior.
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+// Case is one conversation to replay.
+type Case struct {
+	Name string `yaml:"name" json:"name"`
+	// Agent runs the turns; empty uses the default agent.
+	Agent   string `yaml:"agent,omitempty" json:"agent,omitempty"`
+	Channel string `yaml:"channel,omitempty" json:"channel,omitempty"`
+	ChatID  string `yaml:"chat_id,omitempty" json:"chat_id,omitempty"`
+	// Files are written to the case's workspace before the first turn,
+	// keyed by workspace-relative path.
+	Files map[string]string `yaml:"files,omitempty" json:"files,omitempty"`
+	Turns []Turn            `yaml:"turns" json:"turns"`
+
+	// Source is the file or session the case was loaded from.
+	Source string `yaml:"-" json:"-"`
+}
+
+// Turn is one user message and what the reply must look like.
+type Turn struct {
+	User string `yaml:"user" json:"user"`
+	// Responses are served in order, one per LLM call, unless the run is
+	// live.
+	Responses []Response `yaml:"responses,omitempty" json:"responses,omitempty"`
+	Expect    Expect     `yaml:"expect,omitempty" json:"expect,omitempty"`
+}
+
+// Response is a fixture LLM response.
+type Response struct {
+	Content   string     `yaml:"content,omitempty" json:"content,omitempty"`
+	ToolCalls []ToolCall `yaml:"tool_calls,omitempty" json:"tool_calls,omitempty"`
+	Usage     *Usage     `yaml:"usage,omitempty" json:"usage,omitempty"`
+	// Error makes the call fail with this message.
+	Error string `yaml:"error,omitempty" json:"error,omitempty"`
+}
+
+// Usage is the token usage a fixture response reports, which is what the
+// turn's cost is computed from.
+type Usage struct {
+	PromptTokens     int `yaml:"prompt_tokens" json:"prompt_tokens"`
+	CompletionTokens int `yaml:"completion_tokens" json:"completion_tokens"`
+}
+
+// ToolCall is a tool call requested by a fixture response, or one observed
+// during a run.
+type ToolCall struct {
+	Name      string                 `yaml:"name" json:"name"`
+	Arguments map[string]interface{} `yaml:"arguments,omitempty" json:"arguments,omitempty"`
+}
+
+// Expect holds the assertions for a turn. Unset fields are not checked.
+type Expect struct {
+	Equals      *string  `yaml:"equals,omitempty" json:"equals,omitempty"`
+	Contains    []string `yaml:"contains,omitempty" json:"contains,omitempty"`
+	NotContains []string `yaml:"not_contains,omitempty" json:"not_contains,omitempty"`
+	// Matches is a regular expression the reply must match.
+	Matches string `yaml:"matches,omitempty" json:"matches,omitempty"`
+	// Tools is the exact sequence of tools called; an empty list asserts
+	// that no tool was called.
+	Tools *[]string `yaml:"tools,omitempty" json:"tools,omitempty"`
+	// ToolArgs requires a call of Tool whose arguments include Args.
+	ToolArgs []ToolCall `yaml:"tool_args,omitempty" json:"tool_args,omitempty"`
+	// MaxCostUSD bounds the turn's cost at the configured prices.
+	MaxCostUSD  float64 `yaml:"max_cost_usd,omitempty" json:"max_cost_usd,omitempty"`
+	MaxLLMCalls int     `yaml:"max_llm_calls,omitempty" json:"max_llm_calls,omitempty"`
+}
+
+// LoadCases reads cases from YAML (.yaml, .yml) or JSON files. Directories
+// are searched recursively. A file holds a single case or a list of cases.
+func LoadCases(paths ...string) ([]Case, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && isCaseFile(p) {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+
+	var cases []Case
+	for _, file := range files {
+		loaded, err := loadCaseFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		cases = append(cases, loaded...)
+	}
+	return cases, nil
+}
+
+func isCaseFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+func loadCaseFile(path string) ([]Case, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is valid YAML, but decode it as JSON for exact number handling
+	unmarshal := yaml.Unmarshal
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		unmarshal = json.Unmarshal
+	}
+
+	var cases []Case
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "-") {
+		if err := unmarshal(data, &cases); err != nil {
+			return nil, err
+		}
+	} else {
+		var c Case
+		if err := unmarshal(data, &c); err != nil {
+			return nil, err
+		}
+		cases = []Case{c}
+	}
+
+	for i := range cases {
+		c := &cases[i]
+		c.Source = path
+		if c.Name == "" {
+			c.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+			if len(cases) > 1 {
+				c.Name = fmt.Sprintf("%s[%d]", c.Name, i)
+			}
+		}
+		if len(c.Turns) == 0 {
+			return nil, fmt.Errorf("case %q has no turns", c.Name)
+		}
+		for j, t := range c.Turns {
+			if t.User == "" {
+				return nil, fmt.Errorf("case %q turn %d has no user message", c.Name, j+1)
+			}
+		}
+	}
+	return cases, nil
+}
+
+// CaseFromSession turns a recorded session into a case: every user message
+// becomes a turn whose fixture responses are the assistant messages that
+// followed it, and which expects the same tools to be called again.
+func CaseFromSession(s *session.Session) Case {
+	c := Case{Name: "session " + s.Key, Source: "session:" + s.Key}
+	if channel, chatID, ok := strings.Cut(s.Key, ":"); ok {
+		c.Channel, c.ChatID = channel, chatID
+	}
+	var turn *Turn
+	var tools []string
+	flush := func() {
+		if turn == nil {
+			return
+		}
+		recorded := append([]string{}, tools...)
+		turn.Expect.Tools = &recorded
+		c.Turns = append(c.Turns, *turn)
+	}
+	for _, msg := range s.Messages {
+		switch msg.Role {
+		case "user":
+			flush()
+			turn = &Turn{User: msg.Content}
+			tools = nil
+		case "assistant":
+			if turn == nil {
+				continue
+			}
+			resp := Response{Content: msg.Content}
+			for _, tc := range msg.ToolCalls {
+				call := observedCall(tc)
+				resp.ToolCalls = append(resp.ToolCalls, call)
+				tools = append(tools, call.Name)
+			}
+			turn.Responses = append(turn.Responses, resp)
+		}
+	}
+	flush()
+	return c
+}
+
+// SessionsDir returns the sessions directory of agentID, or of the default
+// agent when agentID is empty.
+func SessionsDir(cfg *config.Config, agentID string) string {
+	for _, a := range cfg.Agents.List {
+		if a.ID == agentID && a.Workspace != "" {
+			return filepath.Join(expandHome(a.Workspace), "sessions")
+		}
+	}
+	return filepath.Join(cfg.WorkspacePath(), "sessions")
+}
+
+// LoadSession reads a recorded session from an agent's sessions directory.
+func LoadSession(sessionsDir, key string) (*session.Session, error) {
+	data, err := os.ReadFile(filepath.Join(sessionsDir, session.SanitizeSessionKey(key)+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var s session.Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
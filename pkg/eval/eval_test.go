@@ -0,0 +1,192 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+func newTestRunner(t *testing.T) *Runner {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Cost.Prices = map[string]config.ModelPriceConfig{
+		cfg.Agents.Defaults.Model: {Input: 1, Output: 2},
+	}
+	return NewRunner(cfg, Options{})
+}
+
+func TestLoadCasesYAML(t *testing.T) {
+	cases, err := LoadCases("testdata")
+	if err != nil {
+		t.Fatalf("LoadCases: %v", err)
+	}
+	if len(cases) != 1 {
+		t.Fatalf("got %d cases, want 1", len(cases))
+	}
+	c := cases[0]
+	if c.Name != "write a note" || len(c.Turns) != 2 {
+		t.Fatalf("unexpected case: %+v", c)
+	}
+	first := c.Turns[0]
+	if len(first.Responses) != 2 || first.Responses[0].ToolCalls[0].Name != "write_file" {
+		t.Errorf("responses not parsed: %+v", first.Responses)
+	}
+	if first.Responses[0].Usage == nil || first.Responses[0].Usage.PromptTokens != 1200 {
+		t.Errorf("usage not parsed: %+v", first.Responses[0].Usage)
+	}
+	if c.Turns[1].Expect.Tools == nil || len(*c.Turns[1].Expect.Tools) != 0 {
+		t.Errorf("empty tools list should assert no tool calls, got %v", c.Turns[1].Expect.Tools)
+	}
+}
+
+func TestLoadCasesRejectsEmptyTurns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.yaml")
+	os.WriteFile(path, []byte("name: bad\nturns: []\n"), 0644)
+	if _, err := LoadCases(path); err == nil {
+		t.Fatal("expected an error for a case without turns")
+	}
+}
+
+func TestRunFixtureCase(t *testing.T) {
+	cases, err := LoadCases("testdata/notes.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := newTestRunner(t).Run(context.Background(), cases)
+	if !report.OK() {
+		var buf bytes.Buffer
+		report.WriteText(&buf)
+		t.Fatalf("case failed:\n%s", buf.String())
+	}
+
+	turn := report.Cases[0].Turns[0]
+	if turn.Reply != "Saved to notes/todo.md." {
+		t.Errorf("reply = %q", turn.Reply)
+	}
+	if turn.LLMCalls != 2 || turn.Tokens != 2552 {
+		t.Errorf("llm calls = %d, tokens = %d", turn.LLMCalls, turn.Tokens)
+	}
+	// 2500 input tokens at $1/M plus 52 output tokens at $2/M
+	if want := 0.002604; turn.CostUSD < want-1e-9 || turn.CostUSD > want+1e-9 {
+		t.Errorf("cost = %f, want %f", turn.CostUSD, want)
+	}
+}
+
+func TestRunReportsFailures(t *testing.T) {
+	equals := "something else"
+	tools := []string{"read_file"}
+	c := Case{
+		Name: "failing",
+		Turns: []Turn{{
+			User: "hi",
+			Responses: []Response{
+				{Content: "hello"},
+				{Content: "never used"},
+			},
+			Expect: Expect{
+				Equals:      &equals,
+				Contains:    []string{"bye"},
+				Matches:     "^h",
+				Tools:       &tools,
+				MaxLLMCalls: 1,
+			},
+		}},
+	}
+	report := newTestRunner(t).Run(context.Background(), []Case{c})
+	if report.OK() || report.Failed != 1 {
+		t.Fatalf("expected the case to fail: %+v", report)
+	}
+	failures := report.Cases[0].Turns[0].Failures
+	want := []string{
+		"1 fixture response(s) were not used",
+		`reply = "hello", want "something else"`,
+		`reply does not contain "bye"`,
+		"tools called = [], want [read_file]",
+	}
+	if len(failures) != len(want) {
+		t.Fatalf("failures = %q, want %q", failures, want)
+	}
+	for i := range want {
+		if failures[i] != want[i] {
+			t.Errorf("failure %d = %q, want %q", i, failures[i], want[i])
+		}
+	}
+}
+
+func TestRunWithoutFixtures(t *testing.T) {
+	c := Case{Name: "no fixtures", Turns: []Turn{{User: "hi"}}}
+	report := newTestRunner(t).Run(context.Background(), []Case{c})
+	if report.OK() {
+		t.Fatal("a fixture-mode turn without responses should fail")
+	}
+}
+
+func TestCaseFromSession(t *testing.T) {
+	s := &session.Session{
+		Key: "telegram:42",
+		Messages: []providers.Message{
+			{Role: "user", Content: "list my files"},
+			{Role: "assistant", ToolCalls: []providers.ToolCall{{
+				ID:   "call_1",
+				Type: "function",
+				Function: &providers.FunctionCall{
+					Name:      "list_dir",
+					Arguments: `{"path":"."}`,
+				},
+			}}},
+			{Role: "tool", Content: "a.txt", ToolCallID: "call_1"},
+			{Role: "assistant", Content: "You have a.txt."},
+			{Role: "user", Content: "thanks"},
+			{Role: "assistant", Content: "Any time."},
+		},
+	}
+	c := CaseFromSession(s)
+	if c.Channel != "telegram" || c.ChatID != "42" {
+		t.Errorf("channel/chat = %q/%q", c.Channel, c.ChatID)
+	}
+	if len(c.Turns) != 2 {
+		t.Fatalf("got %d turns, want 2", len(c.Turns))
+	}
+	first := c.Turns[0]
+	if len(first.Responses) != 2 || first.Responses[0].ToolCalls[0].Arguments["path"] != "." {
+		t.Errorf("responses = %+v", first.Responses)
+	}
+	if first.Expect.Tools == nil || strings.Join(*first.Expect.Tools, ",") != "list_dir" {
+		t.Errorf("expected tools = %v", first.Expect.Tools)
+	}
+	if c.Turns[1].Expect.Tools == nil || len(*c.Turns[1].Expect.Tools) != 0 {
+		t.Errorf("second turn should expect no tools, got %v", c.Turns[1].Expect.Tools)
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	report := &Report{Mode: "fixture", Passed: 1, Failed: 1}
+	report.Cases = []CaseResult{
+		{Name: "ok", Source: "a.yaml", Passed: true},
+		{Name: "bad", Source: "b.yaml", Turns: []TurnResult{{Index: 1, User: "<hi>", Failures: []string{"reply does not contain \"&\""}}}},
+	}
+	var buf bytes.Buffer
+	if err := report.WriteJUnit(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc junitSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, buf.String())
+	}
+	if doc.Tests != 2 || doc.Failures != 1 || len(doc.Suites[0].Cases) != 2 {
+		t.Fatalf("unexpected document: %+v", doc)
+	}
+	failure := doc.Suites[0].Cases[1].Failure
+	if failure == nil || failure.Message != `turn 1: reply does not contain "&"` {
+		t.Errorf("failure = %+v", failure)
+	}
+}
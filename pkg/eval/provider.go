@@ -0,0 +1,195 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/cost"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// FixtureModel is the model name FixtureProvider reports.
+const FixtureModel = "fixture"
+
+// ErrFixturesExhausted is returned when the agent makes more LLM calls than
+// the turn has fixture responses.
+var ErrFixturesExhausted = errors.New("no fixture response left for this LLM call")
+
+// FixtureProvider is an LLMProvider that serves queued responses in order,
+// ignoring the request. It lets tests drive AgentLoop without a live LLM.
+type FixtureProvider struct {
+	mu        sync.Mutex
+	responses []Response
+	calls     int
+}
+
+// NewFixtureProvider returns a provider that serves responses in order.
+func NewFixtureProvider(responses ...Response) *FixtureProvider {
+	p := &FixtureProvider{}
+	p.Queue(responses...)
+	return p
+}
+
+// Queue appends responses to serve.
+func (p *FixtureProvider) Queue(responses ...Response) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.responses = append(p.responses, responses...)
+}
+
+// Remaining returns the number of responses not served yet.
+func (p *FixtureProvider) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.responses)
+}
+
+// Reset drops the responses not served yet.
+func (p *FixtureProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.responses = nil
+}
+
+func (p *FixtureProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.responses) == 0 {
+		return nil, ErrFixturesExhausted
+	}
+	r := p.responses[0]
+	p.responses = p.responses[1:]
+	p.calls++
+	if r.Error != "" {
+		return nil, errors.New(r.Error)
+	}
+
+	resp := &providers.LLMResponse{Content: r.Content, FinishReason: "stop"}
+	if r.Usage != nil {
+		resp.Usage = &providers.UsageInfo{
+			PromptTokens:     r.Usage.PromptTokens,
+			CompletionTokens: r.Usage.CompletionTokens,
+			TotalTokens:      r.Usage.PromptTokens + r.Usage.CompletionTokens,
+		}
+	}
+	for i, tc := range r.ToolCalls {
+		resp.ToolCalls = append(resp.ToolCalls, providers.ToolCall{
+			ID:        fmt.Sprintf("call_%d_%d", p.calls, i+1),
+			Type:      "function",
+			Name:      tc.Name,
+			Arguments: normalizeArgs(tc.Arguments),
+		})
+	}
+	if len(resp.ToolCalls) > 0 {
+		resp.FinishReason = "tool_calls"
+	}
+	return resp, nil
+}
+
+func (p *FixtureProvider) GetDefaultModel() string {
+	return FixtureModel
+}
+
+// llmCall is one observed LLM call.
+type llmCall struct {
+	Model     string
+	Usage     *providers.UsageInfo
+	ToolCalls []ToolCall
+	CostUSD   float64
+	Err       error
+}
+
+// recorder collects the LLM calls of every agent in a run. WrapProvider is
+// called once per agent, so each agent gets its own observer sharing it.
+type recorder struct {
+	mu    sync.Mutex
+	calls []llmCall
+}
+
+func (r *recorder) wrap(inner providers.LLMProvider, cfg config.CostConfig) *observer {
+	prices := make(map[string]cost.ModelPrice, len(cfg.Prices))
+	for k, v := range cfg.Prices {
+		prices[k] = cost.ModelPrice{Input: v.Input, Output: v.Output}
+	}
+	return &observer{inner: inner, prices: prices, rec: r}
+}
+
+func (r *recorder) add(call llmCall) {
+	r.mu.Lock()
+	r.calls = append(r.calls, call)
+	r.mu.Unlock()
+}
+
+// take returns the calls recorded since the last take.
+func (r *recorder) take() []llmCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	calls := r.calls
+	r.calls = nil
+	return calls
+}
+
+// observer wraps a provider and records every call for assertions.
+type observer struct {
+	inner  providers.LLMProvider
+	prices map[string]cost.ModelPrice
+	rec    *recorder
+}
+
+func (o *observer) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	resp, err := o.inner.Chat(ctx, messages, tools, model, options)
+	call := llmCall{Model: model, Err: err}
+	if resp != nil {
+		call.Usage = resp.Usage
+		for _, tc := range resp.ToolCalls {
+			call.ToolCalls = append(call.ToolCalls, observedCall(tc))
+		}
+		if resp.Usage != nil {
+			price := cost.PriceForModel(model, o.prices)
+			call.CostUSD = cost.NewTokenUsage(model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens, price.Input, price.Output).CostUSD
+		}
+	}
+	o.rec.add(call)
+	return resp, err
+}
+
+func (o *observer) GetDefaultModel() string {
+	return o.inner.GetDefaultModel()
+}
+
+// observedCall converts a provider tool call, in either of its two shapes,
+// to a ToolCall.
+func observedCall(tc providers.ToolCall) ToolCall {
+	call := ToolCall{Name: tc.Name, Arguments: tc.Arguments}
+	if tc.Function != nil {
+		if call.Name == "" {
+			call.Name = tc.Function.Name
+		}
+		if call.Arguments == nil && tc.Function.Arguments != "" {
+			json.Unmarshal([]byte(tc.Function.Arguments), &call.Arguments)
+		}
+	}
+	call.Arguments = normalizeArgs(call.Arguments)
+	return call
+}
+
+// normalizeArgs round-trips args through JSON so values decoded from YAML
+// (ints, nested maps) compare like those the LLM sends (float64s).
+func normalizeArgs(args map[string]interface{}) map[string]interface{} {
+	if args == nil {
+		return nil
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return args
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return args
+	}
+	return out
+}
@@ -0,0 +1,179 @@
+package eval
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Report is the outcome of a run.
+type Report struct {
+	// Mode is "fixture" or "live".
+	Mode       string       `json:"mode"`
+	Started    time.Time    `json:"started"`
+	DurationMS int64        `json:"duration_ms"`
+	Passed     int          `json:"passed"`
+	Failed     int          `json:"failed"`
+	CostUSD    float64      `json:"cost_usd"`
+	Cases      []CaseResult `json:"cases"`
+}
+
+// CaseResult is the outcome of one case.
+type CaseResult struct {
+	Name   string `json:"name"`
+	Source string `json:"source,omitempty"`
+	Passed bool   `json:"passed"`
+	// Error is set when the case could not run at all.
+	Error      string       `json:"error,omitempty"`
+	DurationMS int64        `json:"duration_ms"`
+	CostUSD    float64      `json:"cost_usd"`
+	Turns      []TurnResult `json:"turns,omitempty"`
+}
+
+// TurnResult is the outcome of one turn.
+type TurnResult struct {
+	Index      int        `json:"index"`
+	User       string     `json:"user"`
+	Reply      string     `json:"reply"`
+	Tools      []ToolCall `json:"tools,omitempty"`
+	LLMCalls   int        `json:"llm_calls"`
+	Tokens     int        `json:"tokens"`
+	CostUSD    float64    `json:"cost_usd"`
+	DurationMS int64      `json:"duration_ms"`
+	Failures   []string   `json:"failures,omitempty"`
+}
+
+func (r *Report) add(c CaseResult) {
+	if c.Passed {
+		r.Passed++
+	} else {
+		r.Failed++
+	}
+	r.CostUSD += c.CostUSD
+	r.Cases = append(r.Cases, c)
+}
+
+// OK reports whether every case passed.
+func (r *Report) OK() bool {
+	return r.Failed == 0
+}
+
+// failures lists a case's failures, prefixed with their turn.
+func (c CaseResult) failures() []string {
+	var out []string
+	if c.Error != "" {
+		out = append(out, c.Error)
+	}
+	for _, t := range c.Turns {
+		for _, f := range t.Failures {
+			out = append(out, fmt.Sprintf("turn %d: %s", t.Index, f))
+		}
+	}
+	return out
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteText writes a human-readable summary.
+func (r *Report) WriteText(w io.Writer) error {
+	var b strings.Builder
+	for _, c := range r.Cases {
+		status := "PASS"
+		if !c.Passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(&b, "%s  %s (%d turns, %dms, $%.4f)\n", status, c.Name, len(c.Turns), c.DurationMS, c.CostUSD)
+		for _, f := range c.failures() {
+			fmt.Fprintf(&b, "      %s\n", f)
+		}
+	}
+	fmt.Fprintf(&b, "\n%d passed, %d failed (%s mode, %dms, $%.4f)\n",
+		r.Passed, r.Failed, r.Mode, r.DurationMS, r.CostUSD)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Time      string      `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+func seconds(ms int64) string {
+	return fmt.Sprintf("%.3f", float64(ms)/1000)
+}
+
+// WriteJUnit writes the report as JUnit XML, one testcase per case, for CI
+// systems that render test results.
+func (r *Report) WriteJUnit(w io.Writer) error {
+	suite := junitSuite{
+		Name:      "picoclaw eval",
+		Tests:     len(r.Cases),
+		Failures:  r.Failed,
+		Time:      seconds(r.DurationMS),
+		Timestamp: r.Started.UTC().Format(time.RFC3339),
+	}
+	for _, c := range r.Cases {
+		tc := junitCase{Name: c.Name, Classname: c.Source, Time: seconds(c.DurationMS)}
+		if tc.Classname == "" {
+			tc.Classname = "eval"
+		}
+		var out strings.Builder
+		for _, t := range c.Turns {
+			fmt.Fprintf(&out, "> %s\n%s\n\n", t.User, t.Reply)
+		}
+		tc.SystemOut = out.String()
+		if !c.Passed {
+			failures := c.failures()
+			tc.Failure = &junitFailure{Message: fmt.Sprintf("%d assertion(s) failed", len(failures)), Body: strings.Join(failures, "\n")}
+			if len(failures) > 0 {
+				tc.Failure.Message = failures[0]
+			}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	doc := junitSuites{Tests: suite.Tests, Failures: suite.Failures, Time: suite.Time, Suites: []junitSuite{suite}}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
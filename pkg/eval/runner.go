@@ -0,0 +1,445 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// Options configures a Runner.
+type Options struct {
+	// Live sends LLM calls to the configured providers instead of serving
+	// the fixture responses.
+	Live bool
+	// Workspace is the workspace whose bootstrap files (AGENTS.md, SOUL.md
+	// and so on) and skills are copied into each case's scratch workspace,
+	// so the system prompt matches production. Empty uses the config's.
+	Workspace string
+	// TurnTimeout bounds a single turn (default 5 minutes).
+	TurnTimeout time.Duration
+}
+
+// Runner replays cases through a fresh AgentLoop each.
+type Runner struct {
+	cfg  *config.Config
+	opts Options
+}
+
+// NewRunner returns a runner for cfg. cfg is not modified; every case runs
+// on a copy whose workspace is a temporary directory.
+func NewRunner(cfg *config.Config, opts Options) *Runner {
+	if opts.Workspace == "" {
+		opts.Workspace = cfg.WorkspacePath()
+	}
+	if opts.TurnTimeout <= 0 {
+		opts.TurnTimeout = 5 * time.Minute
+	}
+	return &Runner{cfg: cfg, opts: opts}
+}
+
+// Run runs every case in order.
+func (r *Runner) Run(ctx context.Context, cases []Case) *Report {
+	report := &Report{Mode: "fixture", Started: time.Now()}
+	if r.opts.Live {
+		report.Mode = "live"
+	}
+	for _, c := range cases {
+		result := r.RunCase(ctx, c)
+		report.add(result)
+	}
+	report.DurationMS = time.Since(report.Started).Milliseconds()
+	return report
+}
+
+// RunCase runs one case in a scratch workspace.
+func (r *Runner) RunCase(ctx context.Context, c Case) (result CaseResult) {
+	start := time.Now()
+	result = CaseResult{Name: c.Name, Source: c.Source}
+	defer func() {
+		result.DurationMS = time.Since(start).Milliseconds()
+	}()
+
+	workspace, err := os.MkdirTemp("", "picoclaw-eval-*")
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer os.RemoveAll(workspace)
+
+	cfg, err := r.caseConfig(workspace)
+	if err == nil {
+		err = writeFiles(workspace, c.Files)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	rec := &recorder{}
+	fixtures := NewFixtureProvider()
+	opts := agent.Options{
+		WrapProvider: func(p providers.LLMProvider) providers.LLMProvider {
+			return rec.wrap(p, cfg.Cost)
+		},
+	}
+	if !r.opts.Live {
+		opts.Provider = fixtures
+	}
+
+	msgBus := bus.NewMessageBus()
+	defer msgBus.Close()
+	drainCtx, stopDrain := context.WithCancel(ctx)
+	defer stopDrain()
+	go func() {
+		for {
+			if _, ok := msgBus.SubscribeOutbound(drainCtx); !ok {
+				return
+			}
+		}
+	}()
+
+	al, err := agent.NewAgentLoopWithOptions(cfg, msgBus, opts)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer al.Shutdown()
+
+	channel, chatID := c.Channel, c.ChatID
+	if channel == "" {
+		channel = "cli"
+	}
+	if chatID == "" {
+		chatID = "eval"
+	}
+	sessionKey := "eval:" + c.Name
+
+	result.Passed = true
+	for i, turn := range c.Turns {
+		turn = expandTurn(turn, r.workspaceReplacer(workspace))
+		tr := r.runTurn(ctx, al, fixtures, rec, c, turn, sessionKey, channel, chatID)
+		tr.Index = i + 1
+		result.CostUSD += tr.CostUSD
+		if len(tr.Failures) > 0 {
+			result.Passed = false
+		}
+		result.Turns = append(result.Turns, tr)
+	}
+	return result
+}
+
+func (r *Runner) runTurn(ctx context.Context, al *agent.AgentLoop, fixtures *FixtureProvider, rec *recorder, c Case, turn Turn, sessionKey, channel, chatID string) TurnResult {
+	tr := TurnResult{User: turn.User}
+	if !r.opts.Live {
+		if len(turn.Responses) == 0 {
+			tr.Failures = append(tr.Failures, "no fixture responses for this turn; add responses or run live")
+			return tr
+		}
+		fixtures.Reset()
+		fixtures.Queue(turn.Responses...)
+	}
+
+	start := time.Now()
+	turnCtx, cancel := context.WithTimeout(ctx, r.opts.TurnTimeout)
+	reply, err := al.ProcessDirectForAgent(turnCtx, c.Agent, turn.User, sessionKey, channel, chatID)
+	cancel()
+	tr.DurationMS = time.Since(start).Milliseconds()
+	tr.Reply = reply
+
+	for _, call := range rec.take() {
+		tr.LLMCalls++
+		tr.CostUSD += call.CostUSD
+		if call.Usage != nil {
+			tr.Tokens += call.Usage.PromptTokens + call.Usage.CompletionTokens
+		}
+		tr.Tools = append(tr.Tools, call.ToolCalls...)
+	}
+
+	if err != nil {
+		tr.Failures = append(tr.Failures, "agent error: "+err.Error())
+	}
+	if !r.opts.Live {
+		if left := fixtures.Remaining(); left > 0 {
+			tr.Failures = append(tr.Failures, fmt.Sprintf("%d fixture response(s) were not used", left))
+		}
+	}
+	tr.Failures = append(tr.Failures, check(turn.Expect, tr)...)
+	return tr
+}
+
+// mount maps a source workspace to its copy in a case's workspace.
+type mount struct {
+	src, dst string
+}
+
+// mounts lists the workspaces a case needs: the default one at dir, and
+// one under dir/agents for each agent with a workspace of its own.
+func (r *Runner) mounts(dir string) []mount {
+	var ms []mount
+	for _, a := range r.cfg.Agents.List {
+		if a.Workspace != "" {
+			ms = append(ms, mount{src: expandHome(a.Workspace), dst: filepath.Join(dir, "agents", a.ID)})
+		}
+	}
+	return append(ms, mount{src: r.opts.Workspace, dst: dir})
+}
+
+// workspaceReplacer maps the ${workspace} placeholder, and the source
+// workspace paths found in recorded sessions, to the case's workspaces.
+func (r *Runner) workspaceReplacer(dir string) *strings.Replacer {
+	pairs := []string{"${workspace}", dir}
+	for _, m := range r.mounts(dir) {
+		if m.src != "" {
+			pairs = append(pairs, m.src, m.dst)
+		}
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, _ := os.UserHomeDir()
+		return home + path[1:]
+	}
+	return path
+}
+
+// expandTurn applies rep to the tool arguments of turn's fixture responses
+// and expected tool calls.
+func expandTurn(turn Turn, rep *strings.Replacer) Turn {
+	expandCalls := func(calls []ToolCall) []ToolCall {
+		out := make([]ToolCall, len(calls))
+		for i, tc := range calls {
+			out[i] = ToolCall{Name: tc.Name, Arguments: expandValue(normalizeArgs(tc.Arguments), rep).(map[string]interface{})}
+		}
+		return out
+	}
+	responses := make([]Response, len(turn.Responses))
+	for i, resp := range turn.Responses {
+		resp.ToolCalls = expandCalls(resp.ToolCalls)
+		responses[i] = resp
+	}
+	turn.Responses = responses
+	turn.Expect.ToolArgs = expandCalls(turn.Expect.ToolArgs)
+	return turn
+}
+
+func expandValue(v interface{}, rep *strings.Replacer) interface{} {
+	switch v := v.(type) {
+	case string:
+		return rep.Replace(v)
+	case map[string]interface{}:
+		if v == nil {
+			return v
+		}
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			out[k] = expandValue(e, rep)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = expandValue(e, rep)
+		}
+		return out
+	}
+	return v
+}
+
+// caseConfig copies the runner's config and points every workspace into dir.
+func (r *Runner) caseConfig(dir string) (*config.Config, error) {
+	cfg, err := r.cfg.Clone()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Agents.Defaults.Workspace = dir
+	for i := range cfg.Agents.List {
+		if cfg.Agents.List[i].Workspace != "" {
+			cfg.Agents.List[i].Workspace = filepath.Join(dir, "agents", cfg.Agents.List[i].ID)
+		}
+	}
+	if err := r.prepareWorkspaces(dir); err != nil {
+		return nil, err
+	}
+	// Costs are computed per turn by the recorder; budgets must not stop a run
+	cfg.Cost.Enabled = false
+	cfg.Cost.Quotas.Enabled = false
+	cfg.Memory.SnapshotOnExit = false
+	return cfg, nil
+}
+
+// prepareWorkspaces copies bootstrap files (AGENTS.md, SOUL.md, ...) and
+// skills from each source workspace into the case's workspaces.
+func (r *Runner) prepareWorkspaces(dir string) error {
+	for _, m := range r.mounts(dir) {
+		if err := os.MkdirAll(m.dst, 0755); err != nil {
+			return err
+		}
+		entries, _ := os.ReadDir(m.src)
+		for _, e := range entries {
+			src := filepath.Join(m.src, e.Name())
+			switch {
+			case e.Type().IsRegular() && strings.EqualFold(filepath.Ext(e.Name()), ".md"):
+				if err := copyFile(src, filepath.Join(m.dst, e.Name())); err != nil {
+					return err
+				}
+			case e.IsDir() && e.Name() == "skills":
+				if err := copyTree(src, filepath.Join(m.dst, "skills")); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// writeFiles writes a case's files, keyed by path relative to dir.
+func writeFiles(dir string, files map[string]string) error {
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return fmt.Errorf("file %q is outside the workspace", name)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return copyFile(path, target)
+	})
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// check evaluates a turn's assertions and returns the failures.
+func check(e Expect, tr TurnResult) []string {
+	var failures []string
+	fail := func(format string, args ...interface{}) {
+		failures = append(failures, fmt.Sprintf(format, args...))
+	}
+
+	if e.Equals != nil && tr.Reply != *e.Equals {
+		fail("reply = %q, want %q", tr.Reply, *e.Equals)
+	}
+	for _, s := range e.Contains {
+		if !strings.Contains(tr.Reply, s) {
+			fail("reply does not contain %q", s)
+		}
+	}
+	for _, s := range e.NotContains {
+		if strings.Contains(tr.Reply, s) {
+			fail("reply contains %q", s)
+		}
+	}
+	if e.Matches != "" {
+		re, err := regexp.Compile(e.Matches)
+		if err != nil {
+			fail("invalid matches pattern: %v", err)
+		} else if !re.MatchString(tr.Reply) {
+			fail("reply does not match %q", e.Matches)
+		}
+	}
+
+	if e.Tools != nil {
+		got := make([]string, 0, len(tr.Tools))
+		for _, tc := range tr.Tools {
+			got = append(got, tc.Name)
+		}
+		if !reflect.DeepEqual(got, *e.Tools) && (len(got) != 0 || len(*e.Tools) != 0) {
+			fail("tools called = %v, want %v", got, *e.Tools)
+		}
+	}
+	for _, want := range e.ToolArgs {
+		if !calledWith(tr.Tools, want) {
+			fail("no %s call with arguments %v", want.Name, compactArgs(want.Arguments))
+		}
+	}
+
+	if e.MaxCostUSD > 0 && tr.CostUSD > e.MaxCostUSD {
+		fail("cost $%.6f exceeds $%.6f", tr.CostUSD, e.MaxCostUSD)
+	}
+	if e.MaxLLMCalls > 0 && tr.LLMCalls > e.MaxLLMCalls {
+		fail("%d LLM calls, want at most %d", tr.LLMCalls, e.MaxLLMCalls)
+	}
+	return failures
+}
+
+// calledWith reports whether a call of want.Name has all of want's
+// arguments (other arguments are ignored).
+func calledWith(calls []ToolCall, want ToolCall) bool {
+	wantArgs := normalizeArgs(want.Arguments)
+	for _, call := range calls {
+		if call.Name != want.Name {
+			continue
+		}
+		match := true
+		for k, v := range wantArgs {
+			if !reflect.DeepEqual(call.Arguments[k], v) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// compactArgs renders arguments as JSON for failure messages.
+func compactArgs(args map[string]interface{}) string {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Sprint(args)
+	}
+	return string(data)
+}
@@ -0,0 +1,168 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DOCX extracts paragraphs from a Word document. Heading styles become
+// Markdown headings and tables become pipe-separated rows.
+func DOCX(data []byte) (*Result, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	var doc, core *zip.File
+	for _, f := range zr.File {
+		switch f.Name {
+		case "word/document.xml":
+			doc = f
+		case "docProps/core.xml":
+			core = f
+		}
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("word/document.xml not found")
+	}
+
+	rc, err := doc.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	text, err := docxText(rc)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Title: docxTitle(core), Text: text, Extractor: KindDOCX}, nil
+}
+
+func docxText(r io.Reader) (string, error) {
+	dec := xml.NewDecoder(r)
+	var (
+		out       strings.Builder
+		para      strings.Builder
+		heading   int
+		listItem  bool
+		cellParts []string
+		tableRow  []string
+		tableDeep int
+	)
+
+	flushPara := func() {
+		text := strings.TrimSpace(para.String())
+		para.Reset()
+		if tableDeep > 0 {
+			if text != "" {
+				cellParts = append(cellParts, text)
+			}
+			heading, listItem = 0, false
+			return
+		}
+		if text != "" {
+			switch {
+			case heading > 0:
+				out.WriteString(strings.Repeat("#", heading) + " ")
+			case listItem:
+				out.WriteString("- ")
+			}
+			out.WriteString(text)
+			out.WriteString("\n\n")
+		}
+		heading, listItem = 0, false
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "tbl":
+				tableDeep++
+			case "tr":
+				tableRow = nil
+			case "pStyle":
+				style := strings.ToLower(xmlAttr(t, "val"))
+				if strings.HasPrefix(style, "heading") {
+					fmt.Sscanf(strings.TrimPrefix(style, "heading"), "%d", &heading)
+					if heading < 1 || heading > 6 {
+						heading = 1
+					}
+				} else if style == "title" {
+					heading = 1
+				} else if strings.Contains(style, "list") {
+					listItem = true
+				}
+			case "numPr":
+				listItem = true
+			case "t":
+				var s string
+				if err := dec.DecodeElement(&s, &t); err != nil {
+					return "", err
+				}
+				para.WriteString(s)
+			case "tab":
+				para.WriteString("\t")
+			case "br", "cr":
+				para.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p":
+				flushPara()
+			case "tc":
+				// Cells hold paragraphs; merge them into one cell value
+				tableRow = append(tableRow, strings.Join(cellParts, " "))
+				cellParts = nil
+			case "tr":
+				out.WriteString("| " + strings.Join(tableRow, " | ") + " |\n")
+			case "tbl":
+				tableDeep--
+				out.WriteString("\n")
+			}
+		}
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+func xmlAttr(el xml.StartElement, local string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// docxTitle reads dc:title from docProps/core.xml.
+func docxTitle(core *zip.File) string {
+	if core == nil {
+		return ""
+	}
+	rc, err := core.Open()
+	if err != nil {
+		return ""
+	}
+	defer rc.Close()
+
+	var props struct {
+		Title string `xml:"title"`
+	}
+	if err := xml.NewDecoder(rc).Decode(&props); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(props.Title)
+}
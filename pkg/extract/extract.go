@@ -0,0 +1,145 @@
+// Package extract turns fetched or local documents (HTML, PDF, DOCX,
+// RSS/Atom feeds, JSON) into text an LLM can read.
+package extract
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// Extractor names reported in Result.Extractor.
+const (
+	KindHTML = "html"
+	KindPDF  = "pdf"
+	KindDOCX = "docx"
+	KindFeed = "feed"
+	KindJSON = "json"
+	KindText = "text"
+)
+
+// Result is the readable form of a document.
+type Result struct {
+	Title     string
+	Text      string
+	Extractor string
+}
+
+// Options tune extraction.
+type Options struct {
+	// BaseURL resolves relative links in HTML.
+	BaseURL string
+}
+
+// Detect picks an extractor from the content type, the file name and the
+// leading bytes, in that order of preference. Unknown content is "text".
+func Detect(data []byte, contentType, name string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/pdf":
+		return KindPDF
+	case "application/vnd.openxmlformats-officedocument.wordprocessingml.document":
+		return KindDOCX
+	case "application/rss+xml", "application/atom+xml", "application/feed+xml":
+		return KindFeed
+	case "application/json":
+		return KindJSON
+	case "text/html", "application/xhtml+xml":
+		return KindHTML
+	}
+	if strings.HasSuffix(mediaType, "+json") {
+		return KindJSON
+	}
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".pdf":
+		return KindPDF
+	case ".docx":
+		return KindDOCX
+	case ".rss", ".atom":
+		return KindFeed
+	case ".html", ".htm", ".xhtml":
+		return KindHTML
+	case ".json":
+		return KindJSON
+	}
+
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	switch {
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		return KindPDF
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")) && bytes.Contains(data, []byte("word/document.xml")):
+		return KindDOCX
+	case isFeed(head):
+		return KindFeed
+	}
+	if mediaType == "" || mediaType == "application/octet-stream" {
+		if strings.HasPrefix(http.DetectContentType(head), "text/html") {
+			return KindHTML
+		}
+	}
+	return KindText
+}
+
+// isFeed reports whether an XML document's root is <rss>, <feed> or <rdf:RDF>.
+func isFeed(head []byte) bool {
+	s := strings.ToLower(string(head))
+	if !strings.Contains(s, "<rss") && !strings.Contains(s, "<feed") && !strings.Contains(s, "<rdf:rdf") {
+		return false
+	}
+	trimmed := strings.TrimSpace(s)
+	return strings.HasPrefix(trimmed, "<?xml") || strings.HasPrefix(trimmed, "<rss") || strings.HasPrefix(trimmed, "<feed") || strings.HasPrefix(trimmed, "<rdf:rdf")
+}
+
+// IsDocument reports whether name is a binary document format that needs
+// extraction to be readable at all.
+func IsDocument(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".pdf", ".docx":
+		return true
+	}
+	return false
+}
+
+// Extract converts data to readable text with the detected extractor.
+func Extract(data []byte, contentType, name string, opts Options) (*Result, error) {
+	kind := Detect(data, contentType, name)
+	var (
+		res *Result
+		err error
+	)
+	switch kind {
+	case KindPDF:
+		res, err = PDF(data)
+	case KindDOCX:
+		res, err = DOCX(data)
+	case KindFeed:
+		res, err = Feed(data)
+	case KindHTML:
+		res, err = HTML(data, opts.BaseURL)
+	case KindJSON:
+		res = formatJSON(data)
+	default:
+		res = &Result{Text: string(data), Extractor: KindText}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s extraction failed: %w", kind, err)
+	}
+	return res, nil
+}
+
+func formatJSON(data []byte) *Result {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return &Result{Text: string(data), Extractor: KindText}
+	}
+	formatted, _ := json.MarshalIndent(v, "", "  ")
+	return &Result{Text: string(formatted), Extractor: KindJSON}
+}
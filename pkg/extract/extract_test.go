@@ -0,0 +1,198 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const articleHTML = `<!DOCTYPE html>
+<html><head><title>Release notes</title><script>var x = 1;</script></head>
+<body>
+<nav><a href="/">Home</a> <a href="/blog">Blog</a></nav>
+<div class="sidebar"><p>Subscribe to our newsletter for more updates, offers, and news.</p></div>
+<article>
+  <h1>Version 2.0</h1>
+  <p>This release brings <strong>faster</strong> startup, a new <a href="/docs/config">config format</a>, and many fixes.</p>
+  <h2>Changes</h2>
+  <ul><li>Lower memory use, by about half</li><li>New <code>--quiet</code> flag</li></ul>
+  <table>
+    <tr><th>Board</th><th>RAM</th></tr>
+    <tr><td>LicheeRV</td><td>64 MB</td></tr>
+  </table>
+  <pre>go build ./...
+go test ./...</pre>
+  <p>Thanks to everyone who reported issues, tested builds, and sent patches.</p>
+</article>
+<footer>Copyright 2026</footer>
+</body></html>`
+
+func TestHTMLMainContent(t *testing.T) {
+	res, err := HTML([]byte(articleHTML), "https://example.com/blog/v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Title != "Release notes" {
+		t.Errorf("title = %q", res.Title)
+	}
+	for _, want := range []string{
+		"# Version 2.0",
+		"## Changes",
+		"This release brings **faster** startup, a new [config format](https://example.com/docs/config), and many fixes.",
+		"- Lower memory use, by about half\n- New `--quiet` flag",
+		"| Board | RAM |\n| --- | --- |\n| LicheeRV | 64 MB |",
+		"```\ngo build ./...\ngo test ./...\n```",
+	} {
+		if !strings.Contains(res.Text, want) {
+			t.Errorf("missing %q in:\n%s", want, res.Text)
+		}
+	}
+	for _, junk := range []string{"var x", "Home", "newsletter", "Copyright"} {
+		if strings.Contains(res.Text, junk) {
+			t.Errorf("page chrome %q leaked into:\n%s", junk, res.Text)
+		}
+	}
+}
+
+func TestHTMLScoresParagraphContainer(t *testing.T) {
+	para := "<p>Pico boards run the agent on very little memory, which makes them useful, cheap, and quiet.</p>"
+	page := `<html><body><div id="top"><a href="/a">A</a> <a href="/b">B</a></div>
+<div class="post-body">` + strings.Repeat(para, 4) + `</div>
+<div class="links"><a href="/x">Some link text that is long enough to count, really</a></div></body></html>`
+
+	res, err := HTML([]byte(page), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(res.Text, "Some link text") || strings.Count(res.Text, "Pico boards") != 4 {
+		t.Errorf("expected only the post body, got:\n%s", res.Text)
+	}
+}
+
+const rssFeed = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Pico News</title>
+<item><title>Release 2.0</title><link>https://example.com/2</link><pubDate>Mon, 05 Oct 2026 10:00:00 GMT</pubDate>
+<description>&lt;p&gt;Faster &lt;b&gt;startup&lt;/b&gt;&lt;/p&gt;</description></item>
+<item><title>Release 1.9</title><link>https://example.com/1.9</link></item>
+</channel></rss>`
+
+const atomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom"><title>Pico Blog</title>
+<entry><title>Hello</title><link rel="alternate" href="https://example.com/hello"/><updated>2026-10-01T00:00:00Z</updated><summary>First post</summary></entry>
+</feed>`
+
+func TestFeed(t *testing.T) {
+	res, err := Extract([]byte(rssFeed), "application/xml", "", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Pico News\n\n1. Release 2.0 (Mon, 05 Oct 2026 10:00:00 GMT)\n   https://example.com/2\n   Faster **startup**\n2. Release 1.9\n   https://example.com/1.9"
+	if res.Extractor != KindFeed || res.Text != want {
+		t.Errorf("rss: got %s %q", res.Extractor, res.Text)
+	}
+
+	res, err = Extract([]byte(atomFeed), "application/atom+xml", "", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "# Pico Blog\n\n1. Hello (2026-10-01T00:00:00Z)\n   https://example.com/hello\n   First post"
+	if res.Text != want {
+		t.Errorf("atom: got %q", res.Text)
+	}
+}
+
+func buildDOCX(t *testing.T) []byte {
+	t.Helper()
+	const body = `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Quarterly report</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">Sales grew </w:t></w:r><w:r><w:t>12%.</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/></w:numPr></w:pPr><w:r><w:t>New boards</w:t></w:r></w:p>
+<w:tbl><w:tr><w:tc><w:p><w:r><w:t>Region</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Units</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>EU</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>4</w:t></w:r></w:p><w:p><w:r><w:t>k</w:t></w:r></w:p></w:tc></w:tr></w:tbl>
+</w:body></w:document>`
+	const core = `<?xml version="1.0"?><cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>Q3</dc:title></cp:coreProperties>`
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{"word/document.xml": body, "docProps/core.xml": core} {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestDOCX(t *testing.T) {
+	res, err := Extract(buildDOCX(t), "application/octet-stream", "report.docx", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Quarterly report\n\nSales grew 12%.\n\n- New boards\n\n| Region | Units |\n| EU | 4 k |"
+	if res.Extractor != KindDOCX || res.Title != "Q3" || res.Text != want {
+		t.Errorf("got %s %q %q", res.Extractor, res.Title, res.Text)
+	}
+}
+
+// buildPDF writes a one-page PDF with a single line of text.
+func buildPDF(text string) []byte {
+	stream := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+	}
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+func TestPDF(t *testing.T) {
+	res, err := Extract(buildPDF("Hello from PicoClaw"), "", "", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Extractor != KindPDF || !strings.Contains(res.Text, "Hello from PicoClaw") {
+		t.Errorf("got %s %q", res.Extractor, res.Text)
+	}
+
+	if _, err := Extract([]byte("%PDF-1.4 garbage"), "application/pdf", "", Options{}); err == nil {
+		t.Error("expected an error for a malformed PDF")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		data, contentType, name, want string
+	}{
+		{"{}", "application/json; charset=utf-8", "", KindJSON},
+		{"{}", "application/ld+json", "", KindJSON},
+		{"<html><body>x</body></html>", "", "", KindHTML},
+		{"<!DOCTYPE html><p>x", "text/plain", "", KindText},
+		{"%PDF-1.7", "application/octet-stream", "", KindPDF},
+		{"anything", "", "notes.PDF", KindPDF},
+		{rssFeed, "text/xml", "", KindFeed},
+		{"plain words", "text/plain", "", KindText},
+	}
+	for _, tt := range tests {
+		if got := Detect([]byte(tt.data), tt.contentType, tt.name); got != tt.want {
+			t.Errorf("Detect(%.20q, %q, %q) = %s, want %s", tt.data, tt.contentType, tt.name, got, tt.want)
+		}
+	}
+}
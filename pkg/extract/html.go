@@ -0,0 +1,480 @@
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	// unlikelyCandidate matches class/id values of page chrome.
+	unlikelyCandidate = regexp.MustCompile(`(?i)(^|[-_ ])(comments?|sidebar|footer|nav|navbar|menu|share|social|promo|advert|ads?|cookie|banner|related|popup|modal|subscribe|newsletter|breadcrumbs?)([-_ ]|$)`)
+	// maybeCandidate keeps elements that also look like content.
+	maybeCandidate = regexp.MustCompile(`(?i)article|content|main|post|entry|body|text|story`)
+
+	spaceRe    = regexp.MustCompile(`[ \t\r\n\f]+`)
+	multiSpace = regexp.MustCompile(` {2,}`)
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// junkElements never contain readable content.
+var junkElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Iframe: true, atom.Svg: true, atom.Canvas: true, atom.Form: true,
+	atom.Button: true, atom.Input: true, atom.Select: true, atom.Textarea: true,
+	atom.Nav: true, atom.Aside: true, atom.Footer: true, atom.Head: true,
+}
+
+// minContentLen is the text length below which a picked candidate is
+// distrusted and the whole body is used.
+const minContentLen = 200
+
+// HTML extracts the main content of a page as Markdown, keeping headings,
+// links, lists, tables and code blocks. baseURL resolves relative links.
+func HTML(data []byte, baseURL string) (*Result, error) {
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	r := &mdRenderer{}
+	if baseURL != "" {
+		r.base, _ = url.Parse(baseURL)
+	}
+
+	title := pageTitle(doc)
+	body := findFirst(doc, atom.Body)
+	if body == nil {
+		body = doc
+	}
+	removeJunk(body)
+
+	text := r.blocks(mainContent(body), "\n\n")
+	text = blankLines.ReplaceAllString(strings.TrimSpace(text), "\n\n")
+	return &Result{Title: title, Text: text, Extractor: KindHTML}, nil
+}
+
+func pageTitle(doc *html.Node) string {
+	if t := findFirst(doc, atom.Title); t != nil {
+		if s := collapse(textContent(t)); s != "" {
+			return s
+		}
+	}
+	if h := findFirst(doc, atom.H1); h != nil {
+		return collapse(textContent(h))
+	}
+	return ""
+}
+
+// removeJunk drops scripts, navigation, hidden elements and page chrome.
+func removeJunk(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode || (c.Type == html.ElementNode && isJunk(c)) {
+			n.RemoveChild(c)
+		} else {
+			removeJunk(c)
+		}
+		c = next
+	}
+}
+
+func isJunk(n *html.Node) bool {
+	if junkElements[n.DataAtom] {
+		return true
+	}
+	if _, hidden := attr(n, "hidden"); hidden {
+		return true
+	}
+	if v, _ := attr(n, "aria-hidden"); v == "true" {
+		return true
+	}
+	if style, _ := attr(n, "style"); strings.Contains(strings.ReplaceAll(style, " ", ""), "display:none") {
+		return true
+	}
+	switch role, _ := attr(n, "role"); role {
+	case "navigation", "banner", "contentinfo", "complementary", "dialog":
+		return true
+	}
+	switch n.DataAtom {
+	case atom.Div, atom.Section, atom.Ul, atom.Span, atom.Header, atom.Table:
+		class, _ := attr(n, "class")
+		id, _ := attr(n, "id")
+		names := class + " " + id
+		return unlikelyCandidate.MatchString(names) && !maybeCandidate.MatchString(names)
+	}
+	return false
+}
+
+// mainContent picks the node holding the article: a single <article> or
+// <main>, otherwise the best-scoring paragraph container.
+func mainContent(body *html.Node) *html.Node {
+	articles := findAll(body, func(n *html.Node) bool { return n.DataAtom == atom.Article })
+	if len(articles) == 1 && len(collapse(textContent(articles[0]))) >= minContentLen {
+		return articles[0]
+	}
+	mains := findAll(body, func(n *html.Node) bool {
+		role, _ := attr(n, "role")
+		return n.DataAtom == atom.Main || role == "main"
+	})
+	if len(mains) == 1 && len(collapse(textContent(mains[0]))) >= minContentLen {
+		return mains[0]
+	}
+
+	scores := map[*html.Node]float64{}
+	for _, p := range findAll(body, func(n *html.Node) bool {
+		return n.DataAtom == atom.P || n.DataAtom == atom.Pre || n.DataAtom == atom.Blockquote
+	}) {
+		text := collapse(textContent(p))
+		if len(text) < 25 || p.Parent == nil {
+			continue
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+		scores[p.Parent] += score
+		if gp := p.Parent.Parent; gp != nil {
+			scores[gp] += score / 2
+		}
+	}
+
+	var best *html.Node
+	bestScore := 0.0
+	for n, s := range scores {
+		s *= 1 - linkDensity(n)
+		if s > bestScore {
+			best, bestScore = n, s
+		}
+	}
+	if best == nil || len(collapse(textContent(best))) < minContentLen {
+		return body
+	}
+
+	// Content split across sibling containers: take their common parent
+	if parent := best.Parent; parent != nil && parent != body.Parent {
+		for sib := parent.FirstChild; sib != nil; sib = sib.NextSibling {
+			if sib != best && scores[sib] >= bestScore*0.2 {
+				return parent
+			}
+		}
+	}
+	return best
+}
+
+func linkDensity(n *html.Node) float64 {
+	total := len(collapse(textContent(n)))
+	if total == 0 {
+		return 0
+	}
+	links := 0
+	for _, a := range findAll(n, func(c *html.Node) bool { return c.DataAtom == atom.A }) {
+		links += len(collapse(textContent(a)))
+	}
+	return float64(links) / float64(total)
+}
+
+// mdRenderer converts an HTML subtree to Markdown.
+type mdRenderer struct {
+	base *url.URL
+}
+
+// blocks renders n's children as block content joined by sep. Runs of
+// inline content become paragraphs.
+func (r *mdRenderer) blocks(n *html.Node, sep string) string {
+	var parts []string
+	var run strings.Builder
+	flush := func() {
+		if p := cleanInline(run.String()); p != "" {
+			parts = append(parts, p)
+		}
+		run.Reset()
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode || (c.Type == html.ElementNode && !isBlock(c)) {
+			run.WriteString(r.inline(c))
+			continue
+		}
+		if c.Type != html.ElementNode {
+			continue
+		}
+		flush()
+		if b := r.block(c); b != "" {
+			parts = append(parts, b)
+		}
+	}
+	flush()
+	return strings.Join(parts, sep)
+}
+
+func (r *mdRenderer) block(n *html.Node) string {
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := cleanInline(r.inlineChildren(n))
+		if text == "" {
+			return ""
+		}
+		level := int(n.Data[1] - '0')
+		return strings.Repeat("#", level) + " " + strings.ReplaceAll(text, "\n", " ")
+	case atom.Ul, atom.Ol:
+		return r.list(n)
+	case atom.Pre:
+		code := strings.Trim(textContent(n), "\n")
+		if strings.TrimSpace(code) == "" {
+			return ""
+		}
+		return "```\n" + code + "\n```"
+	case atom.Blockquote:
+		inner := r.blocks(n, "\n\n")
+		if inner == "" {
+			return ""
+		}
+		return prefixLines(inner, "> ", "> ")
+	case atom.Table:
+		return r.table(n)
+	case atom.Hr:
+		return "---"
+	case atom.Li:
+		return "- " + r.blocks(n, "\n")
+	}
+	if junkElements[n.DataAtom] {
+		return ""
+	}
+	return r.blocks(n, "\n\n")
+}
+
+func (r *mdRenderer) list(n *html.Node) string {
+	var items []string
+	i := 0
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.DataAtom != atom.Li {
+			continue
+		}
+		i++
+		content := r.blocks(li, "\n")
+		if content == "" {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = fmt.Sprintf("%d. ", i)
+		}
+		items = append(items, prefixLines(content, marker, strings.Repeat(" ", len(marker))))
+	}
+	return strings.Join(items, "\n")
+}
+
+func (r *mdRenderer) table(n *html.Node) string {
+	var rows [][]string
+	width := 0
+	for _, tr := range findAll(n, func(c *html.Node) bool { return c.DataAtom == atom.Tr }) {
+		// Skip rows of nested tables; they are rendered inside their cell
+		if closest(tr, atom.Table) != n {
+			continue
+		}
+		var cells []string
+		for c := tr.FirstChild; c != nil; c = c.NextSibling {
+			if c.DataAtom == atom.Td || c.DataAtom == atom.Th {
+				cell := strings.ReplaceAll(cleanInline(r.inlineChildren(c)), "\n", " ")
+				cells = append(cells, strings.ReplaceAll(cell, "|", `\|`))
+			}
+		}
+		if len(cells) == 0 {
+			continue
+		}
+		rows = append(rows, cells)
+		width = max(width, len(cells))
+	}
+	if len(rows) == 0 {
+		return ""
+	}
+	// Layout tables with a single column are just blocks of content
+	if width == 1 {
+		return r.blocks(n, "\n\n")
+	}
+
+	var b strings.Builder
+	for i, row := range rows {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			b.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (r *mdRenderer) inline(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return spaceRe.ReplaceAllString(n.Data, " ")
+	}
+	if n.Type != html.ElementNode || junkElements[n.DataAtom] {
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		return "\n"
+	case atom.Img:
+		return ""
+	case atom.A:
+		text := strings.TrimSpace(r.inlineChildren(n))
+		href, _ := attr(n, "href")
+		href = r.resolve(href)
+		if text == "" || href == "" {
+			return text
+		}
+		return fmt.Sprintf("[%s](%s)", text, href)
+	case atom.Strong, atom.B:
+		return wrapInline(r.inlineChildren(n), "**")
+	case atom.Em, atom.I:
+		return wrapInline(r.inlineChildren(n), "*")
+	case atom.Code, atom.Kbd, atom.Samp:
+		return wrapInline(textContent(n), "`")
+	}
+	return r.inlineChildren(n)
+}
+
+func (r *mdRenderer) inlineChildren(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && isBlock(c) {
+			b.WriteString(" " + r.inlineChildren(c) + " ")
+			continue
+		}
+		b.WriteString(r.inline(c))
+	}
+	return b.String()
+}
+
+// resolve makes href absolute and drops script and fragment-only links.
+func (r *mdRenderer) resolve(href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return ""
+	}
+	if r.base == nil {
+		return href
+	}
+	u, err := r.base.Parse(href)
+	if err != nil {
+		return href
+	}
+	return u.String()
+}
+
+func isBlock(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Main, atom.Header,
+		atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6,
+		atom.Ul, atom.Ol, atom.Li, atom.Dl, atom.Dt, atom.Dd,
+		atom.Pre, atom.Blockquote, atom.Table, atom.Tr, atom.Td, atom.Th,
+		atom.Thead, atom.Tbody, atom.Tfoot, atom.Hr, atom.Figure, atom.Figcaption,
+		atom.Details, atom.Summary, atom.Address, atom.Center:
+		return true
+	}
+	return junkElements[n.DataAtom]
+}
+
+func wrapInline(s, mark string) string {
+	trimmed := strings.TrimSpace(spaceRe.ReplaceAllString(s, " "))
+	if trimmed == "" {
+		return s
+	}
+	return mark + trimmed + mark
+}
+
+// cleanInline trims each line of an inline run and squeezes spaces.
+func cleanInline(s string) string {
+	lines := strings.Split(s, "\n")
+	out := lines[:0]
+	for _, line := range lines {
+		line = strings.TrimSpace(multiSpace.ReplaceAllString(line, " "))
+		if line != "" {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+func prefixLines(s, first, rest string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		switch {
+		case i == 0:
+			lines[i] = first + line
+		case line == "":
+			lines[i] = strings.TrimRight(rest, " ")
+		default:
+			lines[i] = rest + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func collapse(s string) string {
+	return strings.TrimSpace(spaceRe.ReplaceAllString(s, " "))
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+func attr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func findFirst(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findFirst(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func findAll(n *html.Node, match func(*html.Node) bool) []*html.Node {
+	var out []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode {
+				if match(c) {
+					out = append(out, c)
+				}
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+	return out
+}
+
+func closest(n *html.Node, a atom.Atom) *html.Node {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.DataAtom == a {
+			return p
+		}
+	}
+	return nil
+}
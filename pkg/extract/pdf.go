@@ -0,0 +1,56 @@
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// PDF extracts the text layer of each page. Scanned PDFs without a text
+// layer yield an empty result rather than an error.
+func PDF(data []byte) (res *Result, err error) {
+	// The parser panics on some malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			res, err = nil, fmt.Errorf("malformed pdf: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	pages := reader.NumPage()
+	for i := 1; i <= pages; i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		text, err := page.GetPlainText(nil)
+		if err != nil {
+			continue
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		if pages > 1 {
+			fmt.Fprintf(&b, "--- Page %d ---\n", i)
+		}
+		b.WriteString(text)
+		b.WriteString("\n\n")
+	}
+
+	res = &Result{Text: strings.TrimSpace(b.String()), Extractor: KindPDF}
+	if title := reader.Trailer().Key("Info").Key("Title").Text(); title != "" {
+		res.Title = title
+	}
+	if res.Text == "" {
+		res.Text = fmt.Sprintf("(no extractable text in %d page(s); the PDF may be scanned images)", pages)
+	}
+	return res, nil
+}
@@ -0,0 +1,120 @@
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+const defaultCheckTimeout = 10 * time.Second
+
+// CheckResult is the outcome of one pre-agent probe.
+type CheckResult struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+// RunChecks executes every probe sequentially and returns one result per check.
+func RunChecks(ctx context.Context, checks []config.HeartbeatCheckConfig) []CheckResult {
+	results := make([]CheckResult, 0, len(checks))
+	for _, c := range checks {
+		switch c.Type {
+		case "disk":
+			results = append(results, runDiskCheck(c))
+		case "http":
+			results = append(results, runHTTPCheck(ctx, c))
+		default:
+			results = append(results, CheckResult{
+				Name:   c.Type,
+				Detail: fmt.Sprintf("unknown check type %q", c.Type),
+			})
+		}
+	}
+	return results
+}
+
+func runDiskCheck(c config.HeartbeatCheckConfig) CheckResult {
+	path := c.Path
+	if path == "" {
+		path = "/"
+	}
+	res := CheckResult{Name: "disk " + path}
+
+	used, err := diskUsedPercent(path)
+	if err != nil {
+		res.Detail = err.Error()
+		return res
+	}
+	res.OK = c.MaxUsedPercent <= 0 || used <= c.MaxUsedPercent
+	res.Detail = fmt.Sprintf("%.1f%% used", used)
+	if c.MaxUsedPercent > 0 {
+		res.Detail += fmt.Sprintf(" (limit %.1f%%)", c.MaxUsedPercent)
+	}
+	return res
+}
+
+func runHTTPCheck(ctx context.Context, c config.HeartbeatCheckConfig) CheckResult {
+	res := CheckResult{Name: "http " + c.URL}
+
+	timeout := defaultCheckTimeout
+	if c.TimeoutSeconds > 0 {
+		timeout = time.Duration(c.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL, nil)
+	if err != nil {
+		res.Detail = err.Error()
+		return res
+	}
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		res.Detail = err.Error()
+		return res
+	}
+	resp.Body.Close()
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	if c.ExpectStatus > 0 {
+		res.OK = resp.StatusCode == c.ExpectStatus
+	} else {
+		res.OK = resp.StatusCode < 400
+	}
+	res.Detail = fmt.Sprintf("status %d in %s", resp.StatusCode, elapsed)
+	return res
+}
+
+// allPassed reports whether every check succeeded.
+func allPassed(results []CheckResult) bool {
+	for _, r := range results {
+		if !r.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// formatResults renders check results as a prompt section.
+func formatResults(results []CheckResult) string {
+	if len(results) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("## Check Results\n\n")
+	for _, r := range results {
+		status := "OK"
+		if !r.OK {
+			status = "FAIL"
+		}
+		fmt.Fprintf(&sb, "- [%s] %s: %s\n", status, r.Name, r.Detail)
+	}
+	return sb.String()
+}
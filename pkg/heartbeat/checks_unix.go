@@ -0,0 +1,18 @@
+//go:build !windows
+
+package heartbeat
+
+import "syscall"
+
+func diskUsedPercent(path string) (float64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	total := st.Blocks * uint64(st.Bsize)
+	if total == 0 {
+		return 0, nil
+	}
+	free := st.Bfree * uint64(st.Bsize)
+	return float64(total-free) / float64(total) * 100, nil
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package heartbeat
+
+import "fmt"
+
+func diskUsedPercent(path string) (float64, error) {
+	return 0, fmt.Errorf("disk check is not supported on windows")
+}
@@ -1,6 +1,8 @@
 package heartbeat
 
 import (
+	"context"
+	"crypto/sha256"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,49 +11,153 @@ import (
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
+// OKToken is the sentinel the agent replies with when nothing needs attention.
+const OKToken = "HEARTBEAT_OK"
+
+// defaultItemName is used for the legacy single-prompt heartbeat built from
+// memory/HEARTBEAT.md when no items are configured.
+const defaultItemName = "default"
+
+// Handler runs a heartbeat prompt through an agent and returns its reply.
+// agentID may be empty to use the default agent.
+type Handler func(agentID, prompt, sessionKey, channel, chatID string) (string, error)
+
+// Target is a delivery destination for heartbeat alerts.
+type Target struct {
+	Channel string
+	ChatID  string
+}
+
+// Item is one entry of the heartbeat checklist.
+type Item struct {
+	Name          string
+	Prompt        string
+	Interval      time.Duration
+	Agent         string
+	Targets       []Target
+	Checks        []config.HeartbeatCheckConfig
+	OnlyOnFailure bool
+}
+
+type itemState struct {
+	lastRun   time.Time
+	running   bool
+	alertHash [sha256.Size]byte
+	alertAt   time.Time
+}
+
 type HeartbeatService struct {
-	workspace      string
-	onHeartbeat    func(string) (string, error)
-	interval       time.Duration
-	enabled        bool
-	mu             sync.RWMutex
-	stopChan       chan struct{}
-	deliverChannel string
-	deliverChatID  string
-	bus            *bus.MessageBus
+	workspace     string
+	onHeartbeat   Handler
+	interval      time.Duration
+	tick          time.Duration
+	dedupWindow   time.Duration
+	enabled       bool
+	mu            sync.RWMutex
+	started       bool
+	stopChan      chan struct{}
+	defaultTarget Target
+	items         []Item
+	state         map[string]*itemState
+	bus           *bus.MessageBus
 }
 
 func NewHeartbeatService(workspace string, intervalS int, enabled bool) *HeartbeatService {
 	return &HeartbeatService{
 		workspace: workspace,
 		interval:  time.Duration(intervalS) * time.Second,
+		tick:      10 * time.Second,
 		enabled:   enabled,
-		stopChan:  make(chan struct{}),
+		state:     make(map[string]*itemState),
 	}
 }
 
-func (hs *HeartbeatService) SetOnHeartbeat(fn func(string) (string, error)) {
+// ItemsFromConfig converts the configured checklist into heartbeat items.
+// Targets are given as "channel:chat_id".
+func ItemsFromConfig(cfg config.HeartbeatConfig) ([]Item, error) {
+	items := make([]Item, 0, len(cfg.Items))
+	seen := make(map[string]bool)
+	for i, ic := range cfg.Items {
+		name := strings.TrimSpace(ic.Name)
+		if name == "" {
+			return nil, fmt.Errorf("heartbeat item %d: name is required", i)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("heartbeat item %q: duplicate name", name)
+		}
+		seen[name] = true
+		if ic.Prompt == "" && len(ic.Checks) == 0 {
+			return nil, fmt.Errorf("heartbeat item %q: prompt or checks required", name)
+		}
+
+		item := Item{
+			Name:          name,
+			Prompt:        ic.Prompt,
+			Interval:      time.Duration(ic.IntervalSeconds) * time.Second,
+			Agent:         ic.Agent,
+			Checks:        ic.Checks,
+			OnlyOnFailure: ic.OnlyOnFailure,
+		}
+		for _, t := range ic.Targets {
+			channel, chatID, ok := strings.Cut(t, ":")
+			if !ok || channel == "" || chatID == "" {
+				return nil, fmt.Errorf("heartbeat item %q: invalid target %q (want channel:chat_id)", name, t)
+			}
+			item.Targets = append(item.Targets, Target{Channel: channel, ChatID: chatID})
+		}
+		for _, c := range ic.Checks {
+			if c.Type != "disk" && c.Type != "http" {
+				return nil, fmt.Errorf("heartbeat item %q: unknown check type %q", name, c.Type)
+			}
+			if c.Type == "http" && c.URL == "" {
+				return nil, fmt.Errorf("heartbeat item %q: http check requires url", name)
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (hs *HeartbeatService) SetOnHeartbeat(fn Handler) {
 	hs.mu.Lock()
 	defer hs.mu.Unlock()
 	hs.onHeartbeat = fn
 }
 
+// SetDelivery sets the bus used for alerts and the target used by items
+// that do not list their own.
 func (hs *HeartbeatService) SetDelivery(msgBus *bus.MessageBus, channel, chatID string) {
 	hs.mu.Lock()
 	defer hs.mu.Unlock()
 	hs.bus = msgBus
-	hs.deliverChannel = channel
-	hs.deliverChatID = chatID
+	hs.defaultTarget = Target{Channel: channel, ChatID: chatID}
+}
+
+// SetItems replaces the heartbeat checklist. With no items the service
+// falls back to a single prompt built from memory/HEARTBEAT.md.
+func (hs *HeartbeatService) SetItems(items []Item) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.items = items
+}
+
+// SetDedupWindow suppresses an item's alert when it repeats the previous
+// alert within the window. Zero disables deduplication.
+func (hs *HeartbeatService) SetDedupWindow(d time.Duration) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.dedupWindow = d
 }
 
 func (hs *HeartbeatService) Start() error {
 	hs.mu.Lock()
 	defer hs.mu.Unlock()
 
-	if hs.running() {
+	if hs.started {
 		return nil
 	}
 
@@ -59,7 +165,9 @@ func (hs *HeartbeatService) Start() error {
 		return fmt.Errorf("heartbeat service is disabled")
 	}
 
-	go hs.runLoop()
+	hs.started = true
+	hs.stopChan = make(chan struct{})
+	go hs.runLoop(hs.stopChan)
 
 	return nil
 }
@@ -68,83 +176,229 @@ func (hs *HeartbeatService) Stop() {
 	hs.mu.Lock()
 	defer hs.mu.Unlock()
 
-	if !hs.running() {
+	if !hs.started {
 		return
 	}
 
+	hs.started = false
 	close(hs.stopChan)
 }
 
-func (hs *HeartbeatService) running() bool {
-	select {
-	case <-hs.stopChan:
-		return false
-	default:
-		return true
+func (hs *HeartbeatService) runLoop(stopChan chan struct{}) {
+	tick := hs.tick
+	if hs.interval > 0 && hs.interval < tick {
+		tick = hs.interval
 	}
-}
-
-func (hs *HeartbeatService) runLoop() {
-	ticker := time.NewTicker(hs.interval)
+	ticker := time.NewTicker(tick)
 	defer ticker.Stop()
 
+	// Items first fire one interval after startup, like the original loop.
+	hs.mu.Lock()
+	start := time.Now()
+	for _, item := range hs.checklist() {
+		hs.stateFor(item.Name).lastRun = start
+	}
+	hs.mu.Unlock()
+
 	for {
 		select {
-		case <-hs.stopChan:
+		case <-stopChan:
 			return
-		case <-ticker.C:
-			hs.checkHeartbeat()
+		case now := <-ticker.C:
+			hs.runDue(now)
 		}
 	}
 }
 
-func (hs *HeartbeatService) checkHeartbeat() {
-	hs.mu.RLock()
-	if !hs.enabled || !hs.running() {
-		hs.mu.RUnlock()
+// checklist returns the configured items, or the legacy single item.
+// Caller must hold hs.mu.
+func (hs *HeartbeatService) checklist() []Item {
+	if len(hs.items) > 0 {
+		return hs.items
+	}
+	return []Item{{Name: defaultItemName}}
+}
+
+// stateFor returns the mutable state for an item. Caller must hold hs.mu.
+func (hs *HeartbeatService) stateFor(name string) *itemState {
+	st, ok := hs.state[name]
+	if !ok {
+		st = &itemState{}
+		hs.state[name] = st
+	}
+	return st
+}
+
+// runDue starts every item whose interval has elapsed and that is not
+// already running.
+func (hs *HeartbeatService) runDue(now time.Time) {
+	hs.mu.Lock()
+	if !hs.enabled || !hs.started {
+		hs.mu.Unlock()
 		return
 	}
+	var due []Item
+	for _, item := range hs.checklist() {
+		interval := item.Interval
+		if interval <= 0 {
+			interval = hs.interval
+		}
+		st := hs.stateFor(item.Name)
+		if st.running || now.Sub(st.lastRun) < interval {
+			continue
+		}
+		st.running = true
+		st.lastRun = now
+		due = append(due, item)
+	}
+	hs.mu.Unlock()
+
+	for _, item := range due {
+		go func(item Item) {
+			hs.runItem(item)
+			hs.mu.Lock()
+			hs.stateFor(item.Name).running = false
+			hs.mu.Unlock()
+		}(item)
+	}
+}
+
+// runItem executes one heartbeat item: checks, agent turn, suppression,
+// deduplication and delivery.
+func (hs *HeartbeatService) runItem(item Item) {
+	hs.mu.RLock()
 	onHeartbeat := hs.onHeartbeat
 	msgBus := hs.bus
-	channel := hs.deliverChannel
-	chatID := hs.deliverChatID
+	targets := item.Targets
+	if len(targets) == 0 && hs.defaultTarget.Channel != "" && hs.defaultTarget.ChatID != "" {
+		targets = []Target{hs.defaultTarget}
+	}
 	hs.mu.RUnlock()
 
 	if onHeartbeat == nil {
 		return
 	}
 
-	prompt := hs.buildPrompt()
+	results := RunChecks(context.Background(), item.Checks)
+	if item.OnlyOnFailure && allPassed(results) {
+		logger.DebugCF("heartbeat", "All checks passed, skipping agent", map[string]interface{}{
+			"item": item.Name,
+		})
+		return
+	}
+
+	prompt := hs.buildItemPrompt(item, results)
+
+	var channel, chatID string
+	if len(targets) > 0 {
+		channel, chatID = targets[0].Channel, targets[0].ChatID
+	}
+
+	sessionKey := "heartbeat:" + item.Name
+	if item.Name == defaultItemName {
+		sessionKey = "heartbeat:system"
+	}
 
-	response, err := onHeartbeat(prompt)
+	response, err := onHeartbeat(item.Agent, prompt, sessionKey, channel, chatID)
 	if err != nil {
 		logger.ErrorCF("heartbeat", "Heartbeat callback error", map[string]interface{}{
+			"item":  item.Name,
 			"error": err.Error(),
 		})
-		hs.log(fmt.Sprintf("Heartbeat error: %v", err))
+		hs.log(fmt.Sprintf("Heartbeat %s error: %v", item.Name, err))
 		return
 	}
 
-	// If response contains HEARTBEAT_OK sentinel, no action needed
-	if strings.Contains(response, "HEARTBEAT_OK") {
-		logger.DebugCF("heartbeat", "Heartbeat OK, no action needed", nil)
+	if IsOK(response) {
+		logger.DebugCF("heartbeat", "Heartbeat OK, no action needed", map[string]interface{}{
+			"item": item.Name,
+		})
+		return
+	}
+
+	if hs.isDuplicate(item.Name, response, time.Now()) {
+		logger.DebugCF("heartbeat", "Duplicate heartbeat alert suppressed", map[string]interface{}{
+			"item": item.Name,
+		})
 		return
 	}
 
-	// Deliver response to configured channel
-	if msgBus != nil && channel != "" && chatID != "" {
+	if msgBus == nil || len(targets) == 0 {
+		logger.WarnCF("heartbeat", "Heartbeat alert has no delivery target", map[string]interface{}{
+			"item": item.Name,
+		})
+		return
+	}
+	for _, t := range targets {
 		msgBus.PublishOutbound(bus.OutboundMessage{
-			Channel: channel,
-			ChatID:  chatID,
+			Channel: t.Channel,
+			ChatID:  t.ChatID,
 			Content: response,
 		})
 		logger.InfoCF("heartbeat", "Heartbeat response delivered", map[string]interface{}{
-			"channel": channel,
-			"chat_id": chatID,
+			"item":    item.Name,
+			"channel": t.Channel,
+			"chat_id": t.ChatID,
 		})
 	}
 }
 
+// IsOK reports whether a reply means "nothing to report": the OK token with
+// nothing else but whitespace, punctuation or markdown around it. Replies
+// that mention the token alongside real content are treated as alerts.
+func IsOK(response string) bool {
+	if !strings.Contains(response, OKToken) {
+		return false
+	}
+	rest := strings.ReplaceAll(response, OKToken, "")
+	rest = strings.TrimFunc(rest, func(r rune) bool {
+		return strings.ContainsRune(" \t\r\n.!*_`'\"", r)
+	})
+	return rest == ""
+}
+
+// isDuplicate records the alert and reports whether it repeats the previous
+// alert for the same item within the dedup window.
+func (hs *HeartbeatService) isDuplicate(name, response string, now time.Time) bool {
+	hash := sha256.Sum256([]byte(normalizeAlert(response)))
+
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	st := hs.stateFor(name)
+	dup := hs.dedupWindow > 0 && st.alertHash == hash && now.Sub(st.alertAt) < hs.dedupWindow
+	if !dup {
+		st.alertHash = hash
+		st.alertAt = now
+	}
+	return dup
+}
+
+func normalizeAlert(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+func (hs *HeartbeatService) buildItemPrompt(item Item, results []CheckResult) string {
+	if item.Prompt == "" && item.Name == defaultItemName && len(results) == 0 {
+		return hs.buildPrompt()
+	}
+
+	now := time.Now().Format("2006-01-02 15:04")
+	task := item.Prompt
+	if task == "" {
+		task = "Review the check results below and report any problems."
+	}
+
+	return fmt.Sprintf(`# Heartbeat: %s
+
+Current time: %s
+
+%s
+
+If there is nothing to report, respond with exactly: %s
+
+%s`, item.Name, now, task, OKToken, formatResults(results))
+}
+
 func (hs *HeartbeatService) buildPrompt() string {
 	notesDir := filepath.Join(hs.workspace, "memory")
 	notesFile := filepath.Join(notesDir, "HEARTBEAT.md")
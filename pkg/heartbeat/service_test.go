@@ -0,0 +1,227 @@
+package heartbeat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestIsOK(t *testing.T) {
+	cases := map[string]bool{
+		"HEARTBEAT_OK":                       true,
+		"  **HEARTBEAT_OK**.\n":              true,
+		"`HEARTBEAT_OK`":                     true,
+		"Disk is 95% full. HEARTBEAT_OK":     false,
+		"Nothing urgent, but check backups.": false,
+		"":                                   false,
+	}
+	for in, want := range cases {
+		if got := IsOK(in); got != want {
+			t.Errorf("IsOK(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestItemsFromConfig(t *testing.T) {
+	items, err := ItemsFromConfig(config.HeartbeatConfig{Items: []config.HeartbeatItemConfig{{
+		Name:            "web",
+		Prompt:          "check",
+		IntervalSeconds: 60,
+		Targets:         []string{"telegram:1", "discord:2:3"},
+	}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 1 || items[0].Interval != time.Minute {
+		t.Fatalf("unexpected items: %+v", items)
+	}
+	want := []Target{{"telegram", "1"}, {"discord", "2:3"}}
+	for i, tg := range items[0].Targets {
+		if tg != want[i] {
+			t.Errorf("target %d = %+v, want %+v", i, tg, want[i])
+		}
+	}
+
+	bad := []config.HeartbeatItemConfig{
+		{Prompt: "no name"},
+		{Name: "x"},
+		{Name: "x", Prompt: "p", Targets: []string{"telegram"}},
+		{Name: "x", Checks: []config.HeartbeatCheckConfig{{Type: "ping"}}},
+		{Name: "x", Checks: []config.HeartbeatCheckConfig{{Type: "http"}}},
+	}
+	for _, ic := range bad {
+		if _, err := ItemsFromConfig(config.HeartbeatConfig{Items: []config.HeartbeatItemConfig{ic}}); err == nil {
+			t.Errorf("expected error for %+v", ic)
+		}
+	}
+}
+
+func TestRunChecks_HTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	results := RunChecks(t.Context(), []config.HeartbeatCheckConfig{
+		{Type: "http", URL: srv.URL + "/up"},
+		{Type: "http", URL: srv.URL + "/down"},
+		{Type: "http", URL: srv.URL + "/up", ExpectStatus: 201},
+	})
+	if !results[0].OK || results[1].OK || results[2].OK {
+		t.Errorf("unexpected results: %+v", results)
+	}
+	if allPassed(results) {
+		t.Error("allPassed should be false")
+	}
+	if out := formatResults(results); !strings.Contains(out, "[FAIL]") || !strings.Contains(out, "status 503") {
+		t.Errorf("unexpected formatted results: %q", out)
+	}
+}
+
+func newTestService(t *testing.T, reply string) (*HeartbeatService, *bus.MessageBus, chan string) {
+	t.Helper()
+	hs := NewHeartbeatService(t.TempDir(), 60, true)
+	msgBus := bus.NewMessageBus()
+	prompts := make(chan string, 10)
+	hs.SetOnHeartbeat(func(agentID, prompt, sessionKey, channel, chatID string) (string, error) {
+		prompts <- agentID + "|" + sessionKey + "|" + channel + ":" + chatID + "|" + prompt
+		return reply, nil
+	})
+	hs.SetDelivery(msgBus, "telegram", "100")
+	return hs, msgBus, prompts
+}
+
+func drainOutbound(msgBus *bus.MessageBus) []bus.OutboundMessage {
+	var out []bus.OutboundMessage
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		msg, ok := msgBus.SubscribeOutbound(ctx)
+		cancel()
+		if !ok {
+			return out
+		}
+		out = append(out, msg)
+	}
+}
+
+func TestRunItem_MultiTargetAndDedup(t *testing.T) {
+	hs, msgBus, prompts := newTestService(t, "Backups are failing")
+	hs.SetDedupWindow(time.Hour)
+	item := Item{
+		Name:    "backups",
+		Prompt:  "check backups",
+		Agent:   "ops",
+		Targets: []Target{{"telegram", "1"}, {"discord", "2"}},
+	}
+
+	hs.runItem(item)
+	got := <-prompts
+	if !strings.HasPrefix(got, "ops|heartbeat:backups|telegram:1|") || !strings.Contains(got, "check backups") {
+		t.Errorf("unexpected handler call: %q", got)
+	}
+	if out := drainOutbound(msgBus); len(out) != 2 || out[1].Channel != "discord" {
+		t.Fatalf("expected delivery to both targets, got %+v", out)
+	}
+
+	hs.runItem(item)
+	<-prompts
+	if out := drainOutbound(msgBus); len(out) != 0 {
+		t.Errorf("duplicate alert should be suppressed, got %+v", out)
+	}
+}
+
+func TestRunItem_DefaultTargetAndOK(t *testing.T) {
+	hs, msgBus, prompts := newTestService(t, "HEARTBEAT_OK")
+	hs.runItem(Item{Name: defaultItemName})
+	got := <-prompts
+	if !strings.HasPrefix(got, "|heartbeat:system|telegram:100|") || !strings.Contains(got, "# Heartbeat Check") {
+		t.Errorf("legacy item should use HEARTBEAT.md prompt, got %q", got)
+	}
+	if out := drainOutbound(msgBus); len(out) != 0 {
+		t.Errorf("OK reply should not be delivered, got %+v", out)
+	}
+}
+
+func TestRunItem_OnlyOnFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	hs, _, prompts := newTestService(t, "alert")
+	hs.runItem(Item{
+		Name:          "site",
+		Checks:        []config.HeartbeatCheckConfig{{Type: "http", URL: srv.URL}},
+		OnlyOnFailure: true,
+	})
+	select {
+	case p := <-prompts:
+		t.Errorf("agent should not run when checks pass, got %q", p)
+	default:
+	}
+}
+
+func TestRunDue_PerItemInterval(t *testing.T) {
+	hs, _, prompts := newTestService(t, "HEARTBEAT_OK")
+	hs.SetItems([]Item{
+		{Name: "fast", Prompt: "a", Interval: time.Second},
+		{Name: "slow", Prompt: "b"},
+	})
+	if err := hs.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer hs.Stop()
+	start := time.Now()
+	hs.mu.Lock()
+	hs.stateFor("fast").lastRun = start
+	hs.stateFor("slow").lastRun = start
+	hs.mu.Unlock()
+
+	hs.runDue(start.Add(2 * time.Second))
+	select {
+	case p := <-prompts:
+		if !strings.Contains(p, "heartbeat:fast") {
+			t.Errorf("expected fast item, got %q", p)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("fast item did not run")
+	}
+	select {
+	case p := <-prompts:
+		t.Errorf("slow item should not be due yet, got %q", p)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestStart_RunsItems(t *testing.T) {
+	hs, _, prompts := newTestService(t, "HEARTBEAT_OK")
+	hs.tick = 10 * time.Millisecond
+	hs.SetItems([]Item{{Name: "fast", Prompt: "a", Interval: 20 * time.Millisecond}})
+
+	if err := hs.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := hs.Start(); err != nil {
+		t.Fatalf("second Start: %v", err)
+	}
+	select {
+	case p := <-prompts:
+		if !strings.Contains(p, "heartbeat:fast") {
+			t.Errorf("expected fast item, got %q", p)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("item did not run after Start")
+	}
+	hs.Stop()
+	hs.Stop()
+
+	if err := NewHeartbeatService(t.TempDir(), 60, false).Start(); err == nil {
+		t.Error("disabled service should not start")
+	}
+}
@@ -0,0 +1,115 @@
+package logger
+
+import (
+	"context"
+	"runtime"
+)
+
+type fieldsKey struct{}
+
+var contextExtractors []func(ctx context.Context) map[string]interface{}
+
+// WithFields returns ctx carrying fields that the *Ctx functions and the
+// slog handler add to every entry logged with it. Fields accumulate across
+// calls; later values win.
+func WithFields(ctx context.Context, fields map[string]interface{}) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+	parent, _ := ctx.Value(fieldsKey{}).(map[string]interface{})
+	merged := make(map[string]interface{}, len(parent)+len(fields))
+	for k, v := range parent {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// WithSession attaches the session key and agent ID to ctx. Empty values
+// are skipped.
+func WithSession(ctx context.Context, sessionKey, agentID string) context.Context {
+	fields := make(map[string]interface{}, 2)
+	if sessionKey != "" {
+		fields["session_key"] = sessionKey
+	}
+	if agentID != "" {
+		fields["agent_id"] = agentID
+	}
+	return WithFields(ctx, fields)
+}
+
+// RegisterContextFields adds fn to the extractors consulted by
+// ContextFields. It lets packages this one cannot import contribute fields,
+// such as the tracing package's trace_id. Call it from init.
+func RegisterContextFields(fn func(ctx context.Context) map[string]interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+	contextExtractors = append(contextExtractors, fn)
+}
+
+// ContextFields returns the fields attached to ctx merged with fields; keys
+// in fields win. The result is a new map, or nil when both are empty.
+func ContextFields(ctx context.Context, fields map[string]interface{}) map[string]interface{} {
+	if ctx == nil {
+		return fields
+	}
+	mu.RLock()
+	extractors := contextExtractors
+	mu.RUnlock()
+
+	var merged map[string]interface{}
+	add := func(src map[string]interface{}) {
+		if len(src) == 0 {
+			return
+		}
+		if merged == nil {
+			merged = make(map[string]interface{}, len(src)+len(fields))
+		}
+		for k, v := range src {
+			merged[k] = v
+		}
+	}
+	attached, _ := ctx.Value(fieldsKey{}).(map[string]interface{})
+	add(attached)
+	for _, fn := range extractors {
+		add(fn(ctx))
+	}
+	add(fields)
+	return merged
+}
+
+func logContext(ctx context.Context, level LogLevel, component, message string, fields map[string]interface{}) {
+	if !LevelEnabled(component, level) {
+		return
+	}
+	entry := newEntry(level, component, message, ContextFields(ctx, fields))
+	if callerEnabled() {
+		// logContext <- DebugCtx etc. <- the caller
+		if pc, file, line, ok := runtime.Caller(2); ok {
+			entry.Caller = formatCaller(pc, file, line)
+		}
+	}
+	emit(level, entry)
+}
+
+// DebugCtx logs like DebugCF and adds the fields carried by ctx.
+func DebugCtx(ctx context.Context, component string, message string, fields map[string]interface{}) {
+	logContext(ctx, DEBUG, component, message, fields)
+}
+
+// InfoCtx logs like InfoCF and adds the fields carried by ctx.
+func InfoCtx(ctx context.Context, component string, message string, fields map[string]interface{}) {
+	logContext(ctx, INFO, component, message, fields)
+}
+
+// WarnCtx logs like WarnCF and adds the fields carried by ctx.
+func WarnCtx(ctx context.Context, component string, message string, fields map[string]interface{}) {
+	logContext(ctx, WARN, component, message, fields)
+}
+
+// ErrorCtx logs like ErrorCF and adds the fields carried by ctx.
+func ErrorCtx(ctx context.Context, component string, message string, fields map[string]interface{}) {
+	logContext(ctx, ERROR, component, message, fields)
+}
@@ -3,9 +3,10 @@ package logger
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -30,14 +31,17 @@ var (
 		FATAL: "FATAL",
 	}
 
-	currentLevel = INFO
-	logger       *Logger
-	once         sync.Once
-	mu           sync.RWMutex
+	currentLevel    = INFO
+	componentLevels map[string]LogLevel
+	logger          *Logger
+	once            sync.Once
+	mu              sync.RWMutex
 )
 
 type Logger struct {
-	file *os.File
+	file    io.WriteCloser
+	console io.Writer
+	caller  bool
 }
 
 type LogEntry struct {
@@ -51,10 +55,34 @@ type LogEntry struct {
 
 func init() {
 	once.Do(func() {
-		logger = &Logger{}
+		logger = &Logger{console: os.Stderr}
 	})
 }
 
+func (l LogLevel) String() string {
+	if name, ok := logLevelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("LEVEL(%d)", int(l))
+}
+
+// ParseLevel parses a level name such as "debug" or "WARN".
+func ParseLevel(s string) (LogLevel, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "DEBUG":
+		return DEBUG, nil
+	case "INFO":
+		return INFO, nil
+	case "WARN", "WARNING":
+		return WARN, nil
+	case "ERROR":
+		return ERROR, nil
+	case "FATAL":
+		return FATAL, nil
+	}
+	return INFO, fmt.Errorf("unknown log level %q (want debug, info, warn, error or fatal)", s)
+}
+
 func SetLevel(level LogLevel) {
 	mu.Lock()
 	defer mu.Unlock()
@@ -67,21 +95,86 @@ func GetLevel() LogLevel {
 	return currentLevel
 }
 
-func EnableFileLogging(filePath string) error {
+// SetComponentLevel overrides the level for one component. A component
+// "a.b" falls back to the level of "a", then to the global level.
+func SetComponentLevel(component string, level LogLevel) {
+	mu.Lock()
+	defer mu.Unlock()
+	if componentLevels == nil {
+		componentLevels = make(map[string]LogLevel)
+	}
+	componentLevels[component] = level
+}
+
+// SetComponentLevels replaces all component overrides; nil clears them.
+func SetComponentLevels(levels map[string]LogLevel) {
+	mu.Lock()
+	defer mu.Unlock()
+	componentLevels = make(map[string]LogLevel, len(levels))
+	for c, l := range levels {
+		componentLevels[c] = l
+	}
+}
+
+// LevelEnabled reports whether a message at level from component is logged.
+func LevelEnabled(component string, level LogLevel) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return level >= levelFor(component)
+}
+
+// levelFor must be called with mu held.
+func levelFor(component string) LogLevel {
+	for c := component; c != ""; {
+		if l, ok := componentLevels[c]; ok {
+			return l
+		}
+		i := strings.LastIndex(c, ".")
+		if i < 0 {
+			break
+		}
+		c = c[:i]
+	}
+	return currentLevel
+}
+
+// SetCaller turns on recording the file and line of each log call. It is
+// off by default because runtime.Caller is comparatively expensive.
+func SetCaller(enabled bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	logger.caller = enabled
+}
+
+// SetConsoleOutput changes where the human-readable lines go (stderr by
+// default); nil silences the console.
+func SetConsoleOutput(w io.Writer) {
 	mu.Lock()
 	defer mu.Unlock()
+	logger.console = w
+}
 
-	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+// EnableFileLogging appends JSON entries to filePath without rotation.
+func EnableFileLogging(filePath string) error {
+	return EnableRotatingFileLogging(filePath, RotateOptions{})
+}
+
+// EnableRotatingFileLogging appends JSON entries to filePath, rotating it
+// as described by opts.
+func EnableRotatingFileLogging(filePath string, opts RotateOptions) error {
+	file, err := NewRotatingFile(filePath, opts)
 	if err != nil {
 		return fmt.Errorf("failed to open log file: %w", err)
 	}
 
+	mu.Lock()
 	if logger.file != nil {
 		logger.file.Close()
 	}
-
 	logger.file = file
-	log.Println("File logging enabled:", filePath)
+	mu.Unlock()
+
+	InfoCF("logger", "File logging enabled", map[string]interface{}{"path": filePath})
 	return nil
 }
 
@@ -92,55 +185,80 @@ func DisableFileLogging() {
 	if logger.file != nil {
 		logger.file.Close()
 		logger.file = nil
-		log.Println("File logging disabled")
 	}
 }
 
 func logMessage(level LogLevel, component string, message string, fields map[string]interface{}) {
-	if level < currentLevel {
+	if !LevelEnabled(component, level) {
 		return
 	}
+	entry := newEntry(level, component, message, fields)
+	if callerEnabled() {
+		// logMessage <- DebugCF etc. <- the caller
+		if pc, file, line, ok := runtime.Caller(2); ok {
+			entry.Caller = formatCaller(pc, file, line)
+		}
+	}
+	emit(level, entry)
+}
 
-	entry := LogEntry{
+func newEntry(level LogLevel, component, message string, fields map[string]interface{}) LogEntry {
+	return LogEntry{
 		Level:     logLevelNames[level],
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 		Component: component,
 		Message:   message,
 		Fields:    fields,
 	}
+}
 
-	if pc, file, line, ok := runtime.Caller(2); ok {
-		fn := runtime.FuncForPC(pc)
-		if fn != nil {
-			entry.Caller = fmt.Sprintf("%s:%d (%s)", file, line, fn.Name())
-		}
+func callerEnabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return logger.caller
+}
+
+func formatCaller(pc uintptr, file string, line int) string {
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		return fmt.Sprintf("%s:%d (%s)", file, line, fn.Name())
 	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
 
-	if logger.file != nil {
+// emit writes entry to the log file and the console.
+func emit(level LogLevel, entry LogEntry) {
+	mu.RLock()
+	file, console := logger.file, logger.console
+	mu.RUnlock()
+
+	if file != nil {
 		jsonData, err := json.Marshal(entry)
 		if err == nil {
-			logger.file.WriteString(string(jsonData) + "\n")
+			file.Write(append(jsonData, '\n'))
 		}
 	}
+	if console != nil {
+		io.WriteString(console, FormatEntry(entry)+"\n")
+	}
 
-	var fieldStr string
-	if len(fields) > 0 {
-		fieldStr = " " + formatFields(fields)
+	if level == FATAL {
+		os.Exit(1)
 	}
+}
 
-	logLine := fmt.Sprintf("[%s] [%s]%s %s%s",
+// FormatEntry renders entry as a single human-readable line.
+func FormatEntry(entry LogEntry) string {
+	var fieldStr string
+	if len(entry.Fields) > 0 {
+		fieldStr = " " + formatFields(entry.Fields)
+	}
+	return fmt.Sprintf("[%s] [%s]%s %s%s",
 		entry.Timestamp,
-		logLevelNames[level],
-		formatComponent(component),
-		message,
+		entry.Level,
+		formatComponent(entry.Component),
+		entry.Message,
 		fieldStr,
 	)
-
-	log.Println(logLine)
-
-	if level == FATAL {
-		os.Exit(1)
-	}
 }
 
 func formatComponent(component string) string {
@@ -151,9 +269,14 @@ func formatComponent(component string) string {
 }
 
 func formatFields(fields map[string]interface{}) string {
-	var parts []string
-	for k, v := range fields {
-		parts = append(parts, fmt.Sprintf("%s=%v", k, v))
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, fields[k]))
 	}
 	return fmt.Sprintf("{%s}", strings.Join(parts, ", "))
 }
@@ -1,6 +1,13 @@
 package logger
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -137,3 +144,136 @@ func TestLoggerHelperFunctions(t *testing.T) {
 	DebugC("test", "Debug with component")
 	WarnF("Warning with fields", map[string]interface{}{"key": "value"})
 }
+
+// captureEntries sends file output to a temp file and returns a function
+// that reads the entries written so far.
+func captureEntries(t *testing.T) func() []LogEntry {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.log")
+	if err := EnableFileLogging(path); err != nil {
+		t.Fatal(err)
+	}
+	SetConsoleOutput(io.Discard)
+	initialLevel := GetLevel()
+	t.Cleanup(func() {
+		DisableFileLogging()
+		SetConsoleOutput(os.Stderr)
+		SetComponentLevels(nil)
+		SetLevel(initialLevel)
+	})
+	return func() []LogEntry {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		var entries []LogEntry
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			entry, err := ParseEntry(scanner.Bytes())
+			if err != nil {
+				t.Fatalf("bad line %q: %v", scanner.Text(), err)
+			}
+			if entry.Component != "logger" {
+				entries = append(entries, entry)
+			}
+		}
+		return entries
+	}
+}
+
+func TestComponentLevels(t *testing.T) {
+	read := captureEntries(t)
+	SetLevel(INFO)
+	SetComponentLevels(map[string]LogLevel{"agent": DEBUG, "channels": WARN})
+
+	DebugCF("agent", "agent debug", nil)
+	DebugCF("agent.delegate", "sub-component debug", nil)
+	InfoCF("channels", "channels info", nil)
+	WarnCF("channels", "channels warn", nil)
+	DebugCF("cost", "cost debug", nil)
+	InfoCF("cost", "cost info", nil)
+
+	var got []string
+	for _, e := range read() {
+		got = append(got, e.Message)
+	}
+	want := []string{"agent debug", "sub-component debug", "channels warn", "cost info"}
+	if len(got) != len(want) {
+		t.Fatalf("logged %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("entry %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestContextFields(t *testing.T) {
+	read := captureEntries(t)
+	RegisterContextFields(func(ctx context.Context) map[string]interface{} {
+		if id, ok := ctx.Value("test-trace").(string); ok {
+			return map[string]interface{}{"trace_id": id}
+		}
+		return nil
+	})
+
+	ctx := WithSession(context.Background(), "telegram:42", "main")
+	ctx = WithFields(ctx, map[string]interface{}{"channel": "telegram"})
+	ctx = context.WithValue(ctx, "test-trace", "abc")
+	InfoCtx(ctx, "agent", "turn", map[string]interface{}{"channel": "override"})
+	InfoCtx(context.Background(), "agent", "plain", nil)
+
+	entries := read()
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries", len(entries))
+	}
+	f := entries[0].Fields
+	if f["session_key"] != "telegram:42" || f["agent_id"] != "main" || f["trace_id"] != "abc" || f["channel"] != "override" {
+		t.Errorf("fields = %v", f)
+	}
+	if entries[1].Fields != nil {
+		t.Errorf("plain entry fields = %v", entries[1].Fields)
+	}
+}
+
+func TestSlogHandler(t *testing.T) {
+	read := captureEntries(t)
+	SetLevel(INFO)
+	SetCaller(true)
+	defer SetCaller(false)
+
+	l := slog.New(NewSlogHandler("lib")).With("version", 2).WithGroup("req")
+	l.Debug("hidden")
+	l.Info("request done", "status", 200, "err", errors.New("boom"))
+	slog.New(NewSlogHandler("lib")).Warn("override", "component", "other")
+
+	entries := read()
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries: %v", len(entries), entries)
+	}
+	e := entries[0]
+	if e.Component != "lib" || e.Level != "INFO" || e.Message != "request done" {
+		t.Errorf("entry = %+v", e)
+	}
+	if e.Fields["version"] != float64(2) || e.Fields["req.status"] != float64(200) || e.Fields["req.err"] != "boom" {
+		t.Errorf("fields = %v", e.Fields)
+	}
+	if e.Caller == "" {
+		t.Error("caller not recorded")
+	}
+	if entries[1].Component != "other" || entries[1].Level != "WARN" {
+		t.Errorf("component attribute ignored: %+v", entries[1])
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for in, want := range map[string]LogLevel{"debug": DEBUG, "Info": INFO, "warning": WARN, "ERROR": ERROR} {
+		if got, err := ParseLevel(in); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v", in, got, err)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Query selects log file entries, as used by "picoclaw logs".
+type Query struct {
+	// MinLevel drops entries below this level.
+	MinLevel LogLevel
+	// Components keeps entries from these components or their
+	// sub-components ("agent" matches "agent.delegate"); empty keeps all.
+	Components []string
+	// Fields keeps entries whose fields have these values, e.g.
+	// {"trace_id": "..."}.
+	Fields map[string]string
+	// Contains keeps entries whose message or fields contain this text,
+	// case-insensitively.
+	Contains string
+	// Since drops entries logged before this time.
+	Since time.Time
+}
+
+// ParseEntry decodes one line of a log file.
+func ParseEntry(line []byte) (LogEntry, error) {
+	var entry LogEntry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return entry, err
+	}
+	if entry.Level == "" || entry.Timestamp == "" {
+		return entry, fmt.Errorf("not a log entry")
+	}
+	return entry, nil
+}
+
+// Match reports whether entry passes every filter in q.
+func (q Query) Match(entry LogEntry) bool {
+	if level, err := ParseLevel(entry.Level); err == nil && level < q.MinLevel {
+		return false
+	}
+	if len(q.Components) > 0 && !matchComponent(q.Components, entry.Component) {
+		return false
+	}
+	for k, want := range q.Fields {
+		got, ok := entry.Fields[k]
+		if !ok || fmt.Sprint(got) != want {
+			return false
+		}
+	}
+	if !q.Since.IsZero() {
+		t, err := time.Parse(time.RFC3339, entry.Timestamp)
+		if err != nil || t.Before(q.Since.Truncate(time.Second)) {
+			return false
+		}
+	}
+	if q.Contains != "" {
+		text := strings.ToLower(entry.Message)
+		if len(entry.Fields) > 0 {
+			text += " " + strings.ToLower(formatFields(entry.Fields))
+		}
+		if !strings.Contains(text, strings.ToLower(q.Contains)) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchComponent(components []string, component string) bool {
+	for _, c := range components {
+		if component == c || strings.HasPrefix(component, c+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the timestamp in rotated file names:
+// picoclaw.log -> picoclaw-2026-01-02T15-04-05.000.log
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotateOptions controls when a RotatingFile rotates and which rotated
+// files it keeps. Zero values disable the corresponding limit.
+type RotateOptions struct {
+	// MaxSizeMB rotates the file before it grows past this size.
+	MaxSizeMB int
+	// Interval rotates the file when a new period starts, e.g. 24h rotates
+	// at midnight UTC.
+	Interval time.Duration
+	// MaxBackups is the number of rotated files to keep.
+	MaxBackups int
+	// MaxAge deletes rotated files older than this.
+	MaxAge time.Duration
+}
+
+// RotatingFile is an append-only file that is renamed aside and reopened
+// when it gets too large or too old.
+type RotatingFile struct {
+	mu     sync.Mutex
+	path   string
+	opts   RotateOptions
+	file   *os.File
+	size   int64
+	period time.Time
+}
+
+// NewRotatingFile opens (or creates) path for appending, creating its
+// directory if needed.
+func NewRotatingFile(path string, opts RotateOptions) (*RotatingFile, error) {
+	r := &RotatingFile{path: path, opts: opts}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file = f
+	r.size = info.Size()
+	r.period = r.periodOf(info.ModTime())
+	return nil
+}
+
+func (r *RotatingFile) periodOf(t time.Time) time.Time {
+	if r.opts.Interval <= 0 {
+		return time.Time{}
+	}
+	return t.UTC().Truncate(r.opts.Interval)
+}
+
+// Write appends p, rotating first when p would cross a size or time limit.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	if r.size == 0 {
+		r.period = r.periodOf(time.Now())
+	} else if r.due(int64(len(p))) {
+		// A failed rotation keeps the current file open when it can, so
+		// entries are still written
+		if err := r.rotate(); err != nil && r.file == nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *RotatingFile) due(next int64) bool {
+	if r.opts.MaxSizeMB > 0 && r.size+next > int64(r.opts.MaxSizeMB)*1024*1024 {
+		return true
+	}
+	return r.opts.Interval > 0 && !r.periodOf(time.Now()).Equal(r.period)
+}
+
+// Rotate moves the current file aside and starts a new one.
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return os.ErrClosed
+	}
+	return r.rotate()
+}
+
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+	ext := filepath.Ext(r.path)
+	backup := strings.TrimSuffix(r.path, ext) + "-" + time.Now().UTC().Format(backupTimeFormat) + ext
+	if err := os.Rename(r.path, backup); err != nil && !os.IsNotExist(err) {
+		return r.reopen(err)
+	}
+	if err := r.open(); err != nil {
+		// Put the old file back so logging continues where it was
+		os.Rename(backup, r.path)
+		return r.reopen(err)
+	}
+	r.prune()
+	return nil
+}
+
+// reopen opens the original path again after a failed rotation and returns
+// err. The period is reset so a time-based rotation is not retried on every
+// write.
+func (r *RotatingFile) reopen(err error) error {
+	if openErr := r.open(); openErr != nil {
+		return openErr
+	}
+	r.period = r.periodOf(time.Now())
+	return err
+}
+
+// prune deletes rotated files beyond MaxBackups or older than MaxAge.
+func (r *RotatingFile) prune() {
+	if r.opts.MaxBackups <= 0 && r.opts.MaxAge <= 0 {
+		return
+	}
+	backups := Backups(r.path)
+	for i, b := range backups {
+		expired := r.opts.MaxAge > 0 && time.Since(b.Time) > r.opts.MaxAge
+		if (r.opts.MaxBackups > 0 && i >= r.opts.MaxBackups) || expired {
+			os.Remove(b.Path)
+		}
+	}
+}
+
+// Close closes the file; later writes fail.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// Backup is a rotated log file.
+type Backup struct {
+	Path string
+	Time time.Time
+}
+
+// Backups lists the rotated files of path, newest first.
+func Backups(path string) []Backup {
+	ext := filepath.Ext(path)
+	prefix := filepath.Base(strings.TrimSuffix(path, ext)) + "-"
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return nil
+	}
+	var backups []Backup
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		t, err := time.Parse(backupTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Backup{Path: filepath.Join(filepath.Dir(path), name), Time: t})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Time.After(backups[j].Time) })
+	return backups
+}
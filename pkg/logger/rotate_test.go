@@ -0,0 +1,158 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFileSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "picoclaw.log")
+	r, err := NewRotatingFile(path, RotateOptions{MaxSizeMB: 1, MaxBackups: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	line := []byte(strings.Repeat("x", 400*1024) + "\n")
+	for i := 0; i < 10; i++ {
+		if _, err := r.Write(line); err != nil {
+			t.Fatal(err)
+		}
+		// Backup names have millisecond resolution
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() > 1024*1024 {
+		t.Errorf("current file is %d bytes, want at most 1MB", info.Size())
+	}
+	if backups := Backups(path); len(backups) != 2 {
+		t.Errorf("kept %d backups, want 2", len(backups))
+	}
+}
+
+func TestRotatingFileInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "picoclaw.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	yesterday := time.Now().Add(-25 * time.Hour)
+	os.Chtimes(path, yesterday, yesterday)
+
+	r, err := NewRotatingFile(path, RotateOptions{Interval: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.Write([]byte("new\n"))
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "new\n" {
+		t.Errorf("current file = %q, want only the new line", data)
+	}
+	backups := Backups(path)
+	if len(backups) != 1 {
+		t.Fatalf("backups = %v", backups)
+	}
+	old, _ := os.ReadFile(backups[0].Path)
+	if string(old) != "old\n" {
+		t.Errorf("backup = %q", old)
+	}
+}
+
+func TestRotatingFileMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "picoclaw.log")
+	stale := filepath.Join(dir, "picoclaw-"+time.Now().Add(-48*time.Hour).UTC().Format(backupTimeFormat)+".log")
+	os.WriteFile(stale, []byte("stale\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "other.log"), nil, 0644)
+
+	r, err := NewRotatingFile(path, RotateOptions{MaxAge: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.Write([]byte("line\n"))
+	if err := r.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("stale backup was not deleted")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "other.log")); err != nil {
+		t.Error("unrelated file was deleted")
+	}
+	if backups := Backups(path); len(backups) != 1 {
+		t.Errorf("backups = %v", backups)
+	}
+}
+
+func TestRotatingFileRenameFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "picoclaw.log")
+	r, err := NewRotatingFile(path, RotateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.Write([]byte("before\n"))
+
+	// Directories at every backup name for the next second make the rename fail
+	start := time.Now()
+	for ms := 0; ms < 1000; ms++ {
+		name := "picoclaw-" + start.Add(time.Duration(ms)*time.Millisecond).UTC().Format(backupTimeFormat) + ".log"
+		if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Rotate(); err == nil {
+		t.Fatal("expected the rotation to fail")
+	}
+
+	if _, err := r.Write([]byte("after\n")); err != nil {
+		t.Fatalf("write after failed rotation: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "before\nafter\n" {
+		t.Errorf("current file = %q, want both lines", data)
+	}
+}
+
+func TestQueryMatch(t *testing.T) {
+	entry := LogEntry{
+		Level:     "WARN",
+		Timestamp: "2026-03-01T10:00:00Z",
+		Component: "agent.delegate",
+		Message:   "Tool call failed",
+		Fields:    map[string]interface{}{"trace_id": "abc", "tool": "exec"},
+	}
+	since, _ := time.Parse(time.RFC3339, "2026-03-01T09:00:00Z")
+	tests := []struct {
+		name string
+		q    Query
+		want bool
+	}{
+		{"empty", Query{}, true},
+		{"level", Query{MinLevel: ERROR}, false},
+		{"component prefix", Query{Components: []string{"agent"}}, true},
+		{"other component", Query{Components: []string{"channels", "age"}}, false},
+		{"trace", Query{Fields: map[string]string{"trace_id": "abc"}}, true},
+		{"wrong trace", Query{Fields: map[string]string{"trace_id": "def"}}, false},
+		{"contains field", Query{Contains: "EXEC"}, true},
+		{"contains missing", Query{Contains: "telegram"}, false},
+		{"since", Query{Since: since}, true},
+		{"since later", Query{Since: since.Add(2 * time.Hour)}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.q.Match(entry); got != tt.want {
+			t.Errorf("%s: Match = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
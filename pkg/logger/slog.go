@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// SlogHandler is a slog.Handler that writes records through this package,
+// so libraries that log with log/slog share its levels, file and format.
+// A "component" attribute overrides the handler's component.
+type SlogHandler struct {
+	component string
+	prefix    string
+	attrs     map[string]interface{}
+}
+
+// NewSlogHandler returns a handler that logs as component.
+func NewSlogHandler(component string) *SlogHandler {
+	return &SlogHandler{component: component}
+}
+
+// InstallSlog makes a handler for component the slog default. Since
+// slog.SetDefault also redirects the standard log package, log.Printf
+// output ends up here too, at INFO.
+func InstallSlog(component string) {
+	slog.SetDefault(slog.New(NewSlogHandler(component)))
+}
+
+func fromSlogLevel(l slog.Level) LogLevel {
+	switch {
+	case l < slog.LevelInfo:
+		return DEBUG
+	case l < slog.LevelWarn:
+		return INFO
+	case l < slog.LevelError:
+		return WARN
+	}
+	return ERROR
+}
+
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return LevelEnabled(h.component, fromSlogLevel(level))
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := make(map[string]interface{}, len(h.attrs)+r.NumAttrs())
+	for k, v := range h.attrs {
+		fields[k] = v
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(fields, h.prefix, a)
+		return true
+	})
+
+	component := h.component
+	if c, ok := fields["component"].(string); ok {
+		component = c
+		delete(fields, "component")
+	}
+	level := fromSlogLevel(r.Level)
+	if !LevelEnabled(component, level) {
+		return nil
+	}
+
+	if len(fields) == 0 {
+		fields = nil
+	}
+	entry := newEntry(level, component, r.Message, ContextFields(ctx, fields))
+	if !r.Time.IsZero() {
+		entry.Timestamp = r.Time.UTC().Format(time.RFC3339)
+	}
+	if r.PC != 0 && callerEnabled() {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		entry.Caller = formatCaller(r.PC, frame.File, frame.Line)
+	}
+	emit(level, entry)
+	return nil
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = make(map[string]interface{}, len(h.attrs)+len(attrs))
+	for k, v := range h.attrs {
+		h2.attrs[k] = v
+	}
+	for _, a := range attrs {
+		addAttr(h2.attrs, h.prefix, a)
+	}
+	return &h2
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// addAttr flattens a into fields, joining group names with dots.
+func addAttr(fields map[string]interface{}, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		p := prefix
+		if a.Key != "" {
+			p += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			addAttr(fields, p, ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	if err, ok := v.Any().(error); ok {
+		fields[prefix+a.Key] = err.Error()
+		return
+	}
+	fields[prefix+a.Key] = v.Any()
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	_ "modernc.org/sqlite"
@@ -63,11 +64,16 @@ var ValidCategories = map[string]bool{
 	"custom":       true,
 }
 
+// StoreHook is called after a memory entry has been stored successfully.
+type StoreHook func(key, content, category, owner string)
+
 // MemoryDB manages the SQLite-backed memory database.
 type MemoryDB struct {
 	db        *sql.DB
 	workspace string
 	dbPath    string
+	hookMu    sync.RWMutex
+	onStore   StoreHook
 }
 
 // Open creates or opens the memory database at workspace/memory/memory.db.
@@ -137,6 +143,13 @@ func Open(workspace string) (*MemoryDB, error) {
 	return mdb, nil
 }
 
+// SetStoreHook registers a callback invoked after every successful Store.
+func (m *MemoryDB) SetStoreHook(hook StoreHook) {
+	m.hookMu.Lock()
+	defer m.hookMu.Unlock()
+	m.onStore = hook
+}
+
 // Close closes the database connection.
 func (m *MemoryDB) Close() error {
 	if m.db != nil {
@@ -150,6 +163,17 @@ func (m *MemoryDB) DBPath() string {
 	return m.dbPath
 }
 
+// SizeBytes returns the on-disk size of the database, including its WAL file.
+func (m *MemoryDB) SizeBytes() int64 {
+	var total int64
+	for _, path := range []string{m.dbPath, m.dbPath + "-wal"} {
+		if fi, err := os.Stat(path); err == nil {
+			total += fi.Size()
+		}
+	}
+	return total
+}
+
 // Workspace returns the workspace path.
 func (m *MemoryDB) Workspace() string {
 	return m.workspace
@@ -40,7 +40,17 @@ func (m *MemoryDB) Store(key, content, category, owner string) error {
 	`, key, content, category, owner, createdAt, now); err != nil {
 		return fmt.Errorf("store memory: %w", err)
 	}
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	m.hookMu.RLock()
+	onStore := m.onStore
+	m.hookMu.RUnlock()
+	if onStore != nil {
+		onStore(key, content, category, owner)
+	}
+	return nil
 }
 
 // Get retrieves a memory entry by key. Returns nil if not found.
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounterVec("test_requests_total", "Requests.", "route", "status")
+	depth := r.NewGaugeVec("test_queue_depth", "Queue depth.", "queue")
+	latency := r.NewHistogramVec("test_latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	size := r.NewGaugeVec("test_size_bytes", "Size.")
+
+	requests.Inc("/a", "ok")
+	requests.Add(2, "/a", "ok")
+	requests.Inc(`/b"x`, "error")
+	depth.Set(3, "inbound")
+	queued := 7
+	depth.SetFunc(func() float64 { return float64(queued) }, "outbound")
+	latency.Observe(0.05, "/a")
+	latency.Observe(0.1, "/a")
+	latency.Observe(5, "/a")
+	size.Set(1024)
+	queued = 9
+
+	var b strings.Builder
+	r.WriteText(&b)
+	got := b.String()
+
+	for _, want := range []string{
+		"# HELP test_requests_total Requests.\n# TYPE test_requests_total counter\n",
+		`test_requests_total{route="/a",status="ok"} 3`,
+		`test_requests_total{route="/b\"x",status="error"} 1`,
+		"# TYPE test_queue_depth gauge\n",
+		`test_queue_depth{queue="inbound"} 3`,
+		`test_queue_depth{queue="outbound"} 9`,
+		"# TYPE test_latency_seconds histogram\n",
+		`test_latency_seconds_bucket{route="/a",le="0.1"} 2`,
+		`test_latency_seconds_bucket{route="/a",le="1"} 2`,
+		`test_latency_seconds_bucket{route="/a",le="+Inf"} 3`,
+		`test_latency_seconds_sum{route="/a"} 5.15`,
+		`test_latency_seconds_count{route="/a"} 3`,
+		"test_size_bytes 1024\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Index(got, "test_requests_total") > strings.Index(got, "test_queue_depth") {
+		t.Error("families should be written in registration order")
+	}
+}
+
+func TestDuplicateMetricPanics(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("dup_total", "x")
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a duplicate metric name")
+		}
+	}()
+	r.NewGaugeVec("dup_total", "x")
+}
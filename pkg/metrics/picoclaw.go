@@ -0,0 +1,40 @@
+package metrics
+
+// PicoClaw metrics, registered on Default.
+var (
+	LLMRequests = Default.NewCounterVec("picoclaw_llm_requests_total",
+		"LLM chat requests by provider, model and status (ok or error).", "provider", "model", "status")
+	LLMRequestDuration = Default.NewHistogramVec("picoclaw_llm_request_duration_seconds",
+		"LLM chat request latency in seconds, including rate-limit retries.", LatencyBuckets, "provider", "model")
+	LLMTokens = Default.NewCounterVec("picoclaw_llm_tokens_total",
+		"Tokens reported by LLM providers, by type (prompt or completion).", "provider", "model", "type")
+
+	ToolExecutions = Default.NewCounterVec("picoclaw_tool_executions_total",
+		"Tool executions by tool and status (ok or error).", "tool", "status")
+	ToolDuration = Default.NewHistogramVec("picoclaw_tool_duration_seconds",
+		"Tool execution time in seconds.", LatencyBuckets, "tool")
+
+	MessagesInbound = Default.NewCounterVec("picoclaw_messages_inbound_total",
+		"Messages published to the bus for the agent, by channel.", "channel")
+	MessagesOutbound = Default.NewCounterVec("picoclaw_messages_outbound_total",
+		"Messages published to the bus for delivery, by channel.", "channel")
+	BusQueueDepth = Default.NewGaugeVec("picoclaw_bus_queue_depth",
+		"Messages waiting in the message bus, by queue (inbound or outbound).", "queue")
+
+	CronJobs = Default.NewCounterVec("picoclaw_cron_jobs_total",
+		"Scheduled job runs by schedule kind and status (ok or error).", "kind", "status")
+
+	SecurityDetections = Default.NewCounterVec("picoclaw_security_detections_total",
+		"Security scanner detections by detector and action taken.", "detector", "action")
+
+	MemoryDBBytes = Default.NewGaugeVec("picoclaw_memory_db_bytes",
+		"Size of the memory database files on disk, in bytes.")
+)
+
+// Status returns "error" when err is set and "ok" otherwise.
+func Status(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
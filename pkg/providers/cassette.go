@@ -0,0 +1,475 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// Cassette modes, as set in config.CassetteConfig.Mode.
+const (
+	CassetteOff    = "off"
+	CassetteRecord = "record"
+	CassetteReplay = "replay"
+	CassetteAuto   = "auto"
+)
+
+// Match levels decide which parts of a request must be equal for a
+// recorded response to be served.
+const (
+	// MatchExact compares everything sent: model, every message, tool
+	// definitions and options.
+	MatchExact = "exact"
+	// MatchNormal ignores what differs between machines and runs: the
+	// system prompt (time, workspace path, memory), tool call IDs, tool
+	// descriptions and options.
+	MatchNormal = "normal"
+	// MatchLoose only compares the model and the user messages, so
+	// changing tool results and assistant wording still match.
+	MatchLoose = "loose"
+)
+
+// ErrCassetteMiss is returned in replay mode when no recorded response
+// matches a request.
+var ErrCassetteMiss = errors.New("no recorded response matches this request")
+
+// CassetteRequest is the recorded form of a Chat request.
+type CassetteRequest struct {
+	Model    string                 `json:"model"`
+	Messages []Message              `json:"messages"`
+	Tools    []ToolDefinition       `json:"tools,omitempty"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+// Interaction is one request/response pair in a cassette file.
+type Interaction struct {
+	// Key is the request hash at the match level used when recording; it
+	// is informational, replay recomputes keys at its own level.
+	Key        string          `json:"key"`
+	RecordedAt time.Time       `json:"recorded_at"`
+	Request    CassetteRequest `json:"request"`
+	Response   *LLMResponse    `json:"response,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// Cassette is a JSONL file of interactions. Providers for every agent
+// share one Cassette per file, so concurrent recordings append to the same
+// file and replay serves each recording once.
+type Cassette struct {
+	path  string
+	match string
+
+	mu      sync.Mutex
+	byKey   map[string][]*Interaction
+	served  map[string]int
+	file    *os.File
+	entries int
+}
+
+var (
+	cassettesMu sync.Mutex
+	cassettes   = make(map[string]*Cassette)
+)
+
+// OpenCassette returns the cassette at path, loading it once per process.
+// In record mode the file is truncated on first open, so a recording
+// replaces the previous one; other modes keep what is recorded.
+func OpenCassette(path, mode, match string) (*Cassette, error) {
+	if match == "" {
+		match = MatchNormal
+	}
+	if match != MatchExact && match != MatchNormal && match != MatchLoose {
+		return nil, fmt.Errorf("unknown cassette match level %q", match)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cassettesMu.Lock()
+	defer cassettesMu.Unlock()
+	if c, ok := cassettes[abs]; ok {
+		return c, nil
+	}
+
+	c := &Cassette{path: abs, match: match, byKey: make(map[string][]*Interaction), served: make(map[string]int)}
+	switch mode {
+	case CassetteRecord:
+		if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(abs, nil, 0644); err != nil {
+			return nil, err
+		}
+	case CassetteReplay, CassetteAuto:
+		if err := c.load(); err != nil {
+			if mode == CassetteReplay || !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown cassette mode %q", mode)
+	}
+	cassettes[abs] = c
+	return c, nil
+}
+
+func (c *Cassette) load() error {
+	f, err := os.Open(c.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := strings.TrimSpace(scanner.Text())
+		if data == "" {
+			continue
+		}
+		var in Interaction
+		if err := json.Unmarshal([]byte(data), &in); err != nil {
+			return fmt.Errorf("%s:%d: %w", c.path, line, err)
+		}
+		key := RequestKey(in.Request, c.match)
+		c.byKey[key] = append(c.byKey[key], &in)
+		c.entries++
+	}
+	return scanner.Err()
+}
+
+// Path returns the cassette file.
+func (c *Cassette) Path() string {
+	return c.path
+}
+
+// Len returns the number of interactions loaded or recorded.
+func (c *Cassette) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entries
+}
+
+// Lookup returns the next recorded interaction matching req. Identical
+// requests are served their recordings in order; once those run out the
+// last one is repeated.
+func (c *Cassette) Lookup(req CassetteRequest) (*Interaction, bool) {
+	key := RequestKey(req, c.match)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	recorded := c.byKey[key]
+	if len(recorded) == 0 {
+		return nil, false
+	}
+	i := c.served[key]
+	if i >= len(recorded) {
+		i = len(recorded) - 1
+	}
+	c.served[key] = i + 1
+	return recorded[i], true
+}
+
+// peek reports whether req has a recording, without serving it.
+func (c *Cassette) peek(req CassetteRequest) (string, bool) {
+	key := RequestKey(req, c.match)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return key, len(c.byKey[key]) > 0
+}
+
+// Record appends an interaction to the file.
+func (c *Cassette) Record(req CassetteRequest, resp *LLMResponse, chatErr error) error {
+	in := &Interaction{
+		Key:        RequestKey(req, c.match),
+		RecordedAt: time.Now().UTC(),
+		Request:    req,
+		Response:   resp,
+	}
+	if chatErr != nil {
+		in.Error = chatErr.Error()
+	}
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(c.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		c.file = f
+	}
+	if _, err := c.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	c.byKey[in.Key] = append(c.byKey[in.Key], in)
+	c.served[in.Key]++
+	c.entries++
+	return nil
+}
+
+// RequestKey hashes the parts of req that the match level compares.
+func RequestKey(req CassetteRequest, match string) string {
+	type message struct {
+		Role       string     `json:"role"`
+		Content    string     `json:"content,omitempty"`
+		ToolCalls  []toolCall `json:"tool_calls,omitempty"`
+		ToolCallID string     `json:"tool_call_id,omitempty"`
+	}
+	canonical := struct {
+		Model    string                 `json:"model"`
+		Messages []message              `json:"messages"`
+		Tools    interface{}            `json:"tools,omitempty"`
+		Options  map[string]interface{} `json:"options,omitempty"`
+	}{Model: req.Model}
+
+	// Tool call IDs are assigned by the LLM; number them in order instead
+	ids := make(map[string]string)
+	callID := func(id string) string {
+		if id == "" {
+			return ""
+		}
+		if n, ok := ids[id]; ok {
+			return n
+		}
+		ids[id] = fmt.Sprintf("call_%d", len(ids)+1)
+		return ids[id]
+	}
+
+	for _, m := range req.Messages {
+		switch match {
+		case MatchExact:
+			canonical.Messages = append(canonical.Messages, message{
+				Role: m.Role, Content: m.Content, ToolCalls: toolCalls(m.ToolCalls, nil), ToolCallID: m.ToolCallID,
+			})
+		case MatchLoose:
+			if m.Role == "user" {
+				canonical.Messages = append(canonical.Messages, message{Role: m.Role, Content: strings.TrimSpace(m.Content)})
+			}
+		default:
+			if m.Role == "system" {
+				continue
+			}
+			canonical.Messages = append(canonical.Messages, message{
+				Role:       m.Role,
+				Content:    strings.TrimSpace(m.Content),
+				ToolCalls:  toolCalls(m.ToolCalls, callID),
+				ToolCallID: callID(m.ToolCallID),
+			})
+		}
+	}
+
+	switch match {
+	case MatchExact:
+		canonical.Tools = req.Tools
+		canonical.Options = req.Options
+	case MatchNormal:
+		names := make([]string, 0, len(req.Tools))
+		for _, t := range req.Tools {
+			names = append(names, t.Function.Name)
+		}
+		sort.Strings(names)
+		canonical.Tools = names
+	}
+
+	data, _ := json.Marshal(canonical)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+type toolCall struct {
+	ID        string      `json:"id,omitempty"`
+	Name      string      `json:"name"`
+	Arguments interface{} `json:"arguments,omitempty"`
+}
+
+// toolCalls flattens both tool call shapes to name and decoded arguments,
+// renaming IDs with rename when it is set.
+func toolCalls(calls []ToolCall, rename func(string) string) []toolCall {
+	var out []toolCall
+	for _, tc := range calls {
+		c := toolCall{ID: tc.ID, Name: tc.Name}
+		var args interface{} = tc.Arguments
+		if tc.Function != nil {
+			if c.Name == "" {
+				c.Name = tc.Function.Name
+			}
+			if tc.Arguments == nil && tc.Function.Arguments != "" {
+				var decoded interface{}
+				if json.Unmarshal([]byte(tc.Function.Arguments), &decoded) == nil {
+					args = decoded
+				} else {
+					args = tc.Function.Arguments
+				}
+			}
+		}
+		if m, ok := args.(map[string]interface{}); ok && m == nil {
+			args = nil
+		}
+		c.Arguments = args
+		if rename != nil {
+			c.ID = rename(tc.ID)
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// wrapCassette applies cfg's cassette mode around the provider create
+// would return. Replay mode never calls create, so it needs no API keys.
+func wrapCassette(cfg *config.Config, create func() (LLMProvider, error)) (LLMProvider, error) {
+	cc := cfg.Cassette
+	if cc.Mode == "" || cc.Mode == CassetteOff {
+		return create()
+	}
+	// Cassettes hold whole conversations, so they stay out of the workspace
+	// the agent can read and write
+	cassette, err := OpenCassette(cfg.ResolvePath(cc.Path), cc.Mode, cc.Match)
+	if err != nil {
+		return nil, fmt.Errorf("cassette: %w", err)
+	}
+	if cc.Mode == CassetteReplay {
+		return NewReplayProvider(cassette, nil), nil
+	}
+	inner, err := create()
+	if err != nil {
+		return nil, err
+	}
+	recorder := NewRecordingProvider(inner, cassette)
+	if cc.Mode == CassetteAuto {
+		return NewReplayProvider(cassette, recorder), nil
+	}
+	return recorder, nil
+}
+
+// RecordingProvider passes requests to another provider and writes every
+// request/response pair to a cassette.
+type RecordingProvider struct {
+	inner    LLMProvider
+	cassette *Cassette
+}
+
+func NewRecordingProvider(inner LLMProvider, cassette *Cassette) *RecordingProvider {
+	return &RecordingProvider{inner: inner, cassette: cassette}
+}
+
+func (p *RecordingProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	resp, err := p.inner.Chat(ctx, messages, tools, model, options)
+	// A cancelled request says nothing about the LLM; don't replay it
+	if err != nil && ctx.Err() != nil {
+		return resp, err
+	}
+	req := CassetteRequest{Model: model, Messages: messages, Tools: tools, Options: options}
+	if recErr := p.cassette.Record(req, resp, err); recErr != nil {
+		log.Printf("cassette: failed to record to %s: %v", p.cassette.Path(), recErr)
+	}
+	return resp, err
+}
+
+// ChatStream streams from the inner provider when it can, recording the
+// complete response.
+func (p *RecordingProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onDelta func(StreamDelta)) (*LLMResponse, error) {
+	sp, ok := p.inner.(StreamingProvider)
+	if !ok {
+		return p.Chat(ctx, messages, tools, model, options)
+	}
+	resp, err := sp.ChatStream(ctx, messages, tools, model, options, onDelta)
+	if err != nil && ctx.Err() != nil {
+		return resp, err
+	}
+	req := CassetteRequest{Model: model, Messages: messages, Tools: tools, Options: options}
+	if recErr := p.cassette.Record(req, resp, err); recErr != nil {
+		log.Printf("cassette: failed to record to %s: %v", p.cassette.Path(), recErr)
+	}
+	return resp, err
+}
+
+func (p *RecordingProvider) GetDefaultModel() string {
+	return p.inner.GetDefaultModel()
+}
+
+// ReplayProvider serves responses from a cassette. Requests without a
+// recording go to next when it is set (auto mode) and fail with
+// ErrCassetteMiss otherwise.
+type ReplayProvider struct {
+	cassette *Cassette
+	next     LLMProvider
+}
+
+func NewReplayProvider(cassette *Cassette, next LLMProvider) *ReplayProvider {
+	return &ReplayProvider{cassette: cassette, next: next}
+}
+
+func (p *ReplayProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	req := CassetteRequest{Model: model, Messages: messages, Tools: tools, Options: options}
+	if in, ok := p.cassette.Lookup(req); ok {
+		if in.Error != "" || in.Response == nil {
+			return nil, errors.New(in.Error)
+		}
+		resp := *in.Response
+		return &resp, nil
+	}
+	if p.next != nil {
+		return p.next.Chat(ctx, messages, tools, model, options)
+	}
+	return nil, fmt.Errorf("%w (cassette %s, match %s, key %s, last user message %q)",
+		ErrCassetteMiss, p.cassette.Path(), p.cassette.match, RequestKey(req, p.cassette.match), lastUserMessage(messages))
+}
+
+// ChatStream replays a recorded response as a single delta; misses in
+// auto mode stream from the next provider.
+func (p *ReplayProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onDelta func(StreamDelta)) (*LLMResponse, error) {
+	req := CassetteRequest{Model: model, Messages: messages, Tools: tools, Options: options}
+	if _, ok := p.cassette.peek(req); !ok {
+		if sp, ok := p.next.(StreamingProvider); ok {
+			return sp.ChatStream(ctx, messages, tools, model, options, onDelta)
+		}
+	}
+	resp, err := p.Chat(ctx, messages, tools, model, options)
+	if err == nil && onDelta != nil && (resp.Content != "" || resp.ReasoningContent != "") {
+		onDelta(StreamDelta{Content: resp.Content, Reasoning: resp.ReasoningContent})
+	}
+	return resp, err
+}
+
+func (p *ReplayProvider) GetDefaultModel() string {
+	if p.next != nil {
+		return p.next.GetDefaultModel()
+	}
+	return ""
+}
+
+func lastUserMessage(messages []Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			s := messages[i].Content
+			if len(s) > 80 {
+				s = s[:80] + "..."
+			}
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,216 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// forgetCassettes drops the per-process cassette cache, as a new process
+// would start without it.
+func forgetCassettes() {
+	cassettesMu.Lock()
+	defer cassettesMu.Unlock()
+	for k := range cassettes {
+		delete(cassettes, k)
+	}
+}
+
+func cassetteConfig(t *testing.T, mode, apiBase string) *config.Config {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.SetDir(t.TempDir())
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Providers["zhipu"] = &config.ProviderConfig{
+		APIKey:        "sk-zhipu",
+		APIBase:       apiBase,
+		ModelPatterns: []string{"glm"},
+	}
+	cfg.Cassette.Mode = mode
+	return cfg
+}
+
+func TestCassetteRecordThenReplay(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		fmt.Fprintf(w, `{"choices":[{"message":{"content":"answer %d"},"finish_reason":"stop"}],"usage":{"prompt_tokens":10,"completion_tokens":2}}`, n)
+	}))
+	defer forgetCassettes()
+
+	cfg := cassetteConfig(t, CassetteRecord, server.URL)
+	recorder, err := CreateProviderForModel("glm-4.7", "", cfg)
+	if err != nil {
+		t.Fatalf("CreateProviderForModel: %v", err)
+	}
+	ctx := context.Background()
+	first := []Message{{Role: "system", Content: "It is 09:00."}, {Role: "user", Content: "hello"}}
+	second := []Message{{Role: "system", Content: "It is 09:00."}, {Role: "user", Content: "bye"}}
+	for _, msgs := range [][]Message{first, second} {
+		if _, err := recorder.Chat(ctx, msgs, nil, "glm-4.7", nil); err != nil {
+			t.Fatalf("Chat: %v", err)
+		}
+	}
+	server.Close()
+	if _, err := os.Stat(filepath.Join(cfg.Dir(), cfg.Cassette.Path)); err != nil {
+		t.Errorf("cassette should be saved in the config directory: %v", err)
+	}
+
+	forgetCassettes()
+	cfg.Cassette.Mode = CassetteReplay
+	cfg.Providers["zhipu"].APIKey = ""
+	replayer, err := CreateProviderForModel("glm-4.7", "", cfg)
+	if err != nil {
+		t.Fatalf("replay needs no API key: %v", err)
+	}
+
+	// Out of order, and with a different system prompt
+	second[0].Content = "It is 17:30."
+	resp, err := replayer.Chat(ctx, second, nil, "glm-4.7", nil)
+	if err != nil || resp.Content != "answer 2" {
+		t.Fatalf("replay second = %+v, %v", resp, err)
+	}
+	resp, err = replayer.Chat(ctx, first, nil, "glm-4.7", nil)
+	if err != nil || resp.Content != "answer 1" || resp.Usage.PromptTokens != 10 {
+		t.Fatalf("replay first = %+v, %v", resp, err)
+	}
+
+	_, err = replayer.Chat(ctx, []Message{{Role: "user", Content: "something new"}}, nil, "glm-4.7", nil)
+	if !errors.Is(err, ErrCassetteMiss) {
+		t.Errorf("unrecorded request: err = %v, want ErrCassetteMiss", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("server calls = %d, want 2", calls.Load())
+	}
+}
+
+type countingProvider struct {
+	calls int
+}
+
+func (p *countingProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	p.calls++
+	return &LLMResponse{Content: fmt.Sprintf("live %d", p.calls), FinishReason: "stop"}, nil
+}
+
+func (p *countingProvider) GetDefaultModel() string {
+	return ""
+}
+
+func TestCassetteAutoRecordsMisses(t *testing.T) {
+	defer forgetCassettes()
+	path := filepath.Join(t.TempDir(), "llm.jsonl")
+	cassette, err := OpenCassette(path, CassetteAuto, MatchNormal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner := &countingProvider{}
+	p := NewReplayProvider(cassette, NewRecordingProvider(inner, cassette))
+
+	msgs := []Message{{Role: "user", Content: "hi"}}
+	for i := 0; i < 2; i++ {
+		resp, err := p.Chat(context.Background(), msgs, nil, "m", nil)
+		if err != nil || resp.Content != "live 1" {
+			t.Fatalf("call %d = %+v, %v", i, resp, err)
+		}
+	}
+	if inner.calls != 1 {
+		t.Errorf("inner calls = %d, want 1", inner.calls)
+	}
+
+	// Identical requests recorded twice are served in order
+	forgetCassettes()
+	cassette, _ = OpenCassette(path, CassetteRecord, MatchNormal)
+	rec := NewRecordingProvider(inner, cassette)
+	rec.Chat(context.Background(), msgs, nil, "m", nil)
+	rec.Chat(context.Background(), msgs, nil, "m", nil)
+	forgetCassettes()
+	cassette, err = OpenCassette(path, CassetteReplay, MatchNormal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cassette.Len() != 2 {
+		t.Fatalf("record mode should replace the file; %d interactions", cassette.Len())
+	}
+	replay := NewReplayProvider(cassette, nil)
+	for _, want := range []string{"live 2", "live 3", "live 3"} {
+		resp, err := replay.Chat(context.Background(), msgs, nil, "m", nil)
+		if err != nil || resp.Content != want {
+			t.Errorf("replay = %+v, %v; want %q", resp, err, want)
+		}
+	}
+}
+
+func TestRequestKeyMatchLevels(t *testing.T) {
+	base := CassetteRequest{
+		Model: "m",
+		Messages: []Message{
+			{Role: "system", Content: "Workspace: /home/a"},
+			{Role: "user", Content: "list files"},
+			{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_abc", Type: "function", Function: &FunctionCall{Name: "list_dir", Arguments: `{"path":"."}`}}}},
+			{Role: "tool", Content: "a.txt", ToolCallID: "call_abc"},
+		},
+		Tools:   []ToolDefinition{{Type: "function", Function: ToolFunctionDefinition{Name: "list_dir", Description: "List"}}},
+		Options: map[string]interface{}{"max_tokens": 1024},
+	}
+	modify := func(f func(r *CassetteRequest)) CassetteRequest {
+		r := base
+		r.Messages = append([]Message(nil), base.Messages...)
+		r.Tools = append([]ToolDefinition(nil), base.Tools...)
+		f(&r)
+		return r
+	}
+
+	tests := []struct {
+		name  string
+		req   CassetteRequest
+		equal map[string]bool
+	}{
+		{"system prompt", modify(func(r *CassetteRequest) { r.Messages[0].Content = "Workspace: /ci" }),
+			map[string]bool{MatchExact: false, MatchNormal: true, MatchLoose: true}},
+		{"tool call IDs", modify(func(r *CassetteRequest) {
+			r.Messages[2].ToolCalls = []ToolCall{{ID: "call_xyz", Type: "function", Function: &FunctionCall{Name: "list_dir", Arguments: `{ "path": "." }`}}}
+			r.Messages[3].ToolCallID = "call_xyz"
+		}), map[string]bool{MatchExact: false, MatchNormal: true, MatchLoose: true}},
+		{"options and descriptions", modify(func(r *CassetteRequest) {
+			r.Options = map[string]interface{}{"max_tokens": 2048}
+			r.Tools[0].Function.Description = "List a directory"
+		}), map[string]bool{MatchExact: false, MatchNormal: true, MatchLoose: true}},
+		{"tool result", modify(func(r *CassetteRequest) { r.Messages[3].Content = "b.txt" }),
+			map[string]bool{MatchExact: false, MatchNormal: false, MatchLoose: true}},
+		{"user message", modify(func(r *CassetteRequest) { r.Messages[1].Content = "list all files" }),
+			map[string]bool{MatchExact: false, MatchNormal: false, MatchLoose: false}},
+		{"model", modify(func(r *CassetteRequest) { r.Model = "other" }),
+			map[string]bool{MatchExact: false, MatchNormal: false, MatchLoose: false}},
+	}
+	for _, tt := range tests {
+		for level, want := range tt.equal {
+			got := RequestKey(tt.req, level) == RequestKey(base, level)
+			if got != want {
+				t.Errorf("%s at %s: keys equal = %v, want %v", tt.name, level, got, want)
+			}
+		}
+	}
+}
+
+func TestOpenCassetteErrors(t *testing.T) {
+	defer forgetCassettes()
+	missing := filepath.Join(t.TempDir(), "missing.jsonl")
+	if _, err := OpenCassette(missing, CassetteReplay, ""); err == nil {
+		t.Error("replay of a missing cassette should fail")
+	}
+	if _, err := OpenCassette(missing, CassetteAuto, ""); err != nil {
+		t.Errorf("auto mode starts an empty cassette: %v", err)
+	}
+	if _, err := OpenCassette(missing, CassetteAuto, "fuzzy"); err == nil {
+		t.Error("unknown match level should fail")
+	}
+}
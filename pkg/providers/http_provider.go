@@ -14,16 +14,19 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/metrics"
 )
 
 const maxRetries = 3
 
 type HTTPProvider struct {
+	name       string // config provider name, for metrics
 	apiKey     string
 	apiBase    string
 	userAgent  string
@@ -42,10 +45,45 @@ func NewHTTPProvider(apiKey, apiBase, userAgent string) *HTTPProvider {
 }
 
 func (p *HTTPProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
-	if p.apiBase == "" {
-		return nil, fmt.Errorf("API base not configured")
+	start := time.Now()
+	resp, err := p.chat(ctx, messages, tools, model, options)
+
+	provider := p.metricsName()
+	metrics.LLMRequests.Inc(provider, model, metrics.Status(err))
+	metrics.LLMRequestDuration.Observe(time.Since(start).Seconds(), provider, model)
+	if resp != nil && resp.Usage != nil {
+		metrics.LLMTokens.Add(float64(resp.Usage.PromptTokens), provider, model, "prompt")
+		metrics.LLMTokens.Add(float64(resp.Usage.CompletionTokens), provider, model, "completion")
+	}
+	return resp, err
+}
+
+// metricsName is the provider label: the config name, or the API host for
+// providers created without one.
+func (p *HTTPProvider) metricsName() string {
+	if p.name != "" {
+		return p.name
 	}
+	if u, err := url.Parse(p.apiBase); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "unknown"
+}
 
+func (p *HTTPProvider) chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	resp, err := p.post(ctx, p.requestBody(messages, tools, model, options))
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return p.parseResponse(body)
+}
+
+func (p *HTTPProvider) requestBody(messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) map[string]interface{} {
 	requestBody := map[string]interface{}{
 		"model":    model,
 		"messages": messages,
@@ -68,45 +106,42 @@ func (p *HTTPProvider) Chat(ctx context.Context, messages []Message, tools []Too
 	if temperature, ok := options["temperature"].(float64); ok {
 		requestBody["temperature"] = temperature
 	}
+	return requestBody
+}
 
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+// post sends a chat completions request, retrying on 429, and returns the
+// successful response with its body unread.
+func (p *HTTPProvider) post(ctx context.Context, requestBody map[string]interface{}) (*http.Response, error) {
+	if p.apiBase == "" {
+		return nil, fmt.Errorf("API base not configured")
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", p.apiBase+"/chat/completions", bytes.NewReader(jsonData))
+	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	if p.apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+p.apiKey)
-	}
-	if p.userAgent != "" {
-		req.Header.Set("User-Agent", p.userAgent)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	var body []byte
 	for attempt := 0; attempt <= maxRetries; attempt++ {
-		if attempt > 0 {
-			req, err = http.NewRequestWithContext(ctx, "POST", p.apiBase+"/chat/completions", bytes.NewReader(jsonData))
-			if err != nil {
-				return nil, fmt.Errorf("failed to create request: %w", err)
-			}
-			req.Header.Set("Content-Type", "application/json")
-			if p.apiKey != "" {
-				req.Header.Set("Authorization", "Bearer "+p.apiKey)
-			}
-			if p.userAgent != "" {
-				req.Header.Set("User-Agent", p.userAgent)
-			}
+		req, err := http.NewRequestWithContext(ctx, "POST", p.apiBase+"/chat/completions", bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if p.apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+p.apiKey)
+		}
+		if p.userAgent != "" {
+			req.Header.Set("User-Agent", p.userAgent)
 		}
 
 		resp, err := p.httpClient.Do(req)
 		if err != nil {
 			return nil, fmt.Errorf("failed to send request: %w", err)
 		}
+		if resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
 
 		body, err = io.ReadAll(resp.Body)
 		resp.Body.Close()
@@ -114,10 +149,6 @@ func (p *HTTPProvider) Chat(ctx context.Context, messages []Message, tools []Too
 			return nil, fmt.Errorf("failed to read response: %w", err)
 		}
 
-		if resp.StatusCode == http.StatusOK {
-			return p.parseResponse(body)
-		}
-
 		if resp.StatusCode == http.StatusTooManyRequests && attempt < maxRetries {
 			delay := parseRetryDelay(resp.Header.Get("Retry-After"), body)
 			log.Printf("[provider] Rate limited (429), retrying in %v (attempt %d/%d)", delay, attempt+1, maxRetries)
@@ -298,12 +329,21 @@ func matchProviderByModel(model string, providers config.ProvidersConfig) (strin
 	return "", nil
 }
 
+// CreateProviderForModel returns the provider for a model, wrapped for
+// recording or replay when the cassette is on.
 func CreateProviderForModel(model, providerName string, cfg *config.Config) (LLMProvider, error) {
+	return wrapCassette(cfg, func() (LLMProvider, error) {
+		return createHTTPProvider(model, providerName, cfg)
+	})
+}
+
+func createHTTPProvider(model, providerName string, cfg *config.Config) (LLMProvider, error) {
 	var apiKey, apiBase, userAgent string
 
 	// If explicit provider name is given, use it directly via map lookup
 	if providerName != "" {
-		pcfg := cfg.GetProviderConfig(strings.ToLower(providerName))
+		providerName = strings.ToLower(providerName)
+		pcfg := cfg.GetProviderConfig(providerName)
 		if pcfg == nil {
 			return nil, fmt.Errorf("unknown provider: %s", providerName)
 		}
@@ -312,7 +352,8 @@ func CreateProviderForModel(model, providerName string, cfg *config.Config) (LLM
 		userAgent = pcfg.UserAgent
 	} else {
 		// Match by model name patterns
-		_, matched := matchProviderByModel(model, cfg.Providers)
+		var matched *config.ProviderConfig
+		providerName, matched = matchProviderByModel(model, cfg.Providers)
 		if matched != nil {
 			apiKey = matched.APIKey
 			apiBase = matched.APIBase
@@ -330,7 +371,9 @@ func CreateProviderForModel(model, providerName string, cfg *config.Config) (LLM
 		return nil, fmt.Errorf("no API base configured for provider (model: %s)", model)
 	}
 
-	return NewHTTPProvider(apiKey, apiBase, userAgent), nil
+	p := NewHTTPProvider(apiKey, apiBase, userAgent)
+	p.name = providerName
+	return p, nil
 }
 
 func CreateProvider(cfg *config.Config) (LLMProvider, error) {
@@ -1,10 +1,14 @@
 package providers
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/metrics"
 )
 
 func TestStripThinkTags(t *testing.T) {
@@ -427,3 +431,38 @@ func TestCreateProviderForModel_ExplicitProviderBypassesPatterns(t *testing.T) {
 		t.Errorf("explicit provider not used: got key %q", hp.apiKey)
 	}
 }
+
+func TestChatRecordsMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.Header.Get("Authorization"), "bad") {
+			http.Error(w, `{"error":"invalid key"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"content":"hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":12,"completion_tokens":3}}`))
+	}))
+	defer server.Close()
+
+	p := NewHTTPProvider("good", server.URL, "")
+	p.name = "metrics-test"
+	if _, err := p.Chat(context.Background(), []Message{{Role: "user", Content: "hello"}}, nil, "m1", nil); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	bad := NewHTTPProvider("bad", server.URL, "")
+	bad.name = "metrics-test"
+	if _, err := bad.Chat(context.Background(), nil, nil, "m1", nil); err == nil {
+		t.Fatal("expected an API error")
+	}
+
+	if got := metrics.LLMRequests.Value("metrics-test", "m1", "ok"); got != 1 {
+		t.Errorf("ok requests = %v, want 1", got)
+	}
+	if got := metrics.LLMRequests.Value("metrics-test", "m1", "error"); got != 1 {
+		t.Errorf("error requests = %v, want 1", got)
+	}
+	if got := metrics.LLMRequestDuration.Count("metrics-test", "m1"); got != 2 {
+		t.Errorf("latency observations = %d, want 2", got)
+	}
+	if got := metrics.LLMTokens.Value("metrics-test", "m1", "prompt"); got != 12 {
+		t.Errorf("prompt tokens = %v, want 12", got)
+	}
+}
@@ -0,0 +1,217 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/metrics"
+)
+
+// StreamDelta is a piece of a streamed response.
+type StreamDelta struct {
+	Content   string
+	Reasoning string
+}
+
+// StreamingProvider is an LLMProvider that can report the response as it
+// is generated. The returned LLMResponse is the same Chat would return.
+type StreamingProvider interface {
+	LLMProvider
+	ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onDelta func(StreamDelta)) (*LLMResponse, error)
+}
+
+func (p *HTTPProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onDelta func(StreamDelta)) (*LLMResponse, error) {
+	start := time.Now()
+	resp, err := p.chatStream(ctx, messages, tools, model, options, onDelta)
+
+	provider := p.metricsName()
+	metrics.LLMRequests.Inc(provider, model, metrics.Status(err))
+	metrics.LLMRequestDuration.Observe(time.Since(start).Seconds(), provider, model)
+	if resp != nil && resp.Usage != nil {
+		metrics.LLMTokens.Add(float64(resp.Usage.PromptTokens), provider, model, "prompt")
+		metrics.LLMTokens.Add(float64(resp.Usage.CompletionTokens), provider, model, "completion")
+	}
+	return resp, err
+}
+
+// streamChunk is one server-sent event of an OpenAI-compatible stream.
+type streamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content"`
+			ToolCalls        []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Type     string `json:"type"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *UsageInfo `json:"usage"`
+}
+
+type streamedToolCall struct {
+	ID        string
+	Type      string
+	Name      string
+	Arguments strings.Builder
+}
+
+func (p *HTTPProvider) chatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onDelta func(StreamDelta)) (*LLMResponse, error) {
+	requestBody := p.requestBody(messages, tools, model, options)
+	requestBody["stream"] = true
+	requestBody["stream_options"] = map[string]interface{}{"include_usage": true}
+
+	resp, err := p.post(ctx, requestBody)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// A provider that ignores "stream" answers with a plain JSON body
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		result, err := p.parseResponse(body)
+		if err == nil && onDelta != nil && (result.Content != "" || result.ReasoningContent != "") {
+			onDelta(StreamDelta{Content: result.Content, Reasoning: result.ReasoningContent})
+		}
+		return result, err
+	}
+
+	var (
+		content, reasoning strings.Builder
+		calls              = make(map[int]*streamedToolCall)
+		finishReason       string
+		usage              *UsageInfo
+		emitted            string
+	)
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+		var chunk streamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to parse stream chunk: %w", err)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		choice := chunk.Choices[0]
+		if choice.FinishReason != "" {
+			finishReason = choice.FinishReason
+		}
+		for _, tc := range choice.Delta.ToolCalls {
+			call, ok := calls[tc.Index]
+			if !ok {
+				call = &streamedToolCall{}
+				calls[tc.Index] = call
+			}
+			if tc.ID != "" {
+				call.ID = tc.ID
+			}
+			if tc.Type != "" {
+				call.Type = tc.Type
+			}
+			call.Name += tc.Function.Name
+			call.Arguments.WriteString(tc.Function.Arguments)
+		}
+
+		delta := StreamDelta{Reasoning: choice.Delta.ReasoningContent}
+		reasoning.WriteString(choice.Delta.ReasoningContent)
+		if choice.Delta.Content != "" {
+			content.WriteString(choice.Delta.Content)
+			// Only emit text outside <think> blocks
+			if visible := visibleStreamText(content.String()); strings.HasPrefix(visible, emitted) {
+				delta.Content = visible[len(emitted):]
+				emitted = visible
+			}
+		}
+		if onDelta != nil && (delta.Content != "" || delta.Reasoning != "") {
+			onDelta(delta)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	return p.parseResponse(assembleStream(content.String(), reasoning.String(), calls, finishReason, usage))
+}
+
+// visibleStreamText is the streamed content with <think> blocks removed,
+// holding back a trailing partial "<think>" tag.
+func visibleStreamText(raw string) string {
+	const openTag = "<think>"
+	for n := len(openTag) - 1; n > 0; n-- {
+		if strings.HasSuffix(raw, openTag[:n]) {
+			raw = raw[:len(raw)-n]
+			break
+		}
+	}
+	return stripThinkTags(raw)
+}
+
+// assembleStream rebuilds a non-streamed response body from the chunks, so
+// parseResponse post-processes both the same way.
+func assembleStream(content, reasoning string, calls map[int]*streamedToolCall, finishReason string, usage *UsageInfo) []byte {
+	type function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	}
+	type toolCall struct {
+		ID       string    `json:"id"`
+		Type     string    `json:"type"`
+		Function *function `json:"function"`
+	}
+	indexes := make([]int, 0, len(calls))
+	for i := range calls {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	toolCalls := make([]toolCall, 0, len(calls))
+	for _, i := range indexes {
+		c := calls[i]
+		if c.Type == "" {
+			c.Type = "function"
+		}
+		toolCalls = append(toolCalls, toolCall{ID: c.ID, Type: c.Type, Function: &function{Name: c.Name, Arguments: c.Arguments.String()}})
+	}
+
+	body := map[string]interface{}{
+		"choices": []interface{}{map[string]interface{}{
+			"message": map[string]interface{}{
+				"content":           content,
+				"reasoning_content": reasoning,
+				"tool_calls":        toolCalls,
+			},
+			"finish_reason": finishReason,
+		}},
+		"usage": usage,
+	}
+	data, _ := json.Marshal(body)
+	return data
+}
@@ -0,0 +1,79 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func sseServer(t *testing.T, chunks ...string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["stream"] != true {
+			t.Errorf("request did not ask for a stream: %v", body)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, c := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+}
+
+func TestChatStream(t *testing.T) {
+	server := sseServer(t,
+		`{"choices":[{"delta":{"content":"<thi"}}]}`,
+		`{"choices":[{"delta":{"content":"nk>plan</think>Hel"}}]}`,
+		`{"choices":[{"delta":{"content":"lo"}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"read_file","arguments":"{\"pa"}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"th\":\"a.txt\"}"}}]},"finish_reason":"tool_calls"}]}`,
+		`{"choices":[],"usage":{"prompt_tokens":20,"completion_tokens":7,"total_tokens":27}}`,
+	)
+	defer server.Close()
+
+	p := NewHTTPProvider("key", server.URL, "")
+	var streamed strings.Builder
+	resp, err := p.ChatStream(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "m", nil, func(d StreamDelta) {
+		streamed.WriteString(d.Content)
+	})
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	if streamed.String() != "Hello" {
+		t.Errorf("streamed %q, want %q", streamed.String(), "Hello")
+	}
+	if resp.Content != "Hello" || resp.FinishReason != "tool_calls" {
+		t.Errorf("response = %+v", resp)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "read_file" || resp.ToolCalls[0].Arguments["path"] != "a.txt" {
+		t.Errorf("tool calls = %+v", resp.ToolCalls)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 27 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestChatStreamPlainJSONFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"content":"whole"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	var deltas []string
+	resp, err := NewHTTPProvider("key", server.URL, "").ChatStream(context.Background(), nil, nil, "m", nil, func(d StreamDelta) {
+		deltas = append(deltas, d.Content)
+	})
+	if err != nil || resp.Content != "whole" {
+		t.Fatalf("ChatStream = %+v, %v", resp, err)
+	}
+	if len(deltas) != 1 || deltas[0] != "whole" {
+		t.Errorf("deltas = %q", deltas)
+	}
+}
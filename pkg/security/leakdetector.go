@@ -1,9 +1,23 @@
 package security
 
 import (
+	"net/url"
 	"regexp"
+	"sort"
+	"strings"
 )
 
+// Leak detector actions for outbound content that contains credentials.
+const (
+	LeakActionRedact  = "redact"
+	LeakActionBlock   = "block"
+	LeakActionApprove = "approve"
+)
+
+// minSecretLength is the shortest value AddSecrets accepts; shorter values
+// would redact ordinary words.
+const minSecretLength = 8
+
 // LeakResult contains the outcome of scanning output for credential leaks.
 type LeakResult struct {
 	Clean    bool
@@ -15,6 +29,7 @@ type LeakResult struct {
 type LeakDetector struct {
 	sensitivity float64
 	categories  []leakCategory
+	secrets     []string // exact values, longest first
 }
 
 type leakCategory struct {
@@ -104,11 +119,45 @@ func defaultLeakCategories() []leakCategory {
 	}
 }
 
+// AddSecrets makes Scan redact these exact values, and their URL-encoded
+// forms, regardless of sensitivity. Values shorter than 8 characters are
+// ignored.
+func (ld *LeakDetector) AddSecrets(values []string) {
+	seen := make(map[string]bool, len(ld.secrets))
+	for _, s := range ld.secrets {
+		seen[s] = true
+	}
+	for _, v := range values {
+		if len(v) < minSecretLength {
+			continue
+		}
+		for _, variant := range []string{v, url.QueryEscape(v), url.PathEscape(v)} {
+			if !seen[variant] {
+				seen[variant] = true
+				ld.secrets = append(ld.secrets, variant)
+			}
+		}
+	}
+	// Longest first, so a secret containing another is replaced whole
+	sort.SliceStable(ld.secrets, func(i, j int) bool { return len(ld.secrets[i]) > len(ld.secrets[j]) })
+}
+
 // Scan checks content for credential patterns and returns a redacted version.
 func (ld *LeakDetector) Scan(content string) LeakResult {
 	var matched []string
 	redacted := content
 
+	secretFound := false
+	for _, s := range ld.secrets {
+		if strings.Contains(redacted, s) {
+			secretFound = true
+			redacted = strings.ReplaceAll(redacted, s, "[REDACTED_CONFIG_SECRET]")
+		}
+	}
+	if secretFound {
+		matched = append(matched, "config_secret")
+	}
+
 	for _, cat := range ld.categories {
 		if !cat.alwaysOn && ld.sensitivity <= 0.5 {
 			continue
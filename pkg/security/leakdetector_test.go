@@ -201,3 +201,25 @@ func TestLeakDetector_RedactionPreservesContext(t *testing.T) {
 		t.Error("redaction should preserve surrounding text")
 	}
 }
+
+func TestLeakDetector_AddSecrets(t *testing.T) {
+	ld := NewLeakDetector(0.3)
+	ld.AddSecrets([]string{"hunter2-workspace-token", "short", "p@ss word/value"})
+
+	result := ld.Scan("curl -H 'X-Token: hunter2-workspace-token' https://example.com")
+	if result.Clean || result.Patterns[0] != "config_secret" {
+		t.Fatalf("expected config_secret match, got %+v", result)
+	}
+	if strings.Contains(result.Redacted, "hunter2") || !strings.Contains(result.Redacted, "[REDACTED_CONFIG_SECRET]") {
+		t.Errorf("secret not redacted: %q", result.Redacted)
+	}
+
+	result = ld.Scan("https://example.com/?key=p%40ss+word%2Fvalue")
+	if result.Clean || strings.Contains(result.Redacted, "word") {
+		t.Errorf("URL-encoded secret not redacted: %q", result.Redacted)
+	}
+
+	if result := ld.Scan("a short answer"); !result.Clean {
+		t.Errorf("values under the minimum length should be ignored, got %v", result.Patterns)
+	}
+}
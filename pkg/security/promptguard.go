@@ -1,6 +1,10 @@
 package security
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
 	"regexp"
 	"strings"
 )
@@ -15,10 +19,25 @@ const (
 
 // GuardResult contains the outcome of scanning input for prompt injection.
 type GuardResult struct {
-	Safe     bool
-	Patterns []string
-	Score    float64
-	Action   GuardAction
+	Safe       bool
+	Patterns   []string
+	Score      float64
+	Action     GuardAction
+	Classified bool // the classifier stage decided the verdict
+}
+
+// GuardClassifier is the optional second stage for borderline scores. It
+// reports whether content is a prompt injection.
+type GuardClassifier interface {
+	Classify(ctx context.Context, content string) (bool, error)
+}
+
+// GuardClassifierFunc adapts a function to GuardClassifier.
+type GuardClassifierFunc func(ctx context.Context, content string) (bool, error)
+
+// Classify calls f.
+func (f GuardClassifierFunc) Classify(ctx context.Context, content string) (bool, error) {
+	return f(ctx, content)
 }
 
 // PromptGuard detects prompt injection attempts using regex-based pattern matching.
@@ -26,12 +45,55 @@ type PromptGuard struct {
 	action      GuardAction
 	sensitivity float64
 	categories  []guardCategory
+	allow       []string               // lowercased phrases ignored when scanning
+	allowRe     []*regexp.Regexp       // case-insensitive matchers for allow
+	languages   map[string]bool        // nil enables every language
+	overrides   map[string]GuardAction // "channel:group", "channel", "group"/"dm"
+	classifier  GuardClassifier
+	classifyMin float64
+	classifyMax float64
 }
 
 type guardCategory struct {
-	name    string
-	score   float64
-	pattern *regexp.Regexp
+	name     string
+	score    float64
+	language string // "" applies to every language
+	pattern  *regexp.Regexp
+}
+
+// GuardRule is a custom prompt-guard rule loaded from a rules file.
+type GuardRule struct {
+	Name     string  `json:"name"`
+	Pattern  string  `json:"pattern"`
+	Score    float64 `json:"score"`
+	Language string  `json:"language,omitempty"`
+}
+
+// GuardRuleSet is the content of a prompt-guard rules file. Allow lists
+// phrases that are ignored when scanning; Disable names built-in rules to
+// turn off, and DisableDefaults turns off all of them.
+type GuardRuleSet struct {
+	Rules           []GuardRule `json:"rules"`
+	Allow           []string    `json:"allow,omitempty"`
+	Disable         []string    `json:"disable,omitempty"`
+	DisableDefaults bool        `json:"disable_defaults,omitempty"`
+}
+
+// LoadGuardRules reads a rules file. A missing file returns nil without an
+// error.
+func LoadGuardRules(path string) (*GuardRuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var set GuardRuleSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &set, nil
 }
 
 // NewPromptGuard creates a PromptGuard with the given action and sensitivity.
@@ -50,7 +112,7 @@ func NewPromptGuard(action string, sensitivity float64) *PromptGuard {
 	return &PromptGuard{
 		action:      a,
 		sensitivity: sensitivity,
-		categories:  defaultGuardCategories(),
+		categories:  append(defaultGuardCategories(), localizedGuardCategories()...),
 	}
 }
 
@@ -194,14 +256,170 @@ func defaultGuardCategories() []guardCategory {
 	}
 }
 
+// localizedGuardCategories holds injection phrases for languages other
+// than English.
+func localizedGuardCategories() []guardCategory {
+	return []guardCategory{
+		{
+			name:     "system_override:zh",
+			score:    1.0,
+			language: "zh",
+			pattern:  regexp.MustCompile(`(忽略|无视|忘记|忘掉|不要理会)(之前|以上|上面|前面|先前|所有|全部)的?(所有|全部)?(指令|指示|说明|提示|规则|命令)`),
+		},
+		{
+			name:     "role_confusion:zh",
+			score:    0.9,
+			language: "zh",
+			pattern:  regexp.MustCompile(`(从现在起你是|你现在是|假装你是|假装自己是)`),
+		},
+		{
+			name:     "prompt_leak:zh",
+			score:    0.95,
+			language: "zh",
+			pattern:  regexp.MustCompile(`(显示|输出|告诉我|打印|泄露|重复|给我看)(一下)?(你的)?(系统提示词?|系统指令|初始指令|隐藏指令)`),
+		},
+		{
+			name:     "system_override:vi",
+			score:    1.0,
+			language: "vi",
+			pattern:  regexp.MustCompile(`(?i)(bỏ\s+qua|phớt\s+lờ|quên|lờ\s+đi)\s+(tất\s+cả\s+)?(các\s+|những\s+)?(hướng\s+dẫn|chỉ\s+dẫn|lệnh|chỉ\s+thị)\s+(trước|trước\s+đó|ở\s+trên|phía\s+trên)`),
+		},
+		{
+			name:     "role_confusion:vi",
+			score:    0.9,
+			language: "vi",
+			pattern:  regexp.MustCompile(`(?i)(bây\s+giờ\s+bạn\s+là|từ\s+giờ\s+bạn\s+là|hãy\s+giả\s+vờ|giả\s+vờ\s+là)`),
+		},
+		{
+			name:     "prompt_leak:vi",
+			score:    0.95,
+			language: "vi",
+			pattern:  regexp.MustCompile(`(?i)(hiển\s+thị|in\s+ra|cho\s+tôi\s+xem|tiết\s+lộ|lặp\s+lại)\s+(lời\s+nhắc|chỉ\s+dẫn|hướng\s+dẫn|chỉ\s+thị)\s+(hệ\s+thống|ban\s+đầu)`),
+		},
+	}
+}
+
+// AddRules applies a rules file: custom rules are appended, disabled
+// built-in rules are removed and allow phrases are registered.
+func (pg *PromptGuard) AddRules(set *GuardRuleSet) error {
+	if set == nil {
+		return nil
+	}
+	var added []guardCategory
+	for i, r := range set.Rules {
+		if r.Name == "" || r.Pattern == "" {
+			return fmt.Errorf("rule %d: name and pattern are required", i)
+		}
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return fmt.Errorf("rule %q: invalid pattern: %w", r.Name, err)
+		}
+		score := r.Score
+		if score <= 0 {
+			score = 1.0
+		}
+		added = append(added, guardCategory{name: r.Name, score: score, language: r.Language, pattern: re})
+	}
+
+	disabled := make(map[string]bool, len(set.Disable))
+	for _, name := range set.Disable {
+		disabled[name] = true
+	}
+	var kept []guardCategory
+	if !set.DisableDefaults {
+		for _, cat := range pg.categories {
+			if !disabled[cat.name] {
+				kept = append(kept, cat)
+			}
+		}
+	}
+	pg.categories = append(kept, added...)
+
+	for _, phrase := range set.Allow {
+		if phrase = strings.TrimSpace(phrase); phrase != "" {
+			pg.allow = append(pg.allow, strings.ToLower(phrase))
+			pg.allowRe = append(pg.allowRe, regexp.MustCompile(`(?i)`+regexp.QuoteMeta(phrase)))
+		}
+	}
+	return nil
+}
+
+// SetLanguages limits language-specific rules to the given languages.
+// Rules without a language always apply; an empty list enables all.
+func (pg *PromptGuard) SetLanguages(languages []string) {
+	if len(languages) == 0 {
+		pg.languages = nil
+		return
+	}
+	pg.languages = make(map[string]bool, len(languages))
+	for _, l := range languages {
+		pg.languages[strings.ToLower(l)] = true
+	}
+}
+
+// SetActionOverrides sets per-channel actions. Keys are "group", "dm", a
+// channel name, or "channel:group" / "channel:dm"; the most specific key
+// wins.
+func (pg *PromptGuard) SetActionOverrides(overrides map[string]string) {
+	pg.overrides = make(map[string]GuardAction, len(overrides))
+	for key, action := range overrides {
+		pg.overrides[strings.ToLower(key)] = ParseGuardAction(action)
+	}
+}
+
+// ActionFor returns the action for a message from channel.
+func (pg *PromptGuard) ActionFor(channel string, group bool) GuardAction {
+	chatType := "dm"
+	if group {
+		chatType = "group"
+	}
+	channel = strings.ToLower(channel)
+	for _, key := range []string{channel + ":" + chatType, channel, chatType} {
+		if action, ok := pg.overrides[key]; ok {
+			return action
+		}
+	}
+	return pg.action
+}
+
+// SetClassifier enables the classifier stage for scores in [min, max).
+// Inside that band the classifier's verdict replaces the pattern verdict.
+func (pg *PromptGuard) SetClassifier(c GuardClassifier, min, max float64) {
+	pg.classifier = c
+	pg.classifyMin = min
+	pg.classifyMax = max
+}
+
 const maxGuardScore = 11.6
 
+// ScanContext is Scan followed by the classifier stage for borderline
+// scores. Classifier errors keep the pattern verdict.
+func (pg *PromptGuard) ScanContext(ctx context.Context, content string) GuardResult {
+	result := pg.Scan(content)
+	if pg.classifier == nil || result.Score < pg.classifyMin || result.Score >= pg.classifyMax {
+		return result
+	}
+	injection, err := pg.classifier.Classify(ctx, content)
+	if err != nil {
+		return result
+	}
+	result.Classified = true
+	result.Safe = !injection
+	result.Action = ""
+	if injection {
+		result.Action = pg.action
+		result.Patterns = append(result.Patterns, "classifier")
+	}
+	return result
+}
+
 // Scan checks input content for prompt injection patterns.
 func (pg *PromptGuard) Scan(content string) GuardResult {
 	var matched []string
 	var totalScore float64
 
-	for _, cat := range pg.categories {
+	content = pg.stripAllowed(content)
+	for _, cat := range pg.activeCategories() {
 		if cat.pattern.MatchString(content) {
 			matched = append(matched, cat.name)
 			totalScore += cat.score
@@ -209,6 +427,9 @@ func (pg *PromptGuard) Scan(content string) GuardResult {
 	}
 
 	normalized := totalScore / maxGuardScore
+	if normalized > 1 {
+		normalized = 1
+	}
 	safe := normalized < pg.sensitivity
 
 	action := pg.action
@@ -224,6 +445,54 @@ func (pg *PromptGuard) Scan(content string) GuardResult {
 	}
 }
 
+// Sanitize replaces every span matched by a rule with a placeholder naming
+// the rule.
+func (pg *PromptGuard) Sanitize(content string) string {
+	for _, cat := range pg.activeCategories() {
+		name := cat.name
+		content = cat.pattern.ReplaceAllStringFunc(content, func(match string) string {
+			if pg.isAllowed(match) {
+				return match
+			}
+			return "[removed: " + name + "]"
+		})
+	}
+	return content
+}
+
+func (pg *PromptGuard) activeCategories() []guardCategory {
+	if pg.languages == nil {
+		return pg.categories
+	}
+	active := make([]guardCategory, 0, len(pg.categories))
+	for _, cat := range pg.categories {
+		if cat.language == "" || pg.languages[cat.language] {
+			active = append(active, cat)
+		}
+	}
+	return active
+}
+
+// stripAllowed removes allowlisted phrases (case-insensitive) so they do not
+// trigger rules.
+func (pg *PromptGuard) stripAllowed(content string) string {
+	for _, re := range pg.allowRe {
+		// Replace with a space so surrounding words do not join up
+		content = re.ReplaceAllLiteralString(content, " ")
+	}
+	return content
+}
+
+func (pg *PromptGuard) isAllowed(match string) bool {
+	lower := strings.ToLower(match)
+	for _, phrase := range pg.allow {
+		if strings.Contains(phrase, lower) {
+			return true
+		}
+	}
+	return false
+}
+
 // ParseGuardAction converts a string to GuardAction, defaulting to ActionWarn.
 func ParseGuardAction(s string) GuardAction {
 	if strings.ToLower(s) == "block" {
@@ -155,6 +155,22 @@ func (sm *SessionManager) SetSummary(key string, summary string) {
 	}
 }
 
+// Reset clears a session's message history while keeping its summary, so a
+// new conversation still carries long-term context.
+func (sm *SessionManager) Reset(key string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, ok := sm.sessions[key]
+	if !ok {
+		return
+	}
+
+	session.Messages = []providers.Message{}
+	session.Updated = time.Now()
+	sm.persistSession(session)
+}
+
 func (sm *SessionManager) TruncateHistory(key string, keepLast int) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
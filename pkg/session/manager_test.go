@@ -38,3 +38,18 @@ func TestListSessionKeys_AfterAddMessage(t *testing.T) {
 		t.Errorf("expected [qq:group1], got %v", keys)
 	}
 }
+
+func TestReset_KeepsSummary(t *testing.T) {
+	sm := NewSessionManager("")
+	sm.AddMessage("telegram:1", "user", "hello")
+	sm.SetSummary("telegram:1", "earlier chat")
+
+	sm.Reset("telegram:1")
+
+	if h := sm.GetHistory("telegram:1"); len(h) != 0 {
+		t.Errorf("expected empty history, got %d messages", len(h))
+	}
+	if s := sm.GetSummary("telegram:1"); s != "earlier chat" {
+		t.Errorf("summary = %q, want %q", s, "earlier chat")
+	}
+}
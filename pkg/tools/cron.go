@@ -2,7 +2,10 @@ package tools
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -21,20 +24,35 @@ type CronTool struct {
 	cronService *cron.CronService
 	executor    JobExecutor
 	msgBus      *bus.MessageBus
+	workspace   string
 	channel     string
 	chatID      string
 	mu          sync.RWMutex
+	filter      OutboundFilter
 }
 
-// NewCronTool creates a new CronTool
-func NewCronTool(cronService *cron.CronService, executor JobExecutor, msgBus *bus.MessageBus) *CronTool {
+// OutboundFilter inspects a message before it is published. It returns the
+// message to send, or false to withhold it.
+type OutboundFilter func(msg bus.OutboundMessage) (bus.OutboundMessage, bool)
+
+// NewCronTool creates a new CronTool. File triggers are restricted to
+// directories inside workspace.
+func NewCronTool(cronService *cron.CronService, executor JobExecutor, msgBus *bus.MessageBus, workspace string) *CronTool {
 	return &CronTool{
 		cronService: cronService,
 		executor:    executor,
 		msgBus:      msgBus,
+		workspace:   workspace,
 	}
 }
 
+// SetOutboundFilter sets the filter applied to deliver=true messages.
+func (t *CronTool) SetOutboundFilter(filter OutboundFilter) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.filter = filter
+}
+
 // Name returns the tool name
 func (t *CronTool) Name() string {
 	return "cron"
@@ -42,7 +60,7 @@ func (t *CronTool) Name() string {
 
 // Description returns the tool description
 func (t *CronTool) Description() string {
-	return "Schedule reminders and tasks. IMPORTANT: When user asks to be reminded or scheduled, you MUST call this tool. Use 'at_seconds' for one-time reminders (e.g., 'remind me in 10 minutes' → at_seconds=600). Use 'every_seconds' ONLY for recurring tasks (e.g., 'every 2 hours' → every_seconds=7200). Use 'cron_expr' for complex recurring schedules (e.g., '0 9 * * *' for daily at 9am)."
+	return "Schedule reminders and tasks. IMPORTANT: When user asks to be reminded or scheduled, you MUST call this tool. Use 'at_seconds' for one-time reminders (e.g., 'remind me in 10 minutes' → at_seconds=600). Use 'every_seconds' ONLY for recurring tasks (e.g., 'every 2 hours' → every_seconds=7200). Use 'cron_expr' for complex recurring schedules (e.g., '0 9 * * *' for daily at 9am). Use 'event' to run a task when something happens instead of on a timer: a file created or modified in a workspace directory, a webhook call, a memory stored, or a chat message matching a regex. The event details are appended to the task message."
 }
 
 // Parameters returns the tool parameters schema
@@ -71,13 +89,53 @@ func (t *CronTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "Cron expression for complex recurring schedules (e.g., '0 9 * * *' for daily at 9am). Use this for complex recurring schedules.",
 			},
+			"event": map[string]interface{}{
+				"type":        "object",
+				"description": "Event trigger instead of a time schedule.",
+				"properties": map[string]interface{}{
+					"source": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{cron.EventSourceFile, cron.EventSourceWebhook, cron.EventSourceMemory, cron.EventSourceMessage},
+						"description": "What triggers the job",
+					},
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "file: workspace directory to watch (relative to workspace, not recursive)",
+					},
+					"pattern": map[string]interface{}{
+						"type":        "string",
+						"description": "file: glob on the file name (e.g. '*.csv'); memory: regex on the memory key; message: regex on the message text (required)",
+					},
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "webhook: hook name, served at POST /hooks/<name> on the gateway",
+					},
+					"category": map[string]interface{}{
+						"type":        "string",
+						"description": "memory: only trigger for this memory category",
+					},
+					"channel": map[string]interface{}{
+						"type":        "string",
+						"description": "message: only trigger for this channel (default: current channel)",
+					},
+					"chat_id": map[string]interface{}{
+						"type":        "string",
+						"description": "message: only trigger for this chat (default: current chat)",
+					},
+					"cooldown_seconds": map[string]interface{}{
+						"type":        "integer",
+						"description": "Minimum seconds between two runs of the job",
+					},
+				},
+				"required": []string{"source"},
+			},
 			"job_id": map[string]interface{}{
 				"type":        "string",
 				"description": "Job ID (for remove/enable/disable)",
 			},
 			"deliver": map[string]interface{}{
 				"type":        "boolean",
-				"description": "If true, send message directly to channel. If false, let agent process the message (for complex tasks). Default: true",
+				"description": "If true, send message directly to channel. If false, let agent process the message (for complex tasks). Default: true. Ignored for event jobs, which always run as agent tasks",
 			},
 		},
 		"required": []string{"action"},
@@ -136,8 +194,9 @@ func (t *CronTool) addJob(args map[string]interface{}) (string, error) {
 	atSeconds, hasAt := args["at_seconds"].(float64)
 	everySeconds, hasEvery := args["every_seconds"].(float64)
 	cronExpr, hasCron := args["cron_expr"].(string)
+	eventArgs, hasEvent := args["event"].(map[string]interface{})
 
-	// Priority: at_seconds > every_seconds > cron_expr
+	// Priority: at_seconds > every_seconds > cron_expr > event
 	if hasAt {
 		atMS := time.Now().UnixMilli() + int64(atSeconds)*1000
 		schedule = cron.CronSchedule{
@@ -155,8 +214,17 @@ func (t *CronTool) addJob(args map[string]interface{}) (string, error) {
 			Kind: "cron",
 			Expr: cronExpr,
 		}
+	} else if hasEvent {
+		trigger, err := t.buildEventTrigger(eventArgs, channel, chatID)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err), nil
+		}
+		schedule = cron.CronSchedule{
+			Kind:  "event",
+			Event: trigger,
+		}
 	} else {
-		return "Error: one of at_seconds, every_seconds, or cron_expr is required", nil
+		return "Error: one of at_seconds, every_seconds, cron_expr, or event is required", nil
 	}
 
 	// Read deliver parameter, default to true
@@ -180,9 +248,74 @@ func (t *CronTool) addJob(args map[string]interface{}) (string, error) {
 		return fmt.Sprintf("Error adding job: %v", err), nil
 	}
 
+	if trigger := job.Schedule.Event; trigger != nil && trigger.Source == cron.EventSourceWebhook {
+		return fmt.Sprintf("Created job '%s' (id: %s). Trigger it with POST %s%s on the gateway, header X-Webhook-Secret: %s",
+			job.Name, job.ID, cron.WebhookPathPrefix, trigger.Name, trigger.Secret), nil
+	}
 	return fmt.Sprintf("Created job '%s' (id: %s)", job.Name, job.ID), nil
 }
 
+// buildEventTrigger converts the "event" tool argument into a trigger.
+// Message triggers default to the current chat so a job only reacts to the
+// conversation it was created in.
+func (t *CronTool) buildEventTrigger(args map[string]interface{}, channel, chatID string) (*cron.EventTrigger, error) {
+	trigger := &cron.EventTrigger{}
+	trigger.Source, _ = args["source"].(string)
+	trigger.Pattern, _ = args["pattern"].(string)
+	trigger.Name, _ = args["name"].(string)
+	trigger.Category, _ = args["category"].(string)
+	trigger.Channel, _ = args["channel"].(string)
+	trigger.ChatID, _ = args["chat_id"].(string)
+	if cooldown, ok := args["cooldown_seconds"].(float64); ok && cooldown > 0 {
+		trigger.CooldownS = int(cooldown)
+	}
+
+	switch trigger.Source {
+	case cron.EventSourceFile:
+		dir, _ := args["path"].(string)
+		resolved, err := ResolveWorkspaceDir(t.workspace, dir)
+		if err != nil {
+			return nil, err
+		}
+		trigger.Path = resolved
+	case cron.EventSourceWebhook:
+		trigger.Secret = GenerateWebhookSecret()
+	case cron.EventSourceMessage:
+		if trigger.Channel == "" {
+			trigger.Channel = channel
+			if trigger.ChatID == "" {
+				trigger.ChatID = chatID
+			}
+		}
+	}
+
+	if err := trigger.Validate(); err != nil {
+		return nil, err
+	}
+	return trigger, nil
+}
+
+// ResolveWorkspaceDir resolves dir relative to workspace and rejects
+// directories outside of it.
+func ResolveWorkspaceDir(workspace, dir string) (string, error) {
+	if workspace == "" {
+		return "", fmt.Errorf("file triggers require a workspace")
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(workspace, dir)
+	}
+	return checkAllowedDir(dir, workspace)
+}
+
+// GenerateWebhookSecret returns a random secret for a new webhook trigger.
+func GenerateWebhookSecret() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
 func (t *CronTool) listJobs() (string, error) {
 	jobs := t.cronService.ListJobs(false)
 
@@ -192,17 +325,7 @@ func (t *CronTool) listJobs() (string, error) {
 
 	result := "Scheduled jobs:\n"
 	for _, j := range jobs {
-		var scheduleInfo string
-		if j.Schedule.Kind == "every" && j.Schedule.EveryMS != nil {
-			scheduleInfo = fmt.Sprintf("every %ds", *j.Schedule.EveryMS/1000)
-		} else if j.Schedule.Kind == "cron" {
-			scheduleInfo = j.Schedule.Expr
-		} else if j.Schedule.Kind == "at" {
-			scheduleInfo = "one-time"
-		} else {
-			scheduleInfo = "unknown"
-		}
-		result += fmt.Sprintf("- %s (id: %s, %s)\n", j.Name, j.ID, scheduleInfo)
+		result += fmt.Sprintf("- %s (id: %s, %s)\n", j.Name, j.ID, j.Schedule)
 	}
 
 	return result, nil
@@ -252,13 +375,24 @@ func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
 		chatID = "direct"
 	}
 
-	// If deliver=true, send message directly without agent processing
-	if job.Payload.Deliver {
-		t.msgBus.PublishOutbound(bus.OutboundMessage{
+	// If deliver=true, send message directly without agent processing.
+	// Event jobs never do, since their message carries the event payload.
+	if job.Payload.Deliver && job.Schedule.Kind != "event" {
+		msg := bus.OutboundMessage{
 			Channel: channel,
 			ChatID:  chatID,
 			Content: job.Payload.Message,
-		})
+		}
+		t.mu.RLock()
+		filter := t.filter
+		t.mu.RUnlock()
+		if filter != nil {
+			var ok bool
+			if msg, ok = filter(msg); !ok {
+				return "withheld"
+			}
+		}
+		t.msgBus.PublishOutbound(msg)
 		return "ok"
 	}
 
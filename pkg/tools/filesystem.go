@@ -1,11 +1,19 @@
 package tools
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/extract"
 )
 
 // checkAllowedDir validates that the resolved path is within the allowed directory.
@@ -34,6 +42,15 @@ func checkAllowedDir(path, allowedDir string) (string, error) {
 	return resolvedPath, nil
 }
 
+// read_file output limits. Lines beyond the cap are reached with offset.
+const (
+	readFileDefaultLimit = 2000
+	readFileMaxBytes     = 50000
+	readFileMaxLineLen   = 2000
+	binarySniffLen       = 8000
+	maxExtractBytes      = 20 << 20
+)
+
 type ReadFileTool struct {
 	allowedDir string
 }
@@ -47,7 +64,8 @@ func (t *ReadFileTool) Name() string {
 }
 
 func (t *ReadFileTool) Description() string {
-	return "Read the contents of a file"
+	return fmt.Sprintf("Read a text file with line numbers. Returns at most %d lines or %d bytes per call; use offset and limit to page through large files. PDF and DOCX files are converted to text; other binary files return a short summary instead of their content.",
+		readFileDefaultLimit, readFileMaxBytes)
 }
 
 func (t *ReadFileTool) Parameters() map[string]interface{} {
@@ -58,6 +76,18 @@ func (t *ReadFileTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "Path to the file to read",
 			},
+			"offset": map[string]interface{}{
+				"type":        "integer",
+				"description": "Optional: line number to start from (1-based, default 1)",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Optional: maximum number of lines to return (default %d)", readFileDefaultLimit),
+			},
+			"extract": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Optional: return readable text of HTML pages and RSS/Atom feeds instead of their source (PDF and DOCX are always extracted)",
+			},
 		},
 		"required": []string{"path"},
 	}
@@ -69,17 +99,172 @@ func (t *ReadFileTool) Execute(ctx context.Context, args map[string]interface{})
 		return "", fmt.Errorf("path is required")
 	}
 
+	offset := 1
+	if o, ok := args["offset"].(float64); ok && o > 1 {
+		offset = int(o)
+	}
+	limit := readFileDefaultLimit
+	if l, ok := args["limit"].(float64); ok && l > 0 {
+		limit = int(l)
+	}
+
 	resolvedPath, err := checkAllowedDir(path, t.allowedDir)
 	if err != nil {
 		return "", err
 	}
 
-	content, err := os.ReadFile(resolvedPath)
+	f, err := os.Open(resolvedPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
 	if err != nil {
 		return "", fmt.Errorf("failed to read file: %w", err)
 	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory, use list_dir", path)
+	}
+
+	if wantExtract, _ := args["extract"].(bool); wantExtract || extract.IsDocument(resolvedPath) {
+		if info.Size() > maxExtractBytes {
+			return "", fmt.Errorf("%s is too large to extract (%d MB max)", path, maxExtractBytes>>20)
+		}
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return "", fmt.Errorf("failed to read file: %w", err)
+		}
+		res, err := extract.Extract(data, "", resolvedPath, extract.Options{})
+		if err != nil {
+			return "", err
+		}
+		return readLines(bufio.NewReader(strings.NewReader(res.Text)), offset, limit)
+	}
+
+	reader := bufio.NewReader(f)
+	head, _ := reader.Peek(binarySniffLen)
+	if isBinary(head) {
+		return binarySummary(path, info.Size(), head), nil
+	}
+
+	return readLines(reader, offset, limit)
+}
 
-	return string(content), nil
+// readLines returns lines [offset, offset+limit) numbered like cat -n,
+// stopping early at readFileMaxBytes, followed by a notice telling the
+// model how to continue.
+func readLines(reader *bufio.Reader, offset, limit int) (string, error) {
+	var b strings.Builder
+	lineNo, last := 0, 0
+	byteCapped := false
+
+	for {
+		line, cut, err := readLineCapped(reader, readFileMaxLineLen)
+		if line == "" && !cut && err != nil {
+			if err == io.EOF {
+				break
+			}
+			return "", fmt.Errorf("failed to read file: %w", err)
+		}
+		if err != nil && err != io.EOF {
+			return "", fmt.Errorf("failed to read file: %w", err)
+		}
+		lineNo++
+
+		if lineNo >= offset && lineNo < offset+limit && !byteCapped {
+			line = strings.TrimRight(line, "\r\n")
+			if cut || len(line) > readFileMaxLineLen {
+				line = truncateBytes(line, readFileMaxLineLen) + "... [line truncated]"
+			}
+			entry := fmt.Sprintf("%6d\t%s\n", lineNo, line)
+			if b.Len()+len(entry) > readFileMaxBytes && last > 0 {
+				byteCapped = true
+			} else {
+				b.WriteString(entry)
+				last = lineNo
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+
+	switch {
+	case lineNo == 0:
+		return "(empty file)", nil
+	case offset > lineNo:
+		return fmt.Sprintf("(offset %d is past the end of the file, which has %d lines)", offset, lineNo), nil
+	case byteCapped:
+		fmt.Fprintf(&b, "\n[Output truncated at %d bytes: showing lines %d-%d of %d. Use offset=%d to continue.]",
+			readFileMaxBytes, offset, last, lineNo, last+1)
+	case last < lineNo:
+		fmt.Fprintf(&b, "\n[Showing lines %d-%d of %d. Use offset=%d to continue.]", offset, last, lineNo, last+1)
+	}
+	return b.String(), nil
+}
+
+// readLineCapped reads one line, keeping at most max bytes (plus enough to
+// finish a UTF-8 sequence) and discarding the rest, so a huge line is never
+// held in memory. cut reports whether bytes were dropped. err is io.EOF
+// after the last line.
+func readLineCapped(reader *bufio.Reader, max int) (line string, cut bool, err error) {
+	keep := max + utf8.UTFMax
+	var buf []byte
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if room := keep - len(buf); room > 0 {
+			if len(chunk) > room {
+				buf = append(buf, chunk[:room]...)
+				cut = true
+			} else {
+				buf = append(buf, chunk...)
+			}
+		} else if len(chunk) > 0 {
+			cut = true
+		}
+		if err != bufio.ErrBufferFull {
+			return string(buf), cut, err
+		}
+	}
+}
+
+// truncateBytes cuts s to at most n bytes without splitting a UTF-8
+// sequence.
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// isBinary reports whether a file looks binary: it has NUL bytes or is not
+// valid UTF-8 (allowing a rune cut off at the end of the sample).
+func isBinary(head []byte) bool {
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true
+	}
+	for i := 0; i < len(head); {
+		r, size := utf8.DecodeRune(head[i:])
+		if r == utf8.RuneError && size == 1 {
+			return len(head)-i >= utf8.UTFMax
+		}
+		i += size
+	}
+	return false
+}
+
+// binarySummary describes a binary file without dumping it into context.
+func binarySummary(path string, size int64, head []byte) string {
+	sample := head
+	if len(sample) > 256 {
+		sample = sample[:256]
+	}
+	return fmt.Sprintf("Binary file: %s\nSize: %d bytes\nType: %s\nFirst %d bytes:\n%s",
+		path, size, http.DetectContentType(head), len(sample), hex.Dump(sample))
 }
 
 type WriteFileTool struct {
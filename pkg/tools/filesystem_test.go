@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func writeLinesFile(t *testing.T, dir, name string, n int) string {
+	t.Helper()
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadFile_Range(t *testing.T) {
+	dir := t.TempDir()
+	path := writeLinesFile(t, dir, "app.log", 10)
+	tool := NewReadFileTool(dir)
+
+	got, err := tool.Execute(context.Background(), map[string]interface{}{
+		"path":   path,
+		"offset": float64(3),
+		"limit":  float64(2),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "     3\tline 3\n     4\tline 4\n\n[Showing lines 3-4 of 10. Use offset=5 to continue.]"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got, _ = tool.Execute(context.Background(), map[string]interface{}{"path": path, "offset": float64(9)})
+	if !strings.HasSuffix(got, "    10\tline 10\n") {
+		t.Errorf("reading to the end should have no notice, got %q", got)
+	}
+
+	got, _ = tool.Execute(context.Background(), map[string]interface{}{"path": path, "offset": float64(50)})
+	if !strings.Contains(got, "past the end") {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestReadFile_ByteCap(t *testing.T) {
+	dir := t.TempDir()
+	path := writeLinesFile(t, dir, "big.log", 20000)
+
+	got, err := NewReadFileTool(dir).Execute(context.Background(), map[string]interface{}{
+		"path":  path,
+		"limit": float64(100000),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) > readFileMaxBytes+200 {
+		t.Errorf("output is %d bytes, cap is %d", len(got), readFileMaxBytes)
+	}
+	if !strings.Contains(got, "[Output truncated at") || !strings.Contains(got, "of 20000") {
+		t.Errorf("missing truncation notice: %q", got[len(got)-150:])
+	}
+}
+
+func TestReadFile_LongLineKeepsUTF8(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "wide.txt")
+	// "x" shifts the 3-byte runes so the cap falls mid-rune
+	os.WriteFile(path, []byte("x"+strings.Repeat("界", readFileMaxLineLen)+"\n"), 0644)
+
+	got, err := NewReadFileTool(dir).Execute(context.Background(), map[string]interface{}{"path": path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !utf8.ValidString(got) || !strings.Contains(got, "... [line truncated]") {
+		t.Errorf("long line not cut on a rune boundary: %q", got[len(got)-40:])
+	}
+}
+
+func TestReadLineCapped(t *testing.T) {
+	huge := strings.Repeat("a", 4<<20)
+	reader := bufio.NewReader(strings.NewReader(huge + "\nsecond\n"))
+
+	line, cut, err := readLineCapped(reader, readFileMaxLineLen)
+	if err != nil || !cut || len(line) > readFileMaxLineLen+utf8.UTFMax {
+		t.Fatalf("first line: %d bytes, cut=%v, err=%v", len(line), cut, err)
+	}
+	line, cut, err = readLineCapped(reader, readFileMaxLineLen)
+	if err != nil || cut || line != "second\n" {
+		t.Errorf("second line: %q, cut=%v, err=%v", line, cut, err)
+	}
+	if _, _, err := readLineCapped(reader, readFileMaxLineLen); err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
+
+func TestReadFile_Binary(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "image.png")
+	data := append([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), make([]byte, 1000)...)
+	os.WriteFile(path, data, 0644)
+
+	got, err := NewReadFileTool(dir).Execute(context.Background(), map[string]interface{}{"path": path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "Binary file:") || !strings.Contains(got, "image/png") || !strings.Contains(got, "Size: 1016 bytes") {
+		t.Errorf("unexpected summary %q", got)
+	}
+}
+
+func TestReadFile_OutsideAllowedDir(t *testing.T) {
+	dir := t.TempDir()
+	path := writeLinesFile(t, t.TempDir(), "secret.txt", 1)
+	if _, err := NewReadFileTool(dir).Execute(context.Background(), map[string]interface{}{"path": path}); err == nil {
+		t.Error("expected error for a path outside the allowed directory")
+	}
+}
+
+func TestReadFile_Extract(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "page.html")
+	os.WriteFile(path, []byte(`<html><head><title>T</title><script>x()</script></head><body><h1>Notes</h1><p>See <a href="https://example.com">the docs</a>.</p></body></html>`), 0644)
+	tool := NewReadFileTool(dir)
+
+	raw, _ := tool.Execute(context.Background(), map[string]interface{}{"path": path})
+	if !strings.Contains(raw, "<script>") {
+		t.Errorf("without extract the source should be returned, got %q", raw)
+	}
+
+	got, err := tool.Execute(context.Background(), map[string]interface{}{"path": path, "extract": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "     1\t# Notes\n     2\t\n     3\tSee [the docs](https://example.com).\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,221 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	grepDefaultMaxResults = 100
+	grepMaxFileSize       = 10 << 20
+	grepMaxLineLen        = 300
+)
+
+// grepSkipDirs are never searched.
+var grepSkipDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+	"__pycache__":  true,
+}
+
+// GrepFilesTool searches file contents with a regular expression.
+type GrepFilesTool struct {
+	workspace  string
+	allowedDir string
+}
+
+// NewGrepFilesTool searches workspace when no path is given. allowedDir
+// restricts searches and is empty when the agent is not confined to its
+// workspace.
+func NewGrepFilesTool(workspace, allowedDir string) *GrepFilesTool {
+	return &GrepFilesTool{workspace: workspace, allowedDir: allowedDir}
+}
+
+func (t *GrepFilesTool) Name() string {
+	return "grep_files"
+}
+
+func (t *GrepFilesTool) Description() string {
+	return "Search file contents with a regular expression. Returns matching lines as path:line: text. Binary files, symlinks and .git/node_modules/vendor are skipped."
+}
+
+func (t *GrepFilesTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"pattern": map[string]interface{}{
+				"type":        "string",
+				"description": "Regular expression (Go RE2 syntax)",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: file or directory to search (default: the workspace)",
+			},
+			"include": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: file name glob, e.g. *.go or *.log",
+			},
+			"ignore_case": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Optional: case-insensitive match",
+			},
+			"max_results": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Optional: maximum matching lines to return (default %d)", grepDefaultMaxResults),
+			},
+		},
+		"required": []string{"pattern"},
+	}
+}
+
+func (t *GrepFilesTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	pattern, ok := args["pattern"].(string)
+	if !ok || pattern == "" {
+		return "", fmt.Errorf("pattern is required")
+	}
+	if ignoreCase, _ := args["ignore_case"].(bool); ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	include, _ := args["include"].(string)
+	if include != "" {
+		if _, err := filepath.Match(include, ""); err != nil {
+			return "", fmt.Errorf("invalid include glob: %w", err)
+		}
+	}
+
+	maxResults := grepDefaultMaxResults
+	if m, ok := args["max_results"].(float64); ok && m > 0 {
+		maxResults = int(m)
+	}
+
+	path, _ := args["path"].(string)
+	if path == "" {
+		path = t.workspace
+		if path == "" {
+			path = t.allowedDir
+		}
+		if path == "" {
+			return "", fmt.Errorf("path is required")
+		}
+	}
+	root, err := checkAllowedDir(path, t.allowedDir)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to access %s: %w", path, err)
+	}
+	base := root
+	if !info.IsDir() {
+		base = filepath.Dir(root)
+	}
+
+	var b strings.Builder
+	matches, filesWithMatches := 0, 0
+	truncated := false
+
+	walkErr := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // unreadable entries are skipped
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			if p != root && grepSkipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// Symlinks could point outside the allowed directory
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if include != "" {
+			if ok, _ := filepath.Match(include, d.Name()); !ok {
+				return nil
+			}
+		}
+
+		rel, relErr := filepath.Rel(base, p)
+		if relErr != nil {
+			rel = p
+		}
+		found, err := grepFile(p, rel, re, maxResults-matches, &b)
+		if err != nil {
+			return nil
+		}
+		if found > 0 {
+			filesWithMatches++
+			matches += found
+		}
+		if matches >= maxResults {
+			truncated = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if walkErr != nil {
+		return "", walkErr
+	}
+
+	if matches == 0 {
+		return fmt.Sprintf("No matches for %q in %s", args["pattern"], path), nil
+	}
+	if truncated {
+		fmt.Fprintf(&b, "\n[Stopped after %d matches. Narrow the pattern, path or include to see more.]", maxResults)
+	} else {
+		fmt.Fprintf(&b, "\n%d matches in %d files", matches, filesWithMatches)
+	}
+	return b.String(), nil
+}
+
+// grepFile appends up to limit matching lines of path to b and returns how
+// many it wrote. Binary and oversized files are skipped.
+func grepFile(path, display string, re *regexp.Regexp, limit int, b *strings.Builder) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	if info, err := f.Stat(); err != nil || info.Size() > grepMaxFileSize {
+		return 0, err
+	}
+
+	reader := bufio.NewReader(f)
+	if head, _ := reader.Peek(binarySniffLen); isBinary(head) {
+		return 0, nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	found, lineNo := 0, 0
+	for scanner.Scan() && found < limit {
+		lineNo++
+		line := scanner.Text()
+		if !re.MatchString(line) {
+			continue
+		}
+		line = strings.TrimSpace(line)
+		if len(line) > grepMaxLineLen {
+			line = truncateBytes(line, grepMaxLineLen) + "..."
+		}
+		fmt.Fprintf(b, "%s:%d: %s\n", filepath.ToSlash(display), lineNo, line)
+		found++
+	}
+	return found, nil
+}
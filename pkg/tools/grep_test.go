@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setupGrepWorkspace(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":               "package main\n\nfunc main() {\n\tpanic(\"boom\")\n}\n",
+		"logs/app.log":          "INFO start\nERROR disk full\nINFO retry\nerror: again\n",
+		"node_modules/x/a.js":   "ERROR vendored\n",
+		"bin/tool":              "ERROR\x00binary",
+		"docs/notes/readme.txt": "nothing here\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestGrepFiles(t *testing.T) {
+	dir := setupGrepWorkspace(t)
+	tool := NewGrepFilesTool(dir, dir)
+
+	got, err := tool.Execute(context.Background(), map[string]interface{}{"pattern": "ERROR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "logs/app.log:2: ERROR disk full") || !strings.Contains(got, "1 matches in 1 files") {
+		t.Errorf("unexpected output %q", got)
+	}
+
+	got, _ = tool.Execute(context.Background(), map[string]interface{}{
+		"pattern":     "error",
+		"ignore_case": true,
+		"include":     "*.log",
+	})
+	if !strings.Contains(got, "logs/app.log:4: error: again") || !strings.Contains(got, "2 matches") {
+		t.Errorf("ignore_case: %q", got)
+	}
+
+	got, _ = tool.Execute(context.Background(), map[string]interface{}{"pattern": "INFO", "max_results": float64(1)})
+	if !strings.Contains(got, "[Stopped after 1 matches") {
+		t.Errorf("max_results: %q", got)
+	}
+
+	got, _ = tool.Execute(context.Background(), map[string]interface{}{
+		"pattern": "panic",
+		"path":    filepath.Join(dir, "main.go"),
+	})
+	if !strings.Contains(got, "main.go:4: panic(\"boom\")") {
+		t.Errorf("single file: %q", got)
+	}
+
+	got, _ = tool.Execute(context.Background(), map[string]interface{}{"pattern": "absent"})
+	if !strings.HasPrefix(got, "No matches") {
+		t.Errorf("no matches: %q", got)
+	}
+}
+
+func TestGrepFiles_Restrictions(t *testing.T) {
+	dir := setupGrepWorkspace(t)
+	tool := NewGrepFilesTool(dir, dir)
+
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"pattern": "x", "path": t.TempDir()}); err == nil {
+		t.Error("expected error for a path outside the allowed directory")
+	}
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"pattern": "("}); err == nil {
+		t.Error("expected error for an invalid pattern")
+	}
+
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	os.WriteFile(outside, []byte("ERROR secret\n"), 0644)
+	if err := os.Symlink(outside, filepath.Join(dir, "link.txt")); err == nil {
+		got, _ := tool.Execute(context.Background(), map[string]interface{}{"pattern": "secret"})
+		if strings.Contains(got, "ERROR secret") {
+			t.Error("symlinks must not be followed")
+		}
+	}
+}
+
+func TestGrepFiles_DefaultsToWorkspace(t *testing.T) {
+	dir := setupGrepWorkspace(t)
+	// Unrestricted agents still search their workspace, not the process CWD
+	tool := NewGrepFilesTool(dir, "")
+
+	got, err := tool.Execute(context.Background(), map[string]interface{}{"pattern": "disk full"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "logs/app.log:2: ERROR disk full") {
+		t.Errorf("unexpected output %q", got)
+	}
+}
@@ -0,0 +1,555 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+
+	"github.com/sipeed/picoclaw/pkg/extract"
+)
+
+const defaultCookieJar = "default"
+
+// HTTPCredential is a named secret applied by the http_request tool. The
+// model only ever sees the name. Type is "bearer" (Token), "basic"
+// (Username/Password) or "header" (Header/Value). Credentials without
+// Hosts may only be sent to the tool's allowed internal hosts.
+type HTTPCredential struct {
+	Type     string
+	Token    string
+	Username string
+	Password string
+	Header   string
+	Value    string
+	Hosts    []string
+}
+
+// secret returns the value {{cred:NAME}} expands to.
+func (c HTTPCredential) secret() string {
+	switch c.Type {
+	case "basic":
+		return c.Password
+	case "header":
+		return c.Value
+	}
+	return c.Token
+}
+
+// credPlaceholderRe matches {{cred:NAME}} and {{cred:NAME.username}}.
+var credPlaceholderRe = regexp.MustCompile(`\{\{cred:([A-Za-z0-9_-]+)(\.username)?\}\}`)
+
+// HTTPRequestTool performs arbitrary HTTP requests with per-conversation
+// cookie jars, so the agent can log into dashboards and submit forms.
+type HTTPRequestTool struct {
+	allowedHosts []string
+	credentials  map[string]HTTPCredential
+	timeout      time.Duration
+	maxChars     int
+
+	mu      sync.Mutex
+	channel string
+	chatID  string
+	jars    map[string]http.CookieJar
+}
+
+// NewHTTPRequestTool creates the tool. allowedHosts lists private/internal
+// hosts it may reach despite SSRF protection: host names, "*.suffix"
+// wildcards, IPs or CIDR ranges.
+func NewHTTPRequestTool(allowedHosts []string, credentials map[string]HTTPCredential, timeout time.Duration, maxChars int) *HTTPRequestTool {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	if maxChars <= 0 {
+		maxChars = 50000
+	}
+	return &HTTPRequestTool{
+		allowedHosts: allowedHosts,
+		credentials:  credentials,
+		timeout:      timeout,
+		maxChars:     maxChars,
+		jars:         make(map[string]http.CookieJar),
+	}
+}
+
+func (t *HTTPRequestTool) Name() string {
+	return "http_request"
+}
+
+func (t *HTTPRequestTool) Description() string {
+	desc := "Send an HTTP request with any method, headers and a JSON, form or raw body. Cookies persist per conversation in named jars, so logins carry over between calls. Returns status, headers and the response body."
+	if len(t.credentials) > 0 {
+		names := make([]string, 0, len(t.credentials))
+		for name := range t.credentials {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		desc += " Stored credentials (use via the credential parameter, or {{cred:NAME}} / {{cred:NAME.username}} in headers and body values): " + strings.Join(names, ", ") + "."
+	}
+	return desc
+}
+
+func (t *HTTPRequestTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"url": map[string]interface{}{
+				"type":        "string",
+				"description": "Request URL (http or https)",
+			},
+			"method": map[string]interface{}{
+				"type":        "string",
+				"description": "HTTP method (default GET)",
+				"enum":        []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
+			},
+			"headers": map[string]interface{}{
+				"type":                 "object",
+				"description":          "Optional: request headers",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+			},
+			"json": map[string]interface{}{
+				"description": "Optional: JSON request body (sets Content-Type: application/json)",
+			},
+			"form": map[string]interface{}{
+				"type":                 "object",
+				"description":          "Optional: form fields, sent URL-encoded",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+			},
+			"body": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: raw request body",
+			},
+			"credential": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: name of a stored credential to authenticate with",
+			},
+			"jar": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: cookie jar name (default \"default\"). Use separate jars for separate logins.",
+			},
+			"clear_jar": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Optional: discard the jar's cookies before sending",
+			},
+			"extract": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Optional: return readable text instead of the raw body for HTML, PDF and feeds",
+			},
+		},
+		"required": []string{"url"},
+	}
+}
+
+func (t *HTTPRequestTool) SetContext(channel, chatID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.channel = channel
+	t.chatID = chatID
+}
+
+func (t *HTTPRequestTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	urlStr, ok := args["url"].(string)
+	if !ok || urlStr == "" {
+		return "", fmt.Errorf("url is required")
+	}
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return "", fmt.Errorf("only http/https URLs are allowed")
+	}
+	if parsedURL.Host == "" {
+		return "", fmt.Errorf("missing domain in URL")
+	}
+	host := parsedURL.Hostname()
+	if t.privateHostBlocked(host) {
+		return "", fmt.Errorf("requests to private/internal addresses are not allowed (add %s to allowed_hosts)", host)
+	}
+
+	method := http.MethodGet
+	if m, ok := args["method"].(string); ok && m != "" {
+		method = strings.ToUpper(m)
+	}
+
+	// Credentials referenced anywhere in the request must be usable for this host
+	used := map[string]bool{}
+	credName, _ := args["credential"].(string)
+	if credName != "" {
+		used[credName] = true
+	}
+	collectCredRefs(args["headers"], used)
+	collectCredRefs(args["json"], used)
+	collectCredRefs(args["form"], used)
+	collectCredRefs(args["body"], used)
+	for name := range used {
+		cred, ok := t.credentials[name]
+		if !ok {
+			return "", fmt.Errorf("unknown credential %q", name)
+		}
+		if !t.credentialAllowed(cred, host) {
+			return "", fmt.Errorf("credential %q may not be sent to %s", name, host)
+		}
+	}
+
+	body, contentType, err := t.buildBody(args)
+	if err != nil {
+		return "", err
+	}
+	if body != nil && (method == http.MethodGet || method == http.MethodHead) {
+		return "", fmt.Errorf("%s requests cannot have a body", method)
+	}
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, urlStr, bodyReader)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if headers, ok := args["headers"].(map[string]interface{}); ok {
+		for k, v := range headers {
+			req.Header.Set(k, t.expandCreds(fmt.Sprint(v)))
+		}
+	}
+	if credName != "" {
+		applyCredential(req, t.credentials[credName])
+	}
+
+	jar, err := t.jar(args)
+	if err != nil {
+		return "", err
+	}
+
+	client := &http.Client{
+		Timeout: t.timeout,
+		Jar:     jar,
+		Transport: &http.Transport{
+			MaxIdleConns:        10,
+			IdleConnTimeout:     30 * time.Second,
+			TLSHandshakeTimeout: 15 * time.Second,
+			DialContext:         t.dialContext,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			target := req.URL.Hostname()
+			if t.privateHostBlocked(target) {
+				return fmt.Errorf("redirect to private/internal address %s is not allowed", target)
+			}
+			for name := range used {
+				if !t.credentialAllowed(t.credentials[name], target) {
+					return fmt.Errorf("redirect to %s would send credential %q to a host it is not allowed for", target, name)
+				}
+			}
+			return nil
+		},
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	text := string(respBody)
+	if doExtract, _ := args["extract"].(bool); doExtract {
+		if res, err := extract.Extract(respBody, resp.Header.Get("Content-Type"), resp.Request.URL.Path, extract.Options{BaseURL: resp.Request.URL.String()}); err == nil {
+			text = res.Text
+		}
+	}
+	truncated := len(text) > t.maxChars
+	if truncated {
+		text = text[:t.maxChars]
+	}
+
+	headers := map[string]string{}
+	for k, v := range resp.Header {
+		if k == "Set-Cookie" {
+			continue // stored in the jar, not shown
+		}
+		headers[k] = t.redact(strings.Join(v, ", "))
+	}
+
+	result := map[string]interface{}{
+		"status":    resp.StatusCode,
+		"method":    method,
+		"url":       urlStr,
+		"headers":   headers,
+		"body":      t.redact(text),
+		"truncated": truncated,
+	}
+	if finalURL := resp.Request.URL.String(); finalURL != urlStr {
+		result["final_url"] = finalURL
+	}
+	if cookies := jar.Cookies(resp.Request.URL); len(cookies) > 0 {
+		names := make([]string, 0, len(cookies))
+		for _, c := range cookies {
+			names = append(names, c.Name)
+		}
+		result["cookies"] = names
+	}
+
+	resultJSON, _ := json.MarshalIndent(result, "", "  ")
+	return string(resultJSON), nil
+}
+
+// buildBody encodes whichever of json, form or body was given.
+func (t *HTTPRequestTool) buildBody(args map[string]interface{}) ([]byte, string, error) {
+	jsonBody, hasJSON := args["json"]
+	form, hasForm := args["form"].(map[string]interface{})
+	raw, hasRaw := args["body"].(string)
+	given := 0
+	for _, has := range []bool{hasJSON && jsonBody != nil, hasForm, hasRaw} {
+		if has {
+			given++
+		}
+	}
+	if given > 1 {
+		return nil, "", fmt.Errorf("only one of json, form or body may be given")
+	}
+
+	switch {
+	case hasJSON && jsonBody != nil:
+		data, err := json.Marshal(t.expandCredsValue(jsonBody))
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid json body: %w", err)
+		}
+		return data, "application/json", nil
+	case hasForm:
+		values := url.Values{}
+		for k, v := range form {
+			values.Set(k, t.expandCreds(fmt.Sprint(v)))
+		}
+		return []byte(values.Encode()), "application/x-www-form-urlencoded", nil
+	case hasRaw:
+		return []byte(t.expandCreds(raw)), "", nil
+	}
+	return nil, "", nil
+}
+
+// jar returns the cookie jar for the current conversation and jar name.
+func (t *HTTPRequestTool) jar(args map[string]interface{}) (http.CookieJar, error) {
+	name, _ := args["jar"].(string)
+	if name == "" {
+		name = defaultCookieJar
+	}
+	reset, _ := args["clear_jar"].(bool)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := t.channel + ":" + t.chatID + ":" + name
+	if jar, ok := t.jars[key]; ok && !reset {
+		return jar, nil
+	}
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cookie jar: %w", err)
+	}
+	t.jars[key] = jar
+	return jar, nil
+}
+
+// dialContext is ssrfSafeDialContext with the allowlist applied.
+func (t *HTTPRequestTool) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range ips {
+		if isPrivateIP(ip.IP) && !t.hostAllowed(host, ip.IP) {
+			return nil, fmt.Errorf("connections to private/internal addresses are not allowed")
+		}
+	}
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	return dialer.DialContext(ctx, network, net.JoinHostPort(host, port))
+}
+
+// hostAllowed reports whether host (or its resolved ip) is on the allowlist.
+func (t *HTTPRequestTool) hostAllowed(host string, ip net.IP) bool {
+	return matchHostList(t.allowedHosts, host, ip)
+}
+
+// privateHostBlocked reports whether host is, or resolves to, a private
+// address that is not on the allowlist. CIDR entries are matched against
+// every resolved address, as dialContext does when connecting.
+func (t *HTTPRequestTool) privateHostBlocked(host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return isPrivateIP(ip) && !t.hostAllowed(host, ip)
+	}
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return false // the dial fails or is checked again
+	}
+	for _, ip := range ips {
+		if isPrivateIP(ip) && !t.hostAllowed(host, ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// credentialAllowed reports whether cred may be sent to host.
+func (t *HTTPRequestTool) credentialAllowed(cred HTTPCredential, host string) bool {
+	if len(cred.Hosts) == 0 {
+		return matchHostResolved(t.allowedHosts, host)
+	}
+	return matchHostResolved(cred.Hosts, host)
+}
+
+// matchHostResolved is matchHostList for a host name that may only match
+// through IP or CIDR entries: then every address it resolves to must match.
+func matchHostResolved(patterns []string, host string) bool {
+	if matchHostList(patterns, host, nil) {
+		return true
+	}
+	if net.ParseIP(host) != nil {
+		return false
+	}
+	ips, err := net.LookupIP(host)
+	if err != nil || len(ips) == 0 {
+		return false
+	}
+	for _, ip := range ips {
+		if !matchHostList(patterns, host, ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchHostList(patterns []string, host string, ip net.IP) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if ip == nil {
+		ip = net.ParseIP(host)
+	}
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		switch {
+		case p == "":
+			continue
+		case strings.HasPrefix(p, "*."):
+			if strings.HasSuffix(host, p[1:]) {
+				return true
+			}
+		case strings.Contains(p, "/"):
+			if _, network, err := net.ParseCIDR(p); err == nil && ip != nil && network.Contains(ip) {
+				return true
+			}
+		case p == host:
+			return true
+		default:
+			if pip := net.ParseIP(p); pip != nil && ip != nil && pip.Equal(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func applyCredential(req *http.Request, cred HTTPCredential) {
+	switch cred.Type {
+	case "basic":
+		req.SetBasicAuth(cred.Username, cred.Password)
+	case "header":
+		req.Header.Set(cred.Header, cred.Value)
+	default:
+		req.Header.Set("Authorization", "Bearer "+cred.Token)
+	}
+}
+
+// collectCredRefs records every credential named by a placeholder in v.
+func collectCredRefs(v interface{}, used map[string]bool) {
+	switch val := v.(type) {
+	case string:
+		for _, m := range credPlaceholderRe.FindAllStringSubmatch(val, -1) {
+			used[m[1]] = true
+		}
+	case map[string]interface{}:
+		for _, item := range val {
+			collectCredRefs(item, used)
+		}
+	case []interface{}:
+		for _, item := range val {
+			collectCredRefs(item, used)
+		}
+	}
+}
+
+func (t *HTTPRequestTool) expandCreds(s string) string {
+	return credPlaceholderRe.ReplaceAllStringFunc(s, func(m string) string {
+		parts := credPlaceholderRe.FindStringSubmatch(m)
+		cred, ok := t.credentials[parts[1]]
+		if !ok {
+			return m
+		}
+		if parts[2] != "" {
+			return cred.Username
+		}
+		return cred.secret()
+	})
+}
+
+func (t *HTTPRequestTool) expandCredsValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		return t.expandCreds(val)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = t.expandCredsValue(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = t.expandCredsValue(item)
+		}
+		return out
+	}
+	return v
+}
+
+// redact hides credential values a server echoes back.
+func (t *HTTPRequestTool) redact(s string) string {
+	for name, cred := range t.credentials {
+		secrets := []string{cred.Token, cred.Password, cred.Value}
+		if cred.Type == "basic" && cred.Password != "" {
+			secrets = append(secrets, base64.StdEncoding.EncodeToString([]byte(cred.Username+":"+cred.Password)))
+		}
+		for _, secret := range secrets {
+			if len(secret) >= 4 {
+				s = strings.ReplaceAll(s, secret, "[credential:"+name+"]")
+			}
+		}
+	}
+	return s
+}
@@ -0,0 +1,181 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func runHTTPRequest(t *testing.T, tool *HTTPRequestTool, args map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	out, err := tool.Execute(context.Background(), args)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatalf("invalid result JSON: %v\n%s", err, out)
+	}
+	return result
+}
+
+func TestHTTPRequest_BlocksPrivateHostsUnlessAllowed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal"))
+	}))
+	defer srv.Close()
+
+	blocked := NewHTTPRequestTool(nil, nil, 0, 0)
+	if _, err := blocked.Execute(context.Background(), map[string]interface{}{"url": srv.URL}); err == nil {
+		t.Fatal("expected private host to be rejected")
+	}
+
+	allowed := NewHTTPRequestTool([]string{"127.0.0.0/8"}, nil, 0, 0)
+	result := runHTTPRequest(t, allowed, map[string]interface{}{"url": srv.URL})
+	if result["body"] != "internal" {
+		t.Errorf("body = %v, want internal", result["body"])
+	}
+
+	// CIDR entries also cover host names that resolve into the range
+	byName := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+	allowed = NewHTTPRequestTool([]string{"127.0.0.0/8", "::1/128"}, nil, 0, 0)
+	result = runHTTPRequest(t, allowed, map[string]interface{}{"url": byName})
+	if result["body"] != "internal" {
+		t.Errorf("localhost via CIDR: body = %v, want internal", result["body"])
+	}
+}
+
+func TestHTTPRequest_CookieJarPerConversation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			r.ParseForm()
+			if r.Form.Get("user") != "alice" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+			w.Write([]byte("logged in"))
+		case "/dashboard":
+			if c, err := r.Cookie("session"); err != nil || c.Value != "abc" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte("welcome"))
+		}
+	}))
+	defer srv.Close()
+
+	tool := NewHTTPRequestTool([]string{"127.0.0.1"}, nil, 0, 0)
+	tool.SetContext("telegram", "1")
+	login := runHTTPRequest(t, tool, map[string]interface{}{
+		"url":    srv.URL + "/login",
+		"method": "POST",
+		"form":   map[string]interface{}{"user": "alice"},
+	})
+	if login["status"].(float64) != 200 {
+		t.Fatalf("login status = %v", login["status"])
+	}
+	if dash := runHTTPRequest(t, tool, map[string]interface{}{"url": srv.URL + "/dashboard"}); dash["body"] != "welcome" {
+		t.Errorf("dashboard with session = %v, want welcome", dash["body"])
+	}
+
+	tool.SetContext("telegram", "2")
+	if dash := runHTTPRequest(t, tool, map[string]interface{}{"url": srv.URL + "/dashboard"}); dash["status"].(float64) != 401 {
+		t.Errorf("other chat status = %v, want 401", dash["status"])
+	}
+
+	tool.SetContext("telegram", "1")
+	if dash := runHTTPRequest(t, tool, map[string]interface{}{"url": srv.URL + "/dashboard", "clear_jar": true}); dash["status"].(float64) != 401 {
+		t.Errorf("cleared jar status = %v, want 401", dash["status"])
+	}
+}
+
+func TestHTTPRequest_CredentialsStayOutOfResults(t *testing.T) {
+	var gotAuth, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		data, _ := io.ReadAll(r.Body)
+		gotBody = string(data)
+		w.Write([]byte("echo " + gotAuth + " " + gotBody))
+	}))
+	defer srv.Close()
+
+	creds := map[string]HTTPCredential{
+		"grafana": {Type: "bearer", Token: "s3cr3t-token"},
+		"db":      {Type: "basic", Username: "admin", Password: "hunter22"},
+	}
+	tool := NewHTTPRequestTool([]string{"127.0.0.1"}, creds, 0, 0)
+
+	if desc := tool.Description(); !strings.Contains(desc, "db, grafana") || strings.Contains(desc, "s3cr3t") {
+		t.Errorf("description should list names only: %s", desc)
+	}
+
+	result := runHTTPRequest(t, tool, map[string]interface{}{
+		"url":        srv.URL,
+		"method":     "POST",
+		"credential": "grafana",
+		"json":       map[string]interface{}{"user": "{{cred:db.username}}", "pass": "{{cred:db}}"},
+	})
+	if gotAuth != "Bearer s3cr3t-token" {
+		t.Errorf("Authorization = %q", gotAuth)
+	}
+	if !strings.Contains(gotBody, `"pass":"hunter22"`) || !strings.Contains(gotBody, `"user":"admin"`) {
+		t.Errorf("placeholders not expanded: %s", gotBody)
+	}
+	body := result["body"].(string)
+	if strings.Contains(body, "s3cr3t-token") || strings.Contains(body, "hunter22") {
+		t.Errorf("secret leaked into result: %s", body)
+	}
+	if !strings.Contains(body, "[credential:grafana]") {
+		t.Errorf("expected redaction marker: %s", body)
+	}
+}
+
+func TestHTTPRequest_CredentialHostRestriction(t *testing.T) {
+	creds := map[string]HTTPCredential{
+		"internal": {Type: "bearer", Token: "tok"},
+		"github":   {Type: "bearer", Token: "tok2", Hosts: []string{"api.github.com"}},
+	}
+	tool := NewHTTPRequestTool([]string{"*.corp.local"}, creds, 0, 0)
+
+	cases := []struct {
+		cred, host string
+		ok         bool
+	}{
+		{"internal", "grafana.corp.local", true},
+		{"internal", "example.com", false},
+		{"github", "api.github.com", true},
+		{"github", "grafana.corp.local", false},
+	}
+	for _, c := range cases {
+		if got := tool.credentialAllowed(creds[c.cred], c.host); got != c.ok {
+			t.Errorf("credentialAllowed(%s, %s) = %v, want %v", c.cred, c.host, got, c.ok)
+		}
+	}
+
+	_, err := tool.Execute(context.Background(), map[string]interface{}{
+		"url":  "https://example.com/",
+		"body": "token={{cred:internal}}",
+	})
+	if err == nil || !strings.Contains(err.Error(), "may not be sent") {
+		t.Errorf("expected credential host error, got %v", err)
+	}
+}
+
+func TestHTTPRequest_RejectsMultipleBodies(t *testing.T) {
+	tool := NewHTTPRequestTool(nil, nil, 0, 0)
+	_, err := tool.Execute(context.Background(), map[string]interface{}{
+		"url":    "https://example.com/",
+		"method": "POST",
+		"json":   map[string]interface{}{"a": 1},
+		"body":   "raw",
+	})
+	if err == nil {
+		t.Error("expected error for json and body together")
+	}
+}
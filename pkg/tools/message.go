@@ -3,20 +3,39 @@ package tools
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
 )
 
-type SendCallback func(channel, chatID, content string) error
+// maxAttachmentSize matches the largest upload most chat platforms accept
+// from bots (Telegram's 50 MB).
+const maxAttachmentSize = 50 << 20
+
+type SendCallback func(channel, chatID, content string, attachments []bus.Attachment) error
 
 type MessageTool struct {
 	sendCallback   SendCallback
 	mu             sync.Mutex
 	defaultChannel string
 	defaultChatID  string
+	workspace      string
+	mediaDir       string
 }
 
 func NewMessageTool() *MessageTool {
-	return &MessageTool{}
+	return &MessageTool{mediaDir: filepath.Join(os.TempDir(), "picoclaw_media")}
+}
+
+// SetWorkspace sets the directory relative attachment paths resolve
+// against. Attachments leave the machine, so they must live inside it or
+// in the media directory where channels store downloads, whether or not
+// the agent is restricted to its workspace.
+func (t *MessageTool) SetWorkspace(workspace string) {
+	t.workspace = workspace
 }
 
 func (t *MessageTool) Name() string {
@@ -24,7 +43,7 @@ func (t *MessageTool) Name() string {
 }
 
 func (t *MessageTool) Description() string {
-	return "Send a message to user on a chat channel. Use this when you want to communicate something."
+	return "Send a message to user on a chat channel. Use this when you want to communicate something. Files from the workspace (charts, reports, images) can be sent as attachments."
 }
 
 func (t *MessageTool) Parameters() map[string]interface{} {
@@ -33,7 +52,12 @@ func (t *MessageTool) Parameters() map[string]interface{} {
 		"properties": map[string]interface{}{
 			"content": map[string]interface{}{
 				"type":        "string",
-				"description": "The message content to send",
+				"description": "The message content to send (optional when sending attachments)",
+			},
+			"attachments": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Optional: paths of workspace files to send with the message",
 			},
 			"channel": map[string]interface{}{
 				"type":        "string",
@@ -44,7 +68,6 @@ func (t *MessageTool) Parameters() map[string]interface{} {
 				"description": "Optional: target chat/user ID",
 			},
 		},
-		"required": []string{"content"},
 	}
 }
 
@@ -60,9 +83,14 @@ func (t *MessageTool) SetSendCallback(callback SendCallback) {
 }
 
 func (t *MessageTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
-	content, ok := args["content"].(string)
-	if !ok {
-		return "", fmt.Errorf("content is required")
+	content, _ := args["content"].(string)
+
+	attachments, err := t.resolveAttachments(args["attachments"])
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	if strings.TrimSpace(content) == "" && len(attachments) == 0 {
+		return "Error: content or attachments are required", nil
 	}
 
 	channel, _ := args["channel"].(string)
@@ -85,9 +113,84 @@ func (t *MessageTool) Execute(ctx context.Context, args map[string]interface{})
 		return "Error: Message sending not configured", nil
 	}
 
-	if err := t.sendCallback(channel, chatID, content); err != nil {
+	if err := t.sendCallback(channel, chatID, content, attachments); err != nil {
 		return fmt.Sprintf("Error sending message: %v", err), nil
 	}
 
+	if len(attachments) > 0 {
+		return fmt.Sprintf("Message with %d attachment(s) sent to %s:%s", len(attachments), channel, chatID), nil
+	}
 	return fmt.Sprintf("Message sent to %s:%s", channel, chatID), nil
 }
+
+// resolveAttachments validates attachment paths and turns them into
+// absolute paths of existing regular files.
+func (t *MessageTool) resolveAttachments(raw interface{}) ([]bus.Attachment, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("attachments must be an array of file paths")
+	}
+
+	var allowedDirs []string
+	for _, dir := range []string{t.workspace, t.mediaDir} {
+		if dir != "" {
+			allowedDirs = append(allowedDirs, dir)
+		}
+	}
+	if len(allowedDirs) == 0 {
+		return nil, fmt.Errorf("attachments are not available without a workspace")
+	}
+
+	attachments := make([]bus.Attachment, 0, len(items))
+	for _, item := range items {
+		path, ok := item.(string)
+		if !ok || strings.TrimSpace(path) == "" {
+			return nil, fmt.Errorf("attachments must be an array of file paths")
+		}
+		if !filepath.IsAbs(path) && t.workspace != "" {
+			path = filepath.Join(t.workspace, path)
+		}
+		resolved, ok := withinDirs(path, allowedDirs, false)
+		if !ok {
+			return nil, fmt.Errorf("attachment %s is outside the workspace", item)
+		}
+		// A symlink must not point outside the allowed directories either
+		if real, err := filepath.EvalSymlinks(resolved); err == nil {
+			if _, ok := withinDirs(real, allowedDirs, true); !ok {
+				return nil, fmt.Errorf("attachment %s is outside the workspace", item)
+			}
+		}
+		info, err := os.Stat(resolved)
+		if err != nil {
+			return nil, fmt.Errorf("attachment not found: %s", item)
+		}
+		if !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("attachment is not a regular file: %s", item)
+		}
+		if info.Size() > maxAttachmentSize {
+			return nil, fmt.Errorf("attachment %s is too large (%d MB max)", item, maxAttachmentSize>>20)
+		}
+		attachments = append(attachments, bus.Attachment{Path: resolved, Name: filepath.Base(resolved)})
+	}
+	return attachments, nil
+}
+
+// withinDirs reports whether path lies inside one of dirs and returns it
+// cleaned and absolute. With realDirs set, the directories' own symlinks
+// are resolved first.
+func withinDirs(path string, dirs []string, realDirs bool) (string, bool) {
+	for _, dir := range dirs {
+		if realDirs {
+			if real, err := filepath.EvalSymlinks(dir); err == nil {
+				dir = real
+			}
+		}
+		if resolved, err := checkAllowedDir(path, dir); err == nil {
+			return resolved, true
+		}
+	}
+	return "", false
+}
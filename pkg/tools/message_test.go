@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+func newTestMessageTool(t *testing.T) (*MessageTool, string, *[]bus.Attachment) {
+	t.Helper()
+	workspace := t.TempDir()
+	var sent []bus.Attachment
+	tool := NewMessageTool()
+	tool.SetWorkspace(workspace)
+	tool.mediaDir = t.TempDir()
+	tool.SetContext("telegram", "42")
+	tool.SetSendCallback(func(channel, chatID, content string, attachments []bus.Attachment) error {
+		sent = attachments
+		return nil
+	})
+	return tool, workspace, &sent
+}
+
+func TestMessageTool_RelativeAttachment(t *testing.T) {
+	tool, workspace, sent := newTestMessageTool(t)
+	os.MkdirAll(filepath.Join(workspace, "out"), 0755)
+	os.WriteFile(filepath.Join(workspace, "out", "chart.png"), []byte("png"), 0644)
+
+	result, _ := tool.Execute(context.Background(), map[string]interface{}{
+		"attachments": []interface{}{"out/chart.png"},
+	})
+	if !strings.Contains(result, "1 attachment") {
+		t.Fatalf("unexpected result %q", result)
+	}
+	if len(*sent) != 1 || (*sent)[0].Path != filepath.Join(workspace, "out", "chart.png") || (*sent)[0].Name != "chart.png" {
+		t.Errorf("unexpected attachments %+v", *sent)
+	}
+}
+
+func TestMessageTool_AttachmentOutsideWorkspace(t *testing.T) {
+	tool, workspace, sent := newTestMessageTool(t)
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	os.WriteFile(outside, []byte("secret"), 0644)
+
+	for _, path := range []string{outside, "../" + filepath.Base(filepath.Dir(outside)) + "/secret.txt"} {
+		result, _ := tool.Execute(context.Background(), map[string]interface{}{
+			"content":     "here",
+			"attachments": []interface{}{path},
+		})
+		if !strings.HasPrefix(result, "Error:") || *sent != nil {
+			t.Errorf("%s: expected rejection, got %q", path, result)
+		}
+	}
+
+	// A symlink inside the workspace must not leak the file it points to
+	link := filepath.Join(workspace, "link.txt")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Skip("symlinks not supported")
+	}
+	result, _ := tool.Execute(context.Background(), map[string]interface{}{
+		"content":     "here",
+		"attachments": []interface{}{"link.txt"},
+	})
+	if !strings.HasPrefix(result, "Error:") || *sent != nil {
+		t.Errorf("symlink: expected rejection, got %q", result)
+	}
+}
+
+func TestMessageTool_AttachmentFromMediaDir(t *testing.T) {
+	tool, _, sent := newTestMessageTool(t)
+	download := filepath.Join(tool.mediaDir, "photo.jpg")
+	os.WriteFile(download, []byte("jpg"), 0644)
+
+	tool.Execute(context.Background(), map[string]interface{}{
+		"content":     "your photo",
+		"attachments": []interface{}{download},
+	})
+	if len(*sent) != 1 || (*sent)[0].Path != download {
+		t.Errorf("media dir attachment should be allowed, got %+v", *sent)
+	}
+}
+
+func TestMessageTool_InvalidAttachments(t *testing.T) {
+	tool, workspace, _ := newTestMessageTool(t)
+	os.Mkdir(filepath.Join(workspace, "dir"), 0755)
+
+	cases := []interface{}{
+		"not-an-array",
+		[]interface{}{"missing.txt"},
+		[]interface{}{"dir"},
+		[]interface{}{42},
+	}
+	for _, attachments := range cases {
+		result, _ := tool.Execute(context.Background(), map[string]interface{}{
+			"content":     "x",
+			"attachments": attachments,
+		})
+		if !strings.HasPrefix(result, "Error:") {
+			t.Errorf("%v: expected error, got %q", attachments, result)
+		}
+	}
+
+	result, _ := tool.Execute(context.Background(), map[string]interface{}{"content": " "})
+	if !strings.HasPrefix(result, "Error:") {
+		t.Errorf("empty message without attachments should fail, got %q", result)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 	"sort"
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/metrics"
 )
 
 type ToolRegistry struct {
@@ -61,6 +62,8 @@ func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args
 	start := time.Now()
 	result, err := tool.Execute(ctx, args)
 	duration := time.Since(start)
+	metrics.ToolExecutions.Inc(name, metrics.Status(err))
+	metrics.ToolDuration.Observe(duration.Seconds(), name)
 
 	if err != nil {
 		logger.ErrorCF("tool", "Tool execution failed",
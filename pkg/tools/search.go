@@ -0,0 +1,437 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Search strategies for WebSearchTool.
+const (
+	// SearchFallback queries backends in order until enough results arrive.
+	SearchFallback = "fallback"
+	// SearchFusion queries every backend and merges the rankings.
+	SearchFusion = "fusion"
+)
+
+const (
+	searchSnippetLen = 300
+	// rrfK dampens rank differences in reciprocal rank fusion.
+	rrfK = 60
+)
+
+// Time ranges accepted by web_search.
+var searchTimeRanges = []string{"day", "week", "month", "year"}
+
+// SearchQuery is a backend-independent search request.
+type SearchQuery struct {
+	Query     string
+	Count     int
+	TimeRange string // "", day, week, month or year
+	Site      string // restrict to this domain
+}
+
+// SearchResult is one hit from a backend.
+type SearchResult struct {
+	Title   string
+	URL     string
+	Snippet string
+	Sources []string
+}
+
+// SearchPage is a backend's answer to one query.
+type SearchPage struct {
+	Results     []SearchResult
+	CacheStatus string
+}
+
+// SearchBackend is a search engine behind the web_search tool.
+type SearchBackend interface {
+	Name() string
+	// SupportsTimeRange reports whether SearchQuery.TimeRange is applied.
+	SupportsTimeRange() bool
+	Search(ctx context.Context, q SearchQuery) (*SearchPage, error)
+}
+
+// WebSearchTool is the single web_search tool. It tries its backends in
+// order (or all at once with SearchFusion), deduplicates results by URL
+// and normalizes snippets.
+type WebSearchTool struct {
+	backends   []SearchBackend
+	maxResults int
+	strategy   string
+}
+
+func NewWebSearchTool(backends []SearchBackend, maxResults int, strategy string) *WebSearchTool {
+	if maxResults <= 0 || maxResults > 10 {
+		maxResults = 5
+	}
+	if strategy != SearchFusion {
+		strategy = SearchFallback
+	}
+	return &WebSearchTool{
+		backends:   backends,
+		maxResults: maxResults,
+		strategy:   strategy,
+	}
+}
+
+func (t *WebSearchTool) Name() string {
+	return "web_search"
+}
+
+func (t *WebSearchTool) Description() string {
+	return "Search the web for current information. Returns titles, URLs, and snippets from search results. Can limit results to recent pages or a single site."
+}
+
+func (t *WebSearchTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "Search query",
+			},
+			"count": map[string]interface{}{
+				"type":        "integer",
+				"description": "Number of results (1-10)",
+				"minimum":     1.0,
+				"maximum":     10.0,
+			},
+			"time_range": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: only pages from the past day, week, month or year",
+				"enum":        searchTimeRanges,
+			},
+			"site": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: only results from this domain, e.g. github.com",
+			},
+		},
+		"required": []string{"query"},
+	}
+}
+
+func (t *WebSearchTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	query, ok := args["query"].(string)
+	if !ok || strings.TrimSpace(query) == "" {
+		return "", fmt.Errorf("query is required")
+	}
+	if len(t.backends) == 0 {
+		return "Error: no search backend configured", nil
+	}
+
+	q := SearchQuery{Query: query, Count: t.maxResults}
+	if c, ok := args["count"].(float64); ok {
+		if int(c) > 0 && int(c) <= 10 {
+			q.Count = int(c)
+		}
+	}
+	if tr, ok := args["time_range"].(string); ok && tr != "" {
+		valid := false
+		for _, r := range searchTimeRanges {
+			if tr == r {
+				valid = true
+			}
+		}
+		if !valid {
+			return "", fmt.Errorf("time_range must be one of %s", strings.Join(searchTimeRanges, ", "))
+		}
+		q.TimeRange = tr
+	}
+	if site, ok := args["site"].(string); ok {
+		q.Site = normalizeSite(site)
+	}
+
+	var pages []backendPage
+	if t.strategy == SearchFusion {
+		pages = t.searchAll(ctx, q)
+	} else {
+		pages = t.searchInOrder(ctx, q)
+	}
+
+	var failures []string
+	var succeeded []backendPage
+	for _, p := range pages {
+		if p.err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", p.backend.Name(), p.err))
+			continue
+		}
+		succeeded = append(succeeded, p)
+	}
+	if len(succeeded) == 0 {
+		return "", fmt.Errorf("all search backends failed: %s", strings.Join(failures, "; "))
+	}
+	if len(failures) > 0 {
+		logger.WarnCF("tool", "Some search backends failed",
+			map[string]interface{}{"query": query, "failures": strings.Join(failures, "; ")})
+	}
+
+	var results []SearchResult
+	if t.strategy == SearchFusion {
+		results = fuseResults(succeeded)
+	} else {
+		results = mergeResults(succeeded)
+	}
+	if q.Site != "" {
+		results = filterSite(results, q.Site)
+	}
+	if len(results) > q.Count {
+		results = results[:q.Count]
+	}
+	if len(results) == 0 {
+		return fmt.Sprintf("No results for: %s", query), nil
+	}
+
+	var lines []string
+	lines = append(lines, fmt.Sprintf("Results for: %s", query))
+	for i, item := range results {
+		lines = append(lines, fmt.Sprintf("%d. %s\n   %s", i+1, item.Title, item.URL))
+		if item.Snippet != "" {
+			lines = append(lines, fmt.Sprintf("   %s", item.Snippet))
+		}
+		if len(item.Sources) > 1 {
+			lines = append(lines, fmt.Sprintf("   (found by %s)", strings.Join(item.Sources, ", ")))
+		}
+	}
+	if q.TimeRange != "" {
+		var ignored []string
+		for _, p := range succeeded {
+			if !p.backend.SupportsTimeRange() {
+				ignored = append(ignored, p.backend.Name())
+			}
+		}
+		if len(ignored) > 0 {
+			lines = append(lines, fmt.Sprintf("[time_range not supported by %s; those results may be older]", strings.Join(ignored, ", ")))
+		}
+	}
+
+	return withCacheNote(strings.Join(lines, "\n"), combinedCacheStatus(succeeded)), nil
+}
+
+type backendPage struct {
+	backend SearchBackend
+	page    *SearchPage
+	err     error
+}
+
+// searchInOrder stops at the first backends that together return enough
+// distinct results.
+func (t *WebSearchTool) searchInOrder(ctx context.Context, q SearchQuery) []backendPage {
+	var pages []backendPage
+	for _, b := range t.backends {
+		page, err := b.Search(ctx, q)
+		pages = append(pages, backendPage{backend: b, page: page, err: err})
+		if err == nil {
+			results := mergeResults(successful(pages))
+			if q.Site != "" {
+				results = filterSite(results, q.Site)
+			}
+			if len(results) >= q.Count {
+				break
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return pages
+}
+
+// searchAll queries every backend concurrently, keeping backend order.
+func (t *WebSearchTool) searchAll(ctx context.Context, q SearchQuery) []backendPage {
+	pages := make([]backendPage, len(t.backends))
+	var wg sync.WaitGroup
+	for i, b := range t.backends {
+		wg.Add(1)
+		go func(i int, b SearchBackend) {
+			defer wg.Done()
+			page, err := b.Search(ctx, q)
+			pages[i] = backendPage{backend: b, page: page, err: err}
+		}(i, b)
+	}
+	wg.Wait()
+	return pages
+}
+
+func successful(pages []backendPage) []backendPage {
+	var out []backendPage
+	for _, p := range pages {
+		if p.err == nil {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// mergeResults concatenates pages in order, dropping URLs already seen.
+func mergeResults(pages []backendPage) []SearchResult {
+	var out []SearchResult
+	index := map[string]int{}
+	for _, p := range pages {
+		for _, r := range p.page.Results {
+			r = normalizeResult(r)
+			if r.URL == "" {
+				continue
+			}
+			key := canonicalURL(r.URL)
+			if i, seen := index[key]; seen {
+				out[i] = mergeDuplicate(out[i], r, p.backend.Name())
+				continue
+			}
+			r.Sources = []string{p.backend.Name()}
+			index[key] = len(out)
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// fuseResults ranks results by reciprocal rank fusion across backends, so
+// URLs several engines rank highly come first.
+func fuseResults(pages []backendPage) []SearchResult {
+	merged := mergeResults(pages)
+	scores := make([]float64, len(merged))
+	index := map[string]int{}
+	for i, r := range merged {
+		index[canonicalURL(r.URL)] = i
+	}
+	for _, p := range pages {
+		seen := map[string]bool{}
+		rank := 0
+		for _, r := range p.page.Results {
+			key := canonicalURL(strings.TrimSpace(r.URL))
+			i, ok := index[key]
+			if !ok || seen[key] {
+				continue
+			}
+			seen[key] = true
+			rank++
+			scores[i] += 1.0 / float64(rrfK+rank)
+		}
+	}
+	order := make([]int, len(merged))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+	out := make([]SearchResult, len(merged))
+	for i, idx := range order {
+		out[i] = merged[idx]
+	}
+	return out
+}
+
+func mergeDuplicate(existing, dup SearchResult, source string) SearchResult {
+	if existing.Title == "" {
+		existing.Title = dup.Title
+	}
+	if len(dup.Snippet) > len(existing.Snippet) {
+		existing.Snippet = dup.Snippet
+	}
+	for _, s := range existing.Sources {
+		if s == source {
+			return existing
+		}
+	}
+	existing.Sources = append(existing.Sources, source)
+	return existing
+}
+
+var searchTagRe = regexp.MustCompile(`<[^>]*>`)
+
+// normalizeResult strips markup and entities from titles and snippets and
+// caps snippet length.
+func normalizeResult(r SearchResult) SearchResult {
+	r.URL = strings.TrimSpace(r.URL)
+	r.Title = cleanSearchText(r.Title)
+	r.Snippet = cleanSearchText(r.Snippet)
+	if runes := []rune(r.Snippet); len(runes) > searchSnippetLen {
+		r.Snippet = strings.TrimSpace(string(runes[:searchSnippetLen])) + "..."
+	}
+	if r.Title == "" {
+		r.Title = r.URL
+	}
+	return r
+}
+
+func cleanSearchText(s string) string {
+	s = searchTagRe.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// canonicalURL is the deduplication key: scheme, "www.", fragment,
+// trailing slash and tracking parameters do not make a URL distinct.
+func canonicalURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return strings.ToLower(raw)
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	query := u.Query()
+	for k := range query {
+		if strings.HasPrefix(k, "utm_") || k == "ref" || k == "fbclid" || k == "gclid" {
+			query.Del(k)
+		}
+	}
+	key := host + strings.TrimSuffix(u.EscapedPath(), "/")
+	if enc := query.Encode(); enc != "" {
+		key += "?" + enc
+	}
+	return key
+}
+
+// normalizeSite reduces "https://www.example.com/path" to "example.com".
+func normalizeSite(site string) string {
+	site = strings.TrimSpace(strings.ToLower(site))
+	site = strings.TrimPrefix(site, "site:")
+	if i := strings.Index(site, "://"); i >= 0 {
+		site = site[i+3:]
+	}
+	if i := strings.IndexAny(site, "/?#"); i >= 0 {
+		site = site[:i]
+	}
+	return strings.TrimPrefix(site, "www.")
+}
+
+// filterSite drops results outside site or its subdomains.
+func filterSite(results []SearchResult, site string) []SearchResult {
+	var out []SearchResult
+	for _, r := range results {
+		u, err := url.Parse(r.URL)
+		if err != nil {
+			continue
+		}
+		host := strings.ToLower(u.Hostname())
+		if host == site || strings.HasSuffix(host, "."+site) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// siteQuery adds a site: operator for engines that understand it.
+func siteQuery(q SearchQuery) string {
+	if q.Site == "" {
+		return q.Query
+	}
+	return q.Query + " site:" + q.Site
+}
+
+// combinedCacheStatus is a hit only when every page came from the cache.
+func combinedCacheStatus(pages []backendPage) string {
+	for _, p := range pages {
+		if p.page.CacheStatus != CacheHit && p.page.CacheStatus != CacheRevalidated {
+			return p.page.CacheStatus
+		}
+	}
+	return CacheHit
+}
@@ -0,0 +1,188 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeBackend struct {
+	name      string
+	results   []SearchResult
+	err       error
+	timeRange bool
+	calls     int
+	lastQuery SearchQuery
+}
+
+func (f *fakeBackend) Name() string            { return f.name }
+func (f *fakeBackend) SupportsTimeRange() bool { return f.timeRange }
+
+func (f *fakeBackend) Search(ctx context.Context, q SearchQuery) (*SearchPage, error) {
+	f.calls++
+	f.lastQuery = q
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &SearchPage{Results: f.results, CacheStatus: CacheMiss}, nil
+}
+
+func TestWebSearch_FallsBackOnError(t *testing.T) {
+	broken := &fakeBackend{name: "brave", err: errors.New("status 429")}
+	ddg := &fakeBackend{name: "duckduckgo", results: []SearchResult{{Title: "Go", URL: "https://go.dev/"}}}
+	tool := NewWebSearchTool([]SearchBackend{broken, ddg}, 1, SearchFallback)
+
+	out, err := tool.Execute(context.Background(), map[string]interface{}{"query": "golang"})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if !strings.Contains(out, "https://go.dev/") {
+		t.Errorf("expected fallback results, got:\n%s", out)
+	}
+}
+
+func TestWebSearch_StopsWhenEnoughResults(t *testing.T) {
+	first := &fakeBackend{name: "brave", results: []SearchResult{{Title: "A", URL: "https://a.com"}, {Title: "B", URL: "https://b.com"}}}
+	second := &fakeBackend{name: "duckduckgo"}
+	tool := NewWebSearchTool([]SearchBackend{first, second}, 2, SearchFallback)
+
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"query": "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if second.calls != 0 {
+		t.Errorf("second backend called %d times, want 0", second.calls)
+	}
+}
+
+func TestWebSearch_AllBackendsFail(t *testing.T) {
+	tool := NewWebSearchTool([]SearchBackend{
+		&fakeBackend{name: "brave", err: errors.New("boom")},
+		&fakeBackend{name: "duckduckgo", err: errors.New("blocked")},
+	}, 5, SearchFallback)
+
+	_, err := tool.Execute(context.Background(), map[string]interface{}{"query": "x"})
+	if err == nil || !strings.Contains(err.Error(), "brave: boom") || !strings.Contains(err.Error(), "duckduckgo: blocked") {
+		t.Errorf("error = %v, want both failures", err)
+	}
+}
+
+func TestWebSearch_DedupesAndNormalizes(t *testing.T) {
+	brave := &fakeBackend{name: "brave", results: []SearchResult{
+		{Title: "<b>Go</b> &amp; you", URL: "https://www.go.dev/doc/?utm_source=x", Snippet: "short"},
+	}}
+	ddg := &fakeBackend{name: "duckduckgo", results: []SearchResult{
+		{Title: "Go docs", URL: "http://go.dev/doc#intro", Snippet: "a   <em>longer</em>\n snippet"},
+		{Title: "Other", URL: "https://other.org"},
+	}}
+	tool := NewWebSearchTool([]SearchBackend{brave, ddg}, 5, SearchFallback)
+
+	out, err := tool.Execute(context.Background(), map[string]interface{}{"query": "go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(out, "go.dev") != 1 {
+		t.Errorf("duplicate URL not merged:\n%s", out)
+	}
+	for _, want := range []string{"1. Go & you", "a longer snippet", "(found by brave, duckduckgo)", "2. Other"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWebSearch_FusionRanksSharedResultsFirst(t *testing.T) {
+	a := &fakeBackend{name: "brave", results: []SearchResult{{Title: "Only A", URL: "https://a.com"}, {Title: "Shared", URL: "https://shared.com"}}}
+	b := &fakeBackend{name: "ollama", results: []SearchResult{{Title: "Only B", URL: "https://b.com"}, {Title: "Shared", URL: "https://shared.com/"}}}
+	tool := NewWebSearchTool([]SearchBackend{a, b}, 5, SearchFusion)
+
+	out, err := tool.Execute(context.Background(), map[string]interface{}{"query": "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "1. Shared") {
+		t.Errorf("expected shared result first:\n%s", out)
+	}
+	if a.calls != 1 || b.calls != 1 {
+		t.Errorf("fusion should query every backend, calls = %d, %d", a.calls, b.calls)
+	}
+}
+
+func TestWebSearch_SiteAndTimeRange(t *testing.T) {
+	ollama := &fakeBackend{name: "ollama", results: []SearchResult{
+		{Title: "Issue", URL: "https://github.com/sipeed/picoclaw/issues/1"},
+		{Title: "Blog", URL: "https://blog.example.com/picoclaw"},
+	}}
+	tool := NewWebSearchTool([]SearchBackend{ollama}, 5, SearchFallback)
+
+	out, err := tool.Execute(context.Background(), map[string]interface{}{
+		"query":      "picoclaw",
+		"site":       "https://www.github.com/",
+		"time_range": "week",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ollama.lastQuery.Site != "github.com" || ollama.lastQuery.TimeRange != "week" {
+		t.Errorf("query = %+v", ollama.lastQuery)
+	}
+	if strings.Contains(out, "blog.example.com") || !strings.Contains(out, "github.com/sipeed") {
+		t.Errorf("site filter not applied:\n%s", out)
+	}
+	if !strings.Contains(out, "time_range not supported by ollama") {
+		t.Errorf("expected time_range note:\n%s", out)
+	}
+
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"query": "x", "time_range": "decade"}); err == nil {
+		t.Error("expected invalid time_range error")
+	}
+}
+
+func TestDuckDuckGoBackend_ParsesResults(t *testing.T) {
+	var gotQuery, gotDF string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query().Get("q")
+		gotDF = r.URL.Query().Get("df")
+		w.Write([]byte(`<a class="result__a" href="//duckduckgo.com/l/?uddg=https%3A%2F%2Fgo.dev%2F">The <b>Go</b> site</a>
+<a class="result__snippet" href="#">Build &amp; ship</a>`))
+	}))
+	defer srv.Close()
+
+	b := NewDuckDuckGoSearchBackend(nil)
+	b.endpoint = srv.URL
+	page, err := b.Search(context.Background(), SearchQuery{Query: "golang", Count: 5, TimeRange: "month", Site: "go.dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotQuery != "golang site:go.dev" || gotDF != "m" {
+		t.Errorf("q=%q df=%q", gotQuery, gotDF)
+	}
+	if len(page.Results) != 1 || page.Results[0].URL != "https://go.dev/" || page.Results[0].Title != "The Go site" {
+		t.Errorf("results = %+v", page.Results)
+	}
+}
+
+func TestBraveBackend_SendsFreshness(t *testing.T) {
+	var gotFreshness, gotToken string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotFreshness = r.URL.Query().Get("freshness")
+		gotToken = r.Header.Get("X-Subscription-Token")
+		w.Write([]byte(`{"web":{"results":[{"title":"T","url":"https://t.com","description":"D"}]}}`))
+	}))
+	defer srv.Close()
+
+	b := NewBraveSearchBackend("key", nil)
+	b.endpoint = srv.URL
+	page, err := b.Search(context.Background(), SearchQuery{Query: "q", Count: 3, TimeRange: "day"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotFreshness != "pd" || gotToken != "key" {
+		t.Errorf("freshness=%q token=%q", gotFreshness, gotToken)
+	}
+	if len(page.Results) != 1 || page.Results[0].Snippet != "D" {
+		t.Errorf("results = %+v", page.Results)
+	}
+}
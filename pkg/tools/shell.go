@@ -32,6 +32,7 @@ func NewExecTool(workingDir string) *ExecTool {
 		regexp.MustCompile(`:\(\)\s*\{.*\};\s*:`),
 		// Sensitive file access patterns
 		regexp.MustCompile(`\.picoclaw/config\b`),                             // picoclaw config (contains API keys)
+		regexp.MustCompile(`\.picoclaw/quarantine\b`),                         // withheld tool results (prompt injections)
 		regexp.MustCompile(`/etc/(shadow|gshadow|master\.passwd)\b`),          // password databases
 		regexp.MustCompile(`/\.(ssh|gnupg)/`),                                 // SSH and GPG keys
 		regexp.MustCompile(`\.(pem|p12|pfx|key|keystore|jks)\b`),             // private key files
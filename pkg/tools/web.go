@@ -4,99 +4,72 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/sipeed/picoclaw/pkg/extract"
 )
 
 const (
 	userAgent = "Mozilla/5.0 (compatible; picoclaw/1.0)"
+
+	// maxFetchBytes bounds downloads; documents are extracted in memory.
+	maxFetchBytes = 20 << 20
 )
 
-type WebSearchTool struct {
-	apiKey     string
-	maxResults int
-}
+// braveFreshness maps time ranges to Brave's freshness parameter.
+var braveFreshness = map[string]string{"day": "pd", "week": "pw", "month": "pm", "year": "py"}
 
-func NewWebSearchTool(apiKey string, maxResults int) *WebSearchTool {
-	if maxResults <= 0 || maxResults > 10 {
-		maxResults = 5
-	}
-	return &WebSearchTool{
-		apiKey:     apiKey,
-		maxResults: maxResults,
-	}
+// BraveSearchBackend queries the Brave Search API.
+type BraveSearchBackend struct {
+	apiKey   string
+	cache    *WebCache
+	endpoint string
 }
 
-func (t *WebSearchTool) Name() string {
-	return "web_search"
+func NewBraveSearchBackend(apiKey string, cache *WebCache) *BraveSearchBackend {
+	return &BraveSearchBackend{
+		apiKey:   apiKey,
+		cache:    cache,
+		endpoint: "https://api.search.brave.com/res/v1/web/search",
+	}
 }
 
-func (t *WebSearchTool) Description() string {
-	return "Search the web for current information. Returns titles, URLs, and snippets from search results."
+func (b *BraveSearchBackend) Name() string {
+	return "brave"
 }
 
-func (t *WebSearchTool) Parameters() map[string]interface{} {
-	return map[string]interface{}{
-		"type": "object",
-		"properties": map[string]interface{}{
-			"query": map[string]interface{}{
-				"type":        "string",
-				"description": "Search query",
-			},
-			"count": map[string]interface{}{
-				"type":        "integer",
-				"description": "Number of results (1-10)",
-				"minimum":     1.0,
-				"maximum":     10.0,
-			},
-		},
-		"required": []string{"query"},
-	}
+func (b *BraveSearchBackend) SupportsTimeRange() bool {
+	return true
 }
 
-func (t *WebSearchTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
-	if t.apiKey == "" {
-		return "Error: BRAVE_API_KEY not configured", nil
-	}
-
-	query, ok := args["query"].(string)
-	if !ok {
-		return "", fmt.Errorf("query is required")
+func (b *BraveSearchBackend) Search(ctx context.Context, q SearchQuery) (*SearchPage, error) {
+	params := url.Values{}
+	params.Set("q", siteQuery(q))
+	params.Set("count", fmt.Sprint(q.Count))
+	if f, ok := braveFreshness[q.TimeRange]; ok {
+		params.Set("freshness", f)
 	}
 
-	count := t.maxResults
-	if c, ok := args["count"].(float64); ok {
-		if int(c) > 0 && int(c) <= 10 {
-			count = int(c)
-		}
-	}
-
-	searchURL := fmt.Sprintf("https://api.search.brave.com/res/v1/web/search?q=%s&count=%d",
-		url.QueryEscape(query), count)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", b.endpoint+"?"+params.Encode(), nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Accept", "application/json")
-	req.Header.Set("X-Subscription-Token", t.apiKey)
+	req.Header.Set("X-Subscription-Token", b.apiKey)
 
 	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := b.cache.Do(client, req, nil)
 	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
 	}
 
 	var searchResp struct {
@@ -109,40 +82,29 @@ func (t *WebSearchTool) Execute(ctx context.Context, args map[string]interface{}
 		} `json:"web"`
 	}
 
-	if err := json.Unmarshal(body, &searchResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	results := searchResp.Web.Results
-	if len(results) == 0 {
-		return fmt.Sprintf("No results for: %s", query), nil
+	if err := json.Unmarshal(resp.Body, &searchResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	var lines []string
-	lines = append(lines, fmt.Sprintf("Results for: %s", query))
-	for i, item := range results {
-		if i >= count {
-			break
-		}
-		lines = append(lines, fmt.Sprintf("%d. %s\n   %s", i+1, item.Title, item.URL))
-		if item.Description != "" {
-			lines = append(lines, fmt.Sprintf("   %s", item.Description))
-		}
+	page := &SearchPage{CacheStatus: resp.CacheStatus}
+	for _, item := range searchResp.Web.Results {
+		page.Results = append(page.Results, SearchResult{Title: item.Title, URL: item.URL, Snippet: item.Description})
 	}
-
-	return strings.Join(lines, "\n"), nil
+	return page, nil
 }
 
 type WebFetchTool struct {
 	maxChars int
+	cache    *WebCache
 }
 
-func NewWebFetchTool(maxChars int) *WebFetchTool {
+func NewWebFetchTool(maxChars int, cache *WebCache) *WebFetchTool {
 	if maxChars <= 0 {
 		maxChars = 50000
 	}
 	return &WebFetchTool{
 		maxChars: maxChars,
+		cache:    cache,
 	}
 }
 
@@ -151,7 +113,7 @@ func (t *WebFetchTool) Name() string {
 }
 
 func (t *WebFetchTool) Description() string {
-	return "Fetch a URL and extract readable content (HTML to text). Use this to get weather info, news, articles, or any web content."
+	return "Fetch a URL and extract readable content: the main text of HTML pages as Markdown, PDF and DOCX text, and RSS/Atom feeds as item lists. Use this to get weather info, news, articles, or any web content."
 }
 
 func (t *WebFetchTool) Parameters() map[string]interface{} {
@@ -230,38 +192,27 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 		},
 	}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	resp, err := t.cache.Do(client, req, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return "", err
 	}
+	body := resp.Body
 
 	contentType := resp.Header.Get("Content-Type")
+	finalURL := resp.FinalURL
+	finalPath := parsedURL.Path
+	if u, err := url.Parse(finalURL); err == nil {
+		finalPath = u.Path
+	}
 
-	var text, extractor string
-
-	if strings.Contains(contentType, "application/json") {
-		var jsonData interface{}
-		if err := json.Unmarshal(body, &jsonData); err == nil {
-			formatted, _ := json.MarshalIndent(jsonData, "", "  ")
-			text = string(formatted)
-			extractor = "json"
-		} else {
-			text = string(body)
-			extractor = "raw"
-		}
-	} else if strings.Contains(contentType, "text/html") || len(body) > 0 &&
-		(strings.HasPrefix(string(body), "<!DOCTYPE") || strings.HasPrefix(strings.ToLower(string(body)), "<html")) {
-		text = t.extractText(string(body))
-		extractor = "text"
-	} else {
+	var text, extractor, title string
+	extracted, err := extract.Extract(body, contentType, finalPath, extract.Options{BaseURL: finalURL})
+	if err != nil {
+		// Fall back to the raw body rather than failing the fetch
 		text = string(body)
 		extractor = "raw"
+	} else {
+		text, extractor, title = extracted.Text, extracted.Extractor, extracted.Title
 	}
 
 	truncated := len(text) > maxChars
@@ -277,6 +228,15 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 		"length":    len(text),
 		"text":      text,
 	}
+	if title != "" {
+		result["title"] = title
+	}
+	if finalURL != urlStr {
+		result["final_url"] = finalURL
+	}
+	if resp.CacheStatus != "" {
+		result["cache"] = resp.CacheStatus
+	}
 
 	resultJSON, _ := json.MarshalIndent(result, "", "  ")
 	return string(resultJSON), nil
@@ -349,93 +309,54 @@ func ssrfSafeDialContext(ctx context.Context, network, addr string) (net.Conn, e
 	return dialer.DialContext(ctx, network, net.JoinHostPort(host, port))
 }
 
-// OllamaSearchTool uses Ollama's free web search API as an alternative to Brave Search.
-type OllamaSearchTool struct {
-	apiKey     string
-	maxResults int
+// OllamaSearchBackend uses Ollama's web search API.
+type OllamaSearchBackend struct {
+	apiKey   string
+	cache    *WebCache
+	endpoint string
 }
 
-func NewOllamaSearchTool(apiKey string, maxResults int) *OllamaSearchTool {
-	if maxResults <= 0 || maxResults > 10 {
-		maxResults = 5
-	}
-	return &OllamaSearchTool{
-		apiKey:     apiKey,
-		maxResults: maxResults,
+func NewOllamaSearchBackend(apiKey string, cache *WebCache) *OllamaSearchBackend {
+	return &OllamaSearchBackend{
+		apiKey:   apiKey,
+		cache:    cache,
+		endpoint: "https://ollama.com/api/web_search",
 	}
 }
 
-func (t *OllamaSearchTool) Name() string {
-	return "web_search"
+func (b *OllamaSearchBackend) Name() string {
+	return "ollama"
 }
 
-func (t *OllamaSearchTool) Description() string {
-	return "Search the web for current information. Returns titles, URLs, and snippets from search results."
-}
-
-func (t *OllamaSearchTool) Parameters() map[string]interface{} {
-	return map[string]interface{}{
-		"type": "object",
-		"properties": map[string]interface{}{
-			"query": map[string]interface{}{
-				"type":        "string",
-				"description": "Search query",
-			},
-			"count": map[string]interface{}{
-				"type":        "integer",
-				"description": "Number of results (1-10)",
-				"minimum":     1.0,
-				"maximum":     10.0,
-			},
-		},
-		"required": []string{"query"},
-	}
+func (b *OllamaSearchBackend) SupportsTimeRange() bool {
+	return false
 }
 
-func (t *OllamaSearchTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
-	if t.apiKey == "" {
-		return "Error: Ollama API key not configured", nil
-	}
-
-	query, ok := args["query"].(string)
-	if !ok {
-		return "", fmt.Errorf("query is required")
-	}
-
-	count := t.maxResults
-	if c, ok := args["count"].(float64); ok {
-		if int(c) > 0 && int(c) <= 10 {
-			count = int(c)
-		}
-	}
-
+func (b *OllamaSearchBackend) Search(ctx context.Context, q SearchQuery) (*SearchPage, error) {
 	reqBody := map[string]interface{}{
-		"query":       query,
-		"max_results": count,
+		"query":       siteQuery(q),
+		"max_results": q.Count,
 	}
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://ollama.com/api/web_search", strings.NewReader(string(jsonData)))
+	req, err := http.NewRequestWithContext(ctx, "POST", b.endpoint, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	req.Header.Set("Authorization", "Bearer "+b.apiKey)
 
 	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := b.cache.Do(client, req, jsonData)
 	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
 	}
 
 	var searchResp struct {
@@ -446,36 +367,25 @@ func (t *OllamaSearchTool) Execute(ctx context.Context, args map[string]interfac
 		} `json:"results"`
 	}
 
-	if err := json.Unmarshal(body, &searchResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	if len(searchResp.Results) == 0 {
-		return fmt.Sprintf("No results for: %s", query), nil
+	if err := json.Unmarshal(resp.Body, &searchResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	var lines []string
-	lines = append(lines, fmt.Sprintf("Results for: %s", query))
-	for i, item := range searchResp.Results {
-		if i >= count {
-			break
-		}
-		lines = append(lines, fmt.Sprintf("%d. %s\n   %s", i+1, item.Title, item.URL))
-		if item.Content != "" {
-			lines = append(lines, fmt.Sprintf("   %s", item.Content))
-		}
+	page := &SearchPage{CacheStatus: resp.CacheStatus}
+	for _, item := range searchResp.Results {
+		page.Results = append(page.Results, SearchResult{Title: item.Title, URL: item.URL, Snippet: item.Content})
 	}
-
-	return strings.Join(lines, "\n"), nil
+	return page, nil
 }
 
 // OllamaFetchTool uses Ollama's free web fetch API as an alternative to direct fetching.
 type OllamaFetchTool struct {
 	apiKey string
+	cache  *WebCache
 }
 
-func NewOllamaFetchTool(apiKey string) *OllamaFetchTool {
-	return &OllamaFetchTool{apiKey: apiKey}
+func NewOllamaFetchTool(apiKey string, cache *WebCache) *OllamaFetchTool {
+	return &OllamaFetchTool{apiKey: apiKey, cache: cache}
 }
 
 func (t *OllamaFetchTool) Name() string {
@@ -517,7 +427,7 @@ func (t *OllamaFetchTool) Execute(ctx context.Context, args map[string]interface
 		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://ollama.com/api/web_fetch", strings.NewReader(string(jsonData)))
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://ollama.com/api/web_fetch", nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -526,16 +436,11 @@ func (t *OllamaFetchTool) Execute(ctx context.Context, args map[string]interface
 	req.Header.Set("Authorization", "Bearer "+t.apiKey)
 
 	client := &http.Client{Timeout: 60 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := t.cache.Do(client, req, jsonData)
 	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return "", err
 	}
+	body := resp.Body
 
 	var fetchResp struct {
 		Title   string   `json:"title"`
@@ -555,118 +460,85 @@ func (t *OllamaFetchTool) Execute(ctx context.Context, args map[string]interface
 	if len(fetchResp.Links) > 0 {
 		result["links"] = fetchResp.Links
 	}
+	if resp.CacheStatus != "" {
+		result["cache"] = resp.CacheStatus
+	}
 
 	resultJSON, _ := json.MarshalIndent(result, "", "  ")
 	return string(resultJSON), nil
 }
 
-// DuckDuckGoSearchTool scrapes DuckDuckGo's HTML search endpoint.
+// ddgTimeRange maps time ranges to DuckDuckGo's df parameter.
+var ddgTimeRange = map[string]string{"day": "d", "week": "w", "month": "m", "year": "y"}
+
+var (
+	ddgLinkRe    = regexp.MustCompile(`class="result__a"[^>]*href="([^"]*)"[^>]*>(.*?)</a>`)
+	ddgSnippetRe = regexp.MustCompile(`class="result__snippet"[^>]*>(.*?)</`)
+)
+
+// DuckDuckGoSearchBackend scrapes DuckDuckGo's HTML search endpoint.
 // No API key required - works as a free fallback search provider.
-type DuckDuckGoSearchTool struct {
-	maxResults int
+type DuckDuckGoSearchBackend struct {
+	cache    *WebCache
+	endpoint string
 }
 
-func NewDuckDuckGoSearchTool(maxResults int) *DuckDuckGoSearchTool {
-	if maxResults <= 0 || maxResults > 10 {
-		maxResults = 5
+func NewDuckDuckGoSearchBackend(cache *WebCache) *DuckDuckGoSearchBackend {
+	return &DuckDuckGoSearchBackend{
+		cache:    cache,
+		endpoint: "https://html.duckduckgo.com/html/",
 	}
-	return &DuckDuckGoSearchTool{maxResults: maxResults}
 }
 
-func (t *DuckDuckGoSearchTool) Name() string {
-	return "web_search"
+func (b *DuckDuckGoSearchBackend) Name() string {
+	return "duckduckgo"
 }
 
-func (t *DuckDuckGoSearchTool) Description() string {
-	return "Search the web for current information. Returns titles, URLs, and snippets from search results."
-}
-
-func (t *DuckDuckGoSearchTool) Parameters() map[string]interface{} {
-	return map[string]interface{}{
-		"type": "object",
-		"properties": map[string]interface{}{
-			"query": map[string]interface{}{
-				"type":        "string",
-				"description": "Search query",
-			},
-			"count": map[string]interface{}{
-				"type":        "integer",
-				"description": "Number of results (1-10)",
-				"minimum":     1.0,
-				"maximum":     10.0,
-			},
-		},
-		"required": []string{"query"},
-	}
+func (b *DuckDuckGoSearchBackend) SupportsTimeRange() bool {
+	return true
 }
 
-func (t *DuckDuckGoSearchTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
-	query, ok := args["query"].(string)
-	if !ok {
-		return "", fmt.Errorf("query is required")
+func (b *DuckDuckGoSearchBackend) Search(ctx context.Context, q SearchQuery) (*SearchPage, error) {
+	params := url.Values{}
+	params.Set("q", siteQuery(q))
+	if df, ok := ddgTimeRange[q.TimeRange]; ok {
+		params.Set("df", df)
 	}
 
-	count := t.maxResults
-	if c, ok := args["count"].(float64); ok {
-		if int(c) > 0 && int(c) <= 10 {
-			count = int(c)
-		}
-	}
-
-	searchURL := fmt.Sprintf("https://html.duckduckgo.com/html/?q=%s", url.QueryEscape(query))
-
-	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", b.endpoint+"?"+params.Encode(), nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
 
 	client := &http.Client{Timeout: 15 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := b.cache.Do(client, req, nil)
 	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
 	}
 
-	html := string(body)
+	html := string(resp.Body)
 
 	// Extract result blocks: links from class="result__a" and snippets from class="result__snippet"
-	linkRe := regexp.MustCompile(`class="result__a"[^>]*href="([^"]*)"[^>]*>(.*?)</a>`)
-	snippetRe := regexp.MustCompile(`class="result__snippet"[^>]*>(.*?)</`)
-
-	linkMatches := linkRe.FindAllStringSubmatch(html, -1)
-	snippetMatches := snippetRe.FindAllStringSubmatch(html, -1)
-
-	if len(linkMatches) == 0 {
-		return fmt.Sprintf("No results for: %s", query), nil
-	}
+	linkMatches := ddgLinkRe.FindAllStringSubmatch(html, -1)
+	snippetMatches := ddgSnippetRe.FindAllStringSubmatch(html, -1)
 
-	var lines []string
-	lines = append(lines, fmt.Sprintf("Results for: %s", query))
+	page := &SearchPage{CacheStatus: resp.CacheStatus}
 	for i, match := range linkMatches {
-		if i >= count {
-			break
+		result := SearchResult{
+			Title: stripHTMLTags(match[2]),
+			URL:   decodeDDGRedirectURL(match[1]),
 		}
-		rawURL := match[1]
-		title := stripHTMLTags(match[2])
-		realURL := decodeDDGRedirectURL(rawURL)
-
-		lines = append(lines, fmt.Sprintf("%d. %s\n   %s", i+1, title, realURL))
 		if i < len(snippetMatches) {
-			snippet := stripHTMLTags(snippetMatches[i][1])
-			if snippet != "" {
-				lines = append(lines, fmt.Sprintf("   %s", snippet))
-			}
+			result.Snippet = stripHTMLTags(snippetMatches[i][1])
 		}
+		page.Results = append(page.Results, result)
 	}
-
-	return strings.Join(lines, "\n"), nil
+	return page, nil
 }
 
 // decodeDDGRedirectURL extracts the actual URL from DuckDuckGo's redirect wrapper.
@@ -694,28 +566,3 @@ func stripHTMLTags(s string) string {
 	s = strings.ReplaceAll(s, "&nbsp;", " ")
 	return strings.TrimSpace(s)
 }
-
-func (t *WebFetchTool) extractText(htmlContent string) string {
-	re := regexp.MustCompile(`<script[\s\S]*?</script>`)
-	result := re.ReplaceAllLiteralString(htmlContent, "")
-	re = regexp.MustCompile(`<style[\s\S]*?</style>`)
-	result = re.ReplaceAllLiteralString(result, "")
-	re = regexp.MustCompile(`<[^>]+>`)
-	result = re.ReplaceAllLiteralString(result, "")
-
-	result = strings.TrimSpace(result)
-
-	re = regexp.MustCompile(`\s+`)
-	result = re.ReplaceAllLiteralString(result, " ")
-
-	lines := strings.Split(result, "\n")
-	var cleanLines []string
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" {
-			cleanLines = append(cleanLines, line)
-		}
-	}
-
-	return strings.Join(cleanLines, "\n")
-}
@@ -0,0 +1,322 @@
+package tools
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Cache status values reported by web tools.
+const (
+	CacheMiss        = "miss"
+	CacheHit         = "hit"
+	CacheRevalidated = "revalidated"
+)
+
+// CachedResponse is a fully read HTTP response, possibly served from disk.
+type CachedResponse struct {
+	StatusCode  int
+	Header      http.Header
+	Body        []byte
+	FinalURL    string
+	CacheStatus string
+}
+
+// webCacheEntry is the on-disk form of a cached response.
+type webCacheEntry struct {
+	URL          string      `json:"url"`
+	FinalURL     string      `json:"final_url"`
+	StatusCode   int         `json:"status"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+	StoredAt     time.Time   `json:"stored_at"`
+	ExpiresAt    time.Time   `json:"expires_at"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"last_modified,omitempty"`
+}
+
+// cachedHeaders are the response headers worth keeping; cookies and the
+// like are never written to disk.
+var cachedHeaders = []string{"Content-Type", "Content-Language", "Cache-Control", "Expires", "ETag", "Last-Modified"}
+
+// WebCache is an on-disk HTTP response cache shared by the web tools. It
+// serves fresh entries without touching the network, revalidates stale
+// ones with If-None-Match/If-Modified-Since, and honors Cache-Control
+// no-store, no-cache and max-age (capped at the configured TTL). A nil
+// *WebCache performs every request uncached.
+type WebCache struct {
+	dir      string
+	ttl      time.Duration
+	maxBytes int64
+	mu       sync.Mutex
+}
+
+// NewWebCache creates a cache in dir. Entries live at most ttl, and the
+// oldest are evicted once the directory exceeds maxBytes (0 = unlimited).
+func NewWebCache(dir string, ttl time.Duration, maxBytes int64) *WebCache {
+	return &WebCache{dir: dir, ttl: ttl, maxBytes: maxBytes}
+}
+
+// Do performs req through the cache. body is the request body for POST
+// APIs; it is part of the cache key and is re-sent on revalidation.
+func (c *WebCache) Do(client *http.Client, req *http.Request, body []byte) (*CachedResponse, error) {
+	if c == nil || (req.Method != http.MethodGet && req.Method != http.MethodPost) {
+		return doUncached(client, req, body)
+	}
+
+	key := webCacheKey(req.Method, req.URL.String(), body)
+	entry := c.load(key)
+	now := time.Now()
+	if entry != nil && now.Before(entry.ExpiresAt) {
+		return entry.response(CacheHit), nil
+	}
+
+	if entry != nil {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := doUncached(client, req, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		// Refresh freshness from the 304's headers, falling back to the stored ones
+		header := entry.Header.Clone()
+		for _, h := range cachedHeaders {
+			if v := resp.Header.Get(h); v != "" {
+				header.Set(h, v)
+			}
+		}
+		entry.Header = header
+		entry.StoredAt = now
+		entry.ExpiresAt = now.Add(c.freshness(header, now))
+		c.store(key, entry)
+		return entry.response(CacheRevalidated), nil
+	}
+
+	resp.CacheStatus = CacheMiss
+	if resp.StatusCode == http.StatusOK && cacheable(resp.Header) {
+		header := http.Header{}
+		for _, h := range cachedHeaders {
+			if v := resp.Header.Get(h); v != "" {
+				header.Set(h, v)
+			}
+		}
+		c.store(key, &webCacheEntry{
+			URL:          req.URL.String(),
+			FinalURL:     resp.FinalURL,
+			StatusCode:   resp.StatusCode,
+			Header:       header,
+			Body:         resp.Body,
+			StoredAt:     now,
+			ExpiresAt:    now.Add(c.freshness(resp.Header, now)),
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		})
+	}
+	return resp, nil
+}
+
+func (e *webCacheEntry) response(status string) *CachedResponse {
+	return &CachedResponse{
+		StatusCode:  e.StatusCode,
+		Header:      e.Header,
+		Body:        e.Body,
+		FinalURL:    e.FinalURL,
+		CacheStatus: status,
+	}
+}
+
+// freshness returns how long a response may be served without
+// revalidation: max-age, else Expires, else the TTL, never above the TTL.
+func (c *WebCache) freshness(header http.Header, now time.Time) time.Duration {
+	directives := cacheControl(header)
+	if _, ok := directives["no-cache"]; ok {
+		return 0
+	}
+	fresh := c.ttl
+	if v, ok := directives["max-age"]; ok {
+		if secs, err := strconv.Atoi(v); err == nil {
+			fresh = time.Duration(secs) * time.Second
+		}
+	} else if exp := header.Get("Expires"); exp != "" {
+		if t, err := http.ParseTime(exp); err == nil {
+			fresh = t.Sub(now)
+		} else {
+			fresh = 0 // invalid Expires means already expired
+		}
+	}
+	if fresh > c.ttl {
+		fresh = c.ttl
+	}
+	if fresh < 0 {
+		fresh = 0
+	}
+	return fresh
+}
+
+// cacheable reports whether a response may be written to disk at all.
+func cacheable(header http.Header) bool {
+	_, noStore := cacheControl(header)["no-store"]
+	return !noStore
+}
+
+func cacheControl(header http.Header) map[string]string {
+	directives := map[string]string{}
+	for _, part := range strings.Split(header.Get("Cache-Control"), ",") {
+		part = strings.TrimSpace(strings.ToLower(part))
+		if part == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(part, "=")
+		directives[name] = strings.Trim(value, `"`)
+	}
+	return directives
+}
+
+func webCacheKey(method, url string, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", method, url)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *WebCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+func (c *WebCache) load(key string) *webCacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil
+	}
+	var entry webCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		os.Remove(c.path(key))
+		return nil
+	}
+	return &entry
+}
+
+func (c *WebCache) store(key string, entry *webCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if c.maxBytes > 0 && int64(len(data)) > c.maxBytes {
+		return
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		logger.WarnCF("web", "Failed to create web cache directory",
+			map[string]interface{}{"dir": c.dir, "error": err.Error()})
+		return
+	}
+	tmp := c.path(key) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, c.path(key)); err != nil {
+		os.Remove(tmp)
+		return
+	}
+	c.prune()
+}
+
+// prune evicts the least recently stored entries until the cache fits in
+// maxBytes. Callers hold c.mu.
+func (c *WebCache) prune() {
+	if c.maxBytes <= 0 {
+		return
+	}
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	type file struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var files []file
+	var total int64
+	for _, de := range dirEntries {
+		if de.IsDir() || filepath.Ext(de.Name()) != ".json" {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, file{filepath.Join(c.dir, de.Name()), info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+	if total <= c.maxBytes {
+		return
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	for _, f := range files {
+		if total <= c.maxBytes {
+			break
+		}
+		if os.Remove(f.path) == nil {
+			total -= f.size
+		}
+	}
+}
+
+// doUncached sends req and reads the body, up to maxFetchBytes.
+func doUncached(client *http.Client, req *http.Request, body []byte) (*CachedResponse, error) {
+	if body != nil {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return &CachedResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       data,
+		FinalURL:   resp.Request.URL.String(),
+	}, nil
+}
+
+// withCacheNote marks a text result served from the cache.
+func withCacheNote(text, status string) string {
+	if status == CacheHit || status == CacheRevalidated {
+		return text + "\n[cache: " + status + "]"
+	}
+	return text
+}
@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func cacheGet(t *testing.T, c *WebCache, url string) *CachedResponse {
+	t.Helper()
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Do(http.DefaultClient, req, nil)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	return resp
+}
+
+func TestWebCache_HitWithinTTL(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	c := NewWebCache(t.TempDir(), time.Hour, 0)
+	if resp := cacheGet(t, c, srv.URL); resp.CacheStatus != CacheMiss {
+		t.Errorf("first fetch status = %q, want miss", resp.CacheStatus)
+	}
+	resp := cacheGet(t, c, srv.URL)
+	if resp.CacheStatus != CacheHit || string(resp.Body) != "hello" {
+		t.Errorf("second fetch = %q %q, want hit hello", resp.CacheStatus, resp.Body)
+	}
+	if resp.Header.Get("Content-Type") != "text/plain" {
+		t.Errorf("cached Content-Type = %q", resp.Header.Get("Content-Type"))
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("server called %d times, want 1", n)
+	}
+}
+
+func TestWebCache_RevalidatesWithETag(t *testing.T) {
+	var calls, notModified int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "no-cache")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("body v1"))
+	}))
+	defer srv.Close()
+
+	c := NewWebCache(t.TempDir(), time.Hour, 0)
+	cacheGet(t, c, srv.URL)
+	resp := cacheGet(t, c, srv.URL)
+	if resp.CacheStatus != CacheRevalidated || string(resp.Body) != "body v1" {
+		t.Errorf("got %q %q, want revalidated body v1", resp.CacheStatus, resp.Body)
+	}
+	if calls != 2 || notModified != 1 {
+		t.Errorf("calls=%d notModified=%d, want 2 and 1", calls, notModified)
+	}
+}
+
+func TestWebCache_NoStoreAndMaxAge(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		switch r.URL.Path {
+		case "/nostore":
+			w.Header().Set("Cache-Control", "no-store")
+		case "/expired":
+			w.Header().Set("Cache-Control", "max-age=0")
+		}
+		w.Write([]byte("x"))
+	}))
+	defer srv.Close()
+
+	c := NewWebCache(t.TempDir(), time.Hour, 0)
+	for _, path := range []string{"/nostore", "/expired"} {
+		cacheGet(t, c, srv.URL+path)
+		if resp := cacheGet(t, c, srv.URL+path); resp.CacheStatus != CacheMiss {
+			t.Errorf("%s second fetch = %q, want miss", path, resp.CacheStatus)
+		}
+	}
+	if calls != 4 {
+		t.Errorf("server called %d times, want 4", calls)
+	}
+}
+
+func TestWebCache_PostBodyIsPartOfKey(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	c := NewWebCache(t.TempDir(), time.Hour, 0)
+	post := func(body string) string {
+		req, _ := http.NewRequest("POST", srv.URL, nil)
+		resp, err := c.Do(http.DefaultClient, req, []byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.CacheStatus
+	}
+	post(`{"query":"a"}`)
+	if got := post(`{"query":"b"}`); got != CacheMiss {
+		t.Errorf("different body = %q, want miss", got)
+	}
+	if got := post(`{"query":"a"}`); got != CacheHit {
+		t.Errorf("same body = %q, want hit", got)
+	}
+}
+
+func TestWebCache_PrunesToMaxSize(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 1000))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	c := NewWebCache(dir, time.Hour, 5000)
+	for _, path := range []string{"/a", "/b", "/c", "/d"} {
+		cacheGet(t, c, srv.URL+path)
+	}
+	entries, _ := os.ReadDir(dir)
+	var total int64
+	for _, e := range entries {
+		info, _ := e.Info()
+		total += info.Size()
+	}
+	if total > 5000 {
+		t.Errorf("cache size = %d, want <= 5000", total)
+	}
+	if len(entries) == 0 || len(entries) == 4 {
+		t.Errorf("cache has %d entries, want some but not all", len(entries))
+	}
+}
+
+func TestWebCache_NilPassesThrough(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("direct"))
+	}))
+	defer srv.Close()
+
+	var c *WebCache
+	resp := cacheGet(t, c, srv.URL)
+	if string(resp.Body) != "direct" || resp.CacheStatus != "" {
+		t.Errorf("got %q %q", resp.CacheStatus, resp.Body)
+	}
+}
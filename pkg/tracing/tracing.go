@@ -0,0 +1,356 @@
+// Package tracing records span trees for agent turns and exports them over
+// OTLP/HTTP (JSON encoding) or to a JSONL file. It follows the OpenTelemetry
+// data model without depending on the OpenTelemetry SDK.
+//
+// Tracing is off until SetTracer installs a Tracer; until then Start returns
+// a nil *Span, and every Span method is a no-op on nil.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// MetadataKey is the InboundMessage.Metadata key that carries the trace ID.
+const MetadataKey = "trace_id"
+
+// SpanData is a finished span as handed to exporters.
+type SpanData struct {
+	TraceID  string                 `json:"trace_id"`
+	SpanID   string                 `json:"span_id"`
+	ParentID string                 `json:"parent_id,omitempty"`
+	Name     string                 `json:"name"`
+	Start    time.Time              `json:"start"`
+	End      time.Time              `json:"end"`
+	Attrs    map[string]interface{} `json:"attrs,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+}
+
+// Exporter receives batches of finished spans.
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+	Close() error
+}
+
+// Span is an operation in progress. The zero of *Span (nil) is a valid
+// no-op span.
+type Span struct {
+	tracer *Tracer
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+// TraceID returns the span's trace ID, or "" for a nil span.
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return s.data.TraceID
+}
+
+// SpanID returns the span's ID, or "" for a nil span.
+func (s *Span) SpanID() string {
+	if s == nil {
+		return ""
+	}
+	return s.data.SpanID
+}
+
+// SetAttr records an attribute; values should be strings, numbers or bools.
+func (s *Span) SetAttr(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Attrs == nil {
+		s.data.Attrs = make(map[string]interface{})
+	}
+	s.data.Attrs[key] = value
+}
+
+// SetError marks the span as failed. A nil err is ignored.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Error = err.Error()
+	s.mu.Unlock()
+}
+
+// End finishes the span and queues it for export. Later calls do nothing.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+	s.tracer.enqueue(data)
+}
+
+type spanKey struct{}
+type traceKey struct{}
+
+// FromContext returns the current span, or nil.
+func FromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// ContextWithSpan returns ctx with span as the current span.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	if span == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// WithTraceID makes the next root span started from ctx join traceID, e.g.
+// one carried in InboundMessage.Metadata.
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	if traceID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, traceKey{}, traceID)
+}
+
+// TraceIDFromContext returns the trace ID of the current span, or the one
+// set by WithTraceID.
+func TraceIDFromContext(ctx context.Context) string {
+	if s := FromContext(ctx); s != nil {
+		return s.TraceID()
+	}
+	id, _ := ctx.Value(traceKey{}).(string)
+	return id
+}
+
+// Detach returns a background context that keeps ctx's span, for work that
+// outlives the request (async delegation).
+func Detach(ctx context.Context) context.Context {
+	bg := context.Background()
+	if id, ok := ctx.Value(traceKey{}).(string); ok {
+		bg = WithTraceID(bg, id)
+	}
+	return ContextWithSpan(bg, FromContext(ctx))
+}
+
+// Start begins a span named name as a child of ctx's span, or as a root
+// span otherwise. It returns a context carrying the new span.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	t := current.Load()
+	if t == nil {
+		return ctx, nil
+	}
+	data := SpanData{Name: name, SpanID: newID(8), Start: time.Now()}
+	if parent := FromContext(ctx); parent != nil {
+		data.TraceID = parent.data.TraceID
+		data.ParentID = parent.data.SpanID
+	} else if id, ok := ctx.Value(traceKey{}).(string); ok && validTraceID(id) {
+		data.TraceID = id
+	} else {
+		data.TraceID = NewTraceID()
+	}
+	span := &Span{tracer: t, data: data}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// NewTraceID returns a random 32-hex-digit trace ID.
+func NewTraceID() string {
+	return newID(16)
+}
+
+func newID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func validTraceID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// LogFields adds trace_id and span_id from ctx to fields, for log entries
+// that should be correlated with a trace. fields may be nil.
+func LogFields(ctx context.Context, fields map[string]interface{}) map[string]interface{} {
+	traceID := TraceIDFromContext(ctx)
+	if traceID == "" {
+		return fields
+	}
+	if fields == nil {
+		fields = make(map[string]interface{}, 2)
+	}
+	fields["trace_id"] = traceID
+	if s := FromContext(ctx); s != nil {
+		fields["span_id"] = s.SpanID()
+	}
+	return fields
+}
+
+func init() {
+	// Entries logged with logger.InfoCtx etc. carry the trace and span IDs
+	logger.RegisterContextFields(func(ctx context.Context) map[string]interface{} {
+		return LogFields(ctx, nil)
+	})
+}
+
+var current atomic.Pointer[Tracer]
+
+// SetTracer installs t as the process tracer; nil turns tracing off. The
+// previous tracer, if any, is returned so the caller can shut it down.
+func SetTracer(t *Tracer) *Tracer {
+	return current.Swap(t)
+}
+
+// Enabled reports whether a tracer is installed.
+func Enabled() bool {
+	return current.Load() != nil
+}
+
+// Tracer batches finished spans and hands them to its exporters from a
+// background goroutine.
+type Tracer struct {
+	exporters []Exporter
+	queue     chan SpanData
+	flush     chan chan struct{}
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+	interval  time.Duration
+	batchSize int
+}
+
+// NewTracer starts a tracer that exports every interval or whenever
+// batchSize spans are waiting.
+func NewTracer(interval time.Duration, batchSize int, exporters ...Exporter) *Tracer {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	if batchSize <= 0 {
+		batchSize = 256
+	}
+	t := &Tracer{
+		exporters: exporters,
+		queue:     make(chan SpanData, batchSize*4),
+		flush:     make(chan chan struct{}),
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+		interval:  interval,
+		batchSize: batchSize,
+	}
+	go t.run()
+	return t
+}
+
+func (t *Tracer) enqueue(data SpanData) {
+	select {
+	case <-t.done:
+		return
+	default:
+	}
+	select {
+	case t.queue <- data:
+	default:
+		logger.WarnCF("tracing", "Span queue full, dropping span",
+			map[string]interface{}{"name": data.Name, "trace_id": data.TraceID})
+	}
+}
+
+func (t *Tracer) run() {
+	defer close(t.stopped)
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	var batch []SpanData
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		for _, e := range t.exporters {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if err := e.Export(ctx, batch); err != nil {
+				logger.WarnCF("tracing", "Span export failed",
+					map[string]interface{}{"spans": len(batch), "error": err.Error()})
+			}
+			cancel()
+		}
+		batch = nil
+	}
+	drain := func() {
+		for {
+			select {
+			case data := <-t.queue:
+				batch = append(batch, data)
+			default:
+				return
+			}
+		}
+	}
+	for {
+		select {
+		case data := <-t.queue:
+			batch = append(batch, data)
+			if len(batch) >= t.batchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case ack := <-t.flush:
+			drain()
+			export()
+			close(ack)
+		case <-t.done:
+			drain()
+			export()
+			return
+		}
+	}
+}
+
+// Flush exports all queued spans and waits until that is done or ctx ends.
+func (t *Tracer) Flush(ctx context.Context) {
+	ack := make(chan struct{})
+	select {
+	case t.flush <- ack:
+	case <-ctx.Done():
+		return
+	case <-t.done:
+		return
+	}
+	select {
+	case <-ack:
+	case <-ctx.Done():
+	}
+}
+
+// Shutdown exports the remaining spans and closes the exporters. Spans
+// ended afterwards are dropped.
+func (t *Tracer) Shutdown(ctx context.Context) {
+	t.closeOnce.Do(func() {
+		close(t.done)
+		select {
+		case <-t.stopped:
+		case <-ctx.Done():
+		}
+		for _, e := range t.exporters {
+			e.Close()
+		}
+	})
+}
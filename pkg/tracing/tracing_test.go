@@ -0,0 +1,191 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryExporter keeps exported spans for inspection.
+type memoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func (m *memoryExporter) Export(ctx context.Context, spans []SpanData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.spans = append(m.spans, spans...)
+	return nil
+}
+
+func (m *memoryExporter) Close() error { return nil }
+
+func (m *memoryExporter) byName() map[string]SpanData {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string]SpanData)
+	for _, s := range m.spans {
+		out[s.Name] = s
+	}
+	return out
+}
+
+func withTracer(t *testing.T, exporters ...Exporter) *Tracer {
+	t.Helper()
+	tracer := NewTracer(time.Hour, 100, exporters...)
+	SetTracer(tracer)
+	t.Cleanup(func() {
+		SetTracer(nil)
+		tracer.Shutdown(context.Background())
+	})
+	return tracer
+}
+
+func TestDisabledTracingIsNoop(t *testing.T) {
+	ctx, span := Start(context.Background(), "turn")
+	if span != nil || FromContext(ctx) != nil {
+		t.Fatal("Start should return a nil span without a tracer")
+	}
+	span.SetAttr("k", "v")
+	span.SetError(errors.New("x"))
+	span.End()
+	if fields := LogFields(ctx, nil); fields != nil {
+		t.Errorf("LogFields = %v, want nil", fields)
+	}
+}
+
+func TestSpanTree(t *testing.T) {
+	exp := &memoryExporter{}
+	tracer := withTracer(t, exp)
+
+	const carried = "0123456789abcdef0123456789abcdef"
+	ctx, turn := Start(WithTraceID(context.Background(), carried), "agent.turn")
+	toolCtx, tool := Start(ctx, "tool.exec")
+	tool.SetAttr("tool", "exec")
+	tool.SetError(errors.New("exit status 1"))
+	fields := LogFields(toolCtx, map[string]interface{}{"tool": "exec"})
+	tool.End()
+	tool.End() // second End is ignored
+
+	bg := Detach(ctx)
+	_, delegate := Start(bg, "agent.delegate")
+	delegate.End()
+	turn.End()
+
+	tracer.Flush(context.Background())
+	spans := exp.byName()
+	if len(exp.spans) != 3 {
+		t.Fatalf("exported %d spans, want 3", len(exp.spans))
+	}
+	if spans["agent.turn"].TraceID != carried || spans["agent.turn"].ParentID != "" {
+		t.Errorf("root span = %+v", spans["agent.turn"])
+	}
+	for _, name := range []string{"tool.exec", "agent.delegate"} {
+		if spans[name].TraceID != carried || spans[name].ParentID != turn.SpanID() {
+			t.Errorf("%s not a child of the turn: %+v", name, spans[name])
+		}
+	}
+	if spans["tool.exec"].Error != "exit status 1" || spans["tool.exec"].Attrs["tool"] != "exec" {
+		t.Errorf("tool span = %+v", spans["tool.exec"])
+	}
+	if fields["trace_id"] != carried || fields["span_id"] != tool.SpanID() {
+		t.Errorf("LogFields = %v", fields)
+	}
+}
+
+func TestInvalidCarriedTraceIDStartsNewTrace(t *testing.T) {
+	withTracer(t, &memoryExporter{})
+	_, span := Start(WithTraceID(context.Background(), "not-hex"), "turn")
+	if len(span.TraceID()) != 32 || span.TraceID() == "not-hex" {
+		t.Errorf("trace ID = %q", span.TraceID())
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var got otlpTraces
+	var path, auth string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("collector got invalid JSON: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	exp := NewOTLPExporter(collector.URL, "picoclaw-test", map[string]string{"Authorization": "Bearer abc"})
+	start := time.Unix(100, 0)
+	err := exp.Export(context.Background(), []SpanData{{
+		TraceID: "0123456789abcdef0123456789abcdef", SpanID: "0123456789abcdef", ParentID: "fedcba9876543210",
+		Name: "tool.exec", Start: start, End: start.Add(time.Second),
+		Attrs: map[string]interface{}{"tool": "exec", "iteration": 2}, Error: "boom",
+	}})
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if path != "/v1/traces" || auth != "Bearer abc" {
+		t.Errorf("path=%q auth=%q", path, auth)
+	}
+	rs := got.ResourceSpans[0]
+	if rs.Resource.Attributes[0].Value["stringValue"] != "picoclaw-test" {
+		t.Errorf("resource = %+v", rs.Resource)
+	}
+	span := rs.ScopeSpans[0].Spans[0]
+	if span.ParentSpanID != "fedcba9876543210" || span.StartTimeUnixNano != "100000000000" || span.Status.Code != 2 {
+		t.Errorf("span = %+v", span)
+	}
+	if len(span.Attributes) != 2 || span.Attributes[0].Key != "iteration" || span.Attributes[0].Value["intValue"] != "2" {
+		t.Errorf("attributes = %+v", span.Attributes)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer failing.Close()
+	if err := NewOTLPExporter(failing.URL+"/v1/traces", "x", nil).Export(context.Background(), nil); err == nil {
+		t.Error("expected an error for a 400 response")
+	}
+}
+
+func TestFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces", "spans.jsonl")
+	exp, err := NewFileExporter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer := withTracer(t, exp)
+	ctx, root := Start(context.Background(), "agent.turn")
+	_, child := Start(ctx, "llm.chat")
+	child.End()
+	root.End()
+	tracer.Shutdown(context.Background())
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var s SpanData
+		if err := json.Unmarshal(scanner.Bytes(), &s); err != nil {
+			t.Fatalf("bad line %q: %v", scanner.Text(), err)
+		}
+		names = append(names, s.Name)
+	}
+	if len(names) != 2 || names[0] != "llm.chat" || names[1] != "agent.turn" {
+		t.Errorf("file spans = %v", names)
+	}
+}
@@ -0,0 +1,47 @@
+// Package tui is the terminal interface of `picoclaw agent`.
+package tui
+
+import (
+	"context"
+
+	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// Backend is what the TUI needs from the agent.
+type Backend interface {
+	// Send runs one turn, reporting progress to onEvent, and returns the
+	// final response.
+	Send(ctx context.Context, sessionKey, input string, onEvent func(agent.TurnEvent)) (string, error)
+	// Sessions lists the stored session keys.
+	Sessions() []string
+	// History returns the messages of a session.
+	History(sessionKey string) []providers.Message
+	// Model returns the model a session currently uses.
+	Model(sessionKey string) string
+}
+
+type loopBackend struct {
+	loop *agent.AgentLoop
+}
+
+// NewBackend returns a Backend for the default agent of loop.
+func NewBackend(loop *agent.AgentLoop) Backend {
+	return &loopBackend{loop: loop}
+}
+
+func (b *loopBackend) Send(ctx context.Context, sessionKey, input string, onEvent func(agent.TurnEvent)) (string, error) {
+	return b.loop.ProcessDirect(agent.WithTurnObserver(ctx, onEvent, true), input, sessionKey)
+}
+
+func (b *loopBackend) Sessions() []string {
+	return b.loop.Sessions("").ListSessionKeys()
+}
+
+func (b *loopBackend) History(sessionKey string) []providers.Message {
+	return b.loop.Sessions("").GetHistory(sessionKey)
+}
+
+func (b *loopBackend) Model(sessionKey string) string {
+	return b.loop.ModelFor("", sessionKey)
+}
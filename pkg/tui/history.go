@@ -0,0 +1,96 @@
+package tui
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// maxHistory is how many inputs the history file keeps.
+const maxHistory = 500
+
+// inputHistory is the list of previous inputs, stored one JSON string per
+// line so multi-line inputs survive.
+type inputHistory struct {
+	path    string
+	entries []string
+	pos     int    // index being shown; len(entries) is the draft
+	draft   string // input typed before browsing
+}
+
+func loadHistory(path string) *inputHistory {
+	h := &inputHistory{path: path}
+	if path != "" {
+		if f, err := os.Open(path); err == nil {
+			scanner := bufio.NewScanner(f)
+			scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+			for scanner.Scan() {
+				var s string
+				if json.Unmarshal(scanner.Bytes(), &s) == nil && s != "" {
+					h.entries = append(h.entries, s)
+				}
+			}
+			f.Close()
+		}
+	}
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+	}
+	h.pos = len(h.entries)
+	return h
+}
+
+// add appends input and saves the history file.
+func (h *inputHistory) add(input string) error {
+	if n := len(h.entries); n == 0 || h.entries[n-1] != input {
+		h.entries = append(h.entries, input)
+	}
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+	}
+	h.pos = len(h.entries)
+	h.draft = ""
+	if h.path == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	for _, e := range h.entries {
+		line, _ := json.Marshal(e)
+		w.Write(append(line, '\n'))
+	}
+	return w.Flush()
+}
+
+// prev moves back from current, returning the input to show.
+func (h *inputHistory) prev(current string) (string, bool) {
+	if h.pos == 0 {
+		return "", false
+	}
+	if h.pos == len(h.entries) {
+		h.draft = current
+	}
+	h.pos--
+	return h.entries[h.pos], true
+}
+
+// next moves forward, ending at the draft.
+func (h *inputHistory) next() (string, bool) {
+	if h.pos >= len(h.entries) {
+		return "", false
+	}
+	h.pos++
+	if h.pos == len(h.entries) {
+		return h.draft, true
+	}
+	return h.entries[h.pos], true
+}
@@ -0,0 +1,626 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/commands"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// Options configures the TUI.
+type Options struct {
+	// Session is the session key to start in.
+	Session string
+	// HistoryPath is where input history is kept; empty keeps none.
+	HistoryPath string
+}
+
+// Run starts the TUI and returns when the user quits.
+func Run(ctx context.Context, backend Backend, opts Options) error {
+	m := New(ctx, backend, opts)
+	m.renderer = newMarkdownRenderer(terminalStyle())
+	_, err := tea.NewProgram(m, tea.WithAltScreen(), tea.WithContext(ctx)).Run()
+	return err
+}
+
+type entryKind int
+
+const (
+	entryUser entryKind = iota
+	entryAssistant
+	entryReasoning
+	entryTool
+	entryNotice
+	entryError
+)
+
+// entry is one block of the transcript.
+type entry struct {
+	kind     entryKind
+	text     string
+	tool     string
+	callID   string
+	args     map[string]interface{}
+	result   string
+	duration time.Duration
+	running  bool // still streaming or executing
+	expanded bool
+
+	// rendered caches the Markdown rendering of text at renderedWidth
+	rendered      string
+	renderedWidth int
+}
+
+func (e *entry) collapsible() bool {
+	return e.kind == entryTool || e.kind == entryReasoning
+}
+
+type focus int
+
+const (
+	focusInput focus = iota
+	focusTranscript
+	focusSessions
+)
+
+// Messages from the turn goroutine
+type (
+	turnEventMsg agent.TurnEvent
+	turnDoneMsg  struct {
+		response string
+		err      error
+	}
+)
+
+// Model is the bubbletea model of the TUI.
+type Model struct {
+	ctx     context.Context
+	backend Backend
+	history *inputHistory
+
+	session string
+	agentID string
+	model   string
+
+	entries  []*entry
+	selected int // index into entries while focusTranscript, -1 for none
+	focus    focus
+
+	// session switcher
+	sessionKeys []string
+	cursor      int
+
+	busy     bool
+	status   string
+	cancel   context.CancelFunc
+	events   chan tea.Msg
+	turnHead int // index of the first entry of the running turn
+
+	promptTokens     int
+	completionTokens int
+	costUSD          float64
+
+	width, height int
+	viewport      viewport.Model
+	input         textarea.Model
+	renderer      *markdownRenderer
+}
+
+// New returns the TUI model for backend.
+func New(ctx context.Context, backend Backend, opts Options) *Model {
+	input := textarea.New()
+	input.Placeholder = "Message, or /help"
+	input.ShowLineNumbers = false
+	input.Prompt = "┃ "
+	input.CharLimit = 0
+	input.SetHeight(1)
+	input.KeyMap.InsertNewline = key.NewBinding(key.WithKeys("alt+enter", "ctrl+j"))
+	input.Focus()
+
+	m := &Model{
+		ctx:      ctx,
+		backend:  backend,
+		history:  loadHistory(opts.HistoryPath),
+		selected: -1,
+		viewport: viewport.New(80, 20),
+		input:    input,
+		renderer: newMarkdownRenderer("notty"),
+		width:    80,
+		height:   24,
+	}
+	m.viewport.KeyMap = viewport.KeyMap{
+		PageDown:     key.NewBinding(key.WithKeys("pgdown")),
+		PageUp:       key.NewBinding(key.WithKeys("pgup")),
+		HalfPageUp:   key.NewBinding(key.WithKeys("ctrl+u")),
+		HalfPageDown: key.NewBinding(key.WithKeys("ctrl+d")),
+	}
+	m.switchSession(opts.Session)
+	return m
+}
+
+func (m *Model) Init() tea.Cmd {
+	return textarea.Blink
+}
+
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.layout()
+		return m, nil
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+
+	case tea.MouseMsg:
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		return m, cmd
+
+	case turnEventMsg:
+		m.applyEvent(agent.TurnEvent(msg))
+		m.refresh()
+		return m, m.waitForTurn()
+
+	case turnDoneMsg:
+		m.finishTurn(msg.response, msg.err)
+		m.refresh()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m *Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		if m.busy {
+			m.cancel()
+			m.status = "cancelling…"
+			return m, nil
+		}
+		return m, tea.Quit
+	case "ctrl+t":
+		m.toggleAll()
+		m.refresh()
+		return m, nil
+	case "ctrl+s":
+		m.openSwitcher()
+		return m, nil
+	case "pgup", "pgdown", "ctrl+u", "ctrl+d":
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		return m, cmd
+	}
+
+	switch m.focus {
+	case focusSessions:
+		return m.switcherKey(msg)
+	case focusTranscript:
+		return m.transcriptKey(msg)
+	}
+
+	switch msg.String() {
+	case "enter":
+		return m, m.submit()
+	case "esc":
+		m.focus = focusTranscript
+		m.input.Blur()
+		m.selectNext(-1, true)
+		m.refresh()
+		return m, nil
+	case "up":
+		if m.input.Line() == 0 {
+			if s, ok := m.history.prev(m.input.Value()); ok {
+				m.setInput(s)
+			}
+			return m, nil
+		}
+	case "down":
+		if m.input.Line() == m.input.LineCount()-1 {
+			if s, ok := m.history.next(); ok {
+				m.setInput(s)
+			}
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	m.layout()
+	return m, cmd
+}
+
+func (m *Model) transcriptKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		m.selectNext(-1, false)
+	case "down", "j":
+		m.selectNext(1, false)
+	case "enter", " ", "tab":
+		if m.selected >= 0 {
+			m.entries[m.selected].expanded = !m.entries[m.selected].expanded
+		}
+	case "esc", "i":
+		m.focus = focusInput
+		m.selected = -1
+		m.refresh()
+		return m, m.input.Focus()
+	}
+	m.refresh()
+	return m, nil
+}
+
+// selectNext moves the selection to the next collapsible entry in dir,
+// starting from the end when fromEnd is set.
+func (m *Model) selectNext(dir int, fromEnd bool) {
+	i := m.selected
+	if fromEnd || i < 0 {
+		i = len(m.entries)
+	}
+	for i += dir; i >= 0 && i < len(m.entries); i += dir {
+		if m.entries[i].collapsible() {
+			m.selected = i
+			return
+		}
+	}
+}
+
+func (m *Model) toggleAll() {
+	expand := false
+	for _, e := range m.entries {
+		if e.collapsible() && !e.expanded {
+			expand = true
+			break
+		}
+	}
+	m.setExpanded(expand)
+}
+
+func (m *Model) setExpanded(expanded bool) {
+	for _, e := range m.entries {
+		if e.collapsible() {
+			e.expanded = expanded
+		}
+	}
+}
+
+func (m *Model) setInput(s string) {
+	m.input.SetValue(s)
+	m.layout()
+}
+
+// submit handles the input: TUI commands here, everything else as a turn.
+func (m *Model) submit() tea.Cmd {
+	text := strings.TrimSpace(m.input.Value())
+	if text == "" {
+		return nil
+	}
+	if m.busy {
+		m.status = "still working — Ctrl+C cancels"
+		return nil
+	}
+	m.history.add(text)
+	m.input.Reset()
+	m.layout()
+
+	if text == "exit" || text == "quit" {
+		return tea.Quit
+	}
+	if name, args, ok := commands.Parse(text); ok {
+		if cmd, handled := m.command(name, args); handled {
+			m.refresh()
+			return cmd
+		}
+	}
+	return m.startTurn(text)
+}
+
+// command runs a TUI command, reporting false for commands the agent
+// should handle.
+func (m *Model) command(name, args string) (tea.Cmd, bool) {
+	switch name {
+	case "quit", "exit":
+		return tea.Quit, true
+	case "help":
+		m.notice(helpText())
+	case "clear":
+		m.entries = nil
+		m.selected = -1
+	case "sessions":
+		m.openSwitcher()
+	case "session":
+		if args == "" {
+			m.notice("Session: " + m.session)
+		} else {
+			m.switchSession(args)
+		}
+	case "expand":
+		m.setExpanded(true)
+	case "collapse":
+		m.setExpanded(false)
+	default:
+		return nil, false
+	}
+	return nil, true
+}
+
+func (m *Model) notice(text string) {
+	m.entries = append(m.entries, &entry{kind: entryNotice, text: text})
+}
+
+// startTurn sends input to the agent on another goroutine; its events
+// come back as messages.
+func (m *Model) startTurn(input string) tea.Cmd {
+	m.entries = append(m.entries, &entry{kind: entryUser, text: input})
+	m.turnHead = len(m.entries)
+	m.busy = true
+	m.status = "thinking…"
+	m.refresh()
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.cancel = cancel
+	events := make(chan tea.Msg, 256)
+	m.events = events
+	session := m.session
+	go func() {
+		defer close(events)
+		defer cancel()
+		response, err := m.backend.Send(ctx, session, input, func(ev agent.TurnEvent) {
+			events <- turnEventMsg(ev)
+		})
+		events <- turnDoneMsg{response: response, err: err}
+	}()
+	return m.waitForTurn()
+}
+
+func (m *Model) waitForTurn() tea.Cmd {
+	events := m.events
+	return func() tea.Msg {
+		msg, ok := <-events
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+// last returns the latest entry of kind in the running turn that is still
+// running, or nil.
+func (m *Model) last(kind entryKind) *entry {
+	for i := len(m.entries) - 1; i >= m.turnHead; i-- {
+		if e := m.entries[i]; e.kind == kind && e.running {
+			return e
+		}
+	}
+	return nil
+}
+
+func (m *Model) applyEvent(ev agent.TurnEvent) {
+	if ev.AgentID != "" {
+		m.agentID = ev.AgentID
+	}
+	if ev.Model != "" {
+		m.model = ev.Model
+	}
+
+	switch ev.Type {
+	case agent.EventLLMStart:
+		m.status = "thinking…"
+
+	case agent.EventDelta:
+		m.status = "writing…"
+		if ev.Reasoning != "" {
+			e := m.last(entryReasoning)
+			if e == nil {
+				e = &entry{kind: entryReasoning, running: true}
+				m.entries = append(m.entries, e)
+			}
+			e.text += ev.Reasoning
+		}
+		if ev.Content != "" {
+			e := m.last(entryAssistant)
+			if e == nil {
+				e = &entry{kind: entryAssistant, running: true}
+				m.entries = append(m.entries, e)
+			}
+			e.text += ev.Content
+			e.rendered = ""
+		}
+
+	case agent.EventLLMDone:
+		if ev.Usage != nil {
+			m.promptTokens += ev.Usage.PromptTokens
+			m.completionTokens += ev.Usage.CompletionTokens
+		}
+		m.costUSD += ev.CostUSD
+		if e := m.last(entryReasoning); e != nil {
+			e.running = false
+		} else if ev.Reasoning != "" {
+			m.entries = append(m.entries, &entry{kind: entryReasoning, text: ev.Reasoning})
+		}
+		if e := m.last(entryAssistant); e != nil {
+			e.running = false
+			e.text = ev.Content
+			e.rendered = ""
+		} else if ev.Content != "" {
+			m.entries = append(m.entries, &entry{kind: entryAssistant, text: ev.Content})
+		}
+
+	case agent.EventToolStart:
+		m.status = "running " + ev.Tool + "…"
+		m.entries = append(m.entries, &entry{kind: entryTool, tool: ev.Tool, callID: ev.ToolCallID, args: ev.Args, running: true})
+
+	case agent.EventToolDone:
+		m.status = "thinking…"
+		for i := len(m.entries) - 1; i >= m.turnHead; i-- {
+			if e := m.entries[i]; e.kind == entryTool && e.callID == ev.ToolCallID && e.running {
+				e.running = false
+				e.result = ev.Result
+				e.duration = ev.Duration
+				return
+			}
+		}
+	}
+}
+
+func (m *Model) finishTurn(response string, err error) {
+	m.busy = false
+	m.status = ""
+	m.cancel = nil
+	for _, e := range m.entries[m.turnHead:] {
+		e.running = false
+	}
+
+	if err != nil {
+		if m.ctx.Err() == nil && strings.Contains(err.Error(), context.Canceled.Error()) {
+			m.entries = append(m.entries, &entry{kind: entryNotice, text: "Cancelled."})
+		} else {
+			m.entries = append(m.entries, &entry{kind: entryError, text: err.Error()})
+		}
+		return
+	}
+
+	// Commands and post-processing can answer with text the LLM never wrote
+	var lastText string
+	for i := len(m.entries) - 1; i >= m.turnHead; i-- {
+		if m.entries[i].kind == entryAssistant {
+			lastText = m.entries[i].text
+			break
+		}
+	}
+	if response != "" && strings.TrimSpace(response) != strings.TrimSpace(lastText) {
+		m.entries = append(m.entries, &entry{kind: entryAssistant, text: response})
+	}
+	// /model and /agent change what the status line shows
+	m.model = m.backend.Model(m.session)
+}
+
+// switchSession shows the stored transcript of key and sends further input
+// to it.
+func (m *Model) switchSession(key string) {
+	m.session = key
+	m.model = m.backend.Model(key)
+	m.entries = entriesFromHistory(m.backend.History(key))
+	m.selected = -1
+	m.promptTokens, m.completionTokens, m.costUSD = 0, 0, 0
+	m.notice(fmt.Sprintf("Session %s", key))
+	m.refresh()
+	m.viewport.GotoBottom()
+}
+
+// entriesFromHistory rebuilds the transcript of a stored session.
+func entriesFromHistory(history []providers.Message) []*entry {
+	var entries []*entry
+	tools := make(map[string]*entry)
+	for _, msg := range history {
+		switch msg.Role {
+		case "user":
+			entries = append(entries, &entry{kind: entryUser, text: msg.Content})
+		case "assistant":
+			if msg.ReasoningContent != "" {
+				entries = append(entries, &entry{kind: entryReasoning, text: msg.ReasoningContent})
+			}
+			if msg.Content != "" {
+				entries = append(entries, &entry{kind: entryAssistant, text: msg.Content})
+			}
+			for _, tc := range msg.ToolCalls {
+				e := &entry{kind: entryTool, callID: tc.ID, tool: tc.Name}
+				if tc.Function != nil {
+					e.tool = tc.Function.Name
+					e.args = parseArgs(tc.Function.Arguments)
+				}
+				tools[tc.ID] = e
+				entries = append(entries, e)
+			}
+		case "tool":
+			if e, ok := tools[msg.ToolCallID]; ok {
+				e.result = msg.Content
+			}
+		}
+	}
+	return entries
+}
+
+func (m *Model) openSwitcher() {
+	m.sessionKeys = m.backend.Sessions()
+	if !contains(m.sessionKeys, m.session) {
+		m.sessionKeys = append(m.sessionKeys, m.session)
+	}
+	m.cursor = 0
+	for i, k := range m.sessionKeys {
+		if k == m.session {
+			m.cursor = i
+		}
+	}
+	m.focus = focusSessions
+	m.input.Blur()
+}
+
+func (m *Model) switcherKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.sessionKeys)-1 {
+			m.cursor++
+		}
+	case "enter":
+		if m.busy {
+			m.status = "wait for the turn to finish before switching"
+		} else if m.cursor < len(m.sessionKeys) {
+			m.switchSession(m.sessionKeys[m.cursor])
+		}
+		fallthrough
+	case "esc", "q":
+		m.focus = focusInput
+		return m, m.input.Focus()
+	}
+	return m, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func helpText() string {
+	var b strings.Builder
+	b.WriteString("Keys: Enter send · Alt+Enter/Ctrl+J newline · ↑/↓ history · PgUp/PgDn scroll\n")
+	b.WriteString("      Esc select panels (↑/↓, Enter toggles) · Ctrl+T toggle all · Ctrl+S sessions · Ctrl+C cancel/quit\n\n")
+	b.WriteString("Terminal commands:\n")
+	b.WriteString("  /sessions           Switch session\n")
+	b.WriteString("  /session [key]      Show or switch to a session\n")
+	b.WriteString("  /clear              Clear the screen\n")
+	b.WriteString("  /expand, /collapse  Expand or collapse tool and reasoning panels\n")
+	b.WriteString("  /quit               Exit\n\n")
+	b.WriteString("Agent commands:\n")
+	for _, c := range commands.Builtin {
+		if c.Name == "help" {
+			continue
+		}
+		usage := "/" + c.Name
+		if c.Args != "" {
+			usage += " " + c.Args
+		}
+		fmt.Fprintf(&b, "  %-19s %s\n", usage, c.Description)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
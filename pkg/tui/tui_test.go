@@ -0,0 +1,224 @@
+package tui
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+type fakeBackend struct {
+	events   []agent.TurnEvent
+	response string
+	sessions map[string][]providers.Message
+	inputs   []string
+}
+
+func (b *fakeBackend) Send(ctx context.Context, sessionKey, input string, onEvent func(agent.TurnEvent)) (string, error) {
+	b.inputs = append(b.inputs, sessionKey+": "+input)
+	for _, ev := range b.events {
+		onEvent(ev)
+	}
+	return b.response, nil
+}
+
+func (b *fakeBackend) Sessions() []string {
+	keys := make([]string, 0, len(b.sessions))
+	for k := range b.sessions {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (b *fakeBackend) History(sessionKey string) []providers.Message {
+	return b.sessions[sessionKey]
+}
+
+func (b *fakeBackend) Model(sessionKey string) string {
+	return "test-model"
+}
+
+// drive runs cmd and feeds its messages back into m until the turn ends.
+func drive(t *testing.T, m *Model, cmd tea.Cmd) {
+	t.Helper()
+	for i := 0; cmd != nil; i++ {
+		if i > 1000 {
+			t.Fatal("turn did not finish")
+		}
+		msg := cmd()
+		if msg == nil {
+			return
+		}
+		_, cmd = m.Update(msg)
+	}
+}
+
+func typeAndSend(t *testing.T, m *Model, text string) {
+	t.Helper()
+	m.input.SetValue(text)
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	drive(t, m, cmd)
+}
+
+func TestTurnEventsBuildTranscript(t *testing.T) {
+	backend := &fakeBackend{
+		events: []agent.TurnEvent{
+			{Type: agent.EventLLMStart, AgentID: "main", Model: "m1"},
+			{Type: agent.EventDelta, Reasoning: "need the file"},
+			{Type: agent.EventDelta, Content: "Let me "},
+			{Type: agent.EventDelta, Content: "look."},
+			{Type: agent.EventLLMDone, Model: "m1", Content: "Let me look.", Usage: &providers.UsageInfo{PromptTokens: 100, CompletionTokens: 10}, CostUSD: 0.01},
+			{Type: agent.EventToolStart, ToolCallID: "c1", Tool: "read_file", Args: map[string]interface{}{"path": "a.txt"}},
+			{Type: agent.EventToolDone, ToolCallID: "c1", Tool: "read_file", Result: "hello"},
+			{Type: agent.EventLLMStart, Model: "m1"},
+			{Type: agent.EventDelta, Content: "It says **hello**."},
+			{Type: agent.EventLLMDone, Model: "m1", Content: "It says **hello**.", Usage: &providers.UsageInfo{PromptTokens: 150, CompletionTokens: 5}, CostUSD: 0.02},
+		},
+		response: "It says **hello**.",
+	}
+	m := New(context.Background(), backend, Options{Session: "cli:default"})
+	typeAndSend(t, m, "what is in a.txt?")
+
+	var kinds []entryKind
+	for _, e := range m.entries[1:] { // after the session notice
+		kinds = append(kinds, e.kind)
+		if e.running {
+			t.Errorf("entry %+v still running after the turn", e)
+		}
+	}
+	want := []entryKind{entryUser, entryReasoning, entryAssistant, entryTool, entryAssistant}
+	if len(kinds) != len(want) {
+		t.Fatalf("entry kinds = %v, want %v", kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Fatalf("entry kinds = %v, want %v", kinds, want)
+		}
+	}
+	if tool := m.entries[4]; tool.result != "hello" || tool.expanded {
+		t.Errorf("tool entry = %+v", tool)
+	}
+	if m.promptTokens != 250 || m.completionTokens != 15 || m.costUSD < 0.0299 || m.costUSD > 0.0301 {
+		t.Errorf("usage = %d/%d $%f", m.promptTokens, m.completionTokens, m.costUSD)
+	}
+	if m.busy || m.agentID != "main" {
+		t.Errorf("busy = %v, agent = %q", m.busy, m.agentID)
+	}
+
+	view := m.View()
+	if !strings.Contains(view, "read_file") || !strings.Contains(view, "path=a.txt") || strings.Contains(view, "\nhello") {
+		t.Errorf("view should show a collapsed tool panel:\n%s", view)
+	}
+	if final := m.entries[5]; final.rendered == "" {
+		t.Error("finished assistant text should be rendered as Markdown")
+	}
+	if !strings.Contains(m.statusLine(), "cli:default") || !strings.Contains(m.statusLine(), "$0.0300") {
+		t.Errorf("status line = %q", m.statusLine())
+	}
+
+	// Esc selects the last panel, Enter expands it
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.entries[4].expanded {
+		t.Error("Enter on the selected tool panel should expand it")
+	}
+	if !strings.Contains(m.transcript(), "hello") {
+		t.Error("expanded tool panel should show the result")
+	}
+}
+
+func TestCommandResponseWithoutEvents(t *testing.T) {
+	backend := &fakeBackend{response: "Model: test-model"}
+	m := New(context.Background(), backend, Options{Session: "cli:default"})
+	typeAndSend(t, m, "/model")
+
+	last := m.entries[len(m.entries)-1]
+	if last.kind != entryAssistant || last.text != "Model: test-model" {
+		t.Errorf("last entry = %+v", last)
+	}
+	if len(backend.inputs) != 1 || backend.inputs[0] != "cli:default: /model" {
+		t.Errorf("agent commands should reach the backend: %q", backend.inputs)
+	}
+}
+
+func TestSessionSwitch(t *testing.T) {
+	backend := &fakeBackend{sessions: map[string][]providers.Message{
+		"cli:default": nil,
+		"cli:work": {
+			{Role: "user", Content: "list files"},
+			{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "c1", Type: "function", Function: &providers.FunctionCall{Name: "list_dir", Arguments: `{"path":"."}`}}}},
+			{Role: "tool", Content: "a.txt", ToolCallID: "c1"},
+			{Role: "assistant", Content: "One file."},
+		},
+	}}
+	m := New(context.Background(), backend, Options{Session: "cli:default"})
+
+	typeAndSend(t, m, "/session cli:work")
+	if m.session != "cli:work" {
+		t.Fatalf("session = %q", m.session)
+	}
+	var tool *entry
+	for _, e := range m.entries {
+		if e.kind == entryTool {
+			tool = e
+		}
+	}
+	if tool == nil || tool.tool != "list_dir" || tool.result != "a.txt" || tool.args["path"] != "." {
+		t.Errorf("tool entry from history = %+v", tool)
+	}
+
+	typeAndSend(t, m, "/sessions")
+	if m.focus != focusSessions || len(m.sessionKeys) != 2 {
+		t.Fatalf("switcher: focus = %v, keys = %v", m.focus, m.sessionKeys)
+	}
+	m.cursor = indexOf(m.sessionKeys, "cli:default")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.session != "cli:default" || m.focus != focusInput {
+		t.Errorf("after switch: session = %q, focus = %v", m.session, m.focus)
+	}
+
+	typeAndSend(t, m, "hi")
+	if backend.inputs[len(backend.inputs)-1] != "cli:default: hi" {
+		t.Errorf("inputs = %q", backend.inputs)
+	}
+}
+
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestInputHistoryPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	h := loadHistory(path)
+	h.add("first")
+	h.add("two\nlines")
+	h.add("two\nlines")
+
+	h = loadHistory(path)
+	if len(h.entries) != 2 {
+		t.Fatalf("entries = %q", h.entries)
+	}
+	if s, _ := h.prev("draft"); s != "two\nlines" {
+		t.Errorf("prev = %q", s)
+	}
+	if s, _ := h.prev(""); s != "first" {
+		t.Errorf("prev = %q", s)
+	}
+	if _, ok := h.prev(""); ok {
+		t.Error("prev past the oldest entry")
+	}
+	h.next()
+	if s, _ := h.next(); s != "draft" {
+		t.Errorf("next should return to the draft, got %q", s)
+	}
+}
@@ -0,0 +1,261 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	userStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Bold(true)
+	dimStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	toolStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	panelStyle    = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("8")).Padding(0, 1)
+	statusStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("15")).Background(lipgloss.Color("236")).Padding(0, 1)
+	busyStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Background(lipgloss.Color("236"))
+	switcherStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("12")).Padding(0, 1)
+)
+
+const (
+	// maxPanelResult is how much of a tool result an expanded panel shows.
+	maxPanelResult = 4000
+	// maxInputLines is how tall the input grows before it scrolls.
+	maxInputLines = 8
+)
+
+// markdownRenderer renders assistant text, rebuilding the glamour renderer
+// when the width changes.
+type markdownRenderer struct {
+	style    string
+	width    int
+	renderer *glamour.TermRenderer
+}
+
+// newMarkdownRenderer renders with a glamour standard style. The style is
+// fixed up front: detecting it queries the terminal, whose reply would
+// arrive as input once the TUI reads the keyboard.
+func newMarkdownRenderer(style string) *markdownRenderer {
+	return &markdownRenderer{style: style}
+}
+
+// terminalStyle picks the glamour style for the terminal's background.
+func terminalStyle() string {
+	if lipgloss.HasDarkBackground() {
+		return "dark"
+	}
+	return "light"
+}
+
+func (r *markdownRenderer) render(text string, width int) string {
+	if r.renderer == nil || r.width != width {
+		tr, err := glamour.NewTermRenderer(glamour.WithStandardStyle(r.style), glamour.WithWordWrap(width))
+		if err != nil {
+			return text
+		}
+		r.renderer, r.width = tr, width
+	}
+	out, err := r.renderer.Render(text)
+	if err != nil {
+		return text
+	}
+	return strings.Trim(out, "\n")
+}
+
+// layout sizes the viewport and input to the window.
+func (m *Model) layout() {
+	lines := m.input.LineCount()
+	if lines > maxInputLines {
+		lines = maxInputLines
+	}
+	m.input.SetWidth(m.width)
+	m.input.SetHeight(lines)
+
+	m.viewport.Width = m.width
+	m.viewport.Height = m.height - lines - 1 // status line
+	if m.viewport.Height < 1 {
+		m.viewport.Height = 1
+	}
+	m.refresh()
+}
+
+// refresh re-renders the transcript, following the bottom unless the user
+// scrolled up.
+func (m *Model) refresh() {
+	atBottom := m.viewport.AtBottom()
+	m.viewport.SetContent(m.transcript())
+	if atBottom && m.focus != focusTranscript {
+		m.viewport.GotoBottom()
+	}
+}
+
+func (m *Model) transcript() string {
+	width := m.width
+	if width < 20 {
+		width = 20
+	}
+	blocks := make([]string, 0, len(m.entries))
+	for i, e := range m.entries {
+		blocks = append(blocks, m.renderEntry(e, i == m.selected, width))
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+func (m *Model) renderEntry(e *entry, selected bool, width int) string {
+	switch e.kind {
+	case entryUser:
+		return userStyle.Render("› ") + e.text
+	case entryAssistant:
+		if e.running {
+			return e.text
+		}
+		if e.rendered == "" || e.renderedWidth != width {
+			e.rendered = m.renderer.render(e.text, width)
+			e.renderedWidth = width
+		}
+		return e.rendered
+	case entryNotice:
+		return dimStyle.Render(e.text)
+	case entryError:
+		return errorStyle.Render("Error: " + e.text)
+	}
+
+	// Collapsible panels
+	marker := "▸"
+	if e.expanded {
+		marker = "▾"
+	}
+	var header, body string
+	if e.kind == entryReasoning {
+		header = dimStyle.Render(fmt.Sprintf("%s thinking (%d chars)", marker, len(e.text)))
+		body = e.text
+	} else {
+		state := ""
+		switch {
+		case e.running:
+			state = " running…"
+		case e.duration > 0:
+			state = " " + e.duration.Round(time.Millisecond).String()
+		}
+		header = toolStyle.Render(fmt.Sprintf("%s %s", marker, e.tool)) + dimStyle.Render("("+argsSummary(e.args, width/2)+")"+state)
+		if e.expanded {
+			body = toolBody(e)
+		}
+	}
+	if selected {
+		header = selectedStyle.Render(header)
+	}
+	if !e.expanded || body == "" {
+		return header
+	}
+	return header + "\n" + panelStyle.Width(width-2).Render(body)
+}
+
+func toolBody(e *entry) string {
+	args, _ := json.MarshalIndent(e.args, "", "  ")
+	result := e.result
+	if len(result) > maxPanelResult {
+		result = result[:maxPanelResult] + fmt.Sprintf("\n… %d more bytes", len(e.result)-maxPanelResult)
+	}
+	if e.running {
+		result = "running…"
+	}
+	return dimStyle.Render("arguments") + "\n" + string(args) + "\n" + dimStyle.Render("result") + "\n" + result
+}
+
+// argsSummary is a one-line form of tool arguments, e.g. path=a.txt.
+func argsSummary(args map[string]interface{}, max int) string {
+	keys := make([]string, 0, len(args))
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		v := fmt.Sprint(args[k])
+		if s, ok := args[k].(string); ok {
+			v = s
+		}
+		parts = append(parts, k+"="+strings.ReplaceAll(v, "\n", "⏎"))
+	}
+	s := strings.Join(parts, ", ")
+	if max < 10 {
+		max = 10
+	}
+	if r := []rune(s); len(r) > max {
+		s = string(r[:max-1]) + "…"
+	}
+	return s
+}
+
+func parseArgs(raw string) map[string]interface{} {
+	var args map[string]interface{}
+	if json.Unmarshal([]byte(raw), &args) != nil {
+		return map[string]interface{}{"raw": raw}
+	}
+	return args
+}
+
+func (m *Model) statusLine() string {
+	model := m.model
+	if model == "" {
+		model = "?"
+	}
+	parts := []string{model}
+	if m.agentID != "" {
+		parts = append(parts, m.agentID)
+	}
+	parts = append(parts, m.session,
+		fmt.Sprintf("%s in / %s out", formatTokens(m.promptTokens), formatTokens(m.completionTokens)),
+		fmt.Sprintf("$%.4f", m.costUSD))
+	left := strings.Join(parts, " · ")
+	right := ""
+	switch {
+	case m.status != "":
+		right = m.status
+	case m.focus == focusTranscript:
+		right = "↑/↓ select · Enter toggle · Esc back"
+	}
+	gap := m.width - lipgloss.Width(left) - lipgloss.Width(right) - 2
+	if gap < 1 {
+		gap = 1
+	}
+	return statusStyle.Width(m.width).Render(left + strings.Repeat(" ", gap) + busyStyle.Render(right))
+}
+
+func formatTokens(n int) string {
+	if n >= 1000 {
+		return fmt.Sprintf("%.1fk", float64(n)/1000)
+	}
+	return fmt.Sprint(n)
+}
+
+func (m *Model) switcher() string {
+	var b strings.Builder
+	b.WriteString("Sessions (Enter switch · Esc close)\n\n")
+	for i, k := range m.sessionKeys {
+		line := "  " + k
+		if k == m.session {
+			line += dimStyle.Render(" (current)")
+		}
+		if i == m.cursor {
+			line = selectedStyle.Render("› " + k)
+		}
+		b.WriteString(line + "\n")
+	}
+	return switcherStyle.Render(strings.TrimRight(b.String(), "\n"))
+}
+
+func (m *Model) View() string {
+	main := m.viewport.View()
+	if m.focus == focusSessions {
+		main = lipgloss.Place(m.viewport.Width, m.viewport.Height, lipgloss.Center, lipgloss.Center, m.switcher())
+	}
+	return main + "\n" + m.statusLine() + "\n" + m.input.View()
+}
@@ -0,0 +1,70 @@
+package voice
+
+import (
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// NewTranscriberFromConfig builds the transcriber selected by
+// voice.transcription. It returns nil when transcription is disabled or no
+// backend is configured.
+func NewTranscriberFromConfig(cfg *config.Config) Transcriber {
+	tc := cfg.Voice.Transcription
+	name := tc.Provider
+	if name == "none" {
+		return nil
+	}
+
+	opts := OpenAITranscriberOptions{
+		Name:       name,
+		APIBase:    tc.APIBase,
+		APIKey:     tc.APIKey,
+		Model:      tc.Model,
+		Language:   tc.Language,
+		Prompt:     tc.Prompt,
+		ConvertTo:  tc.ConvertTo,
+		FFmpegPath: tc.FFmpegPath,
+		Timeout:    time.Duration(tc.TimeoutSeconds) * time.Second,
+	}
+
+	// Without an explicit backend, keep the historical behaviour of using
+	// Groq whenever it has an API key.
+	if name == "" && opts.APIBase == "" {
+		if groq := cfg.GetProviderConfig("groq"); groq != nil && groq.APIKey != "" {
+			name = "groq"
+			opts.Name = name
+		} else {
+			return nil
+		}
+	}
+	if name == "" {
+		opts.Name = "custom"
+	}
+
+	if name != "" {
+		if p := cfg.GetProviderConfig(name); p != nil {
+			if opts.APIBase == "" {
+				opts.APIBase = p.APIBase
+			}
+			if opts.APIKey == "" {
+				opts.APIKey = p.APIKey
+			}
+		}
+	}
+	if opts.APIBase == "" {
+		logger.WarnCF("voice", "Transcription backend has no api_base, disabling transcription",
+			map[string]interface{}{"provider": name})
+		return nil
+	}
+
+	if opts.Model == "" {
+		opts.Model = "whisper-1"
+		if name == "groq" {
+			opts.Model = "whisper-large-v3"
+		}
+	}
+
+	return NewOpenAITranscriber(opts)
+}
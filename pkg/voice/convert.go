@@ -0,0 +1,90 @@
+package voice
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// oggExtensions are the container formats Telegram, WhatsApp, Feishu and
+// DingTalk use for voice notes (OGG/Opus and friends).
+var oggExtensions = map[string]bool{
+	".ogg":  true,
+	".oga":  true,
+	".opus": true,
+	".amr":  true,
+	".silk": true,
+}
+
+// NeedsConversion reports whether path is a voice-note format that should be
+// converted to format before upload.
+func NeedsConversion(path, format string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return oggExtensions[ext] && ext != "."+strings.TrimPrefix(format, ".")
+}
+
+// ConvertAudio converts src to format ("wav" or "mp3") as 16 kHz mono using
+// ffmpeg and returns the path of a temporary file the caller must remove.
+func ConvertAudio(ctx context.Context, ffmpegPath, src, format string) (string, error) {
+	format = strings.TrimPrefix(format, ".")
+	if format != "wav" && format != "mp3" {
+		return "", fmt.Errorf("unsupported conversion format %q", format)
+	}
+
+	out, err := os.CreateTemp("", "picoclaw-voice-*."+format)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	dst := out.Name()
+	out.Close()
+
+	if err := convertAudioFile(ctx, ffmpegPath, src, dst, format); err != nil {
+		os.Remove(dst)
+		return "", err
+	}
+	return dst, nil
+}
+
+// convertAudioFile writes src to dst as 16 kHz mono in format: "wav",
+// "mp3" or "ogg" (Opus).
+func convertAudioFile(ctx context.Context, ffmpegPath, src, dst, format string) error {
+	if ffmpegPath == "" {
+		ffmpegPath = "ffmpeg"
+	}
+
+	var codec []string
+	switch format {
+	case "wav":
+		codec = []string{"-c:a", "pcm_s16le"}
+	case "mp3":
+		codec = []string{"-c:a", "libmp3lame", "-q:a", "4"}
+	case "ogg":
+		codec = []string{"-c:a", "libopus", "-b:a", "32k"}
+	default:
+		return fmt.Errorf("unsupported conversion format %q", format)
+	}
+
+	args := append([]string{"-y", "-loglevel", "error", "-i", src, "-ar", "16000", "-ac", "1"}, codec...)
+	args = append(args, dst)
+	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// IsAudioFile reports whether path looks like an audio file by extension.
+func IsAudioFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	if oggExtensions[ext] {
+		return true
+	}
+	switch ext {
+	case ".mp3", ".m4a", ".wav", ".aac", ".flac", ".webm", ".mpga", ".mpeg":
+		return true
+	}
+	return false
+}
@@ -10,15 +10,16 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
-type GroqTranscriber struct {
-	apiKey     string
-	apiBase    string
-	httpClient *http.Client
+// Transcriber converts an audio file to text.
+type Transcriber interface {
+	Transcribe(ctx context.Context, audioFilePath string) (*TranscriptionResponse, error)
+	IsAvailable() bool
 }
 
 type TranscriptionResponse struct {
@@ -27,44 +28,104 @@ type TranscriptionResponse struct {
 	Duration float64 `json:"duration,omitempty"`
 }
 
-func NewGroqTranscriber(apiKey string) *GroqTranscriber {
-	logger.DebugCF("voice", "Creating Groq transcriber", map[string]interface{}{"has_api_key": apiKey != ""})
+// OpenAITranscriberOptions configures an OpenAITranscriber.
+type OpenAITranscriberOptions struct {
+	Name       string // backend name for logs
+	APIBase    string
+	APIKey     string
+	Model      string
+	Language   string // ISO-639-1 hint; overrides per-message hints
+	Prompt     string
+	ConvertTo  string // target format for OGG/Opus input, "" to send as-is
+	FFmpegPath string
+	Timeout    time.Duration
+}
+
+// OpenAITranscriber talks to any server implementing the OpenAI
+// /audio/transcriptions API (OpenAI, Groq, faster-whisper-server,
+// whisper.cpp server, ...).
+type OpenAITranscriber struct {
+	opts       OpenAITranscriberOptions
+	httpClient *http.Client
+}
+
+func NewOpenAITranscriber(opts OpenAITranscriberOptions) *OpenAITranscriber {
+	if opts.Name == "" {
+		opts.Name = "openai"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 60 * time.Second
+	}
+	opts.APIBase = strings.TrimRight(opts.APIBase, "/")
+
+	logger.DebugCF("voice", "Creating transcriber", map[string]interface{}{
+		"backend":     opts.Name,
+		"api_base":    opts.APIBase,
+		"model":       opts.Model,
+		"has_api_key": opts.APIKey != "",
+	})
 
-	apiBase := "https://api.groq.com/openai/v1"
-	return &GroqTranscriber{
-		apiKey:  apiKey,
-		apiBase: apiBase,
+	return &OpenAITranscriber{
+		opts: opts,
 		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
+			Timeout: opts.Timeout,
 		},
 	}
 }
 
-func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string) (*TranscriptionResponse, error) {
-	logger.InfoCF("voice", "Starting transcription", map[string]interface{}{"audio_file": audioFilePath})
+// NewGroqTranscriber returns a transcriber for Groq's hosted Whisper.
+func NewGroqTranscriber(apiKey string) *OpenAITranscriber {
+	return NewOpenAITranscriber(OpenAITranscriberOptions{
+		Name:    "groq",
+		APIBase: "https://api.groq.com/openai/v1",
+		APIKey:  apiKey,
+		Model:   "whisper-large-v3",
+	})
+}
+
+func (t *OpenAITranscriber) Transcribe(ctx context.Context, audioFilePath string) (*TranscriptionResponse, error) {
+	logger.InfoCF("voice", "Starting transcription", map[string]interface{}{
+		"audio_file": audioFilePath,
+		"backend":    t.opts.Name,
+	})
+
+	uploadPath := audioFilePath
+	if t.opts.ConvertTo != "" && NeedsConversion(audioFilePath, t.opts.ConvertTo) {
+		converted, err := ConvertAudio(ctx, t.opts.FFmpegPath, audioFilePath, t.opts.ConvertTo)
+		if err != nil {
+			// Many servers accept OGG directly, so try the original file.
+			logger.WarnCF("voice", "Audio conversion failed, sending original file", map[string]interface{}{
+				"path":  audioFilePath,
+				"error": err.Error(),
+			})
+		} else {
+			defer os.Remove(converted)
+			uploadPath = converted
+		}
+	}
 
-	audioFile, err := os.Open(audioFilePath)
+	audioFile, err := os.Open(uploadPath)
 	if err != nil {
-		logger.ErrorCF("voice", "Failed to open audio file", map[string]interface{}{"path": audioFilePath, "error": err})
+		logger.ErrorCF("voice", "Failed to open audio file", map[string]interface{}{"path": uploadPath, "error": err})
 		return nil, fmt.Errorf("failed to open audio file: %w", err)
 	}
 	defer audioFile.Close()
 
 	fileInfo, err := audioFile.Stat()
 	if err != nil {
-		logger.ErrorCF("voice", "Failed to get file info", map[string]interface{}{"path": audioFilePath, "error": err})
+		logger.ErrorCF("voice", "Failed to get file info", map[string]interface{}{"path": uploadPath, "error": err})
 		return nil, fmt.Errorf("failed to get file info: %w", err)
 	}
 
 	logger.DebugCF("voice", "Audio file details", map[string]interface{}{
 		"size_bytes": fileInfo.Size(),
-		"file_name":  filepath.Base(audioFilePath),
+		"file_name":  filepath.Base(uploadPath),
 	})
 
 	var requestBody bytes.Buffer
 	writer := multipart.NewWriter(&requestBody)
 
-	part, err := writer.CreateFormFile("file", filepath.Base(audioFilePath))
+	part, err := writer.CreateFormFile("file", filepath.Base(uploadPath))
 	if err != nil {
 		logger.ErrorCF("voice", "Failed to create form file", map[string]interface{}{"error": err})
 		return nil, fmt.Errorf("failed to create form file: %w", err)
@@ -78,14 +139,21 @@ func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string)
 
 	logger.DebugCF("voice", "File copied to request", map[string]interface{}{"bytes_copied": copied})
 
-	if err := writer.WriteField("model", "whisper-large-v3"); err != nil {
-		logger.ErrorCF("voice", "Failed to write model field", map[string]interface{}{"error": err})
-		return nil, fmt.Errorf("failed to write model field: %w", err)
+	fields := map[string]string{
+		"model":           t.opts.Model,
+		"response_format": "json",
 	}
-
-	if err := writer.WriteField("response_format", "json"); err != nil {
-		logger.ErrorCF("voice", "Failed to write response_format field", map[string]interface{}{"error": err})
-		return nil, fmt.Errorf("failed to write response_format field: %w", err)
+	if lang := t.language(ctx); lang != "" {
+		fields["language"] = lang
+	}
+	if t.opts.Prompt != "" {
+		fields["prompt"] = t.opts.Prompt
+	}
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			logger.ErrorCF("voice", "Failed to write form field", map[string]interface{}{"field": name, "error": err})
+			return nil, fmt.Errorf("failed to write %s field: %w", name, err)
+		}
 	}
 
 	if err := writer.Close(); err != nil {
@@ -93,7 +161,7 @@ func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string)
 		return nil, fmt.Errorf("failed to close multipart writer: %w", err)
 	}
 
-	url := t.apiBase + "/audio/transcriptions"
+	url := t.opts.APIBase + "/audio/transcriptions"
 	req, err := http.NewRequestWithContext(ctx, "POST", url, &requestBody)
 	if err != nil {
 		logger.ErrorCF("voice", "Failed to create request", map[string]interface{}{"error": err})
@@ -101,9 +169,12 @@ func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string)
 	}
 
 	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	if t.opts.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.opts.APIKey)
+	}
 
-	logger.DebugCF("voice", "Sending transcription request to Groq API", map[string]interface{}{
+	logger.DebugCF("voice", "Sending transcription request", map[string]interface{}{
+		"backend":            t.opts.Name,
 		"url":                url,
 		"request_size_bytes": requestBody.Len(),
 		"file_size_bytes":    fileInfo.Size(),
@@ -130,7 +201,7 @@ func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string)
 		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
 	}
 
-	logger.DebugCF("voice", "Received response from Groq API", map[string]interface{}{
+	logger.DebugCF("voice", "Received transcription response", map[string]interface{}{
 		"status_code":         resp.StatusCode,
 		"response_size_bytes": len(body),
 	})
@@ -140,6 +211,7 @@ func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string)
 		logger.ErrorCF("voice", "Failed to unmarshal response", map[string]interface{}{"error": err})
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
+	result.Text = strings.TrimSpace(result.Text)
 
 	logger.InfoCF("voice", "Transcription completed successfully", map[string]interface{}{
 		"text_length":           len(result.Text),
@@ -151,12 +223,47 @@ func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string)
 	return &result, nil
 }
 
-func (t *GroqTranscriber) IsAvailable() bool {
-	available := t.apiKey != ""
+// language returns the configured language, or the per-message hint.
+func (t *OpenAITranscriber) language(ctx context.Context) string {
+	if t.opts.Language != "" {
+		return normalizeLanguage(t.opts.Language)
+	}
+	return normalizeLanguage(LanguageHint(ctx))
+}
+
+func (t *OpenAITranscriber) IsAvailable() bool {
+	available := t.opts.APIBase != "" && t.opts.Model != ""
 	logger.DebugCF("voice", "Checking transcriber availability", map[string]interface{}{"available": available})
 	return available
 }
 
+type languageHintKey struct{}
+
+// WithLanguageHint attaches a language hint (e.g. a user's client locale)
+// used when no language is configured.
+func WithLanguageHint(ctx context.Context, lang string) context.Context {
+	if lang == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, languageHintKey{}, lang)
+}
+
+// LanguageHint returns the hint set by WithLanguageHint.
+func LanguageHint(ctx context.Context) string {
+	lang, _ := ctx.Value(languageHintKey{}).(string)
+	return lang
+}
+
+// normalizeLanguage reduces locale tags like "pt-BR" to the ISO-639-1 code
+// Whisper expects.
+func normalizeLanguage(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	return lang
+}
+
 func truncateText(text string, maxLen int) string {
 	if len(text) <= maxLen {
 		return text
@@ -0,0 +1,115 @@
+package voice
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func writeTestAudio(t *testing.T, name string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte("fake audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOpenAITranscriberSendsModelAndLanguage(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("no key configured, got Authorization %q", auth)
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatal(err)
+		}
+		got = map[string]string{
+			"model":    r.FormValue("model"),
+			"language": r.FormValue("language"),
+		}
+		if _, hdr, err := r.FormFile("file"); err != nil || hdr.Filename != "note.ogg" {
+			t.Errorf("file part: %v %v", hdr, err)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"text": " hello world "})
+	}))
+	defer srv.Close()
+
+	tr := NewOpenAITranscriber(OpenAITranscriberOptions{
+		APIBase: srv.URL + "/v1/",
+		Model:   "Systran/faster-whisper-small",
+	})
+	if !tr.IsAvailable() {
+		t.Fatal("local backend without key should be available")
+	}
+
+	ctx := WithLanguageHint(context.Background(), "pt-BR")
+	res, err := tr.Transcribe(ctx, writeTestAudio(t, "note.ogg"))
+	if err != nil {
+		t.Fatalf("Transcribe: %v", err)
+	}
+	if res.Text != "hello world" {
+		t.Errorf("text = %q", res.Text)
+	}
+	if got["model"] != "Systran/faster-whisper-small" || got["language"] != "pt" {
+		t.Errorf("form fields = %v", got)
+	}
+}
+
+func TestConfiguredLanguageWinsOverHint(t *testing.T) {
+	tr := NewOpenAITranscriber(OpenAITranscriberOptions{APIBase: "http://x", Model: "m", Language: "de"})
+	if lang := tr.language(WithLanguageHint(context.Background(), "en")); lang != "de" {
+		t.Errorf("language = %q, want de", lang)
+	}
+}
+
+func TestNewTranscriberFromConfig(t *testing.T) {
+	cfg := config.DefaultConfig()
+	if NewTranscriberFromConfig(cfg) != nil {
+		t.Error("no backend configured should return nil")
+	}
+
+	cfg.Providers = config.ProvidersConfig{"groq": {APIKey: "gk", APIBase: "https://api.groq.com/openai/v1"}}
+	tr, ok := NewTranscriberFromConfig(cfg).(*OpenAITranscriber)
+	if !ok || tr.opts.Model != "whisper-large-v3" || tr.opts.APIKey != "gk" {
+		t.Fatalf("legacy groq fallback: %+v", tr)
+	}
+
+	cfg.Voice.Transcription = config.TranscriptionConfig{APIBase: "http://localhost:8080/v1", Model: "base.en"}
+	tr = NewTranscriberFromConfig(cfg).(*OpenAITranscriber)
+	if tr.opts.APIBase != "http://localhost:8080/v1" || tr.opts.APIKey != "" || tr.opts.Model != "base.en" {
+		t.Errorf("custom backend: %+v", tr.opts)
+	}
+
+	cfg.Voice.Transcription = config.TranscriptionConfig{Provider: "none"}
+	if NewTranscriberFromConfig(cfg) != nil {
+		t.Error("provider none should disable transcription")
+	}
+}
+
+func TestNeedsConversion(t *testing.T) {
+	tests := []struct {
+		path, format string
+		want         bool
+	}{
+		{"a.ogg", "wav", true},
+		{"a.OPUS", "wav", true},
+		{"a.wav", "wav", false},
+		{"a.mp3", "wav", false},
+		{"a.ogg", "ogg", false},
+	}
+	for _, tt := range tests {
+		if got := NeedsConversion(tt.path, tt.format); got != tt.want {
+			t.Errorf("NeedsConversion(%q, %q) = %v", tt.path, tt.format, got)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package voice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// TTS modes.
+const (
+	TTSModeOff       = "off"
+	TTSModeAlways    = "always"
+	TTSModeWhenVoice = "when_voice"
+)
+
+// Speech is a synthesized audio file.
+type Speech struct {
+	Path   string
+	Format string // file extension without the dot, e.g. "ogg"
+}
+
+// Synthesizer converts text to an audio file.
+type Synthesizer interface {
+	Synthesize(ctx context.Context, text string) (*Speech, error)
+}
+
+// fileExt maps an output format to the file extension players expect.
+// OpenAI's "opus" output is an Ogg container.
+func fileExt(format string) string {
+	switch format {
+	case "", "opus":
+		return "ogg"
+	default:
+		return format
+	}
+}
+
+// speechMaxAge is how long synthesized replies are kept. Channels send them
+// asynchronously, so they are pruned by age rather than after sending.
+const speechMaxAge = time.Hour
+
+func speechPath(format string) (string, error) {
+	dir := filepath.Join(os.TempDir(), "picoclaw_media", "tts")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	pruneSpeech(dir, time.Now().Add(-speechMaxAge))
+	return filepath.Join(dir, fmt.Sprintf("reply-%d.%s", time.Now().UnixNano(), fileExt(format))), nil
+}
+
+// pruneSpeech removes replies in dir last modified before cutoff.
+func pruneSpeech(dir string, cutoff time.Time) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if info, err := e.Info(); err == nil && info.Mode().IsRegular() && info.ModTime().Before(cutoff) {
+			os.Remove(filepath.Join(dir, e.Name()))
+		}
+	}
+}
+
+// OpenAISynthesizer calls an OpenAI-compatible /audio/speech endpoint.
+type OpenAISynthesizer struct {
+	apiBase    string
+	apiKey     string
+	model      string
+	voice      string
+	format     string
+	speed      float64
+	httpClient *http.Client
+}
+
+func NewOpenAISynthesizer(apiBase, apiKey, model, voiceName, format string, speed float64, timeout time.Duration) *OpenAISynthesizer {
+	if model == "" {
+		model = "tts-1"
+	}
+	if voiceName == "" {
+		voiceName = "alloy"
+	}
+	if format == "" {
+		format = "opus"
+	}
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+	return &OpenAISynthesizer{
+		apiBase:    strings.TrimRight(apiBase, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		voice:      voiceName,
+		format:     format,
+		speed:      speed,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+func (s *OpenAISynthesizer) Synthesize(ctx context.Context, text string) (*Speech, error) {
+	reqBody := map[string]interface{}{
+		"model":           s.model,
+		"input":           text,
+		"voice":           s.voice,
+		"response_format": s.format,
+	}
+	if s.speed > 0 {
+		reqBody["speed"] = s.speed
+	}
+	data, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.apiBase+"/audio/speech", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	path, err := speechPath(s.format)
+	if err != nil {
+		return nil, err
+	}
+	out, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to write audio: %w", err)
+	}
+
+	logger.DebugCF("voice", "Speech synthesized", map[string]interface{}{
+		"path":  path,
+		"chars": len(text),
+	})
+	return &Speech{Path: path, Format: fileExt(s.format)}, nil
+}
+
+// CommandSynthesizer runs a local TTS engine such as piper or espeak-ng.
+// The command writes format (wav by default); other formats than Ogg/Opus
+// are converted with ffmpeg, since voice notes must be Ogg/Opus.
+type CommandSynthesizer struct {
+	command    []string
+	format     string
+	ffmpegPath string
+	timeout    time.Duration
+}
+
+func NewCommandSynthesizer(command []string, format, ffmpegPath string, timeout time.Duration) *CommandSynthesizer {
+	if format == "" {
+		format = "wav"
+	}
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+	return &CommandSynthesizer{command: command, format: format, ffmpegPath: ffmpegPath, timeout: timeout}
+}
+
+func (s *CommandSynthesizer) Synthesize(ctx context.Context, text string) (*Speech, error) {
+	if len(s.command) == 0 {
+		return nil, fmt.Errorf("tts command is empty")
+	}
+	path, err := speechPath(s.format)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	if err := s.run(ctx, text, path); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	if fileExt(s.format) == "ogg" {
+		return &Speech{Path: path, Format: "ogg"}, nil
+	}
+
+	defer os.Remove(path)
+	oggPath, err := speechPath("ogg")
+	if err != nil {
+		return nil, err
+	}
+	if err := convertAudioFile(ctx, s.ffmpegPath, path, oggPath, "ogg"); err != nil {
+		os.Remove(oggPath)
+		return nil, fmt.Errorf("converting speech to ogg/opus: %w", err)
+	}
+	return &Speech{Path: oggPath, Format: "ogg"}, nil
+}
+
+// run executes the command, writing the speech for text to path.
+func (s *CommandSynthesizer) run(ctx context.Context, text, path string) error {
+	usesText := false
+	args := make([]string, len(s.command))
+	for i, a := range s.command {
+		if strings.Contains(a, "{text}") {
+			usesText = true
+		}
+		a = strings.ReplaceAll(a, "{output}", path)
+		args[i] = strings.ReplaceAll(a, "{text}", text)
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	if !usesText {
+		cmd.Stdin = strings.NewReader(text)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("tts command failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+		return fmt.Errorf("tts command produced no audio at %s", path)
+	}
+	return nil
+}
+
+// NewSynthesizerFromConfig builds the synthesizer selected by voice.tts, or
+// returns nil when TTS is off everywhere or misconfigured.
+func NewSynthesizerFromConfig(cfg *config.Config) Synthesizer {
+	tc := cfg.Voice.TTS
+	enabled := tc.Mode != "" && tc.Mode != TTSModeOff
+	for _, mode := range tc.Channels {
+		if mode != TTSModeOff {
+			enabled = true
+		}
+	}
+	if !enabled {
+		return nil
+	}
+
+	timeout := time.Duration(tc.TimeoutSeconds) * time.Second
+	switch tc.Backend {
+	case "command":
+		if len(tc.Command) == 0 {
+			logger.WarnC("voice", "TTS backend is command but no command is set")
+			return nil
+		}
+		return NewCommandSynthesizer(tc.Command, tc.Format, cfg.Voice.Transcription.FFmpegPath, timeout)
+	case "", "openai":
+		apiBase, apiKey := tc.APIBase, tc.APIKey
+		provider := tc.Provider
+		if provider == "" {
+			provider = "openai"
+		}
+		if p := cfg.GetProviderConfig(provider); p != nil {
+			if apiBase == "" {
+				apiBase = p.APIBase
+			}
+			if apiKey == "" {
+				apiKey = p.APIKey
+			}
+		}
+		if apiBase == "" {
+			logger.WarnCF("voice", "TTS backend has no api_base, disabling voice replies",
+				map[string]interface{}{"provider": provider})
+			return nil
+		}
+		return NewOpenAISynthesizer(apiBase, apiKey, tc.Model, tc.Voice, tc.Format, tc.Speed, timeout)
+	default:
+		logger.WarnCF("voice", "Unknown TTS backend", map[string]interface{}{"backend": tc.Backend})
+		return nil
+	}
+}
@@ -0,0 +1,161 @@
+package voice
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestOpenAISynthesizerWritesAudio(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/speech" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer sk-test" {
+			t.Errorf("Authorization = %q", auth)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte("OggS fake"))
+	}))
+	defer srv.Close()
+
+	s := NewOpenAISynthesizer(srv.URL+"/v1/", "sk-test", "", "nova", "", 0, 0)
+	speech, err := s.Synthesize(context.Background(), "hello there")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(speech.Path)
+
+	if got["model"] != "tts-1" || got["voice"] != "nova" || got["response_format"] != "opus" || got["input"] != "hello there" {
+		t.Errorf("unexpected request body %v", got)
+	}
+	if _, ok := got["speed"]; ok {
+		t.Error("speed should be omitted when unset")
+	}
+	if speech.Format != "ogg" || !strings.HasSuffix(speech.Path, ".ogg") {
+		t.Errorf("opus output should be saved as .ogg, got %+v", speech)
+	}
+	if data, _ := os.ReadFile(speech.Path); string(data) != "OggS fake" {
+		t.Errorf("audio file content = %q", data)
+	}
+}
+
+func TestOpenAISynthesizerAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad voice", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	s := NewOpenAISynthesizer(srv.URL, "", "", "", "mp3", 0, 0)
+	if _, err := s.Synthesize(context.Background(), "hi"); err == nil || !strings.Contains(err.Error(), "bad voice") {
+		t.Errorf("expected API error, got %v", err)
+	}
+}
+
+func TestCommandSynthesizer(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	// The fake ffmpeg copies its input to its output
+	ffmpeg := filepath.Join(t.TempDir(), "ffmpeg")
+	script := "#!/bin/sh\nwhile [ $# -gt 1 ]; do [ \"$1\" = -i ] && src=$2; shift; done\ncp \"$src\" \"$1\"\n"
+	if err := os.WriteFile(ffmpeg, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Text arrives on stdin when {text} is not used; wav output is
+	// converted to ogg/opus
+	s := NewCommandSynthesizer([]string{"sh", "-c", "cat > \"$0\"", "{output}"}, "", ffmpeg, 0)
+	speech, err := s.Synthesize(context.Background(), "from stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(speech.Path)
+	if speech.Format != "ogg" || !strings.HasSuffix(speech.Path, ".ogg") {
+		t.Errorf("speech = %+v, want ogg", speech)
+	}
+	if data, _ := os.ReadFile(speech.Path); string(data) != "from stdin" {
+		t.Errorf("stdin text = %q", data)
+	}
+	if wav := strings.TrimSuffix(speech.Path, ".ogg") + ".wav"; fileExists(wav) {
+		t.Errorf("intermediate file left behind: %s", wav)
+	}
+
+	// Ogg output from the command is used as is
+	s = NewCommandSynthesizer([]string{"sh", "-c", "printf %s \"$1\" > \"$0\"", "{output}", "{text}"}, "opus", "/nonexistent/ffmpeg", 0)
+	speech, err = s.Synthesize(context.Background(), "as argument")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(speech.Path)
+	if data, _ := os.ReadFile(speech.Path); string(data) != "as argument" || !strings.HasSuffix(speech.Path, ".ogg") {
+		t.Errorf("arg text = %q at %s", data, speech.Path)
+	}
+
+	s = NewCommandSynthesizer([]string{"sh", "-c", "printf x > \"$0\"", "{output}"}, "mp3", "/nonexistent/ffmpeg", 0)
+	if _, err := s.Synthesize(context.Background(), "no ffmpeg"); err == nil {
+		t.Error("expected error when conversion fails")
+	}
+
+	s = NewCommandSynthesizer([]string{"sh", "-c", "true"}, "", ffmpeg, 0)
+	if _, err := s.Synthesize(context.Background(), "nothing"); err == nil {
+		t.Error("expected error when the command writes no audio")
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestPruneSpeech(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "reply-1.ogg")
+	fresh := filepath.Join(dir, "reply-2.ogg")
+	for _, p := range []string{old, fresh} {
+		if err := os.WriteFile(p, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	past := time.Now().Add(-2 * speechMaxAge)
+	os.Chtimes(old, past, past)
+
+	pruneSpeech(dir, time.Now().Add(-speechMaxAge))
+	if fileExists(old) || !fileExists(fresh) {
+		t.Errorf("expected only the old reply to be removed")
+	}
+}
+
+func TestNewSynthesizerFromConfig(t *testing.T) {
+	cfg := config.DefaultConfig()
+	if NewSynthesizerFromConfig(cfg) != nil {
+		t.Error("TTS should be off by default")
+	}
+
+	cfg.Providers = config.ProvidersConfig{}
+	cfg.Voice.TTS.Channels = map[string]string{"telegram": TTSModeWhenVoice}
+	if NewSynthesizerFromConfig(cfg) != nil {
+		t.Error("openai backend without api_base should be disabled")
+	}
+
+	cfg.Providers["openai"] = &config.ProviderConfig{APIBase: "https://api.openai.com/v1"}
+	if _, ok := NewSynthesizerFromConfig(cfg).(*OpenAISynthesizer); !ok {
+		t.Error("expected OpenAI synthesizer using provider credentials")
+	}
+
+	cfg.Voice.TTS.Backend = "command"
+	cfg.Voice.TTS.Command = []string{"piper", "--output_file", "{output}"}
+	if _, ok := NewSynthesizerFromConfig(cfg).(*CommandSynthesizer); !ok {
+		t.Error("expected command synthesizer")
+	}
+}